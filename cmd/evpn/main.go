@@ -45,15 +45,24 @@ import (
 	frr "github.com/opiproject/opi-evpn-bridge/pkg/frr"
 	netlink "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
 	intel_e2000_linux "github.com/opiproject/opi-intel-bridge/pkg/evpn/LinuxVendorModule/intele2000"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/debug"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/debug/debugpb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/mirror"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/mirror/mirrorpb"
 	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
 	ipu_vendor "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4translation"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 )
 
 const (
-	intelStr = "intel_e2000"
+	intelStr    = "intel_e2000"
+	intelSimStr = "intel_e2000_sim"
 )
 
+// defaultJournal is the journal file of the simulation build env
+const defaultJournal = "opi-evpn-bridge-journal.json"
+
 var rootCmd = &cobra.Command{
 	Use:   "opi-evpn-bridge",
 	Short: "evpn bridge",
@@ -75,6 +84,12 @@ var rootCmd = &cobra.Command{
 			intel_e2000_linux.Initialize()
 			frr.Initialize()
 			ipu_vendor.Initialize()
+		case intelSimStr:
+			// Run the intel-e2000 modules against the journal, nothing touches the host.
+			// gen_linux and frr configure the host through ip and vtysh and stay off.
+			openJournal()
+			intel_e2000_linux.Initialize()
+			ipu_vendor.Initialize()
 		default:
 			log.Panic(" ERROR: Could not find Build env ")
 		}
@@ -86,6 +101,8 @@ var rootCmd = &cobra.Command{
 		switch config.GlobalConfig.Buildenv {
 		case intelStr:
 			netlink.Initialize()
+		case intelSimStr:
+			go feedNetlink()
 		default:
 		}
 		runGrpcServer(config.GlobalConfig.GRPCPort, config.GlobalConfig.TLSFiles)
@@ -93,6 +110,147 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// replayJournal is the journal file the replayed entries are written to
+var replayJournal string
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <recording>",
+	Short: "replay recorded intel-e2000 events",
+	Long:  "feed an intel-e2000 event recording back through the decoders against the dry run driver and compare the programmed entries",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(_ *cobra.Command, args []string) {
+		var j *journal.Journal
+		if replayJournal != "" {
+			var err error
+			if j, err = journal.Open(replayJournal); err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening journal: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		diffs, err := ipu_vendor.Replay(args[0], j, os.Stdout)
+		_ = j.Close()
+		// the bridge was not started, exit before its clean up runs
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error replaying %s: %v\n", args[0], err)
+			os.Exit(1)
+		case diffs != 0:
+			fmt.Printf("%d events programmed different entries\n", diffs)
+			os.Exit(2)
+		}
+		fmt.Println("Replayed entries match the recording")
+		os.Exit(0)
+	},
+}
+
+// traceFlags are the packet fields given to the trace command
+var traceFlags struct {
+	port, vsi                    int
+	vlans                        []uint
+	vni                          uint32
+	hash                         uint16
+	proto                        uint8
+	srcPort, dstPort             uint16
+	arp                          bool
+	outerSrcMac, outerDstMac     string
+	outerSrcIP, outerDstIP       string
+	srcMac, dstMac, srcIP, dstIP string
+}
+
+var traceCmd = &cobra.Command{
+	Use:   "trace [recording]",
+	Short: "trace a packet through the intel-e2000 tables",
+	Long: "walk a synthetic packet through the desired entries of the running bridge, asked through its debug service, " +
+		"or through the entries an intel-e2000 event recording builds when one is given",
+	Args: cobra.MaximumNArgs(1),
+
+	Run: func(_ *cobra.Command, args []string) {
+		pkt, err := tracePacket()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error in packet description: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) == 0 {
+			res, err := traceLive(pkt)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error tracing through the bridge on port %d: %v\n", config.GlobalConfig.GRPCPort, err)
+				os.Exit(1)
+			}
+			fmt.Print(res)
+			os.Exit(0)
+		}
+		// the replay diffs are of no interest here, only the entries it leaves
+		if _, err := ipu_vendor.Replay(args[0], nil, io.Discard); err != nil {
+			fmt.Fprintf(os.Stderr, "Error replaying %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		fmt.Print(ipu_vendor.Trace(pkt))
+		os.Exit(0)
+	},
+}
+
+// traceLive traces the packet through the desired entries of the bridge
+// listening on the grpc port
+func traceLive(pkt ipu_vendor.TracePacket) (*ipu_vendor.TraceResult, error) {
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", config.GlobalConfig.GRPCPort),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := debugpb.NewDebugServiceClient(conn).Trace(ctx, &debugpb.TraceRequest{Packet: debug.TracePacketToProto(pkt)})
+	if err != nil {
+		return nil, err
+	}
+	return debug.TraceResultFromProto(resp)
+}
+
+// tracePacket builds the packet described by the trace flags
+func tracePacket() (ipu_vendor.TracePacket, error) {
+	f := traceFlags
+	pkt := ipu_vendor.TracePacket{Port: f.port, Vsi: f.vsi, Vni: f.vni, Hash: f.hash, Arp: f.arp,
+		Proto: f.proto, SrcPort: f.srcPort, DstPort: f.dstPort}
+	if (f.port < 0) == (f.vsi < 0) {
+		return pkt, fmt.Errorf("exactly one of --port and --vsi is needed")
+	}
+	for _, vid := range f.vlans {
+		pkt.Vlans = append(pkt.Vlans, uint16(vid))
+	}
+	var err error
+	for _, m := range []struct {
+		value string
+		mac   *net.HardwareAddr
+	}{
+		{f.outerSrcMac, &pkt.OuterSrcMac}, {f.outerDstMac, &pkt.OuterDstMac},
+		{f.srcMac, &pkt.SrcMac}, {f.dstMac, &pkt.DstMac},
+	} {
+		if m.value == "" {
+			continue
+		}
+		if *m.mac, err = net.ParseMAC(m.value); err != nil {
+			return pkt, err
+		}
+	}
+	for _, a := range []struct {
+		value string
+		ip    *net.IP
+	}{
+		{f.outerSrcIP, &pkt.OuterSrcIP}, {f.outerDstIP, &pkt.OuterDstIP},
+		{f.srcIP, &pkt.SrcIP}, {f.dstIP, &pkt.DstIP},
+	} {
+		if a.value == "" {
+			continue
+		}
+		if *a.ip = net.ParseIP(a.value); *a.ip == nil {
+			return pkt, fmt.Errorf("invalid ip address %s", a.value)
+		}
+	}
+	return pkt, nil
+}
+
 // initialize the cobra configuration and bind the flags
 func initialize() error {
 	cobra.OnInitialize(config.Initcfg)
@@ -104,6 +262,28 @@ func initialize() error {
 	rootCmd.PersistentFlags().StringVar(&config.GlobalConfig.DBAddress, "dbaddress", "127.0.0.1:6379", "db address in ip_address:port format")
 	rootCmd.PersistentFlags().StringVar(&config.GlobalConfig.Database, "database", "redis", "Database connection string")
 
+	replayCmd.Flags().StringVar(&replayJournal, "journal", "", "journal file the replayed entries are written to")
+	rootCmd.AddCommand(replayCmd)
+
+	traceCmd.Flags().IntVar(&traceFlags.port, "port", -1, "ingress physical port")
+	traceCmd.Flags().IntVar(&traceFlags.vsi, "vsi", -1, "ingress vsi")
+	traceCmd.Flags().UintSliceVar(&traceFlags.vlans, "vlan", nil, "vlan tags, outermost first")
+	traceCmd.Flags().Uint32Var(&traceFlags.vni, "vni", 0, "vni of a vxlan packet")
+	traceCmd.Flags().Uint16Var(&traceFlags.hash, "hash", 0, "ecmp hash of the flow")
+	traceCmd.Flags().BoolVar(&traceFlags.arp, "arp", false, "trace an arp packet")
+	traceCmd.Flags().StringVar(&traceFlags.outerSrcMac, "outer-src-mac", "", "outer source mac of a vxlan packet")
+	traceCmd.Flags().StringVar(&traceFlags.outerDstMac, "outer-dst-mac", "", "outer destination mac of a vxlan packet")
+	traceCmd.Flags().StringVar(&traceFlags.outerSrcIP, "outer-src-ip", "", "outer source ip of a vxlan packet")
+	traceCmd.Flags().StringVar(&traceFlags.outerDstIP, "outer-dst-ip", "", "outer destination ip of a vxlan packet")
+	traceCmd.Flags().StringVar(&traceFlags.srcMac, "src-mac", "", "source mac")
+	traceCmd.Flags().StringVar(&traceFlags.dstMac, "dst-mac", "", "destination mac")
+	traceCmd.Flags().StringVar(&traceFlags.srcIP, "src-ip", "", "source ip")
+	traceCmd.Flags().StringVar(&traceFlags.dstIP, "dst-ip", "", "destination ip")
+	traceCmd.Flags().Uint8Var(&traceFlags.proto, "proto", 0, "ip protocol")
+	traceCmd.Flags().Uint16Var(&traceFlags.srcPort, "src-port", 0, "tcp or udp source port")
+	traceCmd.Flags().Uint16Var(&traceFlags.dstPort, "dst-port", 0, "tcp or udp destination port")
+	rootCmd.AddCommand(traceCmd)
+
 	// Bind command-line flags to config fields
 	if err := viper.GetViper().BindPFlags(rootCmd.PersistentFlags()); err != nil {
 		log.Printf("Error binding flags to Viper: %v\n", err)
@@ -142,6 +322,12 @@ func cleanUp() {
 		netlink.DeInitialize()
 		ipu_vendor.DeInitialize()
 		close(p4driverapi.StopCh)
+	case intelSimStr:
+		intel_e2000_linux.DeInitialize()
+		ipu_vendor.DeInitialize()
+		if err := journal.Default.Close(); err != nil {
+			log.Println("Failed to close the journal: ", err)
+		}
 
 	default:
 		log.Panic(" ERROR: Could not find Build env ")
@@ -152,6 +338,32 @@ func cleanUp() {
 	}
 }
 
+// openJournal opens the journal the simulation build env records into
+func openJournal() {
+	viper.SetDefault("simulation.journal", defaultJournal)
+	j, err := journal.Open(viper.GetString("simulation.journal"))
+	if err != nil {
+		log.Panicf("Error opening journal: %v", err)
+	}
+	journal.Default = j
+	log.Printf("Simulation mode, recording into %s\n", viper.GetString("simulation.journal"))
+}
+
+// feedNetlink publishes the netlink events recorded in simulation.netlink in
+// place of the netlink watcher, which reads the routes and fdb of the host
+func feedNetlink() {
+	file := viper.GetString("simulation.netlink")
+	if file == "" {
+		log.Println("Simulation mode without simulation.netlink, no routes or fdb entries are fed")
+		return
+	}
+	fed, err := ipu_vendor.FeedNetlink(file, netlink.EventBus.Publish)
+	if err != nil {
+		log.Printf("Error feeding the netlink events of %s: %v", file, err)
+	}
+	log.Printf("Simulation mode, fed %d netlink events of %s\n", fed, file)
+}
+
 // main function
 func main() {
 	// setup file and console logger
@@ -163,25 +375,33 @@ func main() {
 	}
 
 	sigChan := make(chan os.Signal, 1)
-	// Notify sigChan on SIGINT or SIGTERM.
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// Notify sigChan on SIGINT, SIGTERM or SIGHUP.
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	// This goroutine executes a blocking receive for signals.
-	// When it gets one it will then exit the program.
+	// SIGHUP reloads the static p4 entries, acl and pbr policies, any other signal exits the program.
 	go func() {
-		sig := <-sigChan
-		switch sig {
-		case syscall.SIGINT:
-			cleanUp()
-			fmt.Println("Received SIGINT, shutting down.")
-		case syscall.SIGTERM:
-			cleanUp()
-			fmt.Println("Received SIGTERM, shutting down.")
-		default:
-			fmt.Println("Received unknown signal.")
+		for sig := range sigChan {
+			switch sig {
+			case syscall.SIGHUP:
+				if intelBuildenv() {
+					if err := ipu_vendor.Reload(); err != nil {
+						log.Printf("Reloading the p4 entries failed: %v", err)
+					}
+				}
+				continue
+			case syscall.SIGINT:
+				cleanUp()
+				fmt.Println("Received SIGINT, shutting down.")
+			case syscall.SIGTERM:
+				cleanUp()
+				fmt.Println("Received SIGTERM, shutting down.")
+			default:
+				fmt.Println("Received unknown signal.")
+			}
+			// Exit the program.
+			os.Exit(0)
 		}
-		// Exit the program.
-		os.Exit(0)
 	}()
 
 	// start the main cmd
@@ -247,6 +467,10 @@ func runGrpcServer(grpcPort uint16, tlsFiles string) {
 	pe.RegisterVrfServiceServer(s, vrfServer)
 	pe.RegisterSviServiceServer(s, sviServer)
 	pc.RegisterInventoryServiceServer(s, &inventory.Server{})
+	if intelBuildenv() {
+		debugpb.RegisterDebugServiceServer(s, debug.NewServer())
+		mirrorpb.RegisterMirrorServiceServer(s, mirror.NewServer())
+	}
 
 	reflection.Register(s)
 
@@ -272,6 +496,14 @@ func runGatewayServer(grpcPort uint16, httpPort uint16) {
 	if err != nil {
 		log.Panic("cannot register handler server")
 	}
+	if intelBuildenv() {
+		if err := debugpb.RegisterDebugServiceHandlerFromEndpoint(ctx, mux, fmt.Sprintf(":%d", grpcPort), opts); err != nil {
+			log.Panic("cannot register debug handler")
+		}
+		if err := mirrorpb.RegisterMirrorServiceHandlerFromEndpoint(ctx, mux, fmt.Sprintf(":%d", grpcPort), opts); err != nil {
+			log.Panic("cannot register mirror handler")
+		}
+	}
 
 	// Start HTTP server (and proxy calls to gRPC server endpoint)
 	log.Printf("HTTP Server listening at %v", httpPort)
@@ -287,6 +519,11 @@ func runGatewayServer(grpcPort uint16, httpPort uint16) {
 	}
 }
 
+// intelBuildenv tells whether the intel-e2000 plugin runs
+func intelBuildenv() bool {
+	return config.GlobalConfig.Buildenv == intelStr || config.GlobalConfig.Buildenv == intelSimStr
+}
+
 // createGrdVrf creates the grd vrf with vni 0
 func createGrdVrf() error {
 	grdVrf, err := infradb.NewVrfWithArgs("//network.opiproject.org/vrfs/GRD", nil, nil, nil)
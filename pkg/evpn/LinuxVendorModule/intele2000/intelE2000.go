@@ -12,7 +12,6 @@ import (
 
 	"log"
 	"math"
-	"net"
 	"os/exec"
 	"path"
 	"strconv"
@@ -24,12 +23,16 @@ import (
 	"github.com/opiproject/opi-evpn-bridge/pkg/infradb/common"
 	"github.com/opiproject/opi-evpn-bridge/pkg/infradb/subscriberframework/eventbus"
 	"github.com/opiproject/opi-evpn-bridge/pkg/utils"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/e2000config"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmacs"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmux"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vlanmap"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vport"
 	"github.com/vishvananda/netlink"
 )
 
-// portMux variable of type string
-var portMux string
-
 // vrfMux variable of type string
 var vrfMux string
 
@@ -160,31 +163,40 @@ func handlebp(objectData *eventbus.ObjectData) {
 	}
 }
 
-// MactoVport converts mac address to vport
-func MactoVport(mac *net.HardwareAddr) int {
-	byte0 := int((*mac)[0])
-	byte1 := int((*mac)[1])
-	return (byte0 << 8) + byte1
-}
-
 // setUpBp sets up a bridge port
 func setUpBp(bp *infradb.BridgePort) (string, bool) {
 	MacAddress := fmt.Sprintf("%+v", *bp.Spec.MacAddress)
-	vportID := MactoVport(bp.Spec.MacAddress)
+	vportID, err := vport.Of(*bp.Spec.MacAddress)
+	if err != nil {
+		log.Printf("LVM: Failed to resolve the vport of %s: %v\n", MacAddress, err)
+		return fmt.Sprintf("LVM: Failed to resolve the vport of %s: %v\n", MacAddress, err), false
+	}
 	link := fmt.Sprintf("vport-%+v", vportID)
-	vport := fmt.Sprintf("%+v", vportID)
-	bp.Metadata.VPort = vport
-	muxIntf, err := nlink.LinkByName(ctx, portMux)
+	bp.Metadata.VPort = strconv.Itoa(vportID)
+	mux, err := portmux.Assign(bp.Name, vportID)
 	if err != nil {
-		log.Printf("Failed to get link information for %s, error is %v\n", portMux, err)
-		return fmt.Sprintf("Failed to get link information for %s, error is %v\n", portMux, err), false
+		log.Printf("LVM: %v\n", err)
+		return fmt.Sprintf("LVM: %v\n", err), false
 	}
-	vlanLink := &netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Name: link, ParentIndex: muxIntf.Attrs().Index}, VlanId: vportID, VlanProtocol: netlink.VLAN_PROTOCOL_8021AD}
+	muxIntf, err := nlink.LinkByName(ctx, mux.Interface)
+	if err != nil {
+		log.Printf("Failed to get link information for %s, error is %v\n", mux.Interface, err)
+		return fmt.Sprintf("Failed to get link information for %s, error is %v\n", mux.Interface, err), false
+	}
+	vlanLink := &netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Name: link, ParentIndex: muxIntf.Attrs().Index}, VlanId: int(mux.Stag), VlanProtocol: netlink.VLAN_PROTOCOL_8021AD}
 	if err = nlink.LinkAdd(ctx, vlanLink); err != nil {
 		log.Printf("Failed to add VLAN sub-interface %s: %v\n", link, err)
 		return fmt.Sprintf("Failed to add VLAN sub-interface %s: %v\n", link, err), false
 	}
-	log.Printf("LVM: Executed ip link add link %s name %s type vlan protocol 802.1ad id %s\n", portMux, link, vport)
+	log.Printf("LVM: Executed ip link add link %s name %s type vlan protocol 802.1ad id %d\n", mux.Interface, link, mux.Stag)
+	// a failed set up removes the link so the retry finds the s-tag of the
+	// mux free again
+	done := false
+	defer func() {
+		if !done {
+			removeBpLink(vlanLink, mux.Interface)
+		}
+	}()
 	brIntf, err := nlink.LinkByName(ctx, brTenant)
 	if err != nil {
 		log.Printf("Failed to get link information for %s: %v\n", brTenant, err)
@@ -221,18 +233,109 @@ func setUpBp(bp *infradb.BridgePort) (string, bool) {
 		}
 		log.Printf("LVM: Executed bridge vlan add dev %s vid %d \n", link, vid)
 	}
+	if port, ok := vlanmap.Of(bp.Name); ok && bp.Spec.Ptype == infradb.Trunk && len(port.Vlans) != 0 {
+		// br-tenant sees the logical bridge vids, the tenant its own
+		if !setVlanTranslation(link, port.Vlans) {
+			return fmt.Sprintf("LVM: Failed to translate the vlans of %s\n", link), false
+		}
+		log.Printf("LVM: Translated the vlans %v of %s\n", port.Vlans, link)
+	}
 	if err = nlink.BridgeFdbAdd(ctx, link, MacAddress); err != nil {
 		log.Printf("LVM: Error in executing command %s %s with error %s\n", "bridge fdb add", link, err)
 		return fmt.Sprintf("LVM: Error in executing command %s %s with error %s\n", "bridge fdb add", link, err), false
 	}
 	log.Printf("LVM: Executed bridge fdb add %s dev %s master static extern_learn\n", MacAddress, link)
+	if port, ok := portmacs.Of(bp.Name); ok {
+		// the nested vms and containers behind the port, the entries go
+		// away with the link
+		for _, mac := range port.Macs {
+			if err = nlink.BridgeFdbAdd(ctx, link, mac); err != nil {
+				log.Printf("LVM: Error in executing command %s %s with error %s\n", "bridge fdb add", link, err)
+				return fmt.Sprintf("LVM: Error in executing command %s %s with error %s\n", "bridge fdb add", link, err), false
+			}
+			log.Printf("LVM: Executed bridge fdb add %s dev %s master static extern_learn\n", mac, link)
+		}
+	}
+	if segment, ok := multihoming.SegmentOf(bp.Name); ok {
+		if !setEthernetSegment(link, "evpn mh es-id "+segment.ESI) {
+			return fmt.Sprintf("LVM: Failed to attach %s to ethernet segment %s\n", link, segment.ESI), false
+		}
+		log.Printf("LVM: Attached %s to ethernet segment %s\n", link, segment.ESI)
+	}
+	done = true
 	return "", true
 }
 
+// removeBpLink deletes the link of a bridge port whose set up failed, with
+// the translation filters on it
+func removeBpLink(link netlink.Link, mux string) {
+	if err := nlink.LinkDel(ctx, link); err != nil {
+		log.Printf("LVM: Failed to remove %s from %s after its set up failed: %v\n", link.Attrs().Name, mux, err)
+		return
+	}
+	log.Printf("LVM: Executed ip link delete %s after its set up failed\n", link.Attrs().Name)
+}
+
+// setEthernetSegment applies the ethernet segment config of the bridge port
+// interface in frr, which runs the designated forwarder election and
+// advertises the segment routes
+func setEthernetSegment(link string, cmd string) bool {
+	if journal.Enabled() {
+		_ = journal.Default.Record(lvmComp, "vtysh", fmt.Sprintf("interface %s %s", link, cmd))
+		return true
+	}
+	_, errCode := run([]string{"vtysh", "-c", "configure terminal", "-c", "interface " + link, "-c", cmd}, false)
+	return errCode == 0
+}
+
+// runCmd runs the commands of the bridge ports
+var runCmd = run
+
+// setVlanTranslation rewrites the customer vids of the trunk link to the
+// logical bridge vids on ingress and back on egress, the filters go away
+// with the link. A failed filter removes the qdisc with the filters added
+// before it.
+func setVlanTranslation(link string, vlans []vlanmap.Mapping) bool {
+	cmds := [][]string{{"tc", "qdisc", "add", "dev", link, "clsact"}}
+	for _, m := range vlans {
+		customer := strconv.Itoa(int(m.Customer))
+		bridge := strconv.Itoa(int(m.Bridge))
+		cmds = append(cmds,
+			[]string{"tc", "filter", "add", "dev", link, "ingress", "protocol", "802.1Q", "flower", "vlan_id", customer, "action", "vlan", "modify", "id", bridge},
+			[]string{"tc", "filter", "add", "dev", link, "egress", "protocol", "802.1Q", "flower", "vlan_id", bridge, "action", "vlan", "modify", "id", customer})
+	}
+	for _, cmd := range cmds {
+		if journal.Enabled() {
+			_ = journal.Default.Record(lvmComp, "tc", strings.Join(cmd[1:], " "))
+			continue
+		}
+		if _, errCode := runCmd(cmd, false); errCode != 0 {
+			if cmd[1] != "qdisc" {
+				runCmd([]string{"tc", "qdisc", "del", "dev", link, "clsact"}, false)
+			}
+			return false
+		}
+	}
+	return true
+}
+
 // tearDownBp tears down the bridge port
 func tearDownBp(bp *infradb.BridgePort) (string, bool) {
-	vportID := MactoVport(bp.Spec.MacAddress)
-	link := fmt.Sprintf("vport-%+v", vportID)
+	// the link set up for the vport of the metadata, the resolver may have
+	// changed since
+	link := "vport-" + bp.Metadata.VPort
+	if bp.Metadata.VPort == "" {
+		vportID, err := vport.Of(*bp.Spec.MacAddress)
+		if err != nil {
+			log.Printf("LVM: Failed to resolve the vport of %s: %v\n", bp.Spec.MacAddress, err)
+			return fmt.Sprintf("LVM: Failed to resolve the vport of %s: %v\n", bp.Spec.MacAddress, err), false
+		}
+		link = fmt.Sprintf("vport-%+v", vportID)
+	}
+	if _, ok := multihoming.SegmentOf(bp.Name); ok {
+		// frr keeps the interface config after the link is gone
+		setEthernetSegment(link, "no evpn mh es-id")
+	}
 	Intf, err := nlink.LinkByName(ctx, link)
 	if err != nil {
 		log.Printf("Failed to get link %v: %s\n", link, err)
@@ -333,6 +436,10 @@ func handlevrf(objectData *eventbus.ObjectData) {
 
 // disableRpFilter disables the RP filter
 func disableRpFilter(iface string) {
+	if journal.Enabled() {
+		_ = journal.Default.Record(lvmComp, "sysctl", fmt.Sprintf("net.ipv4.conf.%s.rp_filter=0", iface))
+		return
+	}
 	// Work-around for the observation that sometimes the sysctl -w command did not take effect.
 	rpFilterDisabled := false
 	for i := 0; i < maxRetries; i++ {
@@ -431,12 +538,18 @@ func Initialize() {
 			}
 		}
 	}
-	portMux = config.GlobalConfig.Interfaces.PortMux
+	// the plugin loads the same sections, a bad one stops both
+	if _, err := e2000config.Load(); err != nil {
+		log.Fatalf("LVM: %v\n", err)
+	}
 	vrfMux = config.GlobalConfig.Interfaces.VrfMux
 	ipMtu = config.GlobalConfig.LinuxFrr.IPMtu
 	brTenant = "br-tenant"
 	ctx = context.Background()
 	nlink = utils.NewNetlinkWrapperWithArgs(config.GlobalConfig.Tracer)
+	if journal.Enabled() {
+		nlink = journal.NewNetlink(journal.Default)
+	}
 }
 
 // DeInitialize function handles stops functionality
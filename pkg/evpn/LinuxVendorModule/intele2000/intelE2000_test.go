@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package intele2000
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmux"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vlanmap"
+)
+
+func TestSetUpBp_VlanTranslationFailure(t *testing.T) {
+	var buf bytes.Buffer
+	nlink = journal.NewNetlink(journal.New(&buf))
+	ctx = context.Background()
+	brTenant = "br-tenant"
+	if err := portmux.Set(portmux.Config{Interfaces: []string{"enp0s1f0d5"}}); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if err := vlanmap.Set([]vlanmap.Port{{BridgePort: "bp-trunk1", Vlans: []vlanmap.Mapping{{Customer: 10, Bridge: 1010}, {Customer: 20, Bridge: 1020}}}}); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	var cmds []string
+	runCmd = func(cmd []string, _ bool) (string, int) {
+		line := strings.Join(cmd, " ")
+		cmds = append(cmds, line)
+		// the filters of the second mapping fail
+		if strings.Contains(line, "vlan_id 20 ") {
+			return "Error", -1
+		}
+		return "", 0
+	}
+	t.Cleanup(func() {
+		runCmd = run
+		_ = portmux.Set(portmux.Config{})
+		_ = vlanmap.Set(nil)
+	})
+
+	mac, _ := net.ParseMAC("00:18:00:00:00:01")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-trunk1",
+		Spec:     &infradb.BridgePortSpec{Ptype: infradb.Trunk, MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{},
+	}
+	details, ok := setUpBp(bp)
+	if ok || !strings.Contains(details, "Failed to translate the vlans of vport-24") {
+		t.Fatalf("Expected the translation to fail, received %v %q", ok, details)
+	}
+	if last := cmds[len(cmds)-1]; last != "tc qdisc del dev vport-24 clsact" {
+		t.Errorf("Expected the qdisc with its filters removed, received %v", cmds)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if last := lines[len(lines)-1]; !strings.Contains(last, `"op":"LinkDel"`) || !strings.Contains(last, `"link":"vport-24"`) {
+		t.Errorf("Expected the link removed from the mux, received %v", lines)
+	}
+}
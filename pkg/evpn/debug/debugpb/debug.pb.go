@@ -0,0 +1,909 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: debug.proto
+
+package debugpb
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Request of Dump
+type DumpRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DumpRequest) Reset() {
+	*x = DumpRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DumpRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpRequest) ProtoMessage() {}
+
+func (x *DumpRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpRequest.ProtoReflect.Descriptor instead.
+func (*DumpRequest) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{0}
+}
+
+// Response of Dump
+type DumpResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// State of the plugin, in the json layout of its debug dump
+	State *structpb.Struct `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+}
+
+func (x *DumpResponse) Reset() {
+	*x = DumpResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DumpResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpResponse) ProtoMessage() {}
+
+func (x *DumpResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpResponse.ProtoReflect.Descriptor instead.
+func (*DumpResponse) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DumpResponse) GetState() *structpb.Struct {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+// Request of DumpTextproto
+type DumpTextprotoRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DumpTextprotoRequest) Reset() {
+	*x = DumpTextprotoRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DumpTextprotoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpTextprotoRequest) ProtoMessage() {}
+
+func (x *DumpTextprotoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpTextprotoRequest.ProtoReflect.Descriptor instead.
+func (*DumpTextprotoRequest) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{2}
+}
+
+// Response of DumpTextproto
+type DumpTextprotoResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Desired entries as p4runtime textproto
+	Textproto string `protobuf:"bytes,1,opt,name=textproto,proto3" json:"textproto,omitempty"`
+}
+
+func (x *DumpTextprotoResponse) Reset() {
+	*x = DumpTextprotoResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DumpTextprotoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpTextprotoResponse) ProtoMessage() {}
+
+func (x *DumpTextprotoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpTextprotoResponse.ProtoReflect.Descriptor instead.
+func (*DumpTextprotoResponse) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DumpTextprotoResponse) GetTextproto() string {
+	if x != nil {
+		return x.Textproto
+	}
+	return ""
+}
+
+// Packet walked through the tables
+type TracePacket struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Port or vsi the packet enters on
+	//
+	// Types that are assignable to Ingress:
+	//	*TracePacket_Port
+	//	*TracePacket_Vsi
+	Ingress isTracePacket_Ingress `protobuf_oneof:"ingress"`
+	// Vlan tags, outermost first
+	Vlans []uint32 `protobuf:"varint,3,rep,packed,name=vlans,proto3" json:"vlans,omitempty"`
+	// Vni of a vxlan packet, 0 when not encapsulated
+	Vni uint32 `protobuf:"varint,4,opt,name=vni,proto3" json:"vni,omitempty"`
+	// Outer source mac of a vxlan packet
+	OuterSrcMac string `protobuf:"bytes,5,opt,name=outer_src_mac,json=outerSrcMac,proto3" json:"outer_src_mac,omitempty"`
+	// Outer destination mac of a vxlan packet
+	OuterDstMac string `protobuf:"bytes,6,opt,name=outer_dst_mac,json=outerDstMac,proto3" json:"outer_dst_mac,omitempty"`
+	// Outer source ip of a vxlan packet
+	OuterSrcIp string `protobuf:"bytes,7,opt,name=outer_src_ip,json=outerSrcIp,proto3" json:"outer_src_ip,omitempty"`
+	// Outer destination ip of a vxlan packet
+	OuterDstIp string `protobuf:"bytes,8,opt,name=outer_dst_ip,json=outerDstIp,proto3" json:"outer_dst_ip,omitempty"`
+	// Source mac
+	SrcMac string `protobuf:"bytes,9,opt,name=src_mac,json=srcMac,proto3" json:"src_mac,omitempty"`
+	// Destination mac
+	DstMac string `protobuf:"bytes,10,opt,name=dst_mac,json=dstMac,proto3" json:"dst_mac,omitempty"`
+	// Source ip
+	SrcIp string `protobuf:"bytes,11,opt,name=src_ip,json=srcIp,proto3" json:"src_ip,omitempty"`
+	// Destination ip
+	DstIp string `protobuf:"bytes,12,opt,name=dst_ip,json=dstIp,proto3" json:"dst_ip,omitempty"`
+	// Ip protocol, 0 when not given
+	Proto uint32 `protobuf:"varint,13,opt,name=proto,proto3" json:"proto,omitempty"`
+	// Tcp or udp source port
+	SrcPort uint32 `protobuf:"varint,14,opt,name=src_port,json=srcPort,proto3" json:"src_port,omitempty"`
+	// Tcp or udp destination port
+	DstPort uint32 `protobuf:"varint,15,opt,name=dst_port,json=dstPort,proto3" json:"dst_port,omitempty"`
+	// Arp packet
+	Arp bool `protobuf:"varint,16,opt,name=arp,proto3" json:"arp,omitempty"`
+	// Ecmp hash of the flow
+	Hash uint32 `protobuf:"varint,17,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (x *TracePacket) Reset() {
+	*x = TracePacket{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TracePacket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TracePacket) ProtoMessage() {}
+
+func (x *TracePacket) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TracePacket.ProtoReflect.Descriptor instead.
+func (*TracePacket) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{4}
+}
+
+func (m *TracePacket) GetIngress() isTracePacket_Ingress {
+	if m != nil {
+		return m.Ingress
+	}
+	return nil
+}
+
+func (x *TracePacket) GetPort() uint32 {
+	if x, ok := x.GetIngress().(*TracePacket_Port); ok {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *TracePacket) GetVsi() uint32 {
+	if x, ok := x.GetIngress().(*TracePacket_Vsi); ok {
+		return x.Vsi
+	}
+	return 0
+}
+
+func (x *TracePacket) GetVlans() []uint32 {
+	if x != nil {
+		return x.Vlans
+	}
+	return nil
+}
+
+func (x *TracePacket) GetVni() uint32 {
+	if x != nil {
+		return x.Vni
+	}
+	return 0
+}
+
+func (x *TracePacket) GetOuterSrcMac() string {
+	if x != nil {
+		return x.OuterSrcMac
+	}
+	return ""
+}
+
+func (x *TracePacket) GetOuterDstMac() string {
+	if x != nil {
+		return x.OuterDstMac
+	}
+	return ""
+}
+
+func (x *TracePacket) GetOuterSrcIp() string {
+	if x != nil {
+		return x.OuterSrcIp
+	}
+	return ""
+}
+
+func (x *TracePacket) GetOuterDstIp() string {
+	if x != nil {
+		return x.OuterDstIp
+	}
+	return ""
+}
+
+func (x *TracePacket) GetSrcMac() string {
+	if x != nil {
+		return x.SrcMac
+	}
+	return ""
+}
+
+func (x *TracePacket) GetDstMac() string {
+	if x != nil {
+		return x.DstMac
+	}
+	return ""
+}
+
+func (x *TracePacket) GetSrcIp() string {
+	if x != nil {
+		return x.SrcIp
+	}
+	return ""
+}
+
+func (x *TracePacket) GetDstIp() string {
+	if x != nil {
+		return x.DstIp
+	}
+	return ""
+}
+
+func (x *TracePacket) GetProto() uint32 {
+	if x != nil {
+		return x.Proto
+	}
+	return 0
+}
+
+func (x *TracePacket) GetSrcPort() uint32 {
+	if x != nil {
+		return x.SrcPort
+	}
+	return 0
+}
+
+func (x *TracePacket) GetDstPort() uint32 {
+	if x != nil {
+		return x.DstPort
+	}
+	return 0
+}
+
+func (x *TracePacket) GetArp() bool {
+	if x != nil {
+		return x.Arp
+	}
+	return false
+}
+
+func (x *TracePacket) GetHash() uint32 {
+	if x != nil {
+		return x.Hash
+	}
+	return 0
+}
+
+type isTracePacket_Ingress interface {
+	isTracePacket_Ingress()
+}
+
+type TracePacket_Port struct {
+	// Ingress physical port
+	Port uint32 `protobuf:"varint,1,opt,name=port,proto3,oneof"`
+}
+
+type TracePacket_Vsi struct {
+	// Ingress vsi
+	Vsi uint32 `protobuf:"varint,2,opt,name=vsi,proto3,oneof"`
+}
+
+func (*TracePacket_Port) isTracePacket_Ingress() {}
+
+func (*TracePacket_Vsi) isTracePacket_Ingress() {}
+
+// Request of Trace
+type TraceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Packet to trace
+	Packet *TracePacket `protobuf:"bytes,1,opt,name=packet,proto3" json:"packet,omitempty"`
+}
+
+func (x *TraceRequest) Reset() {
+	*x = TraceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TraceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceRequest) ProtoMessage() {}
+
+func (x *TraceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceRequest.ProtoReflect.Descriptor instead.
+func (*TraceRequest) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TraceRequest) GetPacket() *TracePacket {
+	if x != nil {
+		return x.Packet
+	}
+	return nil
+}
+
+// Lookup done by the trace
+type TraceStep struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Table looked up
+	Table string `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
+	// Key fields of the lookup
+	Key []string `protobuf:"bytes,2,rep,name=key,proto3" json:"key,omitempty"`
+	// Whether an entry matched
+	Hit bool `protobuf:"varint,3,opt,name=hit,proto3" json:"hit,omitempty"`
+	// Action of the matched entry
+	Action string `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	// Params of the action
+	Params []string `protobuf:"bytes,5,rep,name=params,proto3" json:"params,omitempty"`
+	// Rewrites of the packet by the action
+	Rewrites []string `protobuf:"bytes,6,rep,name=rewrites,proto3" json:"rewrites,omitempty"`
+}
+
+func (x *TraceStep) Reset() {
+	*x = TraceStep{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TraceStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceStep) ProtoMessage() {}
+
+func (x *TraceStep) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceStep.ProtoReflect.Descriptor instead.
+func (*TraceStep) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TraceStep) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *TraceStep) GetKey() []string {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *TraceStep) GetHit() bool {
+	if x != nil {
+		return x.Hit
+	}
+	return false
+}
+
+func (x *TraceStep) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *TraceStep) GetParams() []string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *TraceStep) GetRewrites() []string {
+	if x != nil {
+		return x.Rewrites
+	}
+	return nil
+}
+
+// Response of Trace
+type TraceResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Lookups on the path of the packet
+	Steps []*TraceStep `protobuf:"bytes,1,rep,name=steps,proto3" json:"steps,omitempty"`
+	// Packet after the rewrites
+	Packet *TracePacket `protobuf:"bytes,2,opt,name=packet,proto3" json:"packet,omitempty"`
+	// Where the packet ends up
+	Verdict string `protobuf:"bytes,3,opt,name=verdict,proto3" json:"verdict,omitempty"`
+}
+
+func (x *TraceResponse) Reset() {
+	*x = TraceResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_debug_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TraceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceResponse) ProtoMessage() {}
+
+func (x *TraceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_debug_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceResponse.ProtoReflect.Descriptor instead.
+func (*TraceResponse) Descriptor() ([]byte, []int) {
+	return file_debug_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TraceResponse) GetSteps() []*TraceStep {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *TraceResponse) GetPacket() *TracePacket {
+	if x != nil {
+		return x.Packet
+	}
+	return nil
+}
+
+func (x *TraceResponse) GetVerdict() string {
+	if x != nil {
+		return x.Verdict
+	}
+	return ""
+}
+
+var File_debug_proto protoreflect.FileDescriptor
+
+var file_debug_proto_rawDesc = []byte{
+	0x0a, 0x0b, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x19, 0x6f,
+	0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e,
+	0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x0d, 0x0a, 0x0b, 0x44, 0x75, 0x6d, 0x70, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x22, 0x3d, 0x0a, 0x0c, 0x44, 0x75, 0x6d, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x2d, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x17, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x74, 0x72, 0x75, 0x63, 0x74, 0x52, 0x05, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x22, 0x16, 0x0a, 0x14, 0x44, 0x75, 0x6d, 0x70, 0x54, 0x65, 0x78, 0x74, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x35, 0x0a, 0x15, 0x44, 0x75,
+	0x6d, 0x70, 0x54, 0x65, 0x78, 0x74, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x65, 0x78, 0x74, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x65, 0x78, 0x74, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0xc8, 0x03, 0x0a, 0x0b, 0x54, 0x72, 0x61, 0x63, 0x65, 0x50, 0x61, 0x63, 0x6b, 0x65,
+	0x74, 0x12, 0x14, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x48,
+	0x00, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x03, 0x76, 0x73, 0x69, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x03, 0x76, 0x73, 0x69, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x6c, 0x61, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x05, 0x76, 0x6c, 0x61, 0x6e,
+	0x73, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x6e, 0x69, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03,
+	0x76, 0x6e, 0x69, 0x12, 0x22, 0x0a, 0x0d, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x5f, 0x73, 0x72, 0x63,
+	0x5f, 0x6d, 0x61, 0x63, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6f, 0x75, 0x74, 0x65,
+	0x72, 0x53, 0x72, 0x63, 0x4d, 0x61, 0x63, 0x12, 0x22, 0x0a, 0x0d, 0x6f, 0x75, 0x74, 0x65, 0x72,
+	0x5f, 0x64, 0x73, 0x74, 0x5f, 0x6d, 0x61, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x6f, 0x75, 0x74, 0x65, 0x72, 0x44, 0x73, 0x74, 0x4d, 0x61, 0x63, 0x12, 0x20, 0x0a, 0x0c, 0x6f,
+	0x75, 0x74, 0x65, 0x72, 0x5f, 0x73, 0x72, 0x63, 0x5f, 0x69, 0x70, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x53, 0x72, 0x63, 0x49, 0x70, 0x12, 0x20, 0x0a,
+	0x0c, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x5f, 0x64, 0x73, 0x74, 0x5f, 0x69, 0x70, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0a, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x44, 0x73, 0x74, 0x49, 0x70, 0x12,
+	0x17, 0x0a, 0x07, 0x73, 0x72, 0x63, 0x5f, 0x6d, 0x61, 0x63, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x73, 0x72, 0x63, 0x4d, 0x61, 0x63, 0x12, 0x17, 0x0a, 0x07, 0x64, 0x73, 0x74, 0x5f,
+	0x6d, 0x61, 0x63, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x73, 0x74, 0x4d, 0x61,
+	0x63, 0x12, 0x15, 0x0a, 0x06, 0x73, 0x72, 0x63, 0x5f, 0x69, 0x70, 0x18, 0x0b, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x73, 0x72, 0x63, 0x49, 0x70, 0x12, 0x15, 0x0a, 0x06, 0x64, 0x73, 0x74, 0x5f,
+	0x69, 0x70, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x64, 0x73, 0x74, 0x49, 0x70, 0x12,
+	0x14, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x19, 0x0a, 0x08, 0x73, 0x72, 0x63, 0x5f, 0x70, 0x6f, 0x72,
+	0x74, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x73, 0x72, 0x63, 0x50, 0x6f, 0x72, 0x74,
+	0x12, 0x19, 0x0a, 0x08, 0x64, 0x73, 0x74, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x0f, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x07, 0x64, 0x73, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x61,
+	0x72, 0x70, 0x18, 0x10, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x72, 0x70, 0x12, 0x12, 0x0a,
+	0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x68, 0x61, 0x73,
+	0x68, 0x42, 0x09, 0x0a, 0x07, 0x69, 0x6e, 0x67, 0x72, 0x65, 0x73, 0x73, 0x22, 0x4e, 0x0a, 0x0c,
+	0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x3e, 0x0a, 0x06,
+	0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x6f,
+	0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e,
+	0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x63, 0x65, 0x50, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x52, 0x06, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x91, 0x01, 0x0a,
+	0x09, 0x54, 0x72, 0x61, 0x63, 0x65, 0x53, 0x74, 0x65, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x61,
+	0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x68, 0x69, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x03, 0x68, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06,
+	0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x70, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x77, 0x72, 0x69, 0x74, 0x65, 0x73,
+	0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x77, 0x72, 0x69, 0x74, 0x65, 0x73,
+	0x22, 0xa5, 0x01, 0x0a, 0x0d, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x3a, 0x0a, 0x05, 0x73, 0x74, 0x65, 0x70, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x24, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72,
+	0x69, 0x64, 0x67, 0x65, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72,
+	0x61, 0x63, 0x65, 0x53, 0x74, 0x65, 0x70, 0x52, 0x05, 0x73, 0x74, 0x65, 0x70, 0x73, 0x12, 0x3e,
+	0x0a, 0x06, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26,
+	0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67,
+	0x65, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x63, 0x65,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x06, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x65, 0x72, 0x64, 0x69, 0x63, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x64, 0x69, 0x63, 0x74, 0x32, 0xb5, 0x03, 0x0a, 0x0c, 0x44, 0x65, 0x62,
+	0x75, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x7d, 0x0a, 0x04, 0x44, 0x75, 0x6d,
+	0x70, 0x12, 0x26, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72,
+	0x69, 0x64, 0x67, 0x65, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x75,
+	0x6d, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x6f, 0x70, 0x69, 0x5f,
+	0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x64, 0x65, 0x62,
+	0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x24, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x1e, 0x62, 0x05, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x12, 0x15, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2f, 0x69, 0x6e, 0x74,
+	0x65, 0x6c, 0x2d, 0x65, 0x32, 0x30, 0x30, 0x30, 0x12, 0x9b, 0x01, 0x0a, 0x0d, 0x44, 0x75, 0x6d,
+	0x70, 0x54, 0x65, 0x78, 0x74, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x2f, 0x2e, 0x6f, 0x70, 0x69,
+	0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x64, 0x65,
+	0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x54, 0x65, 0x78, 0x74, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x30, 0x2e, 0x6f, 0x70,
+	0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x64,
+	0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x75, 0x6d, 0x70, 0x54, 0x65, 0x78, 0x74,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x27, 0x82,
+	0xd3, 0xe4, 0x93, 0x02, 0x21, 0x12, 0x1f, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x65, 0x62, 0x75, 0x67,
+	0x2f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x2d, 0x65, 0x32, 0x30, 0x30, 0x30, 0x2f, 0x74, 0x65, 0x78,
+	0x74, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x87, 0x01, 0x0a, 0x05, 0x54, 0x72, 0x61, 0x63, 0x65,
+	0x12, 0x27, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69,
+	0x64, 0x67, 0x65, 0x2e, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61,
+	0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x28, 0x2e, 0x6f, 0x70, 0x69, 0x5f,
+	0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x64, 0x65, 0x62,
+	0x75, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x2b, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x25, 0x3a, 0x06, 0x70, 0x61, 0x63,
+	0x6b, 0x65, 0x74, 0x22, 0x1b, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2f, 0x69,
+	0x6e, 0x74, 0x65, 0x6c, 0x2d, 0x65, 0x32, 0x30, 0x30, 0x30, 0x2f, 0x74, 0x72, 0x61, 0x63, 0x65,
+	0x42, 0x3f, 0x5a, 0x3d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6f,
+	0x70, 0x69, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74, 0x2f, 0x6f, 0x70, 0x69, 0x2d, 0x69, 0x6e,
+	0x74, 0x65, 0x6c, 0x2d, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x65,
+	0x76, 0x70, 0x6e, 0x2f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x2f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_debug_proto_rawDescOnce sync.Once
+	file_debug_proto_rawDescData = file_debug_proto_rawDesc
+)
+
+func file_debug_proto_rawDescGZIP() []byte {
+	file_debug_proto_rawDescOnce.Do(func() {
+		file_debug_proto_rawDescData = protoimpl.X.CompressGZIP(file_debug_proto_rawDescData)
+	})
+	return file_debug_proto_rawDescData
+}
+
+var file_debug_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_debug_proto_goTypes = []interface{}{
+	(*DumpRequest)(nil),           // 0: opi_intel_bridge.debug.v1.DumpRequest
+	(*DumpResponse)(nil),          // 1: opi_intel_bridge.debug.v1.DumpResponse
+	(*DumpTextprotoRequest)(nil),  // 2: opi_intel_bridge.debug.v1.DumpTextprotoRequest
+	(*DumpTextprotoResponse)(nil), // 3: opi_intel_bridge.debug.v1.DumpTextprotoResponse
+	(*TracePacket)(nil),           // 4: opi_intel_bridge.debug.v1.TracePacket
+	(*TraceRequest)(nil),          // 5: opi_intel_bridge.debug.v1.TraceRequest
+	(*TraceStep)(nil),             // 6: opi_intel_bridge.debug.v1.TraceStep
+	(*TraceResponse)(nil),         // 7: opi_intel_bridge.debug.v1.TraceResponse
+	(*structpb.Struct)(nil),       // 8: google.protobuf.Struct
+}
+var file_debug_proto_depIdxs = []int32{
+	8, // 0: opi_intel_bridge.debug.v1.DumpResponse.state:type_name -> google.protobuf.Struct
+	4, // 1: opi_intel_bridge.debug.v1.TraceRequest.packet:type_name -> opi_intel_bridge.debug.v1.TracePacket
+	6, // 2: opi_intel_bridge.debug.v1.TraceResponse.steps:type_name -> opi_intel_bridge.debug.v1.TraceStep
+	4, // 3: opi_intel_bridge.debug.v1.TraceResponse.packet:type_name -> opi_intel_bridge.debug.v1.TracePacket
+	0, // 4: opi_intel_bridge.debug.v1.DebugService.Dump:input_type -> opi_intel_bridge.debug.v1.DumpRequest
+	2, // 5: opi_intel_bridge.debug.v1.DebugService.DumpTextproto:input_type -> opi_intel_bridge.debug.v1.DumpTextprotoRequest
+	5, // 6: opi_intel_bridge.debug.v1.DebugService.Trace:input_type -> opi_intel_bridge.debug.v1.TraceRequest
+	1, // 7: opi_intel_bridge.debug.v1.DebugService.Dump:output_type -> opi_intel_bridge.debug.v1.DumpResponse
+	3, // 8: opi_intel_bridge.debug.v1.DebugService.DumpTextproto:output_type -> opi_intel_bridge.debug.v1.DumpTextprotoResponse
+	7, // 9: opi_intel_bridge.debug.v1.DebugService.Trace:output_type -> opi_intel_bridge.debug.v1.TraceResponse
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_debug_proto_init() }
+func file_debug_proto_init() {
+	if File_debug_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_debug_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DumpRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_debug_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DumpResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_debug_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DumpTextprotoRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_debug_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DumpTextprotoResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_debug_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TracePacket); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_debug_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TraceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_debug_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TraceStep); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_debug_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TraceResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_debug_proto_msgTypes[4].OneofWrappers = []interface{}{
+		(*TracePacket_Port)(nil),
+		(*TracePacket_Vsi)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_debug_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_debug_proto_goTypes,
+		DependencyIndexes: file_debug_proto_depIdxs,
+		MessageInfos:      file_debug_proto_msgTypes,
+	}.Build()
+	File_debug_proto = out.File
+	file_debug_proto_rawDesc = nil
+	file_debug_proto_goTypes = nil
+	file_debug_proto_depIdxs = nil
+}
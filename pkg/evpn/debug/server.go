@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package debug implements the debug service of the intel-e2000 plugin,
+// the gateway serves it from debugpb
+//
+//nolint:all
+package debug
+
+import (
+	"context"
+	"encoding/json"
+
+	pb "github.com/opiproject/opi-intel-bridge/pkg/evpn/debug/debugpb"
+	ipu_vendor "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4translation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Server implements the debug service
+type Server struct {
+	pb.UnimplementedDebugServiceServer
+}
+
+// NewServer creates the debug server
+func NewServer() *Server {
+	return &Server{}
+}
+
+// Dump returns the plugin state in the json layout of its debug dump
+func (s *Server) Dump(_ context.Context, _ *pb.DumpRequest) (*pb.DumpResponse, error) {
+	b, err := json.Marshal(ipu_vendor.DebugDump())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	state := &structpb.Struct{}
+	if err := protojson.Unmarshal(b, state); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.DumpResponse{State: state}, nil
+}
+
+// DumpTextproto returns the desired entries as p4runtime textproto
+func (s *Server) DumpTextproto(_ context.Context, _ *pb.DumpTextprotoRequest) (*pb.DumpTextprotoResponse, error) {
+	text, err := ipu_vendor.DebugTextproto()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.DumpTextprotoResponse{Textproto: text}, nil
+}
+
+// Trace walks the packet through the desired entries of the running plugin
+func (s *Server) Trace(_ context.Context, in *pb.TraceRequest) (*pb.TraceResponse, error) {
+	pkt, err := TracePacketFromProto(in.GetPacket())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return traceResultToProto(ipu_vendor.Trace(pkt)), nil
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	pb "github.com/opiproject/opi-intel-bridge/pkg/evpn/debug/debugpb"
+	ipu_vendor "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4translation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_Dump(t *testing.T) {
+	out, err := NewServer().Dump(context.Background(), &pb.DumpRequest{})
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	for _, field := range []string{"pools", "l3Decoder", "podDecoder", "vxlanDecoder"} {
+		if _, ok := out.GetState().GetFields()[field]; !ok {
+			t.Errorf("Expected field %v in %v", field, out)
+		}
+	}
+}
+
+func TestServer_Trace(t *testing.T) {
+	tests := map[string]struct {
+		packet  *pb.TracePacket
+		code    codes.Code
+		verdict string
+	}{
+		"packet of a vsi": {
+			packet:  &pb.TracePacket{Ingress: &pb.TracePacket_Vsi{Vsi: 24}, DstIp: "10.0.0.1"},
+			verdict: "dropped, no ingress entry",
+		},
+		"no ingress": {
+			packet: &pb.TracePacket{DstIp: "10.0.0.1"},
+			code:   codes.InvalidArgument,
+		},
+		"invalid mac": {
+			packet: &pb.TracePacket{Ingress: &pb.TracePacket_Port{Port: 0}, SrcMac: "00:11"},
+			code:   codes.InvalidArgument,
+		},
+		"no packet": {
+			code: codes.InvalidArgument,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			out, err := NewServer().Trace(context.Background(), &pb.TraceRequest{Packet: tt.packet})
+			if status.Code(err) != tt.code {
+				t.Fatalf("Expected code: %v, received %v", tt.code, err)
+			}
+			if err == nil && out.GetVerdict() != tt.verdict {
+				t.Errorf("Expected verdict: %v, received %v", tt.verdict, out.GetVerdict())
+			}
+		})
+	}
+}
+
+func TestTracePacketToProto(t *testing.T) {
+	pkt := ipu_vendor.TracePacket{Port: 1, Vsi: -1, Vlans: []uint16{10, 20}, Vni: 1000, Hash: 7,
+		Proto: 6, SrcPort: 1024, DstPort: 443,
+		OuterDstMac: net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}, OuterDstIP: net.ParseIP("192.168.0.1"),
+		SrcIP: net.ParseIP("10.0.0.1"), DstIP: net.ParseIP("fd00::1")}
+	back, err := TracePacketFromProto(TracePacketToProto(pkt))
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if back.String() != pkt.String() || back.Port != 1 || back.Vsi != -1 || back.Hash != 7 {
+		t.Errorf("Expected %v from port 1, received %v from port %d vsi %d", pkt, back, back.Port, back.Vsi)
+	}
+}
+
+func TestDebugServiceHandler(t *testing.T) {
+	tests := map[string]struct {
+		method string
+		path   string
+		body   string
+		code   int
+		field  string
+	}{
+		"dump": {
+			path:  "/v1/debug/intel-e2000",
+			code:  http.StatusOK,
+			field: "l3Decoder",
+		},
+		"textproto of no entries": {
+			path:  "/v1/debug/intel-e2000/textproto",
+			code:  http.StatusOK,
+			field: "textproto",
+		},
+		"trace": {
+			method: http.MethodPost,
+			path:   "/v1/debug/intel-e2000/trace",
+			body:   `{"vsi": 24, "dstIp": "10.0.0.1"}`,
+			code:   http.StatusOK,
+			field:  "verdict",
+		},
+		"trace without ingress": {
+			method: http.MethodPost,
+			path:   "/v1/debug/intel-e2000/trace",
+			body:   `{"dstIp": "10.0.0.1"}`,
+			code:   http.StatusBadRequest,
+			field:  "message",
+		},
+	}
+	mux := runtime.NewServeMux()
+	if err := pb.RegisterDebugServiceHandlerServer(context.Background(), mux, NewServer()); err != nil {
+		t.Fatalf("RegisterDebugServiceHandlerServer failed: %v", err)
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			mux.ServeHTTP(rec, httptest.NewRequest(method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Errorf("Expected code: %v, received %v %s", tt.code, rec.Code, rec.Body.String())
+			}
+			out := map[string]interface{}{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+				t.Fatalf("Expected a json object, received %s", rec.Body.String())
+			}
+			if _, ok := out[tt.field]; !ok {
+				t.Errorf("Expected field %v in %s", tt.field, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package debug
+
+import (
+	"fmt"
+	"net"
+
+	pb "github.com/opiproject/opi-intel-bridge/pkg/evpn/debug/debugpb"
+	ipu_vendor "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4translation"
+)
+
+// TracePacketFromProto converts and validates the packet of a trace request
+func TracePacketFromProto(p *pb.TracePacket) (ipu_vendor.TracePacket, error) {
+	pkt := ipu_vendor.TracePacket{Port: -1, Vsi: -1}
+	if p == nil {
+		return pkt, fmt.Errorf("no packet given")
+	}
+	switch in := p.GetIngress().(type) {
+	case *pb.TracePacket_Port:
+		pkt.Port = int(in.Port)
+	case *pb.TracePacket_Vsi:
+		pkt.Vsi = int(in.Vsi)
+	default:
+		return pkt, fmt.Errorf("exactly one of port and vsi is needed")
+	}
+	for _, vid := range p.GetVlans() {
+		if vid > 4095 {
+			return pkt, fmt.Errorf("invalid vlan %d", vid)
+		}
+		pkt.Vlans = append(pkt.Vlans, uint16(vid))
+	}
+	if p.GetProto() > 255 || p.GetSrcPort() > 65535 || p.GetDstPort() > 65535 || p.GetHash() > 65535 {
+		return pkt, fmt.Errorf("proto, ports or hash out of range")
+	}
+	pkt.Vni, pkt.Arp, pkt.Hash = p.GetVni(), p.GetArp(), uint16(p.GetHash())
+	pkt.Proto, pkt.SrcPort, pkt.DstPort = uint8(p.GetProto()), uint16(p.GetSrcPort()), uint16(p.GetDstPort())
+	var err error
+	for _, m := range []struct {
+		value string
+		mac   *net.HardwareAddr
+	}{
+		{p.GetOuterSrcMac(), &pkt.OuterSrcMac}, {p.GetOuterDstMac(), &pkt.OuterDstMac},
+		{p.GetSrcMac(), &pkt.SrcMac}, {p.GetDstMac(), &pkt.DstMac},
+	} {
+		if m.value == "" {
+			continue
+		}
+		if *m.mac, err = net.ParseMAC(m.value); err != nil {
+			return pkt, err
+		}
+	}
+	for _, a := range []struct {
+		value string
+		ip    *net.IP
+	}{
+		{p.GetOuterSrcIp(), &pkt.OuterSrcIP}, {p.GetOuterDstIp(), &pkt.OuterDstIP},
+		{p.GetSrcIp(), &pkt.SrcIP}, {p.GetDstIp(), &pkt.DstIP},
+	} {
+		if a.value == "" {
+			continue
+		}
+		if *a.ip = net.ParseIP(a.value); *a.ip == nil {
+			return pkt, fmt.Errorf("invalid ip address %s", a.value)
+		}
+	}
+	return pkt, nil
+}
+
+// TracePacketToProto converts the packet for a trace request
+func TracePacketToProto(pkt ipu_vendor.TracePacket) *pb.TracePacket {
+	p := &pb.TracePacket{
+		Vni: pkt.Vni, Arp: pkt.Arp, Hash: uint32(pkt.Hash), Proto: uint32(pkt.Proto),
+		SrcPort: uint32(pkt.SrcPort), DstPort: uint32(pkt.DstPort),
+		OuterSrcMac: pkt.OuterSrcMac.String(), OuterDstMac: pkt.OuterDstMac.String(),
+		SrcMac: pkt.SrcMac.String(), DstMac: pkt.DstMac.String(),
+		OuterSrcIp: ipString(pkt.OuterSrcIP), OuterDstIp: ipString(pkt.OuterDstIP),
+		SrcIp: ipString(pkt.SrcIP), DstIp: ipString(pkt.DstIP),
+	}
+	if pkt.Port >= 0 {
+		p.Ingress = &pb.TracePacket_Port{Port: uint32(pkt.Port)}
+	} else if pkt.Vsi >= 0 {
+		p.Ingress = &pb.TracePacket_Vsi{Vsi: uint32(pkt.Vsi)}
+	}
+	for _, vid := range pkt.Vlans {
+		p.Vlans = append(p.Vlans, uint32(vid))
+	}
+	return p
+}
+
+// TraceResultFromProto converts the response of a trace request
+func TraceResultFromProto(r *pb.TraceResponse) (*ipu_vendor.TraceResult, error) {
+	pkt, err := TracePacketFromProto(r.GetPacket())
+	if err != nil {
+		return nil, fmt.Errorf("traced packet: %w", err)
+	}
+	res := &ipu_vendor.TraceResult{Packet: pkt, Verdict: r.GetVerdict()}
+	for _, s := range r.GetSteps() {
+		res.Steps = append(res.Steps, ipu_vendor.TraceStep{Table: s.GetTable(), Key: s.GetKey(), Hit: s.GetHit(),
+			Action: s.GetAction(), Params: s.GetParams(), Rewrites: s.GetRewrites()})
+	}
+	return res, nil
+}
+
+// traceResultToProto converts the result of a trace for its response
+func traceResultToProto(res *ipu_vendor.TraceResult) *pb.TraceResponse {
+	r := &pb.TraceResponse{Packet: TracePacketToProto(res.Packet), Verdict: res.Verdict}
+	for _, s := range res.Steps {
+		r.Steps = append(r.Steps, &pb.TraceStep{Table: s.Table, Key: s.Key, Hit: s.Hit,
+			Action: s.Action, Params: s.Params, Rewrites: s.Rewrites})
+	}
+	return r
+}
+
+// ipString formats the address, empty when not given
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package e2000config reads the sections of the intel-e2000 config the
+// linux vendor module and the intel-e2000 plugin share. The first of them
+// to load it reads, validates and applies the sections, both get its error.
+//
+//nolint:all
+package e2000config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/config"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmacs"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmux"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vlanmap"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vport"
+	"github.com/spf13/viper"
+)
+
+// Config holds the shared sections of the config, next to the ones of
+// config.GlobalConfig
+type Config struct {
+	Multihoming Multihoming    `mapstructure:"multihoming"`
+	PortMacs    PortMacs       `mapstructure:"portmacs"`
+	VlanMap     VlanMap        `mapstructure:"vlanmap"`
+	Vport       vport.Config   `mapstructure:"vport"`
+	PortMux     portmux.Config `mapstructure:"portmux"`
+}
+
+// Multihoming is the multihoming section, the ethernet segments and whether
+// their remote macs are aliased
+type Multihoming struct {
+	Aliasing bool                  `mapstructure:"aliasing"`
+	Segments []multihoming.Segment `mapstructure:"segments"`
+}
+
+// PortMacs is the portmacs section, the secondary macs of the bridge ports
+type PortMacs struct {
+	BridgePorts []portmacs.Port `mapstructure:"bridgeports"`
+}
+
+// VlanMap is the vlanmap section, the vlan translation of the trunk bridge
+// ports
+type VlanMap struct {
+	BridgePorts []vlanmap.Port `mapstructure:"bridgeports"`
+}
+
+var (
+	// current is the loaded config
+	current Config
+	// loadOnce loads the config for the first of the linux vendor module
+	// and the plugin, loadErr is what both of them get
+	loadOnce sync.Once
+	loadErr  error
+)
+
+// Load reads, validates and applies the shared sections once and returns
+// them, the linux vendor module and the plugin both stop on its error
+func Load() (Config, error) {
+	loadOnce.Do(func() {
+		current, loadErr = load()
+	})
+	return current, loadErr
+}
+
+// load reads the shared sections and hands them to their packages, which
+// validate them before they take them
+func load() (Config, error) {
+	var c Config
+	if err := viper.Unmarshal(&c); err != nil {
+		return Config{}, fmt.Errorf("intel-e2000 config: %w", err)
+	}
+	if len(c.PortMux.Interfaces) == 0 && config.GlobalConfig.Interfaces.PortMux != "" {
+		c.PortMux.Interfaces = []string{config.GlobalConfig.Interfaces.PortMux}
+	}
+	r, err := vport.NewResolver(c.Vport)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := multihoming.Set(c.Multihoming.Segments); err != nil {
+		return Config{}, err
+	}
+	if err := portmacs.Set(c.PortMacs.BridgePorts); err != nil {
+		return Config{}, err
+	}
+	if err := vlanmap.Set(c.VlanMap.BridgePorts); err != nil {
+		return Config{}, err
+	}
+	if err := portmux.Set(c.PortMux); err != nil {
+		return Config{}, err
+	}
+	vport.Set(r)
+	return c, nil
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package e2000config
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/config"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmacs"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmux"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vlanmap"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vport"
+	"github.com/spf13/viper"
+)
+
+func TestLoad(t *testing.T) {
+	viper.Set("multihoming.aliasing", true)
+	viper.Set("multihoming.segments", []map[string]interface{}{{
+		"esi":         "00:11:22:33:44:55:66:77:88:99",
+		"bridgeports": []string{"bp-vm1"},
+		"peers":       []string{"10.0.0.2"},
+	}})
+	viper.Set("portmacs.bridgeports", []map[string]interface{}{{"bridgeport": "bp-vm1", "macs": []string{"02:00:00:00:01:01"}}})
+	viper.Set("vlanmap.bridgeports", []map[string]interface{}{{"bridgeport": "bp-trunk1", "vlans": []map[string]interface{}{{"customer": 10, "bridge": 1010}}}})
+	viper.Set("vport.resolver", "table")
+	viper.Set("vport.table", []map[string]interface{}{{"mac": "02:00:00:00:00:01", "vport": 24}})
+	config.GlobalConfig.Interfaces.PortMux = "enp0s1f0d5"
+	defer viper.Reset()
+	defer func() {
+		_ = multihoming.Set(nil)
+		_ = portmacs.Set(nil)
+		_ = vlanmap.Set(nil)
+		vport.Set(vport.MacResolver{})
+		_ = portmux.Set(portmux.Config{})
+		config.GlobalConfig.Interfaces.PortMux = ""
+	}()
+	c, err := load()
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if !c.Multihoming.Aliasing {
+		t.Errorf("Expected aliasing, received %+v", c.Multihoming)
+	}
+	if _, ok := multihoming.SegmentOf("bp-vm1"); !ok {
+		t.Errorf("Expected the segment of bp-vm1, received %v", multihoming.Segments())
+	}
+	if p, ok := portmacs.Of("bp-vm1"); !ok || len(p.Macs) != 1 {
+		t.Errorf("Expected the secondary mac of bp-vm1, received %v", p)
+	}
+	if vid := vlanmap.CustomerVid("bp-trunk1", 1010); vid != 10 {
+		t.Errorf("Expected the vlan translation of the trunk port, received vid %d", vid)
+	}
+	if muxes := portmux.Interfaces(); !reflect.DeepEqual(muxes, []string{"enp0s1f0d5"}) {
+		t.Errorf("Expected the single mux of the interfaces, received %v", muxes)
+	}
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	if id, err := vport.Of(mac); err != nil || id != 24 {
+		t.Errorf("Expected vport 24 of the table, received %d %v", id, err)
+	}
+
+	viper.Set("vport.resolver", "vsi")
+	if _, err := load(); err == nil || err.Error() != "vport: unknown resolver vsi" {
+		t.Errorf("Expected an unknown resolver, received %v", err)
+	}
+	viper.Set("vport.resolver", "table")
+	viper.Set("multihoming.segments", []map[string]interface{}{{"esi": "00:11"}})
+	if _, err := load(); err == nil || err.Error() != `multihoming: segment 1: invalid esi "00:11"` {
+		t.Errorf("Expected an invalid segment, received %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package journal records the actions the bridge would apply to the
+// device and to linux when running in simulation mode
+//
+//nolint:all
+package journal
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Record is a single journal line
+type Record struct {
+	Seq       uint64      `json:"seq"`
+	Component string      `json:"component"`
+	Op        string      `json:"op"`
+	Object    interface{} `json:"object"`
+}
+
+// Journal writes records as json lines
+type Journal struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+	seq    uint64
+}
+
+// Default is the journal of the running bridge, nil when not simulating
+var Default *Journal
+
+// Enabled tells whether the bridge runs against the journal
+func Enabled() bool {
+	return Default != nil
+}
+
+// New creates a journal writing to w
+func New(w io.Writer) *Journal {
+	j := &Journal{enc: json.NewEncoder(w)}
+	if c, ok := w.(io.Closer); ok {
+		j.closer = c
+	}
+	return j
+}
+
+// Open creates a journal writing to the file at path
+func Open(path string) (*Journal, error) {
+	f, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return New(f), nil
+}
+
+// Record writes an action of component. Recording on a nil journal is a no-op.
+func (j *Journal) Record(component string, op string, object interface{}) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	return j.enc.Encode(Record{Seq: j.seq, Component: component, Op: op, Object: object})
+}
+
+// Close closes the underlying file
+func (j *Journal) Close() error {
+	if j == nil || j.closer == nil {
+		return nil
+	}
+	return j.closer.Close()
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package journal
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestJournal_Record(t *testing.T) {
+	tests := map[string]struct {
+		run func(j *Journal)
+		out string
+	}{
+		"records are numbered json lines": {
+			run: func(j *Journal) {
+				_ = j.Record("p4", "add", map[string]string{"table": "t1"})
+				_ = j.Record("p4", "delete", map[string]string{"table": "t1"})
+			},
+			out: `{"seq":1,"component":"p4","op":"add","object":{"table":"t1"}}` + "\n" +
+				`{"seq":2,"component":"p4","op":"delete","object":{"table":"t1"}}` + "\n",
+		},
+		"netlink actions on simulated links": {
+			run: func(j *Journal) {
+				ctx := context.Background()
+				n := NewNetlink(j)
+				mux, _ := n.LinkByName(ctx, "portmux")
+				vlan := &netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Name: "vlan10", ParentIndex: mux.Attrs().Index}, VlanId: 10}
+				_ = n.LinkAdd(ctx, vlan)
+				_ = n.LinkSetMTU(ctx, vlan, 1500)
+			},
+			out: `{"seq":1,"component":"netlink","op":"LinkAdd","object":{"link":"vlan10","parentindex":1,"type":"vlan","vlanid":10}}` + "\n" +
+				`{"seq":2,"component":"netlink","op":"LinkSetMTU","object":{"link":"vlan10","mtu":1500,"type":"vlan","vlanid":10}}` + "\n",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.run(New(&buf))
+
+			if buf.String() != tt.out {
+				t.Errorf("Expected journal: %s, received %s", tt.out, buf.String())
+			}
+		})
+	}
+}
+
+func TestJournal_NilIsNoop(t *testing.T) {
+	var j *Journal
+	if err := j.Record("p4", "add", nil); err != nil {
+		t.Errorf("Expected no error, received %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Errorf("Expected no error, received %v", err)
+	}
+}
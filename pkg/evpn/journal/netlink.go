@@ -0,0 +1,232 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+//nolint:all
+package journal
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/utils"
+	"github.com/vishvananda/netlink"
+)
+
+// netlinkComp component name of the linux netlink actions
+const netlinkComp = "netlink"
+
+// Netlink records the netlink actions instead of applying them.
+// Links looked up by name are simulated, reads return nothing.
+type Netlink struct {
+	j       *Journal
+	mu      sync.Mutex
+	indexes map[string]int
+}
+
+// build time check that struct implements interface
+var _ utils.Netlink = (*Netlink)(nil)
+
+// NewNetlink creates a netlink recorder writing to j
+func NewNetlink(j *Journal) *Netlink {
+	return &Netlink{j: j, indexes: make(map[string]int)}
+}
+
+// indexOf returns a stable simulated interface index for name
+func (n *Netlink) indexOf(name string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.indexes[name]; !ok {
+		n.indexes[name] = len(n.indexes) + 1
+	}
+	return n.indexes[name]
+}
+
+// linkRecord describes a link in the journal
+func linkRecord(link netlink.Link) map[string]interface{} {
+	rec := map[string]interface{}{}
+	if link == nil {
+		return rec
+	}
+	rec["link"] = link.Attrs().Name
+	rec["type"] = link.Type()
+	if vlan, ok := link.(*netlink.Vlan); ok {
+		rec["vlanid"] = vlan.VlanId
+	}
+	return rec
+}
+
+// record writes op on link with the extra attributes
+func (n *Netlink) record(op string, link netlink.Link, attrs map[string]interface{}) error {
+	rec := linkRecord(link)
+	for k, v := range attrs {
+		rec[k] = v
+	}
+	return n.j.Record(netlinkComp, op, rec)
+}
+
+// LinkByName returns a simulated link of the given name
+func (n *Netlink) LinkByName(_ context.Context, name string) (netlink.Link, error) {
+	return &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: name, Index: n.indexOf(name)}}, nil
+}
+
+// LinkModify records netlink.LinkModify
+func (n *Netlink) LinkModify(_ context.Context, link netlink.Link) error {
+	return n.record("LinkModify", link, nil)
+}
+
+// LinkSetHardwareAddr records netlink.LinkSetHardwareAddr
+func (n *Netlink) LinkSetHardwareAddr(_ context.Context, link netlink.Link, hwaddr net.HardwareAddr) error {
+	return n.record("LinkSetHardwareAddr", link, map[string]interface{}{"mac": hwaddr.String()})
+}
+
+// LinkSetVfHardwareAddr records netlink.LinkSetVfHardwareAddr
+func (n *Netlink) LinkSetVfHardwareAddr(_ context.Context, link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
+	return n.record("LinkSetVfHardwareAddr", link, map[string]interface{}{"vf": vf, "mac": hwaddr.String()})
+}
+
+// AddrAdd records netlink.AddrAdd
+func (n *Netlink) AddrAdd(_ context.Context, link netlink.Link, addr *netlink.Addr) error {
+	return n.record("AddrAdd", link, map[string]interface{}{"addr": addr.String()})
+}
+
+// AddrDel records netlink.AddrDel
+func (n *Netlink) AddrDel(_ context.Context, link netlink.Link, addr *netlink.Addr) error {
+	return n.record("AddrDel", link, map[string]interface{}{"addr": addr.String()})
+}
+
+// AddrList returns no addresses
+func (n *Netlink) AddrList(_ context.Context, _ netlink.Link, _ int) ([]netlink.Addr, error) {
+	return nil, nil
+}
+
+// LinkAdd records netlink.LinkAdd
+func (n *Netlink) LinkAdd(_ context.Context, link netlink.Link) error {
+	attrs := map[string]interface{}{}
+	if link.Attrs().ParentIndex != 0 {
+		attrs["parentindex"] = link.Attrs().ParentIndex
+	}
+	link.Attrs().Index = n.indexOf(link.Attrs().Name)
+	return n.record("LinkAdd", link, attrs)
+}
+
+// LinkDel records netlink.LinkDel
+func (n *Netlink) LinkDel(_ context.Context, link netlink.Link) error {
+	return n.record("LinkDel", link, nil)
+}
+
+// LinkSetUp records netlink.LinkSetUp
+func (n *Netlink) LinkSetUp(_ context.Context, link netlink.Link) error {
+	return n.record("LinkSetUp", link, nil)
+}
+
+// LinkSetDown records netlink.LinkSetDown
+func (n *Netlink) LinkSetDown(_ context.Context, link netlink.Link) error {
+	return n.record("LinkSetDown", link, nil)
+}
+
+// LinkSetMaster records netlink.LinkSetMaster
+func (n *Netlink) LinkSetMaster(_ context.Context, link netlink.Link, master netlink.Link) error {
+	return n.record("LinkSetMaster", link, map[string]interface{}{"master": master.Attrs().Name})
+}
+
+// LinkSetNoMaster records netlink.LinkSetNoMaster
+func (n *Netlink) LinkSetNoMaster(_ context.Context, link netlink.Link) error {
+	return n.record("LinkSetNoMaster", link, nil)
+}
+
+// LinkSetNsFd records netlink.LinkSetNsFd
+func (n *Netlink) LinkSetNsFd(_ context.Context, link netlink.Link, fd int) error {
+	return n.record("LinkSetNsFd", link, map[string]interface{}{"fd": fd})
+}
+
+// LinkSetName records netlink.LinkSetName
+func (n *Netlink) LinkSetName(_ context.Context, link netlink.Link, name string) error {
+	return n.record("LinkSetName", link, map[string]interface{}{"name": name})
+}
+
+// LinkSetVfRate records netlink.LinkSetVfRate
+func (n *Netlink) LinkSetVfRate(_ context.Context, link netlink.Link, vf int, minRate int, maxRate int) error {
+	return n.record("LinkSetVfRate", link, map[string]interface{}{"vf": vf, "minrate": minRate, "maxrate": maxRate})
+}
+
+// LinkSetVfSpoofchk records netlink.LinkSetVfSpoofchk
+func (n *Netlink) LinkSetVfSpoofchk(_ context.Context, link netlink.Link, vf int, check bool) error {
+	return n.record("LinkSetVfSpoofchk", link, map[string]interface{}{"vf": vf, "check": check})
+}
+
+// LinkSetVfTrust records netlink.LinkSetVfTrust
+func (n *Netlink) LinkSetVfTrust(_ context.Context, link netlink.Link, vf int, state bool) error {
+	return n.record("LinkSetVfTrust", link, map[string]interface{}{"vf": vf, "state": state})
+}
+
+// LinkSetVfState records netlink.LinkSetVfState
+func (n *Netlink) LinkSetVfState(_ context.Context, link netlink.Link, vf int, state uint32) error {
+	return n.record("LinkSetVfState", link, map[string]interface{}{"vf": vf, "state": state})
+}
+
+// BridgeVlanAdd records netlink.BridgeVlanAdd
+func (n *Netlink) BridgeVlanAdd(_ context.Context, link netlink.Link, vid uint16, pvid, untagged, self, master bool) error {
+	return n.record("BridgeVlanAdd", link, map[string]interface{}{"vid": vid, "pvid": pvid, "untagged": untagged, "self": self, "master": master})
+}
+
+// BridgeVlanDel records netlink.BridgeVlanDel
+func (n *Netlink) BridgeVlanDel(_ context.Context, link netlink.Link, vid uint16, pvid, untagged, self, master bool) error {
+	return n.record("BridgeVlanDel", link, map[string]interface{}{"vid": vid, "pvid": pvid, "untagged": untagged, "self": self, "master": master})
+}
+
+// LinkSetMTU records netlink.LinkSetMTU
+func (n *Netlink) LinkSetMTU(_ context.Context, link netlink.Link, mtu int) error {
+	return n.record("LinkSetMTU", link, map[string]interface{}{"mtu": mtu})
+}
+
+// BridgeFdbAdd records the bridge fdb add command
+func (n *Netlink) BridgeFdbAdd(_ context.Context, link string, macAddress string) error {
+	return n.j.Record(netlinkComp, "BridgeFdbAdd", map[string]interface{}{"link": link, "mac": macAddress})
+}
+
+// RouteAdd records netlink.RouteAdd
+func (n *Netlink) RouteAdd(_ context.Context, route *netlink.Route) error {
+	return n.j.Record(netlinkComp, "RouteAdd", map[string]interface{}{"route": route.String()})
+}
+
+// RouteListFiltered returns no routes
+func (n *Netlink) RouteListFiltered(_ context.Context, _ int, _ *netlink.Route, _ uint64) ([]netlink.Route, error) {
+	return nil, nil
+}
+
+// RouteFlushTable records the route flush command
+func (n *Netlink) RouteFlushTable(_ context.Context, routingTable string) error {
+	return n.j.Record(netlinkComp, "RouteFlushTable", map[string]interface{}{"table": routingTable})
+}
+
+// RouteListIPTable reports the table as empty
+func (n *Netlink) RouteListIPTable(_ context.Context, _ string) bool {
+	return false
+}
+
+// LinkSetBrNeighSuppress records netlink.LinkSetBrNeighSuppress
+func (n *Netlink) LinkSetBrNeighSuppress(_ context.Context, link netlink.Link, neighSuppress bool) error {
+	return n.record("LinkSetBrNeighSuppress", link, map[string]interface{}{"neighsuppress": neighSuppress})
+}
+
+// ReadNeigh returns an empty neighbor dump
+func (n *Netlink) ReadNeigh(_ context.Context, _ string) (string, error) {
+	return "[]", nil
+}
+
+// ReadRoute returns an empty route dump
+func (n *Netlink) ReadRoute(_ context.Context, _ string) (string, error) {
+	return "[]", nil
+}
+
+// ReadFDB returns an empty fdb dump
+func (n *Netlink) ReadFDB(_ context.Context) (string, error) {
+	return "[]", nil
+}
+
+// RouteLookup returns an empty lookup result
+func (n *Netlink) RouteLookup(_ context.Context, _ string, _ string) (string, error) {
+	return "[]", nil
+}
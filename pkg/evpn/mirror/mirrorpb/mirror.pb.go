@@ -0,0 +1,634 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: mirror.proto
+
+package mirrorpb
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Copies the traffic of a bridge port, svi or vrf to a representor, plain
+// or in an erspan tunnel to a collector
+type MirrorSession struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the session
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Name of the mirrored bridge port, svi or vrf
+	Source string `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	// Kind of the source: bridge-port, svi or vrf
+	SourceKind string `protobuf:"bytes,3,opt,name=source_kind,json=sourceKind,proto3" json:"source_kind,omitempty"`
+	// Mirrored direction: rx, tx or both
+	Direction string `protobuf:"bytes,4,opt,name=direction,proto3" json:"direction,omitempty"`
+	// Representor the copies are sent to
+	Representor string `protobuf:"bytes,5,opt,name=representor,proto3" json:"representor,omitempty"`
+	// Erspan tunnel of the copies, plain copies when unset
+	Erspan *ErspanTunnel `protobuf:"bytes,6,opt,name=erspan,proto3" json:"erspan,omitempty"`
+	// Clone session id given by the plugin
+	Id uint32 `protobuf:"varint,7,opt,name=id,proto3" json:"id,omitempty"`
+	// Set while the source is deleted
+	Detached bool `protobuf:"varint,8,opt,name=detached,proto3" json:"detached,omitempty"`
+}
+
+func (x *MirrorSession) Reset() {
+	*x = MirrorSession{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mirror_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MirrorSession) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MirrorSession) ProtoMessage() {}
+
+func (x *MirrorSession) ProtoReflect() protoreflect.Message {
+	mi := &file_mirror_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MirrorSession.ProtoReflect.Descriptor instead.
+func (*MirrorSession) Descriptor() ([]byte, []int) {
+	return file_mirror_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MirrorSession) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MirrorSession) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *MirrorSession) GetSourceKind() string {
+	if x != nil {
+		return x.SourceKind
+	}
+	return ""
+}
+
+func (x *MirrorSession) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *MirrorSession) GetRepresentor() string {
+	if x != nil {
+		return x.Representor
+	}
+	return ""
+}
+
+func (x *MirrorSession) GetErspan() *ErspanTunnel {
+	if x != nil {
+		return x.Erspan
+	}
+	return nil
+}
+
+func (x *MirrorSession) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *MirrorSession) GetDetached() bool {
+	if x != nil {
+		return x.Detached
+	}
+	return false
+}
+
+// Erspan type II tunnel of a mirror session
+type ErspanTunnel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Ipv4 address of the collector
+	Collector string `protobuf:"bytes,1,opt,name=collector,proto3" json:"collector,omitempty"`
+	// Ipv4 source address of the tunnel
+	Source string `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	// Mac of the nexthop towards the collector
+	NexthopMac string `protobuf:"bytes,3,opt,name=nexthop_mac,json=nexthopMac,proto3" json:"nexthop_mac,omitempty"`
+	// Erspan session id, up to 1023
+	SessionId uint32 `protobuf:"varint,4,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+}
+
+func (x *ErspanTunnel) Reset() {
+	*x = ErspanTunnel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mirror_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ErspanTunnel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErspanTunnel) ProtoMessage() {}
+
+func (x *ErspanTunnel) ProtoReflect() protoreflect.Message {
+	mi := &file_mirror_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErspanTunnel.ProtoReflect.Descriptor instead.
+func (*ErspanTunnel) Descriptor() ([]byte, []int) {
+	return file_mirror_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ErspanTunnel) GetCollector() string {
+	if x != nil {
+		return x.Collector
+	}
+	return ""
+}
+
+func (x *ErspanTunnel) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ErspanTunnel) GetNexthopMac() string {
+	if x != nil {
+		return x.NexthopMac
+	}
+	return ""
+}
+
+func (x *ErspanTunnel) GetSessionId() uint32 {
+	if x != nil {
+		return x.SessionId
+	}
+	return 0
+}
+
+// Request of CreateMirrorSession
+type CreateMirrorSessionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Session to create
+	MirrorSession *MirrorSession `protobuf:"bytes,1,opt,name=mirror_session,json=mirrorSession,proto3" json:"mirror_session,omitempty"`
+}
+
+func (x *CreateMirrorSessionRequest) Reset() {
+	*x = CreateMirrorSessionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mirror_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateMirrorSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateMirrorSessionRequest) ProtoMessage() {}
+
+func (x *CreateMirrorSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mirror_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateMirrorSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateMirrorSessionRequest) Descriptor() ([]byte, []int) {
+	return file_mirror_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateMirrorSessionRequest) GetMirrorSession() *MirrorSession {
+	if x != nil {
+		return x.MirrorSession
+	}
+	return nil
+}
+
+// Request of DeleteMirrorSession
+type DeleteMirrorSessionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the session to delete
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *DeleteMirrorSessionRequest) Reset() {
+	*x = DeleteMirrorSessionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mirror_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteMirrorSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMirrorSessionRequest) ProtoMessage() {}
+
+func (x *DeleteMirrorSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mirror_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMirrorSessionRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMirrorSessionRequest) Descriptor() ([]byte, []int) {
+	return file_mirror_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DeleteMirrorSessionRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Request of ListMirrorSessions
+type ListMirrorSessionsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListMirrorSessionsRequest) Reset() {
+	*x = ListMirrorSessionsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mirror_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMirrorSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMirrorSessionsRequest) ProtoMessage() {}
+
+func (x *ListMirrorSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mirror_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMirrorSessionsRequest.ProtoReflect.Descriptor instead.
+func (*ListMirrorSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_mirror_proto_rawDescGZIP(), []int{4}
+}
+
+// Response of ListMirrorSessions
+type ListMirrorSessionsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Sessions sorted by name
+	MirrorSessions []*MirrorSession `protobuf:"bytes,1,rep,name=mirror_sessions,json=mirrorSessions,proto3" json:"mirror_sessions,omitempty"`
+}
+
+func (x *ListMirrorSessionsResponse) Reset() {
+	*x = ListMirrorSessionsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mirror_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMirrorSessionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMirrorSessionsResponse) ProtoMessage() {}
+
+func (x *ListMirrorSessionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mirror_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMirrorSessionsResponse.ProtoReflect.Descriptor instead.
+func (*ListMirrorSessionsResponse) Descriptor() ([]byte, []int) {
+	return file_mirror_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListMirrorSessionsResponse) GetMirrorSessions() []*MirrorSession {
+	if x != nil {
+		return x.MirrorSessions
+	}
+	return nil
+}
+
+var File_mirror_proto protoreflect.FileDescriptor
+
+var file_mirror_proto_rawDesc = []byte{
+	0x0a, 0x0c, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1a,
+	0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65,
+	0x2e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x8a, 0x02, 0x0a, 0x0d, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72,
+	0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x6b, 0x69,
+	0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x4b, 0x69, 0x6e, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x72, 0x65, 0x70, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x74, 0x6f,
+	0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x70, 0x72, 0x65, 0x73, 0x65,
+	0x6e, 0x74, 0x6f, 0x72, 0x12, 0x40, 0x0a, 0x06, 0x65, 0x72, 0x73, 0x70, 0x61, 0x6e, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c,
+	0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76,
+	0x31, 0x2e, 0x45, 0x72, 0x73, 0x70, 0x61, 0x6e, 0x54, 0x75, 0x6e, 0x6e, 0x65, 0x6c, 0x52, 0x06,
+	0x65, 0x72, 0x73, 0x70, 0x61, 0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x65, 0x74, 0x61, 0x63, 0x68,
+	0x65, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x64, 0x65, 0x74, 0x61, 0x63, 0x68,
+	0x65, 0x64, 0x22, 0x84, 0x01, 0x0a, 0x0c, 0x45, 0x72, 0x73, 0x70, 0x61, 0x6e, 0x54, 0x75, 0x6e,
+	0x6e, 0x65, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f,
+	0x72, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x65, 0x78,
+	0x74, 0x68, 0x6f, 0x70, 0x5f, 0x6d, 0x61, 0x63, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x6e, 0x65, 0x78, 0x74, 0x68, 0x6f, 0x70, 0x4d, 0x61, 0x63, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09,
+	0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0x6e, 0x0a, 0x1a, 0x43, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x50, 0x0a, 0x0e, 0x6d, 0x69, 0x72, 0x72, 0x6f,
+	0x72, 0x5f, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x29, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64,
+	0x67, 0x65, 0x2e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x69, 0x72,
+	0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0d, 0x6d, 0x69, 0x72, 0x72,
+	0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x30, 0x0a, 0x1a, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x1b, 0x0a, 0x19, 0x4c,
+	0x69, 0x73, 0x74, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x70, 0x0a, 0x1a, 0x4c, 0x69, 0x73, 0x74,
+	0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x52, 0x0a, 0x0f, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72,
+	0x5f, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x29, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64,
+	0x67, 0x65, 0x2e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x69, 0x72,
+	0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0e, 0x6d, 0x69, 0x72, 0x72,
+	0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x32, 0x8a, 0x04, 0x0a, 0x0d, 0x4d,
+	0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0xb1, 0x01, 0x0a,
+	0x13, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x36, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c,
+	0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76,
+	0x31, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x6f,
+	0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e,
+	0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72,
+	0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x37, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x31, 0x3a,
+	0x0e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x22,
+	0x1f, 0x2f, 0x76, 0x31, 0x2f, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2f, 0x69, 0x6e, 0x74, 0x65,
+	0x6c, 0x2d, 0x65, 0x32, 0x30, 0x30, 0x30, 0x2f, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73,
+	0x12, 0x95, 0x01, 0x0a, 0x13, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x4d, 0x69, 0x72, 0x72, 0x6f,
+	0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x36, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69,
+	0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x6d, 0x69, 0x72, 0x72,
+	0x6f, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x4d, 0x69, 0x72, 0x72,
+	0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x2e, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x28,
+	0x2a, 0x26, 0x2f, 0x76, 0x31, 0x2f, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2f, 0x69, 0x6e, 0x74,
+	0x65, 0x6c, 0x2d, 0x65, 0x32, 0x30, 0x30, 0x30, 0x2f, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x73, 0x2f, 0x7b, 0x6e, 0x61, 0x6d, 0x65, 0x7d, 0x12, 0xac, 0x01, 0x0a, 0x12, 0x4c, 0x69, 0x73,
+	0x74, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12,
+	0x35, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64,
+	0x67, 0x65, 0x2e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x36, 0x2e, 0x6f, 0x70, 0x69, 0x5f, 0x69, 0x6e, 0x74,
+	0x65, 0x6c, 0x5f, 0x62, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2e, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72,
+	0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x53, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x27,
+	0x82, 0xd3, 0xe4, 0x93, 0x02, 0x21, 0x12, 0x1f, 0x2f, 0x76, 0x31, 0x2f, 0x6d, 0x69, 0x72, 0x72,
+	0x6f, 0x72, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x2d, 0x65, 0x32, 0x30, 0x30, 0x30, 0x2f, 0x73,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x42, 0x41, 0x5a, 0x3f, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6f, 0x70, 0x69, 0x70, 0x72, 0x6f, 0x6a, 0x65, 0x63, 0x74,
+	0x2f, 0x6f, 0x70, 0x69, 0x2d, 0x69, 0x6e, 0x74, 0x65, 0x6c, 0x2d, 0x62, 0x72, 0x69, 0x64, 0x67,
+	0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x65, 0x76, 0x70, 0x6e, 0x2f, 0x6d, 0x69, 0x72, 0x72, 0x6f,
+	0x72, 0x2f, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_mirror_proto_rawDescOnce sync.Once
+	file_mirror_proto_rawDescData = file_mirror_proto_rawDesc
+)
+
+func file_mirror_proto_rawDescGZIP() []byte {
+	file_mirror_proto_rawDescOnce.Do(func() {
+		file_mirror_proto_rawDescData = protoimpl.X.CompressGZIP(file_mirror_proto_rawDescData)
+	})
+	return file_mirror_proto_rawDescData
+}
+
+var file_mirror_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_mirror_proto_goTypes = []interface{}{
+	(*MirrorSession)(nil),              // 0: opi_intel_bridge.mirror.v1.MirrorSession
+	(*ErspanTunnel)(nil),               // 1: opi_intel_bridge.mirror.v1.ErspanTunnel
+	(*CreateMirrorSessionRequest)(nil), // 2: opi_intel_bridge.mirror.v1.CreateMirrorSessionRequest
+	(*DeleteMirrorSessionRequest)(nil), // 3: opi_intel_bridge.mirror.v1.DeleteMirrorSessionRequest
+	(*ListMirrorSessionsRequest)(nil),  // 4: opi_intel_bridge.mirror.v1.ListMirrorSessionsRequest
+	(*ListMirrorSessionsResponse)(nil), // 5: opi_intel_bridge.mirror.v1.ListMirrorSessionsResponse
+	(*emptypb.Empty)(nil),              // 6: google.protobuf.Empty
+}
+var file_mirror_proto_depIdxs = []int32{
+	1, // 0: opi_intel_bridge.mirror.v1.MirrorSession.erspan:type_name -> opi_intel_bridge.mirror.v1.ErspanTunnel
+	0, // 1: opi_intel_bridge.mirror.v1.CreateMirrorSessionRequest.mirror_session:type_name -> opi_intel_bridge.mirror.v1.MirrorSession
+	0, // 2: opi_intel_bridge.mirror.v1.ListMirrorSessionsResponse.mirror_sessions:type_name -> opi_intel_bridge.mirror.v1.MirrorSession
+	2, // 3: opi_intel_bridge.mirror.v1.MirrorService.CreateMirrorSession:input_type -> opi_intel_bridge.mirror.v1.CreateMirrorSessionRequest
+	3, // 4: opi_intel_bridge.mirror.v1.MirrorService.DeleteMirrorSession:input_type -> opi_intel_bridge.mirror.v1.DeleteMirrorSessionRequest
+	4, // 5: opi_intel_bridge.mirror.v1.MirrorService.ListMirrorSessions:input_type -> opi_intel_bridge.mirror.v1.ListMirrorSessionsRequest
+	0, // 6: opi_intel_bridge.mirror.v1.MirrorService.CreateMirrorSession:output_type -> opi_intel_bridge.mirror.v1.MirrorSession
+	6, // 7: opi_intel_bridge.mirror.v1.MirrorService.DeleteMirrorSession:output_type -> google.protobuf.Empty
+	5, // 8: opi_intel_bridge.mirror.v1.MirrorService.ListMirrorSessions:output_type -> opi_intel_bridge.mirror.v1.ListMirrorSessionsResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_mirror_proto_init() }
+func file_mirror_proto_init() {
+	if File_mirror_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_mirror_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MirrorSession); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mirror_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ErspanTunnel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mirror_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateMirrorSessionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mirror_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteMirrorSessionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mirror_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMirrorSessionsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mirror_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMirrorSessionsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_mirror_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mirror_proto_goTypes,
+		DependencyIndexes: file_mirror_proto_depIdxs,
+		MessageInfos:      file_mirror_proto_msgTypes,
+	}.Build()
+	File_mirror_proto = out.File
+	file_mirror_proto_rawDesc = nil
+	file_mirror_proto_goTypes = nil
+	file_mirror_proto_depIdxs = nil
+}
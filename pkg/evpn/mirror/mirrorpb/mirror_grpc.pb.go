@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: mirror.proto
+
+package mirrorpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	MirrorService_CreateMirrorSession_FullMethodName = "/opi_intel_bridge.mirror.v1.MirrorService/CreateMirrorSession"
+	MirrorService_DeleteMirrorSession_FullMethodName = "/opi_intel_bridge.mirror.v1.MirrorService/DeleteMirrorSession"
+	MirrorService_ListMirrorSessions_FullMethodName  = "/opi_intel_bridge.mirror.v1.MirrorService/ListMirrorSessions"
+)
+
+// MirrorServiceClient is the client API for MirrorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MirrorServiceClient interface {
+	// Create a mirror session
+	CreateMirrorSession(ctx context.Context, in *CreateMirrorSessionRequest, opts ...grpc.CallOption) (*MirrorSession, error)
+	// Delete a mirror session
+	DeleteMirrorSession(ctx context.Context, in *DeleteMirrorSessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// List the mirror sessions
+	ListMirrorSessions(ctx context.Context, in *ListMirrorSessionsRequest, opts ...grpc.CallOption) (*ListMirrorSessionsResponse, error)
+}
+
+type mirrorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMirrorServiceClient(cc grpc.ClientConnInterface) MirrorServiceClient {
+	return &mirrorServiceClient{cc}
+}
+
+func (c *mirrorServiceClient) CreateMirrorSession(ctx context.Context, in *CreateMirrorSessionRequest, opts ...grpc.CallOption) (*MirrorSession, error) {
+	out := new(MirrorSession)
+	err := c.cc.Invoke(ctx, MirrorService_CreateMirrorSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mirrorServiceClient) DeleteMirrorSession(ctx context.Context, in *DeleteMirrorSessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, MirrorService_DeleteMirrorSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mirrorServiceClient) ListMirrorSessions(ctx context.Context, in *ListMirrorSessionsRequest, opts ...grpc.CallOption) (*ListMirrorSessionsResponse, error) {
+	out := new(ListMirrorSessionsResponse)
+	err := c.cc.Invoke(ctx, MirrorService_ListMirrorSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MirrorServiceServer is the server API for MirrorService service.
+// All implementations must embed UnimplementedMirrorServiceServer
+// for forward compatibility
+type MirrorServiceServer interface {
+	// Create a mirror session
+	CreateMirrorSession(context.Context, *CreateMirrorSessionRequest) (*MirrorSession, error)
+	// Delete a mirror session
+	DeleteMirrorSession(context.Context, *DeleteMirrorSessionRequest) (*emptypb.Empty, error)
+	// List the mirror sessions
+	ListMirrorSessions(context.Context, *ListMirrorSessionsRequest) (*ListMirrorSessionsResponse, error)
+	mustEmbedUnimplementedMirrorServiceServer()
+}
+
+// UnimplementedMirrorServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedMirrorServiceServer struct {
+}
+
+func (UnimplementedMirrorServiceServer) CreateMirrorSession(context.Context, *CreateMirrorSessionRequest) (*MirrorSession, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateMirrorSession not implemented")
+}
+func (UnimplementedMirrorServiceServer) DeleteMirrorSession(context.Context, *DeleteMirrorSessionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMirrorSession not implemented")
+}
+func (UnimplementedMirrorServiceServer) ListMirrorSessions(context.Context, *ListMirrorSessionsRequest) (*ListMirrorSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMirrorSessions not implemented")
+}
+func (UnimplementedMirrorServiceServer) mustEmbedUnimplementedMirrorServiceServer() {}
+
+// UnsafeMirrorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MirrorServiceServer will
+// result in compilation errors.
+type UnsafeMirrorServiceServer interface {
+	mustEmbedUnimplementedMirrorServiceServer()
+}
+
+func RegisterMirrorServiceServer(s grpc.ServiceRegistrar, srv MirrorServiceServer) {
+	s.RegisterService(&MirrorService_ServiceDesc, srv)
+}
+
+func _MirrorService_CreateMirrorSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateMirrorSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MirrorServiceServer).CreateMirrorSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MirrorService_CreateMirrorSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MirrorServiceServer).CreateMirrorSession(ctx, req.(*CreateMirrorSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MirrorService_DeleteMirrorSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMirrorSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MirrorServiceServer).DeleteMirrorSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MirrorService_DeleteMirrorSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MirrorServiceServer).DeleteMirrorSession(ctx, req.(*DeleteMirrorSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MirrorService_ListMirrorSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMirrorSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MirrorServiceServer).ListMirrorSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MirrorService_ListMirrorSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MirrorServiceServer).ListMirrorSessions(ctx, req.(*ListMirrorSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MirrorService_ServiceDesc is the grpc.ServiceDesc for MirrorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MirrorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opi_intel_bridge.mirror.v1.MirrorService",
+	HandlerType: (*MirrorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateMirrorSession",
+			Handler:    _MirrorService_CreateMirrorSession_Handler,
+		},
+		{
+			MethodName: "DeleteMirrorSession",
+			Handler:    _MirrorService_DeleteMirrorSession_Handler,
+		},
+		{
+			MethodName: "ListMirrorSessions",
+			Handler:    _MirrorService_ListMirrorSessions_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "mirror.proto",
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package mirror implements the mirror service of the intel-e2000 plugin,
+// the gateway serves it from mirrorpb
+//
+//nolint:all
+package mirror
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/opiproject/opi-intel-bridge/pkg/evpn/mirror/mirrorpb"
+	ipu_vendor "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4translation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Server implements the mirror service
+type Server struct {
+	pb.UnimplementedMirrorServiceServer
+}
+
+// NewServer creates the mirror server
+func NewServer() *Server {
+	return &Server{}
+}
+
+// CreateMirrorSession creates the session and returns it with its clone
+// session id
+func (s *Server) CreateMirrorSession(_ context.Context, in *pb.CreateMirrorSessionRequest) (*pb.MirrorSession, error) {
+	if in.GetMirrorSession() == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing required field: mirror_session")
+	}
+	session, err := ipu_vendor.CreateMirrorSession(fromPb(in.GetMirrorSession()))
+	if err != nil {
+		return nil, mirrorStatus(err)
+	}
+	return toPb(session), nil
+}
+
+// DeleteMirrorSession deletes the session of the given name
+func (s *Server) DeleteMirrorSession(_ context.Context, in *pb.DeleteMirrorSessionRequest) (*emptypb.Empty, error) {
+	if err := ipu_vendor.DeleteMirrorSession(in.GetName()); err != nil {
+		return nil, mirrorStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ListMirrorSessions returns the sessions sorted by name
+func (s *Server) ListMirrorSessions(_ context.Context, _ *pb.ListMirrorSessionsRequest) (*pb.ListMirrorSessionsResponse, error) {
+	out := &pb.ListMirrorSessionsResponse{}
+	for _, session := range ipu_vendor.MirrorSessions() {
+		out.MirrorSessions = append(out.MirrorSessions, toPb(session))
+	}
+	return out, nil
+}
+
+// mirrorStatus maps the errors of the plugin to the grpc codes
+func mirrorStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, ipu_vendor.ErrInvalidMirrorSession):
+		code = codes.InvalidArgument
+	case errors.Is(err, ipu_vendor.ErrMirrorSessionExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, ipu_vendor.ErrMirrorSessionNotFound), errors.Is(err, ipu_vendor.ErrMirrorSourceNotFound):
+		code = codes.NotFound
+	case errors.Is(err, ipu_vendor.ErrMirrorIDsExhausted):
+		code = codes.ResourceExhausted
+	case errors.Is(err, ipu_vendor.ErrMirroringDisabled), errors.Is(err, ipu_vendor.ErrErspanUnsupported),
+		errors.Is(err, ipu_vendor.ErrMirrorSourceNotReady):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
+
+// fromPb converts the session of a request to the plugin session
+func fromPb(in *pb.MirrorSession) ipu_vendor.MirrorSession {
+	session := ipu_vendor.MirrorSession{
+		Name:        in.GetName(),
+		Source:      in.GetSource(),
+		SourceKind:  in.GetSourceKind(),
+		Direction:   in.GetDirection(),
+		Representor: in.GetRepresentor(),
+	}
+	if e := in.GetErspan(); e != nil {
+		session.Erspan = &ipu_vendor.ErspanTunnel{
+			Collector:  e.GetCollector(),
+			Source:     e.GetSource(),
+			NexthopMac: e.GetNexthopMac(),
+			SessionID:  e.GetSessionId(),
+		}
+	}
+	return session
+}
+
+// toPb converts a plugin session to its message
+func toPb(session ipu_vendor.MirrorSession) *pb.MirrorSession {
+	out := &pb.MirrorSession{
+		Name:        session.Name,
+		Source:      session.Source,
+		SourceKind:  session.SourceKind,
+		Direction:   session.Direction,
+		Representor: session.Representor,
+		Id:          session.ID,
+		Detached:    session.Detached,
+	}
+	if e := session.Erspan; e != nil {
+		out.Erspan = &pb.ErspanTunnel{
+			Collector:  e.Collector,
+			Source:     e.Source,
+			NexthopMac: e.NexthopMac,
+			SessionId:  e.SessionID,
+		}
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	pb "github.com/opiproject/opi-intel-bridge/pkg/evpn/mirror/mirrorpb"
+	ipu_vendor "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4translation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sessionsPath is the gateway path of the sessions
+const sessionsPath = "/v1/mirror/intel-e2000/sessions"
+
+func TestMirrorServiceHandler(t *testing.T) {
+	tests := map[string]struct {
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		"list sessions": {
+			method: http.MethodGet,
+			path:   sessionsPath,
+			code:   http.StatusOK,
+		},
+		"create from invalid json": {
+			method: http.MethodPost,
+			path:   sessionsPath,
+			body:   "{",
+			code:   http.StatusBadRequest,
+		},
+		"create without a session": {
+			method: http.MethodPost,
+			path:   sessionsPath,
+			code:   http.StatusBadRequest,
+		},
+		"create with mirroring disabled": {
+			method: http.MethodPost,
+			path:   sessionsPath,
+			body:   `{"name": "capture", "sourceKind": "port"}`,
+			code:   http.StatusBadRequest,
+		},
+		"delete with mirroring disabled": {
+			method: http.MethodDelete,
+			path:   sessionsPath + "/capture",
+			code:   http.StatusBadRequest,
+		},
+	}
+	mux := runtime.NewServeMux()
+	if err := pb.RegisterMirrorServiceHandlerServer(context.Background(), mux, NewServer()); err != nil {
+		t.Fatalf("RegisterMirrorServiceHandlerServer failed: %v", err)
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Errorf("Expected code: %v, received %v %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMirrorStatus(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		code codes.Code
+	}{
+		"mirroring disabled": {
+			err:  ipu_vendor.ErrMirroringDisabled,
+			code: codes.FailedPrecondition,
+		},
+		"invalid session": {
+			err:  fmt.Errorf("%w: unknown direction", ipu_vendor.ErrInvalidMirrorSession),
+			code: codes.InvalidArgument,
+		},
+		"name taken": {
+			err:  fmt.Errorf("%w: capture", ipu_vendor.ErrMirrorSessionExists),
+			code: codes.AlreadyExists,
+		},
+		"unknown source": {
+			err:  fmt.Errorf("%w: bp-b", ipu_vendor.ErrMirrorSourceNotFound),
+			code: codes.NotFound,
+		},
+		"ids exhausted": {
+			err:  fmt.Errorf("%w for capture", ipu_vendor.ErrMirrorIDsExhausted),
+			code: codes.ResourceExhausted,
+		},
+		"programming failure": {
+			err:  errors.New("write failed"),
+			code: codes.Internal,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if code := status.Code(mirrorStatus(tt.err)); code != tt.code {
+				t.Errorf("Expected code: %v, received %v", tt.code, code)
+			}
+		})
+	}
+}
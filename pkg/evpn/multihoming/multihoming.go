@@ -0,0 +1,357 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package multihoming keeps the evpn ethernet segments the bridge ports of
+// dual homed servers are attached to
+//
+//nolint:all
+package multihoming
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Segment is an ethernet segment shared by the bridge ports with the peer
+// vteps the servers are also attached to
+type Segment struct {
+	ESI         string   `mapstructure:"esi"`
+	BridgePorts []string `mapstructure:"bridgeports"`
+	Peers       []string `mapstructure:"peers"`
+}
+
+var (
+	mu       sync.RWMutex
+	segments []Segment
+	// elected is the designated forwarder state frr reports by segment
+	elected map[string]bool
+)
+
+// Set validates and stores the ethernet segments
+func Set(segs []Segment) error {
+	esis := make(map[string]bool)
+	ports := make(map[string]string)
+	for i := range segs {
+		s := &segs[i]
+		esi, err := ParseESI(s.ESI)
+		if err != nil {
+			return fmt.Errorf("multihoming: segment %d: %w", i+1, err)
+		}
+		s.ESI = FormatESI(esi)
+		if esis[s.ESI] {
+			return fmt.Errorf("multihoming: duplicate segment %s", s.ESI)
+		}
+		esis[s.ESI] = true
+		if len(s.BridgePorts) == 0 || len(s.Peers) == 0 {
+			return fmt.Errorf("multihoming: segment %s needs bridge ports and peers", s.ESI)
+		}
+		for _, bp := range s.BridgePorts {
+			if other, ok := ports[path.Base(bp)]; ok {
+				return fmt.Errorf("multihoming: bridge port %s is in segments %s and %s", bp, other, s.ESI)
+			}
+			ports[path.Base(bp)] = s.ESI
+		}
+		for j, p := range s.Peers {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return fmt.Errorf("multihoming: segment %s: invalid peer %s", s.ESI, p)
+			}
+			s.Peers[j] = ip.String()
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	segments = segs
+	elected = nil
+	return nil
+}
+
+// Segments returns the ethernet segments
+func Segments() []Segment {
+	mu.RLock()
+	defer mu.RUnlock()
+	return segments
+}
+
+// Lookup returns the segment of the esi
+func Lookup(esi string) (Segment, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, s := range segments {
+		if s.ESI == esi {
+			return s, true
+		}
+	}
+	return Segment{}, false
+}
+
+// SegmentOf returns the segment of the bridge port, the port is given by
+// its name or its full resource name
+func SegmentOf(bridgePort string) (Segment, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, s := range segments {
+		for _, bp := range s.BridgePorts {
+			if path.Base(bp) == path.Base(bridgePort) {
+				return s, true
+			}
+		}
+	}
+	return Segment{}, false
+}
+
+// Peers returns the peer vteps of all segments, sorted
+func Peers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	seen := make(map[string]bool)
+	var peers []string
+	for _, s := range segments {
+		for _, p := range s.Peers {
+			if !seen[p] {
+				seen[p] = true
+				peers = append(peers, p)
+			}
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool { return lessIP(net.ParseIP(peers[i]), net.ParseIP(peers[j])) })
+	return peers
+}
+
+// SharedWith tells whether the peer vtep is attached to the segment
+func (s Segment) SharedWith(vtep string) bool {
+	for _, p := range s.Peers {
+		if p == vtep {
+			return true
+		}
+	}
+	return false
+}
+
+// DesignatedForwarder tells whether this vtep forwards the BUM traffic of
+// the vlan onto the segment. The election frr reports wins, frr elects per
+// segment so it holds for every vlan. Until frr reports the segment the
+// default service carving of RFC 7432 is used: the vteps of the segment are
+// ordered by address and the one at vid modulo their number is elected.
+func (s Segment) DesignatedForwarder(vid uint16, local net.IP) bool {
+	mu.RLock()
+	df, ok := elected[s.ESI]
+	mu.RUnlock()
+	if ok {
+		return df
+	}
+	vteps := []net.IP{local}
+	for _, p := range s.Peers {
+		vteps = append(vteps, net.ParseIP(p))
+	}
+	sort.Slice(vteps, func(i, j int) bool { return lessIP(vteps[i], vteps[j]) })
+	return vteps[int(vid)%len(vteps)].Equal(local)
+}
+
+// SetElections stores the designated forwarder state of the segments as
+// reported by frr, segments missing fall back to the service carving. It
+// tells whether the state changed.
+func SetElections(df map[string]bool) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(df) == len(elected) {
+		same := true
+		for esi, v := range df {
+			if old, ok := elected[esi]; !ok || old != v {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false
+		}
+	}
+	elected = df
+	return true
+}
+
+// frrSegment is a segment in the output of "show evpn es detail json"
+type frrSegment struct {
+	ESI   string    `json:"esi"`
+	Flags []string  `json:"flags"`
+	Vteps []frrVtep `json:"vteps"`
+}
+
+// frrVtep is a vtep of a segment in the output of "show evpn es detail json"
+type frrVtep struct {
+	Vtep string `json:"vtep"`
+}
+
+// frrMac is a mac in the output of "show evpn mac vni all json"
+type frrMac struct {
+	Type     string `json:"type"`
+	RemoteEs string `json:"remoteEs"`
+}
+
+// RemoteMac is a mac frr learned from a remote segment, by the vni it is in
+type RemoteMac struct {
+	Vni uint32
+	Mac string
+}
+
+// ParseElections reads the designated forwarder state of the local
+// segments from the output of "show evpn es detail json", zebra flags the
+// segments where this vtep is not the designated forwarder with nonDF. The
+// output may hold the command echo and the prompt of the zebra vty.
+func ParseElections(out []byte) (map[string]bool, error) {
+	var frr []frrSegment
+	if err := json.Unmarshal(vtyJSON(out), &frr); err != nil {
+		return nil, fmt.Errorf("multihoming: reading the frr segments: %w", err)
+	}
+	df := make(map[string]bool)
+	for _, s := range frr {
+		esi, err := ParseESI(s.ESI)
+		if err != nil {
+			return nil, fmt.Errorf("multihoming: frr segment: %w", err)
+		}
+		local, nonDF := false, false
+		for _, f := range s.Flags {
+			switch f {
+			case "local":
+				local = true
+			case "nonDF":
+				nonDF = true
+			}
+		}
+		if local {
+			df[FormatESI(esi)] = !nonDF
+		}
+	}
+	return df, nil
+}
+
+// ParseRemoteVteps reads the vteps attached to the segments from the
+// output of "show evpn es detail json", sorted by address. The local vtep
+// is not listed.
+func ParseRemoteVteps(out []byte) (map[string][]string, error) {
+	var frr []frrSegment
+	if err := json.Unmarshal(vtyJSON(out), &frr); err != nil {
+		return nil, fmt.Errorf("multihoming: reading the frr segments: %w", err)
+	}
+	vteps := make(map[string][]string)
+	for _, s := range frr {
+		esi, err := ParseESI(s.ESI)
+		if err != nil {
+			return nil, fmt.Errorf("multihoming: frr segment: %w", err)
+		}
+		var ips []net.IP
+		for _, v := range s.Vteps {
+			ip := net.ParseIP(v.Vtep)
+			if ip == nil {
+				return nil, fmt.Errorf("multihoming: frr segment %s: invalid vtep %q", s.ESI, v.Vtep)
+			}
+			ips = append(ips, ip)
+		}
+		if len(ips) == 0 {
+			continue
+		}
+		sort.Slice(ips, func(i, j int) bool { return lessIP(ips[i], ips[j]) })
+		for _, ip := range ips {
+			vteps[FormatESI(esi)] = append(vteps[FormatESI(esi)], ip.String())
+		}
+	}
+	return vteps, nil
+}
+
+// ParseRemoteMacs reads the segments of the remote macs from the output of
+// "show evpn mac vni all json", the macs of a single vtep are left out
+func ParseRemoteMacs(out []byte) (map[RemoteMac]string, error) {
+	var frr map[string]struct {
+		Macs map[string]frrMac `json:"macs"`
+	}
+	if err := json.Unmarshal(vtyJSON(out), &frr); err != nil {
+		return nil, fmt.Errorf("multihoming: reading the frr macs: %w", err)
+	}
+	macs := make(map[RemoteMac]string)
+	for v, vni := range frr {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("multihoming: frr macs: invalid vni %q", v)
+		}
+		for m, mac := range vni.Macs {
+			if mac.Type != "remote" || mac.RemoteEs == "" {
+				continue
+			}
+			hw, err := net.ParseMAC(m)
+			if err != nil {
+				return nil, fmt.Errorf("multihoming: frr macs: %w", err)
+			}
+			esi, err := ParseESI(mac.RemoteEs)
+			if err != nil {
+				return nil, fmt.Errorf("multihoming: frr mac %s: %w", m, err)
+			}
+			macs[RemoteMac{Vni: uint32(n), Mac: hw.String()}] = FormatESI(esi)
+		}
+	}
+	return macs, nil
+}
+
+// vtyJSON cuts the json out of the output of a vty session, the output of
+// vtysh is returned as it is
+func vtyJSON(out []byte) []byte {
+	start := bytes.IndexAny(out, "[{")
+	if start < 0 {
+		return out
+	}
+	closer := byte(']')
+	if out[start] == '{' {
+		closer = '}'
+	}
+	end := bytes.LastIndexByte(out, closer)
+	if end < start {
+		return out
+	}
+	return out[start : end+1]
+}
+
+// lessIP orders addresses numerically, ipv4 before ipv6
+func lessIP(a, b net.IP) bool {
+	if (a.To4() == nil) != (b.To4() == nil) {
+		return a.To4() != nil
+	}
+	return bytes.Compare(a.To16(), b.To16()) < 0
+}
+
+// ParseESI parses the ten octets of an ethernet segment identifier written
+// as colon separated hex, the reserved all zero and all ones values are
+// refused
+func ParseESI(esi string) ([]byte, error) {
+	parts := strings.Split(esi, ":")
+	if len(parts) != 10 {
+		return nil, fmt.Errorf("invalid esi %q", esi)
+	}
+	b := make([]byte, 10)
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 16, 8)
+		if err != nil || len(p) == 0 || len(p) > 2 {
+			return nil, fmt.Errorf("invalid esi %q", esi)
+		}
+		b[i] = byte(n)
+	}
+	if bytes.Equal(b, make([]byte, 10)) || bytes.Equal(b, bytes.Repeat([]byte{0xff}, 10)) {
+		return nil, fmt.Errorf("reserved esi %q", esi)
+	}
+	return b, nil
+}
+
+// FormatESI writes the esi as lower case colon separated hex
+func FormatESI(esi []byte) string {
+	parts := make([]string, len(esi))
+	for i, b := range esi {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
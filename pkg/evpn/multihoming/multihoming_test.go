@@ -0,0 +1,266 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package multihoming
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := map[string]struct {
+		segments []Segment
+		errorMsg string
+	}{
+		"valid segments": {
+			segments: []Segment{
+				{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}},
+				{ESI: "00:11:22:33:44:55:66:77:88:AA", BridgePorts: []string{"bp-b"}, Peers: []string{"10.0.0.3"}},
+			},
+		},
+		"invalid esi": {
+			segments: []Segment{{ESI: "00:11:22", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}}},
+			errorMsg: `multihoming: segment 1: invalid esi "00:11:22"`,
+		},
+		"reserved esi": {
+			segments: []Segment{{ESI: "00:00:00:00:00:00:00:00:00:00", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}}},
+			errorMsg: `multihoming: segment 1: reserved esi "00:00:00:00:00:00:00:00:00:00"`,
+		},
+		"no peers": {
+			segments: []Segment{{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}}},
+			errorMsg: "multihoming: segment 00:11:22:33:44:55:66:77:88:99 needs bridge ports and peers",
+		},
+		"invalid peer": {
+			segments: []Segment{{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"vtep"}}},
+			errorMsg: "multihoming: segment 00:11:22:33:44:55:66:77:88:99: invalid peer vtep",
+		},
+		"bridge port in two segments": {
+			segments: []Segment{
+				{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}},
+				{ESI: "00:11:22:33:44:55:66:77:88:aa", BridgePorts: []string{"//network.opiproject.org/ports/bp-a"}, Peers: []string{"10.0.0.3"}},
+			},
+			errorMsg: "multihoming: bridge port //network.opiproject.org/ports/bp-a is in segments 00:11:22:33:44:55:66:77:88:99 and 00:11:22:33:44:55:66:77:88:aa",
+		},
+		"duplicate segment": {
+			segments: []Segment{
+				{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}},
+				{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-b"}, Peers: []string{"10.0.0.2"}},
+			},
+			errorMsg: "multihoming: duplicate segment 00:11:22:33:44:55:66:77:88:99",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			defer func() { _ = Set(nil) }()
+			err := Set(tt.segments)
+			if tt.errorMsg == "" && err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+			if tt.errorMsg != "" && (err == nil || err.Error() != tt.errorMsg) {
+				t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestSegmentOf(t *testing.T) {
+	err := Set([]Segment{
+		{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.3", "10.0.0.2"}},
+		{ESI: "00:11:22:33:44:55:66:77:88:aa", BridgePorts: []string{"bp-b"}, Peers: []string{"10.0.0.2"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = Set(nil) }()
+
+	s, ok := SegmentOf("//network.opiproject.org/ports/bp-b")
+	if !ok || s.ESI != "00:11:22:33:44:55:66:77:88:aa" {
+		t.Errorf("Expected segment 00:11:22:33:44:55:66:77:88:aa, received %v", s)
+	}
+	if _, ok := SegmentOf("bp-c"); ok {
+		t.Errorf("Expected no segment for bp-c")
+	}
+	if peers := Peers(); !reflect.DeepEqual(peers, []string{"10.0.0.2", "10.0.0.3"}) {
+		t.Errorf("Expected sorted peers, received %v", peers)
+	}
+}
+
+func TestSegment_DesignatedForwarder(t *testing.T) {
+	s := Segment{ESI: "00:11:22:33:44:55:66:77:88:99", Peers: []string{"10.0.0.10", "10.0.0.2"}}
+	tests := map[string]struct {
+		vid   uint16
+		local string
+		df    bool
+	}{
+		"lowest address forwards vlan 0 modulo 3":  {vid: 3, local: "10.0.0.1", df: true},
+		"peer forwards vlan 1 modulo 3":            {vid: 4, local: "10.0.0.1", df: false},
+		"numeric ordering of the addresses":        {vid: 4, local: "10.0.0.9", df: true},
+		"highest address forwards vlan 2 modulo 3": {vid: 5, local: "10.0.0.1", df: false},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if df := s.DesignatedForwarder(tt.vid, net.ParseIP(tt.local)); df != tt.df {
+				t.Errorf("Expected designated forwarder %v, received %v", tt.df, df)
+			}
+		})
+	}
+}
+
+func TestParseElections(t *testing.T) {
+	tests := map[string]struct {
+		out      string
+		df       map[string]bool
+		errorMsg string
+	}{
+		"local segments": {
+			out: `[{"esi":"00:11:22:33:44:55:66:77:88:99","flags":["local","remote","readyForBgp","operUp"]},
+				{"esi":"00:11:22:33:44:55:66:77:88:AA","flags":["local","remote","operUp","nonDF"]},
+				{"esi":"00:11:22:33:44:55:66:77:88:bb","flags":["remote"]}]`,
+			df: map[string]bool{
+				"00:11:22:33:44:55:66:77:88:99": true,
+				"00:11:22:33:44:55:66:77:88:aa": false,
+			},
+		},
+		"no segments": {
+			out: `[]`,
+			df:  map[string]bool{},
+		},
+		"vty session": {
+			out: "show evpn es detail json\r\n[{\"esi\":\"00:11:22:33:44:55:66:77:88:99\",\"flags\":[\"local\"]}]\r\nvtep1# ",
+			df:  map[string]bool{"00:11:22:33:44:55:66:77:88:99": true},
+		},
+		"invalid esi": {
+			out:      `[{"esi":"00:11","flags":["local"]}]`,
+			errorMsg: `multihoming: frr segment: invalid esi "00:11"`,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			df, err := ParseElections([]byte(tt.out))
+			if tt.errorMsg != "" {
+				if err == nil || err.Error() != tt.errorMsg {
+					t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			if !reflect.DeepEqual(df, tt.df) {
+				t.Errorf("Expected elections %v, received %v", tt.df, df)
+			}
+		})
+	}
+}
+
+func TestParseRemoteVteps(t *testing.T) {
+	tests := map[string]struct {
+		out      string
+		vteps    map[string][]string
+		errorMsg string
+	}{
+		"remote segments": {
+			out: `[{"esi":"00:11:22:33:44:55:66:77:88:99","flags":["local","remote"],"vteps":[{"vtep":"10.0.0.2"}]},
+				{"esi":"00:11:22:33:44:55:66:77:88:aa","flags":["remote"],"vteps":[{"vtep":"10.0.0.4"},{"vtep":"10.0.0.3"}]},
+				{"esi":"00:11:22:33:44:55:66:77:88:bb","flags":["local"]}]`,
+			vteps: map[string][]string{
+				"00:11:22:33:44:55:66:77:88:99": {"10.0.0.2"},
+				"00:11:22:33:44:55:66:77:88:aa": {"10.0.0.3", "10.0.0.4"},
+			},
+		},
+		"ipv6 vteps": {
+			out:   `[{"esi":"00:11:22:33:44:55:66:77:88:aa","flags":["remote"],"vteps":[{"vtep":"2001:db8::4"},{"vtep":"2001:db8::3"}]}]`,
+			vteps: map[string][]string{"00:11:22:33:44:55:66:77:88:aa": {"2001:db8::3", "2001:db8::4"}},
+		},
+		"invalid vtep": {
+			out:      `[{"esi":"00:11:22:33:44:55:66:77:88:aa","vteps":[{"vtep":"vtep3"}]}]`,
+			errorMsg: `multihoming: frr segment 00:11:22:33:44:55:66:77:88:aa: invalid vtep "vtep3"`,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			vteps, err := ParseRemoteVteps([]byte(tt.out))
+			if tt.errorMsg != "" {
+				if err == nil || err.Error() != tt.errorMsg {
+					t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			if !reflect.DeepEqual(vteps, tt.vteps) {
+				t.Errorf("Expected vteps %v, received %v", tt.vteps, vteps)
+			}
+		})
+	}
+}
+
+func TestParseRemoteMacs(t *testing.T) {
+	tests := map[string]struct {
+		out      string
+		macs     map[RemoteMac]string
+		errorMsg string
+	}{
+		"remote segment macs": {
+			out: `show evpn mac vni all json
+				{"1000":{"numMacs":3,"macs":{
+					"00:AA:BB:CC:DD:01":{"type":"remote","remoteEs":"00:11:22:33:44:55:66:77:88:aa"},
+					"00:aa:bb:cc:dd:02":{"type":"remote","remoteVtep":"10.0.0.5"},
+					"00:aa:bb:cc:dd:03":{"type":"local","intf":"vport-24"}}}}
+				vtep1# `,
+			macs: map[RemoteMac]string{{Vni: 1000, Mac: "00:aa:bb:cc:dd:01"}: "00:11:22:33:44:55:66:77:88:aa"},
+		},
+		"invalid vni": {
+			out:      `{"vni1000":{"macs":{}}}`,
+			errorMsg: `multihoming: frr macs: invalid vni "vni1000"`,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			macs, err := ParseRemoteMacs([]byte(tt.out))
+			if tt.errorMsg != "" {
+				if err == nil || err.Error() != tt.errorMsg {
+					t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			if !reflect.DeepEqual(macs, tt.macs) {
+				t.Errorf("Expected macs %v, received %v", tt.macs, macs)
+			}
+		})
+	}
+}
+
+func TestSetElections(t *testing.T) {
+	err := Set([]Segment{{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = Set(nil) }()
+	s, _ := Lookup("00:11:22:33:44:55:66:77:88:99")
+	local := net.ParseIP("10.0.0.1")
+	// the carving elects the lower address for the even vlans
+	if !s.DesignatedForwarder(10, local) {
+		t.Errorf("Expected the carving to elect the local vtep")
+	}
+	if !SetElections(map[string]bool{s.ESI: false}) {
+		t.Errorf("Expected the elections changed")
+	}
+	if SetElections(map[string]bool{s.ESI: false}) {
+		t.Errorf("Expected the same elections unchanged")
+	}
+	if s.DesignatedForwarder(10, local) || s.DesignatedForwarder(11, local) {
+		t.Errorf("Expected the frr election to win over the carving")
+	}
+	_ = Set(nil)
+	if SetElections(nil) {
+		t.Errorf("Expected the elections cleared with the segments")
+	}
+}
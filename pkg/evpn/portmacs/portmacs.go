@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package portmacs keeps the secondary macs of the bridge ports with nested
+// vms or containers behind them
+//
+//nolint:all
+package portmacs
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"sync"
+)
+
+// Port is a bridge port with the macs it is allowed besides its own, and
+// whether the macs learned behind it are followed as well
+type Port struct {
+	BridgePort string   `mapstructure:"bridgeport"`
+	Macs       []string `mapstructure:"macs"`
+	Learn      bool     `mapstructure:"learn"`
+}
+
+var (
+	mu    sync.RWMutex
+	ports map[string]Port
+)
+
+// Set validates and stores the bridge ports
+func Set(p []Port) error {
+	byName := make(map[string]Port)
+	macs := make(map[string]string)
+	for i, port := range p {
+		if port.BridgePort == "" {
+			return fmt.Errorf("portmacs: bridge port %d has no name", i+1)
+		}
+		name := path.Base(port.BridgePort)
+		if _, ok := byName[name]; ok {
+			return fmt.Errorf("portmacs: duplicate bridge port %s", port.BridgePort)
+		}
+		parsed := Port{BridgePort: port.BridgePort, Learn: port.Learn}
+		for _, m := range port.Macs {
+			mac, err := net.ParseMAC(m)
+			if err != nil || len(mac) != 6 {
+				return fmt.Errorf("portmacs: bridge port %s: invalid mac %s", port.BridgePort, m)
+			}
+			if other, ok := macs[mac.String()]; ok {
+				return fmt.Errorf("portmacs: mac %s is given to %s and %s", mac, other, port.BridgePort)
+			}
+			macs[mac.String()] = port.BridgePort
+			parsed.Macs = append(parsed.Macs, mac.String())
+		}
+		byName[name] = parsed
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	ports = byName
+	return nil
+}
+
+// Of returns the secondary macs of the bridge port, the port is given by
+// its name or its full resource name
+func Of(bridgePort string) (Port, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := ports[path.Base(bridgePort)]
+	return p, ok
+}
+
+// Ports returns the bridge ports sorted by name
+func Ports() []Port {
+	mu.RLock()
+	defer mu.RUnlock()
+	var list []Port
+	for _, p := range ports {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return path.Base(list[i].BridgePort) < path.Base(list[j].BridgePort) })
+	return list
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package portmacs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := map[string]struct {
+		ports    []Port
+		errorMsg string
+	}{
+		"valid ports": {
+			ports: []Port{
+				{BridgePort: "bp-a", Macs: []string{"00:11:22:33:44:01", "00:11:22:33:44:02"}},
+				{BridgePort: "//network.opiproject.org/ports/bp-b", Learn: true},
+			},
+		},
+		"unnamed port": {
+			ports:    []Port{{Learn: true}},
+			errorMsg: "portmacs: bridge port 1 has no name",
+		},
+		"duplicate port": {
+			ports:    []Port{{BridgePort: "bp-a"}, {BridgePort: "//network.opiproject.org/ports/bp-a"}},
+			errorMsg: "portmacs: duplicate bridge port //network.opiproject.org/ports/bp-a",
+		},
+		"invalid mac": {
+			ports:    []Port{{BridgePort: "bp-a", Macs: []string{"00:11:22"}}},
+			errorMsg: "portmacs: bridge port bp-a: invalid mac 00:11:22",
+		},
+		"mac on two ports": {
+			ports: []Port{
+				{BridgePort: "bp-a", Macs: []string{"00:11:22:33:44:01"}},
+				{BridgePort: "bp-b", Macs: []string{"00:11:22:33:44:01"}},
+			},
+			errorMsg: "portmacs: mac 00:11:22:33:44:01 is given to bp-a and bp-b",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			defer func() { _ = Set(nil) }()
+			err := Set(tt.ports)
+			if tt.errorMsg == "" && err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+			if tt.errorMsg != "" && (err == nil || err.Error() != tt.errorMsg) {
+				t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestOf(t *testing.T) {
+	err := Set([]Port{{BridgePort: "bp-a", Macs: []string{"00:11:22:33:44:AA"}, Learn: true}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = Set(nil) }()
+
+	p, ok := Of("//network.opiproject.org/ports/bp-a")
+	if !ok || !p.Learn || !reflect.DeepEqual(p.Macs, []string{"00:11:22:33:44:aa"}) {
+		t.Errorf("Expected the lower case mac of bp-a, received %v %v", p, ok)
+	}
+	if _, ok := Of("bp-b"); ok {
+		t.Errorf("Expected no secondary macs for bp-b")
+	}
+}
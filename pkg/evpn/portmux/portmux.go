@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package portmux assigns the bridge ports to the port mux interfaces they
+// are 802.1ad sub-interfaces of, and picks the s-tag of the port on its mux.
+// The assignment only depends on the config, the bridge port and its vport,
+// the linux vendor module and the intel-e2000 plugin always agree on it.
+// Every policy numbers the s-tags per mux, each mux carries up to 4094
+// bridge ports.
+//
+//nolint:all
+package portmux
+
+import (
+	"fmt"
+	"path"
+	"sync"
+)
+
+const (
+	// PolicyVport spreads the bridge ports over the muxes by vport, the
+	// s-tag numbers the vports of a mux in order
+	PolicyVport = "vport"
+	// PolicyRange fills the s-tag space of one mux after the other, the
+	// vports beyond the first 4094 go to the next mux
+	PolicyRange = "range"
+	// PolicyStatic puts the bridge ports on the muxes of the assignments,
+	// the s-tag is the position of the port among the assignments of its mux
+	PolicyStatic = "static"
+)
+
+// maxStag is the highest s-tag of a mux
+const maxStag = 4094
+
+// StaticAssignment puts a bridge port on a mux
+type StaticAssignment struct {
+	BridgePort string `mapstructure:"bridgeport"`
+	Interface  string `mapstructure:"interface"`
+}
+
+// Config is the list of port mux interfaces with the assignment policy
+type Config struct {
+	Interfaces  []string           `mapstructure:"interfaces"`
+	Policy      string             `mapstructure:"policy"`
+	Assignments []StaticAssignment `mapstructure:"assignments"`
+}
+
+// Assignment is the mux of a bridge port with its index in the list and the
+// s-tag of the port on it
+type Assignment struct {
+	Interface string
+	Index     int
+	Stag      uint16
+}
+
+var (
+	mu      sync.RWMutex
+	current Config
+	static  map[string]Assignment
+)
+
+// Set validates and stores the muxes
+func Set(c Config) error {
+	if c.Policy == "" {
+		c.Policy = PolicyVport
+	}
+	index := make(map[string]int)
+	for i, name := range c.Interfaces {
+		if name == "" {
+			return fmt.Errorf("portmux: interface %d has no name", i+1)
+		}
+		if _, ok := index[name]; ok {
+			return fmt.Errorf("portmux: duplicate interface %s", name)
+		}
+		index[name] = i
+	}
+	assigned := make(map[string]Assignment)
+	switch c.Policy {
+	case PolicyVport, PolicyRange:
+		if len(c.Assignments) != 0 {
+			return fmt.Errorf("portmux: assignments need the %s policy", PolicyStatic)
+		}
+	case PolicyStatic:
+		stags := make([]int, len(c.Interfaces))
+		for _, a := range c.Assignments {
+			i, ok := index[a.Interface]
+			if !ok {
+				return fmt.Errorf("portmux: bridge port %s: unknown interface %s", a.BridgePort, a.Interface)
+			}
+			if _, ok := assigned[path.Base(a.BridgePort)]; ok {
+				return fmt.Errorf("portmux: duplicate bridge port %s", a.BridgePort)
+			}
+			stags[i]++
+			if stags[i] > maxStag {
+				return fmt.Errorf("portmux: more than %d bridge ports on %s", maxStag, a.Interface)
+			}
+			assigned[path.Base(a.BridgePort)] = Assignment{Interface: a.Interface, Index: i, Stag: uint16(stags[i])}
+		}
+	default:
+		return fmt.Errorf("portmux: unknown policy %s", c.Policy)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+	static = assigned
+	return nil
+}
+
+// Interfaces returns the muxes in the order of the config
+func Interfaces() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.Interfaces
+}
+
+// Assign returns the mux of the bridge port with the vport, the port is
+// given by its name or its full resource name
+func Assign(bridgePort string, vport int) (Assignment, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	n := len(current.Interfaces)
+	if n == 0 {
+		return Assignment{}, fmt.Errorf("portmux: no interfaces")
+	}
+	if vport < 1 {
+		return Assignment{}, fmt.Errorf("portmux: vport %d has no s-tag", vport)
+	}
+	var i, stag int
+	switch current.Policy {
+	case PolicyVport:
+		i = vport % n
+		stag = (vport-1)/n + 1
+	case PolicyRange:
+		i = (vport - 1) / maxStag
+		stag = (vport-1)%maxStag + 1
+		if i >= n {
+			return Assignment{}, fmt.Errorf("portmux: vport %d is beyond the %d muxes", vport, n)
+		}
+	case PolicyStatic:
+		a, ok := static[path.Base(bridgePort)]
+		if !ok {
+			return Assignment{}, fmt.Errorf("portmux: bridge port %s has no interface", bridgePort)
+		}
+		return a, nil
+	}
+	if stag > maxStag {
+		return Assignment{}, fmt.Errorf("portmux: vport %d is beyond the s-tags of a mux", vport)
+	}
+	return Assignment{Interface: current.Interfaces[i], Index: i, Stag: uint16(stag)}, nil
+}
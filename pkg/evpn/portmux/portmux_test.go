@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package portmux
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	crowded := make([]StaticAssignment, maxStag+1)
+	for i := range crowded {
+		crowded[i] = StaticAssignment{BridgePort: fmt.Sprintf("bp-vm%d", i), Interface: "enp0s1f0d5"}
+	}
+	tests := map[string]struct {
+		config   Config
+		errorMsg string
+	}{
+		"default policy": {
+			config: Config{Interfaces: []string{"enp0s1f0d4", "enp0s1f0d5"}},
+		},
+		"static policy": {
+			config: Config{
+				Interfaces:  []string{"enp0s1f0d4", "enp0s1f0d5"},
+				Policy:      PolicyStatic,
+				Assignments: []StaticAssignment{{BridgePort: "bp-vm1", Interface: "enp0s1f0d5"}},
+			},
+		},
+		"unnamed interface": {
+			config:   Config{Interfaces: []string{"enp0s1f0d4", ""}},
+			errorMsg: "portmux: interface 2 has no name",
+		},
+		"duplicate interface": {
+			config:   Config{Interfaces: []string{"enp0s1f0d4", "enp0s1f0d4"}},
+			errorMsg: "portmux: duplicate interface enp0s1f0d4",
+		},
+		"unknown policy": {
+			config:   Config{Interfaces: []string{"enp0s1f0d4"}, Policy: "hash"},
+			errorMsg: "portmux: unknown policy hash",
+		},
+		"assignments without the static policy": {
+			config: Config{
+				Interfaces:  []string{"enp0s1f0d4"},
+				Assignments: []StaticAssignment{{BridgePort: "bp-vm1", Interface: "enp0s1f0d4"}},
+			},
+			errorMsg: "portmux: assignments need the static policy",
+		},
+		"unknown interface": {
+			config: Config{
+				Interfaces:  []string{"enp0s1f0d4"},
+				Policy:      PolicyStatic,
+				Assignments: []StaticAssignment{{BridgePort: "bp-vm1", Interface: "enp0s1f0d5"}},
+			},
+			errorMsg: "portmux: bridge port bp-vm1: unknown interface enp0s1f0d5",
+		},
+		"duplicate bridge port": {
+			config: Config{
+				Interfaces: []string{"enp0s1f0d4", "enp0s1f0d5"},
+				Policy:     PolicyStatic,
+				Assignments: []StaticAssignment{
+					{BridgePort: "bp-vm1", Interface: "enp0s1f0d4"},
+					{BridgePort: "//network.opiproject.org/ports/bp-vm1", Interface: "enp0s1f0d5"},
+				},
+			},
+			errorMsg: "portmux: duplicate bridge port //network.opiproject.org/ports/bp-vm1",
+		},
+		"s-tags of a mux exhausted": {
+			config: Config{
+				Interfaces:  []string{"enp0s1f0d4", "enp0s1f0d5"},
+				Policy:      PolicyStatic,
+				Assignments: crowded,
+			},
+			errorMsg: "portmux: more than 4094 bridge ports on enp0s1f0d5",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			defer func() { _ = Set(Config{}) }()
+			err := Set(tt.config)
+			if tt.errorMsg == "" && err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+			if tt.errorMsg != "" && (err == nil || err.Error() != tt.errorMsg) {
+				t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	muxes := []string{"enp0s1f0d4", "enp0s1f0d5"}
+	tests := map[string]struct {
+		config     Config
+		bridgePort string
+		vport      int
+		want       Assignment
+		errorMsg   string
+	}{
+		"single mux": {
+			config: Config{Interfaces: muxes[:1]},
+			vport:  24,
+			want:   Assignment{Interface: "enp0s1f0d4", Index: 0, Stag: 24},
+		},
+		"vport policy": {
+			config: Config{Interfaces: muxes, Policy: PolicyVport},
+			vport:  25,
+			want:   Assignment{Interface: "enp0s1f0d5", Index: 1, Stag: 13},
+		},
+		"vport policy beyond a single mux": {
+			config: Config{Interfaces: muxes, Policy: PolicyVport},
+			vport:  8188,
+			want:   Assignment{Interface: "enp0s1f0d4", Index: 0, Stag: 4094},
+		},
+		"vport beyond the s-tags": {
+			config:   Config{Interfaces: muxes, Policy: PolicyVport},
+			vport:    8189,
+			errorMsg: "portmux: vport 8189 is beyond the s-tags of a mux",
+		},
+		"range policy": {
+			config: Config{Interfaces: muxes, Policy: PolicyRange},
+			vport:  5000,
+			want:   Assignment{Interface: "enp0s1f0d5", Index: 1, Stag: 906},
+		},
+		"range beyond the muxes": {
+			config:   Config{Interfaces: muxes, Policy: PolicyRange},
+			vport:    9000,
+			errorMsg: "portmux: vport 9000 is beyond the 2 muxes",
+		},
+		"static policy": {
+			config: Config{
+				Interfaces: muxes,
+				Policy:     PolicyStatic,
+				Assignments: []StaticAssignment{
+					{BridgePort: "bp-vm1", Interface: "enp0s1f0d5"},
+					{BridgePort: "bp-vm2", Interface: "enp0s1f0d4"},
+					{BridgePort: "bp-vm3", Interface: "enp0s1f0d5"},
+				},
+			},
+			bridgePort: "//network.opiproject.org/ports/bp-vm3",
+			vport:      5000,
+			want:       Assignment{Interface: "enp0s1f0d5", Index: 1, Stag: 2},
+		},
+		"static policy without an assignment": {
+			config:     Config{Interfaces: muxes, Policy: PolicyStatic},
+			bridgePort: "bp-vm2",
+			vport:      24,
+			errorMsg:   "portmux: bridge port bp-vm2 has no interface",
+		},
+		"vport without an s-tag": {
+			config:   Config{Interfaces: muxes},
+			vport:    0,
+			errorMsg: "portmux: vport 0 has no s-tag",
+		},
+		"no interfaces": {
+			vport:    24,
+			errorMsg: "portmux: no interfaces",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if err := Set(tt.config); err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			defer func() { _ = Set(Config{}) }()
+			got, err := Assign(tt.bridgePort, tt.vport)
+			if tt.errorMsg != "" {
+				if err == nil || err.Error() != tt.errorMsg {
+					t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %+v, received %+v %v", tt.want, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+//nolint:all
+package p4driverapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	p4config "github.com/p4lang/p4runtime/go/p4/config/v1"
+	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+)
+
+// p4Comp component name of the p4 entries in the journal
+const p4Comp = "p4"
+
+var (
+	// dryRun is set when the entries are not programmed into the device
+	dryRun bool
+
+	// recorder journals the entries in dry run mode, may be nil
+	recorder *journal.Journal
+
+	// dryRunInfo holds the p4info the entries are formatted with in dry
+	// run mode, nil until SetP4Info
+	dryRunInfo *client.Client
+)
+
+// SetDryRun stops programming the device, the entries are written to j instead
+func SetDryRun(j *journal.Journal) {
+	dryRun = true
+	recorder = j
+}
+
+// offlineP4Runtime takes the forwarding pipeline of the dry run client
+// without a device, it serves no other rpc
+type offlineP4Runtime struct {
+	p4_v1.P4RuntimeClient
+}
+
+// SetForwardingPipelineConfig accepts the pipeline
+func (offlineP4Runtime) SetForwardingPipelineConfig(context.Context, *p4_v1.SetForwardingPipelineConfigRequest, ...grpc.CallOption) (*p4_v1.SetForwardingPipelineConfigResponse, error) {
+	return &p4_v1.SetForwardingPipelineConfigResponse{}, nil
+}
+
+// SetP4Info sets the p4info the entries are formatted with in dry run mode
+func SetP4Info(info *p4config.P4Info) error {
+	text, err := prototext.Marshal(info)
+	if err != nil {
+		return err
+	}
+	c := client.NewClient(offlineP4Runtime{}, defaultDeviceID, &p4_v1.Uint128{High: 0, Low: 1})
+	if _, err := c.SetFwdPipeFromBytes(context.Background(), nil, text, 0); err != nil {
+		return err
+	}
+	dryRunInfo = c
+	return nil
+}
+
+// IsDryRun tells whether the device is programmed
+func IsDryRun() bool {
+	return dryRun
+}
+
+// MatchRecord is a match field in the journal
+type MatchRecord struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	Kind  string `json:"kind"`
+}
+
+// EntryRecord is a table entry in the journal
+type EntryRecord struct {
+	Table    string        `json:"table"`
+	Match    []MatchRecord `json:"match"`
+	Priority int32         `json:"priority,omitempty"`
+	Action   string        `json:"action,omitempty"`
+	Params   []string      `json:"params,omitempty"`
+}
+
+// valueString formats a field or param value the way the cli shows it
+func valueString(v interface{}) string {
+	switch val := v.(type) {
+	case net.HardwareAddr:
+		return val.String()
+	case net.IP:
+		return val.String()
+	case *net.IPNet:
+		return val.String()
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// NewEntryRecord converts the entry to its journal form, match fields sorted by name
+func NewEntryRecord(entry TableEntry) EntryRecord {
+	rec := EntryRecord{
+		Table:    entry.Tablename,
+		Priority: entry.TableField.Priority,
+		Action:   entry.Action.ActionName,
+	}
+	for field, value := range entry.TableField.FieldValue {
+		kind, _ := value[1].(string)
+		rec.Match = append(rec.Match, MatchRecord{Field: field, Value: valueString(value[0]), Kind: kind})
+	}
+	sort.Slice(rec.Match, func(i, j int) bool { return rec.Match[i].Field < rec.Match[j].Field })
+	for _, param := range entry.Action.Params {
+		rec.Params = append(rec.Params, valueString(param))
+	}
+	return rec
+}
+
+// recordEntry validates the entry and writes it to the journal
+func recordEntry(op string, entry TableEntry) error {
+	if _, _, err := Buildmfs(entry.TableField); err != nil {
+		return err
+	}
+	return recorder.Record(p4Comp, op, NewEntryRecord(entry))
+}
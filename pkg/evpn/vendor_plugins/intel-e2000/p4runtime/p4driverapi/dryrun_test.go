@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4driverapi
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	p4config "github.com/p4lang/p4runtime/go/p4/config/v1"
+
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+)
+
+func TestDryRun_RecordsEntries(t *testing.T) {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	tests := map[string]struct {
+		entry TableEntry
+		add   bool
+		out   string
+		err   bool
+	}{
+		"added entry with params": {
+			entry: TableEntry{
+				Tablename: "l2_fwd",
+				TableField: TableField{FieldValue: map[string][2]interface{}{
+					"vid": {uint16(10), "exact"},
+					"dst": {mac, "exact"},
+				}},
+				Action: Action{ActionName: "fwd", Params: []interface{}{uint32(7), net.ParseIP("10.0.0.1")}},
+			},
+			add: true,
+			out: `{"seq":1,"component":"p4","op":"add","object":{"table":"l2_fwd","match":[{"field":"dst","value":"00:11:22:33:44:55","kind":"exact"},{"field":"vid","value":"10","kind":"exact"}],"action":"fwd","params":["7","10.0.0.1"]}}` + "\n",
+		},
+		"deleted entry without action": {
+			entry: TableEntry{
+				Tablename:  "l2_fwd",
+				TableField: TableField{FieldValue: map[string][2]interface{}{"vid": {uint16(10), "exact"}}},
+			},
+			out: `{"seq":1,"component":"p4","op":"delete","object":{"table":"l2_fwd","match":[{"field":"vid","value":"10","kind":"exact"}]}}` + "\n",
+		},
+		"invalid match field is rejected": {
+			entry: TableEntry{
+				Tablename:  "l2_fwd",
+				TableField: TableField{FieldValue: map[string][2]interface{}{"vid": {"10", "exact"}}},
+			},
+			add: true,
+			err: true,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var buf bytes.Buffer
+			SetDryRun(journal.New(&buf))
+
+			var err error
+			if tt.add {
+				err = AddEntry(tt.entry)
+			} else {
+				err = DelEntry(tt.entry)
+			}
+			if (err != nil) != tt.err {
+				t.Errorf("Expected error: %v, received %v", tt.err, err)
+			}
+			if buf.String() != tt.out {
+				t.Errorf("Expected journal: %s, received %s", tt.out, buf.String())
+			}
+		})
+	}
+}
+
+func TestDryRun_EntryTextproto(t *testing.T) {
+	info := &p4config.P4Info{
+		Tables: []*p4config.Table{{
+			Preamble:    &p4config.Preamble{Id: 33554433, Name: "l2_fwd"},
+			MatchFields: []*p4config.MatchField{{Id: 1, Name: "vid", Bitwidth: 16, Match: &p4config.MatchField_MatchType_{MatchType: p4config.MatchField_EXACT}}},
+			ActionRefs:  []*p4config.ActionRef{{Id: 16777217}},
+		}},
+		Actions: []*p4config.Action{{
+			Preamble: &p4config.Preamble{Id: 16777217, Name: "fwd"},
+			Params:   []*p4config.Action_Param{{Id: 1, Name: "port", Bitwidth: 32}},
+		}},
+	}
+	entry := TableEntry{
+		Tablename:  "l2_fwd",
+		TableField: TableField{FieldValue: map[string][2]interface{}{"vid": {uint16(10), "exact"}}},
+		Action:     Action{ActionName: "fwd", Params: []interface{}{uint32(7)}},
+	}
+	tests := map[string]struct {
+		info *p4config.P4Info
+		out  []string
+		err  bool
+	}{
+		"without p4info": {
+			err: true,
+		},
+		"with the p4info of the pipeline": {
+			info: info,
+			out:  []string{"table_id: 33554433", "field_id: 1", "action_id: 16777217"},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			SetDryRun(nil)
+			dryRunInfo = nil
+			defer func() { dryRunInfo = nil }()
+			if tt.info != nil {
+				if err := SetP4Info(tt.info); err != nil {
+					t.Fatalf("Expected no error, received %v", err)
+				}
+			}
+			text, err := EntryTextproto(entry)
+			if (err != nil) != tt.err {
+				t.Errorf("Expected error: %v, received %v", tt.err, err)
+			}
+			for _, want := range tt.out {
+				if !strings.Contains(text, want) {
+					t.Errorf("Expected %s in the textproto, received %s", want, text)
+				}
+			}
+		})
+	}
+}
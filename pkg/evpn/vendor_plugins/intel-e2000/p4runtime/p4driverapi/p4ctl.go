@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/prototext"
 
 	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
 
@@ -58,6 +59,18 @@ type TableField struct {
 	Priority   int32
 }
 
+// Ternary is a ternary match value with its mask, the bits left out of
+// the mask match anything
+type Ternary struct {
+	Value []byte
+	Mask  []byte
+}
+
+// String formats the ternary as value&&&mask in hex
+func (t Ternary) String() string {
+	return fmt.Sprintf("0x%x&&&0x%x", t.Value, t.Mask)
+}
+
 // uint16toBytes convert uint16 to bytes
 func uint16toBytes(val uint16) []byte {
 	return []byte{byte(val >> 8), byte(val)}
@@ -100,12 +113,18 @@ func Buildmfs(tablefield TableField) (map[string]client.MatchInterface, bool, er
 		case *net.IPNet:
 			maskSize, _ := v.Mask.Size()
 			ip := v.IP.To4()
+			mask := uint32toBytes(4294967295)
+			if ip == nil {
+				// ipv6 prefixes match on all 128 bits
+				ip = v.IP.To16()
+				mask = net.CIDRMask(128, 128)
+			}
 			switch value[1].(string) {
 			case lpmStr:
-				mfs[key] = &client.LpmMatch{Value: v.IP.To4(), PLen: int32(maskSize)}
+				mfs[key] = &client.LpmMatch{Value: ip, PLen: int32(maskSize)}
 			case ternaryStr:
 				isTernary = true
-				mfs[key] = &client.TernaryMatch{Value: []byte(ip), Mask: uint32toBytes(4294967295)}
+				mfs[key] = &client.TernaryMatch{Value: []byte(ip), Mask: mask}
 			default:
 				mfs[key] = &client.ExactMatch{Value: []byte(ip)}
 			}
@@ -123,6 +142,9 @@ func Buildmfs(tablefield TableField) (map[string]client.MatchInterface, bool, er
 			}
 		case bool:
 			mfs[key] = &client.ExactMatch{Value: boolToBytes(value[0].(bool))}
+		case Ternary:
+			isTernary = true
+			mfs[key] = &client.TernaryMatch{Value: v.Value, Mask: v.Mask}
 		case uint32:
 			switch value[1].(string) {
 			case lpmStr:
@@ -150,6 +172,9 @@ func GetEntry(table string) ([]*p4_v1.TableEntry, error) {
 
 // DelEntry deletes the entry
 func DelEntry(entry TableEntry) error {
+	if dryRun {
+		return recordEntry("delete", entry)
+	}
 	Options := &client.TableEntryOptions{
 		Priority: entry.TableField.Priority,
 	}
@@ -167,15 +192,16 @@ func DelEntry(entry TableEntry) error {
 	return P4RtC.DeleteTableEntry(Ctx, entryP)
 }
 
-// AddEntry adds an entry
-func AddEntry(entry TableEntry) error {
+// buildEntry builds the p4runtime table entry with its action, the ids
+// come from the p4info of c
+func buildEntry(c *client.Client, entry TableEntry) (*p4_v1.TableEntry, error) {
 	Options := &client.TableEntryOptions{
 		Priority: entry.TableField.Priority,
 	}
 	mfs, isTernary, err := Buildmfs(entry.TableField)
 	if err != nil {
 		log.Fatalf("intel-e2000: Error in Building mfs: %v", err)
-		return err
+		return nil, err
 	}
 	params := make([][]byte, len(entry.Action.Params))
 	for i := 0; i < len(entry.Action.Params); i++ {
@@ -185,7 +211,7 @@ func AddEntry(entry TableEntry) error {
 			err1 := binary.Write(buf, binary.BigEndian, v)
 			if err1 != nil {
 				log.Println("intel-e2000: binary.Write failed:", err1)
-				return err1
+				return nil, err1
 			}
 			params[i] = buf.Bytes()
 		case uint32:
@@ -193,7 +219,7 @@ func AddEntry(entry TableEntry) error {
 			err1 := binary.Write(buf, binary.BigEndian, v)
 			if err1 != nil {
 				log.Println("inte-e2000: binary.Write failed:", err1)
-				return err1
+				return nil, err1
 			}
 			params[i] = buf.Bytes()
 		case net.HardwareAddr:
@@ -202,20 +228,64 @@ func AddEntry(entry TableEntry) error {
 			params[i] = v
 		default:
 			log.Println("intel-e2000: Unknown actionparam", v)
-			return nil
+			return nil, nil
 		}
 	}
 
-	actionSet := P4RtC.NewTableActionDirect(entry.Action.ActionName, params)
+	actionSet := c.NewTableActionDirect(entry.Action.ActionName, params)
 
 	if isTernary {
-		entryP := P4RtC.NewTableEntry(entry.Tablename, mfs, actionSet, Options)
-		return P4RtC.InsertTableEntry(Ctx, entryP)
+		return c.NewTableEntry(entry.Tablename, mfs, actionSet, Options), nil
+	}
+	return c.NewTableEntry(entry.Tablename, mfs, actionSet, nil), nil
+}
+
+// AddEntry adds an entry
+func AddEntry(entry TableEntry) error {
+	if dryRun {
+		return recordEntry("add", entry)
+	}
+	entryP, err := buildEntry(P4RtC, entry)
+	if err != nil || entryP == nil {
+		return err
 	}
-	entryP := P4RtC.NewTableEntry(entry.Tablename, mfs, actionSet, nil)
 	return P4RtC.InsertTableEntry(Ctx, entryP)
 }
 
+// ModifyEntry rewrites the action of an existing entry
+func ModifyEntry(entry TableEntry) error {
+	if dryRun {
+		return recordEntry("modify", entry)
+	}
+	entryP, err := buildEntry(P4RtC, entry)
+	if err != nil || entryP == nil {
+		return err
+	}
+	return P4RtC.ModifyTableEntry(Ctx, entryP)
+}
+
+// EntryTextproto formats the entry as a p4runtime TableEntry textproto,
+// the ids come from the p4info set on the device, or in dry run mode from
+// the p4info given to SetP4Info
+func EntryTextproto(entry TableEntry) (string, error) {
+	c := P4RtC
+	if dryRun {
+		c = dryRunInfo
+	}
+	if c == nil {
+		return "", fmt.Errorf("no p4info loaded")
+	}
+	entryP, err := buildEntry(c, entry)
+	if err != nil {
+		return "", err
+	}
+	if entryP == nil {
+		return "", fmt.Errorf("unsupported action param in %s entry", entry.Tablename)
+	}
+	b, err := prototext.MarshalOptions{Multiline: true}.Marshal(entryP)
+	return string(b), err
+}
+
 // StopCh is used to when to stop the p4rtc when a terminate signal is generated
 var StopCh = make(chan struct{})
 
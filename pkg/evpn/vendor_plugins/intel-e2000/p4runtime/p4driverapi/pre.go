@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+//nolint:all
+package p4driverapi
+
+import (
+	"fmt"
+
+	p4_v1 "github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// preComp component name of the packet replication engine entries in the journal
+const preComp = "pre"
+
+// Replica is a copy made by the packet replication engine, the instance
+// tells the egress pipeline how to rewrite the copy
+type Replica struct {
+	Port     uint32
+	Instance uint32
+}
+
+// MulticastGroup is a packet replication engine multicast group
+type MulticastGroup struct {
+	ID       uint32
+	Replicas []Replica
+}
+
+// MulticastGroupRecord is a multicast group in the journal
+type MulticastGroupRecord struct {
+	ID       uint32   `json:"id"`
+	Replicas []string `json:"replicas,omitempty"`
+}
+
+// NewMulticastGroupRecord converts the group to its journal form,
+// replicas are written as port/instance
+func NewMulticastGroupRecord(group MulticastGroup) MulticastGroupRecord {
+	rec := MulticastGroupRecord{ID: group.ID}
+	for _, r := range group.Replicas {
+		rec.Replicas = append(rec.Replicas, fmt.Sprintf("%d/%d", r.Port, r.Instance))
+	}
+	return rec
+}
+
+// writeMulticastGroup sends the group to the device
+func writeMulticastGroup(updateType p4_v1.Update_Type, group MulticastGroup) error {
+	entry := &p4_v1.MulticastGroupEntry{MulticastGroupId: group.ID}
+	if updateType != p4_v1.Update_DELETE {
+		for _, r := range group.Replicas {
+			entry.Replicas = append(entry.Replicas, &p4_v1.Replica{EgressPort: r.Port, Instance: r.Instance})
+		}
+	}
+	update := &p4_v1.Update{
+		Type: updateType,
+		Entity: &p4_v1.Entity{
+			Entity: &p4_v1.Entity_PacketReplicationEngineEntry{
+				PacketReplicationEngineEntry: &p4_v1.PacketReplicationEngineEntry{
+					Type: &p4_v1.PacketReplicationEngineEntry_MulticastGroupEntry{
+						MulticastGroupEntry: entry,
+					},
+				},
+			},
+		},
+	}
+	return P4RtC.WriteUpdate(Ctx, update)
+}
+
+// AddMulticastGroup inserts the group
+func AddMulticastGroup(group MulticastGroup) error {
+	if dryRun {
+		return recorder.Record(preComp, "add", NewMulticastGroupRecord(group))
+	}
+	return writeMulticastGroup(p4_v1.Update_INSERT, group)
+}
+
+// ModifyMulticastGroup replaces the replicas of an existing group
+func ModifyMulticastGroup(group MulticastGroup) error {
+	if dryRun {
+		return recorder.Record(preComp, "modify", NewMulticastGroupRecord(group))
+	}
+	return writeMulticastGroup(p4_v1.Update_MODIFY, group)
+}
+
+// DelMulticastGroup deletes the group
+func DelMulticastGroup(group MulticastGroup) error {
+	if dryRun {
+		return recorder.Record(preComp, "delete", MulticastGroupRecord{ID: group.ID})
+	}
+	return writeMulticastGroup(p4_v1.Update_DELETE, group)
+}
+
+// CloneSession is a packet replication engine clone session, the mirrored
+// copies go to its replicas
+type CloneSession struct {
+	ID       uint32
+	Replicas []Replica
+}
+
+// CloneSessionRecord is a clone session in the journal
+type CloneSessionRecord struct {
+	ID       uint32   `json:"id"`
+	Replicas []string `json:"replicas,omitempty"`
+}
+
+// NewCloneSessionRecord converts the session to its journal form,
+// replicas are written as port/instance
+func NewCloneSessionRecord(session CloneSession) CloneSessionRecord {
+	rec := CloneSessionRecord{ID: session.ID}
+	for _, r := range session.Replicas {
+		rec.Replicas = append(rec.Replicas, fmt.Sprintf("%d/%d", r.Port, r.Instance))
+	}
+	return rec
+}
+
+// writeCloneSession sends the session to the device
+func writeCloneSession(updateType p4_v1.Update_Type, session CloneSession) error {
+	entry := &p4_v1.CloneSessionEntry{SessionId: session.ID}
+	if updateType != p4_v1.Update_DELETE {
+		for _, r := range session.Replicas {
+			entry.Replicas = append(entry.Replicas, &p4_v1.Replica{EgressPort: r.Port, Instance: r.Instance})
+		}
+	}
+	update := &p4_v1.Update{
+		Type: updateType,
+		Entity: &p4_v1.Entity{
+			Entity: &p4_v1.Entity_PacketReplicationEngineEntry{
+				PacketReplicationEngineEntry: &p4_v1.PacketReplicationEngineEntry{
+					Type: &p4_v1.PacketReplicationEngineEntry_CloneSessionEntry{
+						CloneSessionEntry: entry,
+					},
+				},
+			},
+		},
+	}
+	return P4RtC.WriteUpdate(Ctx, update)
+}
+
+// AddCloneSession inserts the session
+func AddCloneSession(session CloneSession) error {
+	if dryRun {
+		return recorder.Record(preComp, "add-clone", NewCloneSessionRecord(session))
+	}
+	return writeCloneSession(p4_v1.Update_INSERT, session)
+}
+
+// DelCloneSession deletes the session
+func DelCloneSession(session CloneSession) error {
+	if dryRun {
+		return recorder.Record(preComp, "delete-clone", CloneSessionRecord{ID: session.ID})
+	}
+	return writeCloneSession(p4_v1.Update_DELETE, session)
+}
@@ -0,0 +1,428 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"gopkg.in/yaml.v3"
+)
+
+// aclStr is the name of the acl decoder
+const aclStr = "acl"
+
+// aclRule is a rule of an acl policy, the fields left out match anything.
+// Action is permit, deny or count, count permits and counts the packet.
+type aclRule struct {
+	Action    string `yaml:"action"`
+	SrcMac    string `yaml:"srcmac"`
+	DstMac    string `yaml:"dstmac"`
+	EtherType string `yaml:"ethertype"`
+	SrcIP     string `yaml:"srcip"`
+	DstIP     string `yaml:"dstip"`
+	Protocol  string `yaml:"protocol"`
+	SrcPort   string `yaml:"srcport"`
+	DstPort   string `yaml:"dstport"`
+}
+
+// aclPolicy is an ordered list of rules, the first matching rule wins.
+// Packets matching no rule are permitted unless Default is deny.
+type aclPolicy struct {
+	Name    string    `yaml:"name"`
+	Default string    `yaml:"default"`
+	Rules   []aclRule `yaml:"rules"`
+}
+
+// aclPolicyFile is the layout of the acl policy file, the bridge ports and
+// svis are given by name with the policy they are attached to
+type aclPolicyFile struct {
+	Policies    []aclPolicy       `yaml:"policies"`
+	BridgePorts map[string]string `yaml:"bridgeports"`
+	Svis        map[string]string `yaml:"svis"`
+}
+
+// aclMatch is a validated rule, the match fields without the port
+type aclMatch struct {
+	action string
+	fields map[string][2]interface{}
+}
+
+// aclPolicies are the validated policies of the file
+type aclPolicies struct {
+	rules       map[string][]aclMatch
+	bridgePorts map[string]string
+	svis        map[string]string
+}
+
+// aclPort is a bridge port or svi seen by the decoder with the entries of
+// its policy, the port key is the vsi of a bridge port or the vlan of a svi
+type aclPort struct {
+	table   string
+	field   string
+	key     uint16
+	entries []p4client.TableEntry
+}
+
+// AclDecoder enforces the acl policies of the policy file given in the
+// config on the bridge ports and svis
+type AclDecoder struct {
+	mu       sync.Mutex
+	path     string
+	policies aclPolicies
+	ports    map[string]aclPort
+}
+
+// acls is the acl decoder, nil when no policy file is configured
+var acls *AclDecoder
+
+// NewAclDecoder loads and validates the acl policy file
+func NewAclDecoder(path string) (*AclDecoder, error) {
+	policies, err := readAclPolicies(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := policies.check(); err != nil {
+		return nil, err
+	}
+	return &AclDecoder{path: path, policies: policies, ports: make(map[string]aclPort)}, nil
+}
+
+// Name returns the decoder name
+func (a *AclDecoder) Name() string {
+	return aclStr
+}
+
+// OnBridgePort enforces the policy of the bridge port on the traffic it sends
+func (a *AclDecoder) OnBridgePort(op Operation, bp *infradb.BridgePort) ([]interface{}, error) {
+	name := "bp/" + path.Base(bp.Name)
+	if op == OpDeleted {
+		return a.detach(name), nil
+	}
+	vsi, err := strconv.ParseUint(bp.Metadata.VPort, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	return a.attach(name, aclPort{table: aclBp, field: "vsi", key: uint16(vsi)}), nil
+}
+
+// OnSvi enforces the policy of the svi on the traffic routed through it
+func (a *AclDecoder) OnSvi(op Operation, svi *infradb.Svi) ([]interface{}, error) {
+	name := "svi/" + path.Base(svi.Name)
+	if op == OpDeleted {
+		return a.detach(name), nil
+	}
+	lb, err := objects.GetLB(svi.Spec.LogicalBridge)
+	if err != nil {
+		return nil, err
+	}
+	if lb.Spec.VlanID > math.MaxUint16 {
+		return nil, fmt.Errorf("vlan %d of %s out of range", lb.Spec.VlanID, svi.Spec.LogicalBridge)
+	}
+	return a.attach(name, aclPort{table: aclSvi, field: "vlan_id", key: uint16(lb.Spec.VlanID)}), nil
+}
+
+// attach remembers the port and returns the entries of its policy
+func (a *AclDecoder) attach(name string, port aclPort) []interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	port.entries = a.policies.entries(a.policies.policyOf(name), port)
+	a.ports[name] = port
+	var entries []interface{}
+	for _, e := range port.entries {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// detach forgets the port and returns the deletions of its entries
+func (a *AclDecoder) detach(name string) []interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	port, ok := a.ports[name]
+	if !ok {
+		return nil
+	}
+	delete(a.ports, name)
+	var entries []interface{}
+	for _, e := range port.entries {
+		entries = append(entries, p4client.TableEntry{Tablename: e.Tablename, TableField: e.TableField})
+	}
+	return entries
+}
+
+// reload reads the file again and returns the deletions and additions that
+// bring the entries of the known ports in line with it
+func (a *AclDecoder) reload() ([]interface{}, []interface{}, error) {
+	policies, err := readAclPolicies(a.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := policies.check(); err != nil {
+		return nil, nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.policies = policies
+	names := make([]string, 0, len(a.ports))
+	for name := range a.ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var dels, adds []interface{}
+	for _, name := range names {
+		port := a.ports[name]
+		entries := policies.entries(policies.policyOf(name), port)
+		wanted := make(map[string]bool)
+		for _, e := range entries {
+			wanted[staticEntryString(e)] = true
+		}
+		programmed := make(map[string]bool)
+		for _, e := range port.entries {
+			programmed[staticEntryString(e)] = true
+			if !wanted[staticEntryString(e)] {
+				dels = append(dels, p4client.TableEntry{Tablename: e.Tablename, TableField: e.TableField})
+			}
+		}
+		for _, e := range entries {
+			if !programmed[staticEntryString(e)] {
+				adds = append(adds, e)
+			}
+		}
+		port.entries = entries
+		a.ports[name] = port
+	}
+	return dels, adds, nil
+}
+
+// ReloadAclPolicies reconciles the acl entries with the acl policy file
+func ReloadAclPolicies() error {
+	if acls == nil {
+		return fmt.Errorf("no acl policy file configured")
+	}
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	dels, adds, err := acls.reload()
+	if err != nil {
+		return err
+	}
+	log.Printf("intel-e2000: Reloaded %s, %d acl entries removed, %d added\n", acls.path, len(dels), len(adds))
+	if err := delEntries(dels); err != nil {
+		return err
+	}
+	return addEntries(adds)
+}
+
+// policyOf returns the policy attached to the port, empty for none
+func (p aclPolicies) policyOf(name string) string {
+	kind, port, _ := strings.Cut(name, "/")
+	if kind == "svi" {
+		return p.svis[port]
+	}
+	return p.bridgePorts[port]
+}
+
+// check rejects the bridge ports and svis the policies are attached to that
+// infradb does not have
+func (p aclPolicies) check() error {
+	var bps, svis []string
+	for name := range p.bridgePorts {
+		bps = append(bps, name)
+	}
+	for name := range p.svis {
+		svis = append(svis, name)
+	}
+	return requireConfiguredObjects("aclpolicy", bps, svis)
+}
+
+// entries builds the entries of the policy for the port. The first rule
+// gets the highest priority, the default deny matches the port alone below
+// all rules.
+func (p aclPolicies) entries(policy string, port aclPort) []p4client.TableEntry {
+	rules, ok := p.rules[policy]
+	if !ok {
+		return nil
+	}
+	var entries []p4client.TableEntry
+	for i, r := range rules {
+		fields := map[string][2]interface{}{
+			port.field: {exactTernary(uint16toBytes(port.key)), "ternary"},
+		}
+		for f, v := range r.fields {
+			fields[f] = v
+		}
+		entries = append(entries, p4client.TableEntry{
+			Tablename: port.table,
+			TableField: p4client.TableField{
+				FieldValue: fields,
+				Priority:   int32(len(rules) - i),
+			},
+			Action: p4client.Action{ActionName: r.action},
+		})
+	}
+	return entries
+}
+
+// readAclPolicies reads and validates the acl policy file
+func readAclPolicies(path string) (aclPolicies, error) {
+	policies := aclPolicies{rules: make(map[string][]aclMatch)}
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return policies, err
+	}
+	var file aclPolicyFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return policies, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for _, policy := range file.Policies {
+		if policy.Name == "" {
+			return policies, fmt.Errorf("%s: policy without name", path)
+		}
+		if _, ok := policies.rules[policy.Name]; ok {
+			return policies, fmt.Errorf("%s: duplicate policy %s", path, policy.Name)
+		}
+		var rules []aclMatch
+		for i, r := range policy.Rules {
+			m, err := r.match()
+			if err != nil {
+				return policies, fmt.Errorf("%s: policy %s rule %d: %w", path, policy.Name, i+1, err)
+			}
+			rules = append(rules, m)
+		}
+		switch policy.Default {
+		case "", "permit":
+		case "deny":
+			// matches the port alone, below every rule
+			rules = append(rules, aclMatch{action: controlPrefix + "acl_deny", fields: map[string][2]interface{}{}})
+		default:
+			return policies, fmt.Errorf("%s: policy %s: unknown default %s", path, policy.Name, policy.Default)
+		}
+		policies.rules[policy.Name] = rules
+	}
+	for kind, attached := range map[string]map[string]string{"bridge port": file.BridgePorts, "svi": file.Svis} {
+		for port, policy := range attached {
+			if _, ok := policies.rules[policy]; !ok {
+				return policies, fmt.Errorf("%s: %s %s has unknown policy %s", path, kind, port, policy)
+			}
+		}
+	}
+	policies.bridgePorts = file.BridgePorts
+	policies.svis = file.Svis
+	return policies, nil
+}
+
+// aclProtocols are the ip protocols known by name. The acl tables match
+// ipv4 alone, so icmpv6 is left out.
+var aclProtocols = map[string]uint64{"icmp": 1, "tcp": 6, "udp": 17}
+
+// match validates the rule and converts it to ternary match fields
+func (r aclRule) match() (aclMatch, error) {
+	m := aclMatch{fields: make(map[string][2]interface{})}
+	switch r.Action {
+	case "permit", "deny", "count":
+		m.action = controlPrefix + "acl_" + r.Action
+	default:
+		return m, fmt.Errorf("unknown action %q", r.Action)
+	}
+	for field, mac := range map[string]string{"smac": r.SrcMac, "dmac": r.DstMac} {
+		if mac == "" {
+			continue
+		}
+		hwaddr, err := net.ParseMAC(mac)
+		if err != nil {
+			return m, err
+		}
+		m.fields[field] = [2]interface{}{exactTernary(hwaddr), "ternary"}
+	}
+	if r.EtherType != "" {
+		n, err := strconv.ParseUint(r.EtherType, 0, 16)
+		if err != nil {
+			return m, fmt.Errorf("invalid ethertype %s", r.EtherType)
+		}
+		m.fields["ether_type"] = [2]interface{}{exactTernary(uint16toBytes(uint16(n))), "ternary"}
+	}
+	for field, ip := range map[string]string{"sip": r.SrcIP, "dip": r.DstIP} {
+		if ip == "" {
+			continue
+		}
+		t, err := ipTernary(ip)
+		if err != nil {
+			return m, err
+		}
+		m.fields[field] = [2]interface{}{t, "ternary"}
+	}
+	_, hasIP := m.fields["sip"]
+	if _, ok := m.fields["dip"]; ok || hasIP || r.Protocol != "" {
+		ipv4 := exactTernary(uint16toBytes(0x0800))
+		if et, ok := m.fields["ether_type"]; ok && !bytes.Equal(et[0].(p4client.Ternary).Value, ipv4.Value) {
+			return m, fmt.Errorf("ip fields need ethertype 0x0800")
+		}
+		m.fields["ether_type"] = [2]interface{}{ipv4, "ternary"}
+	}
+	var proto uint64
+	if r.Protocol != "" {
+		var ok bool
+		if proto, ok = aclProtocols[r.Protocol]; !ok {
+			n, err := strconv.ParseUint(r.Protocol, 0, 8)
+			if r.Protocol == "icmpv6" || n == 58 {
+				return m, fmt.Errorf("protocol %s needs ipv6, the acl tables match ipv4", r.Protocol)
+			}
+			if err != nil {
+				return m, fmt.Errorf("invalid protocol %s", r.Protocol)
+			}
+			proto = n
+		}
+		m.fields["ip_proto"] = [2]interface{}{exactTernary([]byte{byte(proto)}), "ternary"}
+	}
+	for field, port := range map[string]string{"sport": r.SrcPort, "dport": r.DstPort} {
+		if port == "" {
+			continue
+		}
+		if proto != aclProtocols["tcp"] && proto != aclProtocols["udp"] {
+			return m, fmt.Errorf("ports need protocol tcp or udp")
+		}
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return m, fmt.Errorf("invalid port %s", port)
+		}
+		m.fields[field] = [2]interface{}{exactTernary(uint16toBytes(uint16(n))), "ternary"}
+	}
+	return m, nil
+}
+
+// exactTernary matches all bits of the value
+func exactTernary(value []byte) p4client.Ternary {
+	return p4client.Ternary{Value: value, Mask: bytes.Repeat([]byte{0xff}, len(value))}
+}
+
+// ipTernary matches an ipv4 address or prefix
+func ipTernary(s string) (p4client.Ternary, error) {
+	if !strings.Contains(s, "/") {
+		s += "/32"
+	}
+	_, prefix, err := net.ParseCIDR(s)
+	if err != nil || prefix.IP.To4() == nil {
+		return p4client.Ternary{}, fmt.Errorf("invalid ipv4 prefix %s", s)
+	}
+	return p4client.Ternary{Value: prefix.IP.To4(), Mask: net.IP(prefix.Mask).To4()}, nil
+}
+
+// uint16toBytes converts the value to network order
+func uint16toBytes(v uint16) []byte {
+	return []byte{byte(v >> 8), byte(v)}
+}
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestReadAclPolicies(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		rules   int
+		errMsg  string
+	}{
+		"rules and default deny": {
+			content: `policies:
+  - name: web
+    default: deny
+    rules:
+      - {action: permit, srcmac: "00:11:22:33:44:55"}
+      - {action: count, protocol: tcp, dstip: 10.0.0.0/24, dstport: "80"}
+bridgeports:
+  bp-a: web
+`,
+			rules: 3,
+		},
+		"unknown action": {
+			content: `policies:
+  - name: web
+    rules:
+      - {action: drop}
+`,
+			errMsg: `policy web rule 1: unknown action "drop"`,
+		},
+		"port without protocol": {
+			content: `policies:
+  - name: web
+    rules:
+      - {action: deny, dstport: "80"}
+`,
+			errMsg: "policy web rule 1: ports need protocol tcp or udp",
+		},
+		"ip fields with another ethertype": {
+			content: `policies:
+  - name: web
+    rules:
+      - {action: deny, ethertype: "0x86dd", dstip: 10.0.0.1}
+`,
+			errMsg: "policy web rule 1: ip fields need ethertype 0x0800",
+		},
+		"ipv6 prefix": {
+			content: `policies:
+  - name: web
+    rules:
+      - {action: deny, srcip: "fd00::/64"}
+`,
+			errMsg: "policy web rule 1: invalid ipv4 prefix fd00::/64",
+		},
+		"icmpv6 protocol": {
+			content: `policies:
+  - name: web
+    rules:
+      - {action: permit, protocol: icmpv6}
+`,
+			errMsg: "policy web rule 1: protocol icmpv6 needs ipv6, the acl tables match ipv4",
+		},
+		"unknown policy": {
+			content: `policies:
+  - name: web
+    rules:
+      - {action: deny}
+svis:
+  svi-a: mail
+`,
+			errMsg: "svi svi-a has unknown policy mail",
+		},
+		"duplicate policy": {
+			content: `policies:
+  - name: web
+  - name: web
+`,
+			errMsg: "duplicate policy web",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			policies, err := readAclPolicies(writeStaticFile(t, tt.content))
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			case tt.errMsg == "" && len(policies.rules["web"]) != tt.rules:
+				t.Errorf("Expected %d rules, received %d", tt.rules, len(policies.rules["web"]))
+			}
+		})
+	}
+}
+
+func TestReadAclPolicies_Example(t *testing.T) {
+	policies, err := readAclPolicies("../../../../../../acl-policy-example.yaml")
+	if err != nil {
+		t.Fatalf("Loading the shipped example failed: %v", err)
+	}
+	if len(policies.rules) != 2 {
+		t.Errorf("Expected 2 policies, received %d", len(policies.rules))
+	}
+}
+
+func TestAclDecoder_OnBridgePort(t *testing.T) {
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-a",
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	store := newSnapshotStore()
+	store.load(&objectSnapshot{BridgePorts: []*infradb.BridgePort{bp}})
+	objects = store
+	t.Cleanup(func() { objects = infradbStore{} })
+	path := writeStaticFile(t, `policies:
+  - name: web
+    default: deny
+    rules:
+      - {action: permit, protocol: tcp, dstport: "443"}
+bridgeports:
+  bp-a: web
+`)
+	d, err := NewAclDecoder(path)
+	if err != nil {
+		t.Fatalf("Loading the acl policies failed: %v", err)
+	}
+	entries, err := d.OnBridgePort(OpAdded, bp)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, received %v", entries)
+	}
+	permit, deny := entries[0].(p4client.TableEntry), entries[1].(p4client.TableEntry)
+	if permit.Priority <= deny.Priority {
+		t.Errorf("Expected the rule above the default, received priorities %d and %d", permit.Priority, deny.Priority)
+	}
+	if vsi := deny.FieldValue["vsi"][0].(p4client.Ternary); vsi.String() != "0x0018&&&0xffff" {
+		t.Errorf("Expected the default to match vsi 24, received %v", vsi)
+	}
+	if len(deny.FieldValue) != 1 || deny.ActionName != "evpn_gw_control.acl_deny" {
+		t.Errorf("Expected a default deny on the port alone, received %v", deny)
+	}
+
+	// the policy no longer denies by default
+	if err := os.WriteFile(path, []byte(`policies:
+  - name: web
+    rules:
+      - {action: permit, protocol: tcp, dstport: "443"}
+bridgeports:
+  bp-a: web
+`), 0o600); err != nil {
+		t.Fatalf("Writing the acl policies failed: %v", err)
+	}
+	dels, adds, err := d.reload()
+	if err != nil {
+		t.Fatalf("Reloading the acl policies failed: %v", err)
+	}
+	// the rule moves from priority 2 to 1 and the default goes away
+	if len(dels) != 2 || len(adds) != 1 {
+		t.Errorf("Expected 2 deletions and 1 addition, received %d and %d", len(dels), len(adds))
+	}
+
+	// a port unknown to infradb rejects the file, the policies stay
+	if err := os.WriteFile(path, []byte(`policies:
+  - name: web
+    default: deny
+bridgeports:
+  bp-a: web
+  bp-b: web
+`), 0o600); err != nil {
+		t.Fatalf("Writing the acl policies failed: %v", err)
+	}
+	if _, _, err := d.reload(); err == nil || err.Error() != "aclpolicy: bridge ports [bp-b] are not in infradb" {
+		t.Errorf("Expected the unknown bridge port rejected, received %v", err)
+	}
+	if _, err := NewAclDecoder(path); err == nil {
+		t.Errorf("Expected the unknown bridge port rejected on load, received no error")
+	}
+
+	entries, _ = d.OnBridgePort(OpDeleted, bp)
+	if len(entries) != 1 || entries[0].(p4client.TableEntry).ActionName != "" {
+		t.Errorf("Expected the deletion of the rule, received %v", entries)
+	}
+	if entries, _ := d.OnBridgePort(OpDeleted, bp); len(entries) != 0 {
+		t.Errorf("Expected nothing for a detached port, received %v", entries)
+	}
+}
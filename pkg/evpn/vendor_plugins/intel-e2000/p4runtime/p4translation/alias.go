@@ -0,0 +1,409 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"context"
+	"log"
+	"net"
+	"sort"
+
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-evpn-bridge/pkg/utils"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// aliasing tells whether the remote macs of an ethernet segment are spread
+// over all vteps attached to the segment, set from multihoming.aliasing
+var aliasing bool
+
+// aliasSlots is the number of hash slots of an alias group
+const aliasSlots = 16
+
+// setUpAliasing turns the aliasing on, the pipeline needs the l2 ecmp
+// selection table and the l2 forwarding action pointing at it
+func setUpAliasing() {
+	aliasing = sharedCfg.Multihoming.Aliasing
+	if !aliasing {
+		return
+	}
+	if pipelineInfo != nil && (!schema.hasTables(pipelineInfo, l2EcmpSel) ||
+		!schema.hasActionParams(pipelineInfo, "set_l2_ecmp_neighbor", 1)) {
+		log.Fatalf("intel-e2000: aliasing needs the %s table and the %s action in pipeline %s\n",
+			schema.tableName(l2EcmpSel), schema.actionName("set_l2_ecmp_neighbor"), schema.Pipeline)
+	}
+	log.Printf("intel-e2000: aliasing of the remote ethernet segments enabled\n")
+}
+
+// aliasKey is a remote mac in a vlan
+type aliasKey struct {
+	vid uint16
+	mac string
+}
+
+// aliasGroupKey is the group of a remote segment in a vlan
+type aliasGroupKey struct {
+	vid uint16
+	esi string
+}
+
+// aliasUser retains the l2 nexthops of a group while its members are
+// programmed, the generation changes with the members
+type aliasUser struct {
+	group aliasGroupKey
+	gen   int
+}
+
+// aliasMember is the l2 nexthop of a vtep in a vlan
+type aliasMember struct {
+	key      netlink_polling.L2NexthopKey
+	neighbor uint16
+}
+
+// aliasGroup is a programmed group, its slots spread over the members
+type aliasGroup struct {
+	id      uint16
+	gen     int
+	members []aliasMember
+}
+
+// aliasFdb is a programmed remote mac with the entries pointing at its own
+// l2 nexthop and the group it points at instead, if any
+type aliasFdb struct {
+	entries []p4client.TableEntry
+	group   aliasGroupKey
+}
+
+// aliasTable keeps what the aliasing of the remote macs needs: the vteps
+// and macs of the remote segments as frr reports them, the vxlan l2
+// nexthops and the remote macs as the netlink watcher reports them. The
+// netlink watcher drops the nexthop group the fdb entry of a remote segment
+// mac points at, frr tells the segment instead. Guarded by translateMu.
+type aliasTable struct {
+	vteps    map[string][]string
+	macs     map[multihoming.RemoteMac]string
+	nexthops map[uint16]map[string]aliasMember
+	vnis     map[uint16]uint32
+	fdbs     map[aliasKey]aliasFdb
+	groups   map[aliasGroupKey]aliasGroup
+	pool     utils.IDPool
+	// dirty tells the groups may be out of line with the above
+	dirty bool
+}
+
+// aliases are the remote macs and their groups
+var aliases = newAliasTable()
+
+// newAliasTable returns an empty alias table
+func newAliasTable() *aliasTable {
+	pool, _ := utils.IDPoolInit("alias", 1, 4095)
+	return &aliasTable{
+		vteps:    make(map[string][]string),
+		macs:     make(map[multihoming.RemoteMac]string),
+		nexthops: make(map[uint16]map[string]aliasMember),
+		vnis:     make(map[uint16]uint32),
+		fdbs:     make(map[aliasKey]aliasFdb),
+		groups:   make(map[aliasGroupKey]aliasGroup),
+		pool:     pool,
+	}
+}
+
+// setSegments stores the vteps of the remote segments and the segments of
+// the remote macs frr reports
+func (a *aliasTable) setSegments(vteps map[string][]string, macs map[multihoming.RemoteMac]string) {
+	a.vteps = vteps
+	a.macs = macs
+	a.dirty = true
+}
+
+// nexthop records or forgets the vxlan l2 nexthop of a vtep in a vlan
+func (a *aliasTable) nexthop(op Operation, nexthop netlink_polling.L2NexthopStruct) {
+	if nexthop.Type != netlink_polling.VXLAN || nexthop.VlanID <= 0 || nexthop.VlanID >= maxVlans {
+		return
+	}
+	vid := uint16(nexthop.VlanID)
+	vtep := net.ParseIP(nexthop.Key.Dst).String()
+	a.dirty = true
+	if op == OpDeleted {
+		delete(a.nexthops[vid], vtep)
+		if len(a.nexthops[vid]) == 0 {
+			delete(a.nexthops, vid)
+			delete(a.vnis, vid)
+		}
+		return
+	}
+	if a.nexthops[vid] == nil {
+		a.nexthops[vid] = make(map[string]aliasMember)
+	}
+	a.nexthops[vid][vtep] = aliasMember{key: nexthop.Key, neighbor: uint16(nexthop.ID)}
+	if vni, ok := nexthop.Metadata["vni"].(uint32); ok {
+		a.vnis[vid] = vni
+	}
+}
+
+// fdb records or forgets the remote mac with the entries pointing at its
+// own l2 nexthop
+func (a *aliasTable) fdb(op Operation, fdb netlink_polling.FdbEntryStruct, entries []interface{}) {
+	if fdb.Type != netlink_polling.VXLAN {
+		return
+	}
+	mac, _ := net.ParseMAC(fdb.Mac)
+	k := aliasKey{vid: uint16(fdb.VlanID), mac: mac.String()}
+	a.dirty = true
+	if op == OpDeleted {
+		delete(a.fdbs, k)
+		return
+	}
+	f := aliasFdb{}
+	for _, entry := range entries {
+		if e, ok := entry.(p4client.TableEntry); ok && e.Tablename == l2Fwd {
+			f.entries = append(f.entries, e)
+		}
+	}
+	a.fdbs[k] = f
+}
+
+// target returns the group the remote mac goes to and its members, none
+// when frr knows no segment of the mac or fewer than two of its vteps have
+// an l2 nexthop in the vlan
+func (a *aliasTable) target(k aliasKey) (aliasGroupKey, []aliasMember) {
+	vni, ok := a.vnis[k.vid]
+	if !ok {
+		return aliasGroupKey{}, nil
+	}
+	esi := a.macs[multihoming.RemoteMac{Vni: vni, Mac: k.mac}]
+	if esi == "" {
+		return aliasGroupKey{}, nil
+	}
+	var members []aliasMember
+	for _, vtep := range a.vteps[esi] {
+		if m, ok := a.nexthops[k.vid][vtep]; ok {
+			members = append(members, m)
+		}
+	}
+	if len(members) < 2 {
+		return aliasGroupKey{}, nil
+	}
+	return aliasGroupKey{vid: k.vid, esi: esi}, members
+}
+
+// sync brings the groups and the entries of the remote macs in line. It
+// returns the groups to add, the entries to rewrite in place and the
+// entries to delete, which are programmed in that order. A group holds its
+// l2 nexthops until the group no longer points at them.
+func (a *aliasTable) sync() (adds, rewrites, dels []interface{}) {
+	if !a.dirty {
+		return nil, nil, nil
+	}
+	a.dirty = false
+	used := make(map[aliasGroupKey]bool)
+	for _, k := range a.keys() {
+		f := a.fdbs[k]
+		key, members := a.target(k)
+		if members != nil {
+			g, ok := a.groups[key]
+			switch {
+			case !ok:
+				id := uint16(a.pool.GetID(key))
+				if id == 0 {
+					log.Printf("intel-e2000: no alias group left for segment %s in vlan %d\n", key.esi, key.vid)
+					key = aliasGroupKey{}
+					break
+				}
+				g = aliasGroup{id: id, gen: 1, members: members}
+				a.retain(key, g)
+				adds = append(adds, g.slots(true)...)
+			case !sameMembers(g.members, members):
+				old := g.gen
+				g.gen++
+				g.members = members
+				a.retain(key, g)
+				rewrites = append(rewrites, g.slots(true)...)
+				dels = append(dels, l2NexthopRefs.releaseUser(aliasUser{group: key, gen: old})...)
+			}
+			if key != (aliasGroupKey{}) {
+				a.groups[key] = g
+				used[key] = true
+			}
+		}
+		if key == f.group {
+			continue
+		}
+		f.group = key
+		a.fdbs[k] = f
+		for _, e := range f.entries {
+			if key != (aliasGroupKey{}) {
+				e.Action = p4client.Action{
+					ActionName: "evpn_gw_control.set_l2_ecmp_neighbor",
+					Params:     []interface{}{a.groups[key].id},
+				}
+			}
+			rewrites = append(rewrites, e)
+		}
+	}
+	var unused []aliasGroupKey
+	for key := range a.groups {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool { return lessGroup(unused[i], unused[j]) })
+	for _, key := range unused {
+		g := a.groups[key]
+		dels = append(dels, g.slots(false)...)
+		dels = append(dels, l2NexthopRefs.releaseUser(aliasUser{group: key, gen: g.gen})...)
+		a.pool.ReleaseID(key)
+		delete(a.groups, key)
+	}
+	return adds, rewrites, dels
+}
+
+// retain keeps the l2 nexthops of the members of the group
+func (a *aliasTable) retain(key aliasGroupKey, g aliasGroup) {
+	for _, m := range g.members {
+		l2NexthopRefs.retain(m.key, aliasUser{group: key, gen: g.gen})
+	}
+}
+
+// keys returns the remote macs sorted
+func (a *aliasTable) keys() []aliasKey {
+	keys := make([]aliasKey, 0, len(a.fdbs))
+	for k := range a.fdbs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].vid != keys[j].vid {
+			return keys[i].vid < keys[j].vid
+		}
+		return keys[i].mac < keys[j].mac
+	})
+	return keys
+}
+
+// slots builds the hash slots of the group, the members take turns
+func (g aliasGroup) slots(withAction bool) []interface{} {
+	var entries = make([]interface{}, 0, aliasSlots)
+	for i := 0; i < aliasSlots; i++ {
+		entry := p4client.TableEntry{
+			Tablename: l2EcmpSel,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"neighbor":    {g.id, "exact"},
+					"hash":        {uint16(i), "exact"},
+					"bit32_zeros": {uint32(0), "exact"},
+				},
+				Priority: int32(0),
+			},
+		}
+		if withAction {
+			entry.Action = p4client.Action{
+				ActionName: "evpn_gw_control.set_neighbor_withoutrec",
+				Params:     []interface{}{g.members[i%len(g.members)].neighbor},
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// sameMembers tells whether the groups have the same members in order
+func sameMembers(a, b []aliasMember) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lessGroup orders the groups by vlan and segment
+func lessGroup(a, b aliasGroupKey) bool {
+	if a.vid != b.vid {
+		return a.vid < b.vid
+	}
+	return a.esi < b.esi
+}
+
+// applyAliases programs the changes of the groups, translateMu held
+func applyAliases() {
+	adds, rewrites, dels := aliases.sync()
+	if err := addEntries(adds); err != nil {
+		log.Printf("intel-e2000: failed to add the alias groups: %v\n", err)
+	}
+	if err := modEntries(rewrites); err != nil {
+		log.Printf("intel-e2000: failed to point the remote macs at their alias groups: %v\n", err)
+	}
+	if err := delEntries(dels); err != nil {
+		log.Printf("intel-e2000: failed to delete the alias groups: %v\n", err)
+	}
+}
+
+// syncsAliases tells whether the netlink event moves the groups of the
+// remote segments, which follow the vteps and macs of the fdb and the l2
+// nexthops and are only kept with multihoming or aliasing configured
+func syncsAliases(eventType string) bool {
+	if !followsFrr() {
+		return false
+	}
+	switch eventType {
+	case netlink_polling.FdbEntryAdded, netlink_polling.FdbEntryUpdated, netlink_polling.FdbEntryDeleted,
+		netlink_polling.L2NexthopAdded, netlink_polling.L2NexthopUpdated, netlink_polling.L2NexthopDeleted:
+		return true
+	}
+	return false
+}
+
+// syncAliases programs the changes of the groups after a netlink event
+func syncAliases() {
+	if !aliasing {
+		return
+	}
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	applyAliases()
+}
+
+// readAliases returns the vteps of the remote segments and the segments of
+// the remote macs frr knows
+var readAliases = func() (map[string][]string, map[multihoming.RemoteMac]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), frrReadTimeout)
+	defer cancel()
+	out, err := frrZebra.FrrZebraCmd(ctx, "show evpn es detail json", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	vteps, err := multihoming.ParseRemoteVteps([]byte(out))
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err = frrZebra.FrrZebraCmd(ctx, "show evpn mac vni all json", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	macs, err := multihoming.ParseRemoteMacs([]byte(out))
+	if err != nil {
+		return nil, nil, err
+	}
+	return vteps, macs, nil
+}
+
+// updateAliases takes the remote segments from frr and points the remote
+// macs at their groups
+func updateAliases() error {
+	vteps, macs, err := readAliases()
+	if err != nil {
+		return err
+	}
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	aliases.setSegments(vteps, macs)
+	applyAliases()
+	return nil
+}
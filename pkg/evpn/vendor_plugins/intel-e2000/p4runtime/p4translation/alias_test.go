@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"reflect"
+	"testing"
+
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestAliasTable_Sync(t *testing.T) {
+	resetState()
+	defer resetState()
+	esi := "00:11:22:33:44:55:66:77:88:aa"
+	mac := "00:aa:bb:cc:dd:01"
+	nexthop := func(vtep string, id int) netlink_polling.L2NexthopStruct {
+		return netlink_polling.L2NexthopStruct{Type: netlink_polling.VXLAN, VlanID: 10, ID: id,
+			Key:      netlink_polling.L2NexthopKey{Dev: "vxlan-vtep", VlanID: 10, Dst: vtep},
+			Metadata: map[interface{}]interface{}{"vni": uint32(1000)}}
+	}
+	// the vxlan decoder owns the l2 nexthops, their deletion waits for the groups
+	deleted := func(id uint16) []interface{} {
+		return []interface{}{p4client.TableEntry{Tablename: l2Nh, TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{"neighbor": {id, "exact"}}}}}
+	}
+	for i, vtep := range []string{"10.0.0.3", "10.0.0.4", "10.0.0.5"} {
+		nh := nexthop(vtep, 20+i)
+		l2NexthopRefs.ownerAdded(nh.Key, "vxlan")
+		aliases.nexthop(OpAdded, nh)
+	}
+	own := p4client.TableEntry{
+		Tablename: l2Fwd,
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{"vlan_id": {uint16(10), "exact"}, "direction": {uint16(0), "exact"}},
+		},
+		Action: p4client.Action{ActionName: "evpn_gw_control.set_neighbor", Params: []interface{}{uint16(20)}},
+	}
+	aliases.fdb(OpAdded, netlink_polling.FdbEntryStruct{Type: netlink_polling.VXLAN, VlanID: 10, Mac: mac}, []interface{}{own})
+
+	// without the segment of the mac it keeps its own nexthop
+	if adds, rewrites, dels := aliases.sync(); len(adds)+len(rewrites)+len(dels) != 0 {
+		t.Fatalf("Expected no changes, received %v %v %v", adds, rewrites, dels)
+	}
+
+	aliases.setSegments(map[string][]string{esi: {"10.0.0.3", "10.0.0.4"}},
+		map[multihoming.RemoteMac]string{{Vni: 1000, Mac: mac}: esi})
+	adds, rewrites, dels := aliases.sync()
+	if len(adds) != aliasSlots || len(dels) != 0 {
+		t.Fatalf("Expected %d slots added, received %v %v", aliasSlots, adds, dels)
+	}
+	for i, entry := range adds {
+		want := []interface{}{uint16(20 + i%2)}
+		if e := entry.(p4client.TableEntry); e.Tablename != l2EcmpSel || !reflect.DeepEqual(e.Params, want) {
+			t.Errorf("Expected slot %d to the neighbor %v, received %v", i, want, e)
+		}
+	}
+	id := aliases.groups[aliasGroupKey{vid: 10, esi: esi}].id
+	if len(rewrites) != 1 || !reflect.DeepEqual(rewrites[0].(p4client.TableEntry).Action,
+		p4client.Action{ActionName: "evpn_gw_control.set_l2_ecmp_neighbor", Params: []interface{}{id}}) {
+		t.Errorf("Expected the mac pointed at group %d, received %v", id, rewrites)
+	}
+
+	// a third vtep joins the segment, the slots are rewritten in place
+	aliases.setSegments(map[string][]string{esi: {"10.0.0.3", "10.0.0.4", "10.0.0.5"}},
+		map[multihoming.RemoteMac]string{{Vni: 1000, Mac: mac}: esi})
+	adds, rewrites, dels = aliases.sync()
+	if len(adds) != 0 || len(rewrites) != aliasSlots || len(dels) != 0 {
+		t.Fatalf("Expected %d slots rewritten, received %v %v %v", aliasSlots, adds, rewrites, dels)
+	}
+	if e := rewrites[2].(p4client.TableEntry); !reflect.DeepEqual(e.Params, []interface{}{uint16(22)}) {
+		t.Errorf("Expected slot 2 to the third vtep, received %v", e)
+	}
+
+	// the netlink watcher drops the vteps, the group goes first and then the nexthops
+	for _, vtep := range []string{"10.0.0.4", "10.0.0.5"} {
+		nh := nexthop(vtep, 0)
+		aliases.nexthop(OpDeleted, nh)
+		if entries := l2NexthopRefs.ownerDeleted(nh.Key, "vxlan", func() []interface{} { return deleted(uint16(nh.ID)) }); len(entries) != 0 {
+			t.Fatalf("Expected the deletion of %s parked, received %v", vtep, entries)
+		}
+	}
+	adds, rewrites, dels = aliases.sync()
+	if len(adds) != 0 || len(rewrites) != 1 || !reflect.DeepEqual(rewrites[0], own) {
+		t.Errorf("Expected the mac back on its own nexthop, received %v %v", adds, rewrites)
+	}
+	if len(dels) != aliasSlots+2 {
+		t.Fatalf("Expected the slots and 2 nexthops deleted, received %v", dels)
+	}
+	for _, entry := range dels[:aliasSlots] {
+		if e := entry.(p4client.TableEntry); e.Tablename != l2EcmpSel || e.ActionName != "" {
+			t.Errorf("Expected a slot deletion, received %v", e)
+		}
+	}
+	for _, entry := range dels[aliasSlots:] {
+		if e := entry.(p4client.TableEntry); e.Tablename != l2Nh {
+			t.Errorf("Expected a nexthop deletion after the slots, received %v", e)
+		}
+	}
+	if len(aliases.groups) != 0 {
+		t.Errorf("Expected no groups left, received %v", aliases.groups)
+	}
+}
+
+func TestSyncsAliases(t *testing.T) {
+	segment := []multihoming.Segment{{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}}}
+	tests := map[string]struct {
+		aliasing  bool
+		segments  []multihoming.Segment
+		eventType string
+		want      bool
+	}{
+		"fdb entry with aliasing":       {aliasing: true, eventType: netlink_polling.FdbEntryAdded, want: true},
+		"l2 nexthop with aliasing":      {aliasing: true, eventType: netlink_polling.L2NexthopDeleted, want: true},
+		"route with aliasing":           {aliasing: true, eventType: netlink_polling.RouteAdded},
+		"nexthop with aliasing":         {aliasing: true, eventType: netlink_polling.NexthopUpdated},
+		"fdb entry without multihoming": {eventType: netlink_polling.FdbEntryUpdated},
+		"l2 nexthop with a segment":     {segments: segment, eventType: netlink_polling.L2NexthopAdded, want: true},
+		"route with a segment":          {segments: segment, eventType: netlink_polling.RouteDeleted},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			aliasing = tt.aliasing
+			if err := multihoming.Set(tt.segments); err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			t.Cleanup(func() {
+				aliasing = false
+				_ = multihoming.Set(nil)
+			})
+			if got := syncsAliases(tt.eventType); got != tt.want {
+				t.Errorf("Expected %v for %s, received %v", tt.want, tt.eventType, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/config"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/e2000config"
+	"github.com/spf13/viper"
+)
+
+// pluginConfig holds the sections of the config only the intel-e2000
+// plugin reads, the ones it shares with the linux vendor module are loaded
+// by e2000config
+type pluginConfig struct {
+	P4           p4Section           `mapstructure:"p4"`
+	Qos          qosSection          `mapstructure:"qos"`
+	Irb          irbSection          `mapstructure:"irb"`
+	Vxlan        vxlanConfig         `mapstructure:"vxlan"`
+	Uplinks      uplinksSection      `mapstructure:"uplinks"`
+	PortSecurity portSecuritySection `mapstructure:"portsecurity"`
+}
+
+// p4Section is the plugin part of the p4 config, the files of the plugin
+// and the features the pipeline is asked for
+type p4Section struct {
+	Schema         string `mapstructure:"schema"`
+	ArpSuppression bool   `mapstructure:"arpsuppression"`
+	StaticEntries  string `mapstructure:"staticentries"`
+	Mirroring      bool   `mapstructure:"mirroring"`
+	MirrorSessions string `mapstructure:"mirrorsessions"`
+	AclPolicy      string `mapstructure:"aclpolicy"`
+	PbrPolicy      string `mapstructure:"pbrpolicy"`
+	RecordFile     string `mapstructure:"recordfile"`
+}
+
+// qosSection is the qos config
+type qosSection struct {
+	Markings []qosConfig `mapstructure:"markings"`
+}
+
+// irbSection is the irb config, the vrfs routing asymmetrically
+type irbSection struct {
+	Asymmetric []string `mapstructure:"asymmetric"`
+}
+
+// uplinksSection is the uplinks config
+type uplinksSection struct {
+	Groups []uplinkGroupConfig `mapstructure:"groups"`
+}
+
+// portSecuritySection is the portsecurity config, the secured bridge ports
+// and where the acks of the dhcp servers are taken from
+type portSecuritySection struct {
+	DhcpServers  []string             `mapstructure:"dhcpservers"`
+	TrustedLinks []string             `mapstructure:"trustedlinks"`
+	BridgePorts  []portSecurityConfig `mapstructure:"bridgeports"`
+}
+
+var (
+	// pluginCfg is the loaded plugin config
+	pluginCfg pluginConfig
+	// sharedCfg is the config the plugin shares with the linux vendor module
+	sharedCfg e2000config.Config
+)
+
+// loadPluginConfig loads the shared sections and reads the plugin sections
+// of the config, validating them before any of them is applied
+func loadPluginConfig() error {
+	shared, err := e2000config.Load()
+	if err != nil {
+		return err
+	}
+	var c pluginConfig
+	if err := viper.Unmarshal(&c); err != nil {
+		return fmt.Errorf("plugin config: %w", err)
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+	pluginCfg = c
+	sharedCfg = shared
+	return nil
+}
+
+// validate checks every plugin section, the sections are applied by their
+// set up functions which take them as given here
+func (c *pluginConfig) validate() error {
+	if c.P4.MirrorSessions != "" && !c.P4.Mirroring {
+		return fmt.Errorf("p4: mirrorsessions %s needs mirroring", c.P4.MirrorSessions)
+	}
+	if _, err := newQosMarkings(c.Qos.Markings); err != nil {
+		return err
+	}
+	asymmetric := make(map[string]bool)
+	for _, vrf := range c.Irb.Asymmetric {
+		if vrf == "" || asymmetric[path.Base(vrf)] {
+			return fmt.Errorf("irb: asymmetric vrf %q is unnamed or given twice", vrf)
+		}
+		asymmetric[path.Base(vrf)] = true
+	}
+	if _, err := newVxlanEncaps(c.Vxlan); err != nil {
+		return err
+	}
+	if _, err := parseUplinkGroups(c.Uplinks.Groups, phyPorts()); err != nil {
+		return err
+	}
+	if len(c.PortSecurity.BridgePorts) == 0 {
+		return nil
+	}
+	d, err := NewPortSecurityDecoder(c.PortSecurity.BridgePorts)
+	if err != nil {
+		return err
+	}
+	return d.trust(c.PortSecurity.DhcpServers, c.PortSecurity.trustedLinks())
+}
+
+// trustedLinks returns the configured trusted links, the vxlan links when
+// none are given
+func (c portSecuritySection) trustedLinks() []string {
+	if len(c.TrustedLinks) == 0 {
+		return defaultTrustedLinks
+	}
+	return c.TrustedLinks
+}
+
+// phyPorts returns the physical ports of the config by representor
+func phyPorts() map[string]uplinkPort {
+	ports := make(map[string]uplinkPort)
+	for i, p := range config.GlobalConfig.Interfaces.PhyPorts {
+		ports[p.Rep] = uplinkPort{index: i, port: p.Vsi}
+	}
+	return ports
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPluginConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		config pluginConfig
+		errMsg string
+	}{
+		"empty config": {
+			config: pluginConfig{},
+		},
+		"all sections": {
+			config: pluginConfig{
+				Qos:   qosSection{Markings: []qosConfig{{LogicalBridge: "lb-voice", Pcp: 5, Dscp: "46"}}},
+				Irb:   irbSection{Asymmetric: []string{"vrf-green"}},
+				Vxlan: vxlanConfig{UDPPort: 8472},
+				PortSecurity: portSecuritySection{
+					DhcpServers: []string{"bp-dhcp1"},
+					BridgePorts: []portSecurityConfig{{BridgePort: "bp-vm1", AllowedIPs: []string{"10.10.0.5"}}},
+				},
+			},
+		},
+		"bad qos marking": {
+			config: pluginConfig{Qos: qosSection{Markings: []qosConfig{{Vrf: "vrf-blue", Pcp: 9}}}},
+			errMsg: "qos: marking 1: pcp 9 above 7",
+		},
+		"duplicate asymmetric vrf": {
+			config: pluginConfig{Irb: irbSection{Asymmetric: []string{"vrf-green", "//network.opiproject.org/vrfs/vrf-green"}}},
+			errMsg: "given twice",
+		},
+		"bad vxlan ttl": {
+			config: pluginConfig{Vxlan: vxlanConfig{TTL: 300}},
+			errMsg: "vxlan: ttl 300 above 255",
+		},
+		"uplink group not in the physical ports": {
+			config: pluginConfig{Uplinks: uplinksSection{Groups: []uplinkGroupConfig{{Name: "bond0", Members: []string{"p0"}}}}},
+			errMsg: "uplinks: group bond0 is not in interfaces.phyports",
+		},
+		"mirror file without mirroring": {
+			config: pluginConfig{P4: p4Section{MirrorSessions: "/var/lib/opi/mirror-sessions.json"}},
+			errMsg: "p4: mirrorsessions /var/lib/opi/mirror-sessions.json needs mirroring",
+		},
+		"secured dhcp server": {
+			config: pluginConfig{PortSecurity: portSecuritySection{
+				DhcpServers: []string{"bp-vm1"},
+				BridgePorts: []portSecurityConfig{{BridgePort: "bp-vm1"}},
+			}},
+			errMsg: "portsecurity: dhcp server bp-vm1 is a secured bridge port",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := tt.config.validate()
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLoadPluginConfig(t *testing.T) {
+	viper.Set("p4.mirroring", true)
+	viper.Set("irb.asymmetric", []string{"vrf-green"})
+	viper.Set("portsecurity.bridgeports", []map[string]interface{}{{"bridgeport": "bp-vm2", "dhcpsnooping": true}})
+	defer viper.Reset()
+	defer func() {
+		pluginCfg = pluginConfig{}
+	}()
+	if err := loadPluginConfig(); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if !pluginCfg.P4.Mirroring {
+		t.Errorf("Expected mirroring, received %+v", pluginCfg.P4)
+	}
+	if !reflect.DeepEqual(pluginCfg.Irb.Asymmetric, []string{"vrf-green"}) {
+		t.Errorf("Expected the asymmetric vrfs, received %v", pluginCfg.Irb)
+	}
+	ports := pluginCfg.PortSecurity.BridgePorts
+	if len(ports) != 1 || ports[0].BridgePort != "bp-vm2" || !ports[0].DhcpSnooping {
+		t.Errorf("Expected the snooping bridge port, received %v", ports)
+	}
+	if links := pluginCfg.PortSecurity.trustedLinks(); !reflect.DeepEqual(links, defaultTrustedLinks) {
+		t.Errorf("Expected the default trusted links, received %v", links)
+	}
+}
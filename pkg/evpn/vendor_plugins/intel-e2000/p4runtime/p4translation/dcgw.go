@@ -22,6 +22,7 @@ import (
 	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
 	"github.com/opiproject/opi-evpn-bridge/pkg/utils"
 	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vlanmap"
 	binarypack "github.com/roman-kachanovsky/go-binary-pack/binary-pack"
 )
 
@@ -205,6 +206,17 @@ const (
 	//
 	//                            )
 
+	// l2EcmpSel evpn p4 table name, present in pipelines with aliasing
+	l2EcmpSel = "evpn_gw_control.l2_ecmp_selection_table" // SEM table for the vteps of a remote segment
+	//                            TableKeys (
+	//                                neighbor,              # Exact
+	//                                hash,                  # Exact (4-bits)
+	//                                bit32_zeros,           # Exact
+	//                            )
+	//                            Actions (
+	//                                set_neighbor_withoutrec(neighbor)
+	//                            )
+
 	// p2pIn  evpn p4 table name
 	p2pIn = "evpn_gw_control.ingress_p2p_table"
 	//                           TableKeys (
@@ -454,7 +466,8 @@ const (
 	//                                                dst_port,
 	//                                                vni,
 	//                                                inner_smac_addr,
-	//                                                inner_dmac_addr)
+	//                                                inner_dmac_addr,
+	//                                                [dscp, dscp_copy, ttl, entropy])
 	//                       )
 
 	// podOutAccess evpn p4 table name
@@ -497,9 +510,250 @@ const (
 
 	//                      src_action="l2_nexthop_table.push_outermac_vxlan()"
 	//			Action(
-	//                           omac_vxlan_push(outer_smac_addr, outer_dmac_addr, src_addr, dst_addr, dst_port, vni)
+	//                           omac_vxlan_push(outer_smac_addr, outer_dmac_addr, src_addr, dst_addr, dst_port, vni, [dscp, dscp_copy, ttl, entropy])
 	//                       )
 
+	// arpProxy evpn p4 table name, present in pipelines with arp suppression
+	arpProxy = "evpn_gw_control.arp_proxy_table"
+	//                       Key {
+	//                           vlan_id,                    // Exact
+	//                           target_ip                   // Exact
+	//                       }
+	//                       Actions(
+	//                           arp_reply(mac)
+	//                       )
+
+	// ndProxy evpn p4 table name, present in pipelines with nd suppression
+	ndProxy = "evpn_gw_control.nd_proxy_table"
+	//                       Key {
+	//                           vlan_id,                    // Exact
+	//                           target_ip                   // Exact
+	//                       }
+	//                       Actions(
+	//                           nd_reply(mac)
+	//                       )
+
+	// bumSrcVtep evpn p4 table name, present in pipelines with multihoming
+	bumSrcVtep = "evpn_gw_control.bum_src_vtep_table"
+	//                       Key {
+	//                           src_ip                      // Exact
+	//                       }
+	//                       Actions(
+	//                           set_flood_peer(peer)
+	//                       )
+
+	// bumSrcVtep6 evpn p4 table name, present in pipelines with multihoming
+	// over an ipv6 underlay
+	bumSrcVtep6 = "evpn_gw_control.bum_src_vtep6_table"
+	//                       Key {
+	//                           src_ip                      // Exact
+	//                       }
+	//                       Actions(
+	//                           set_flood_peer(peer)
+	//                       )
+
+	// aclBp evpn p4 table name, present in pipelines with acls
+	aclBp = "evpn_gw_control.acl_bp_table"
+	//                       Key {
+	//                           vsi,                        // Ternary
+	//                           smac,                       // Ternary
+	//                           dmac,                       // Ternary
+	//                           ether_type,                 // Ternary
+	//                           sip,                        // Ternary
+	//                           dip,                        // Ternary
+	//                           ip_proto,                   // Ternary
+	//                           sport,                      // Ternary
+	//                           dport                       // Ternary
+	//                       }
+	//                       Actions(
+	//                           acl_permit(),
+	//                           acl_deny(),
+	//                           acl_count()
+	//                       )
+
+	// mirrorBp evpn p4 table name, present in pipelines with mirroring
+	mirrorBp = "evpn_gw_control.mirror_bp_table"
+	//                       Key {
+	//                           vsi,                        // Exact
+	//                           direction                   // Exact
+	//                       }
+	//                       Actions(
+	//                           mirror_to_session(session)
+	//                       )
+
+	// mirrorSviTable evpn p4 table name, present in pipelines with mirroring
+	mirrorSviTable = "evpn_gw_control.mirror_svi_table"
+	//                       Key {
+	//                           vlan_id,                    // Exact
+	//                           direction                   // Exact
+	//                       }
+	//                       Actions(
+	//                           mirror_to_session(session)
+	//                       )
+
+	// mirrorVrfTable evpn p4 table name, present in pipelines with mirroring
+	mirrorVrfTable = "evpn_gw_control.mirror_vrf_table"
+	//                       Key {
+	//                           vrf,                        // Exact
+	//                           direction                   // Exact
+	//                       }
+	//                       Actions(
+	//                           mirror_to_session(session)
+	//                       )
+
+	// erspanEncap evpn p4 table name, present in pipelines with mirroring
+	erspanEncap = "evpn_gw_control.erspan_encap_table"
+	//                       Key {
+	//                           session                     // Exact
+	//                       }
+	//                       Actions(
+	//                           push_erspan(smac, dmac, sip, dip, erspan_id)
+	//                       )
+
+	// aclSvi evpn p4 table name, present in pipelines with acls
+	aclSvi = "evpn_gw_control.acl_svi_table"
+	//                       Key {
+	//                           vlan_id,                    // Ternary
+	//                           smac, dmac, ether_type,     // Ternary
+	//                           sip, dip, ip_proto,         // Ternary
+	//                           sport, dport                // Ternary
+	//                       }
+	//                       Actions(
+	//                           acl_permit(),
+	//                           acl_deny(),
+	//                           acl_count()
+	//                       )
+
+	// phyInVxlan6 evpn p4 table name, present in pipelines with an ipv6 underlay
+	phyInVxlan6 = "evpn_gw_control.phy_ingress_vxlan6_table"
+	//                       Key {
+	//                           dst_ip,                     // Exact, 128 bits
+	//                           vni,                        // Exact
+	//                           da                          // Exact
+	//                       }
+	//                       Actions(
+	//                           pop_vxlan_set_vrf_id(mod_ptr, tcam_prefix, vport, vrf)
+	//                       )
+
+	// phyInVxlanL26 evpn p4 table name, present in pipelines with an ipv6 underlay
+	phyInVxlanL26 = "evpn_gw_control.phy_ingress_vxlan6_vlan_table"
+	//                       Key {
+	//                           dst_ip,                     // Exact, 128 bits
+	//                           vni                         // Exact
+	//                       }
+	//                       Actions(
+	//                           pop_vxlan_set_vlan_id(mod_ptr, vlan_id, vport)
+	//                       )
+
+	// pushVxlan6Hdr evpn p4 table name, present in pipelines with an ipv6 underlay
+	pushVxlan6Hdr = "evpn_gw_control.omac_vxlan6_imac_push_mod_table"
+	//                       src_action="push_outermac_vxlan6_innermac"
+	//                       Actions(
+	//                           omac_vxlan6_imac_push(outer_smac_addr, outer_dmac_addr,
+	//                                                 src_addr, dst_addr, dst_port, vni,
+	//                                                 inner_smac_addr, inner_dmac_addr,
+	//                                                 [dscp, dscp_copy, ttl, entropy])
+	//                       )
+
+	// pushVxlan6OutHdr evpn p4 table name, present in pipelines with an ipv6 underlay
+	pushVxlan6OutHdr = "evpn_gw_control.omac_vxlan6_push_mod_table"
+	//                       src_action="l2_nexthop_table.push_outermac_vxlan6()"
+	//                       Actions(
+	//                           omac_vxlan6_push(outer_smac_addr, outer_dmac_addr, src_addr, dst_addr, dst_port, vni, [dscp, dscp_copy, ttl, entropy])
+	//                       )
+
+	// uplinkGroupTable evpn p4 table name, present in pipelines bonding the
+	// physical ports
+	uplinkGroupTable = "evpn_gw_control.uplink_group_table"
+	//                       Key {
+	//                           port,                       // Exact
+	//                           slot                        // Exact
+	//                       }
+	//                       Actions(
+	//                           set_uplink_port(port)
+	//                       )
+
+	// pbrTable evpn p4 table name, present in pipelines steering flows ahead
+	// of the vrf lookup
+	pbrTable = "evpn_gw_control.pbr_table"
+	//                       Key {
+	//                           vsi,                        // Ternary
+	//                           ether_type,                 // Ternary
+	//                           sip, dip, ip_proto,         // Ternary
+	//                           sport, dport                // Ternary
+	//                       }
+	//                       Actions(
+	//                           pbr_fwd_to_port(port),
+	//                           pbr_set_neighbor(neighbor)
+	//                       )
+
+	// portSecTable evpn p4 table name, present in pipelines checking
+	// the source of the bridge port packets, it counts the drops. The sip
+	// of an arp packet is its sender address.
+	portSecTable = "evpn_gw_control.port_security_table"
+	//                       Key {
+	//                           vsi,                        // Ternary
+	//                           smac,                       // Ternary
+	//                           ether_type,                 // Ternary
+	//                           sip                         // Ternary
+	//                       }
+	//                       Actions(
+	//                           port_security_permit(),
+	//                           port_security_drop()
+	//                       )
+
+	// vxlanPort evpn p4 table name, present in pipelines taking vxlan on
+	// other udp ports than 4789
+	vxlanPort = "evpn_gw_control.vxlan_udp_port_table"
+	//                       Key {
+	//                           dst_port,                   // Exact
+	//                           vni                         // Exact
+	//                       }
+	//                       Actions(
+	//                           accept_vxlan_port()
+	//                       )
+
+	// l3Rt6 evpn p4 table name, the lpm routes of the ipv6 destinations
+	l3Rt6 = "evpn_gw_control.l3_routing_table_ipv6"
+	//                       Key {
+	//                           vrf,                        // Exact
+	//                           direction,                  // Exact
+	//                           dst_ip                      // LPM, 128 bits
+	//                       }
+	//                       Actions(
+	//                           set_neighbor(neighbor, ecmp_on)
+	//                       )
+
+	// l3RtHost6 evpn p4 table name, the host routes of the ipv6 destinations
+	l3RtHost6 = "evpn_gw_control.l3_lem_table_ipv6"
+	//                       Key {
+	//                           vrf,                        // Exact
+	//                           direction,                  // Exact
+	//                           dst_ip                      // Exact, 128 bits
+	//                       }
+	//                       Actions(
+	//                           set_neighbor(neighbor, ecmp_on)
+	//                       )
+
+	// l3P2PRt6 evpn p4 table name, the grd routes of the ipv6 vxlan packets
+	l3P2PRt6 = "evpn_gw_control.l3_p2p_routing_table_ipv6"
+	//                       Key {
+	//                           dst_ip                      // LPM, 128 bits
+	//                       }
+	//                       Actions(
+	//                           set_p2p_neighbor(neighbor, ecmp_on)
+	//                       )
+
+	// l3P2PRtHost6 evpn p4 table name, the grd host routes of the ipv6 vxlan packets
+	l3P2PRtHost6 = "evpn_gw_control.l3_p2p_lem_table_ipv6"
+	//                       Key {
+	//                           vrf,                        // Exact
+	//                           direction,                  // Exact
+	//                           dst_ip                      // Exact, 128 bits
+	//                       }
+	//                       Actions(
+	//                           set_p2p_neighbor(neighbor, ecmp_on)
+	//                       )
 )
 
 // _isL3vpnEnabled check if l3 enabled
@@ -654,6 +908,10 @@ func (e *EcmpDispatcher) _p4NexthopID(direction int) int {
 
 // _p2pQid get the qid for p2p port
 func _p2pQid(pID int) int {
+	if g := uplinkGroupOf(pID); g != nil {
+		// the p2p traffic of a group takes the queue of its first member
+		pID = g.ids[g.members[0]]
+	}
 	if pID == PortID.PHY0 {
 		return 0x87
 	} else if pID == PortID.PHY1 {
@@ -871,13 +1129,9 @@ func (l L3Decoder) _getGrpcInfo(representors map[string][2]string) []GrpcPairPor
 	return grpcPorts
 }
 
-// getVrfID get the vrf id from vni
+// getVrfID get the vrf id of the route
 func (l L3Decoder) getVrfID(route netlink_polling.RouteStruct) uint32 {
-	if route.Vrf.Spec.Vni == nil {
-		return 0
-	}
-
-	return *route.Vrf.Metadata.RoutingTable[0]
+	return irbVrfID(route.Vrf)
 }
 
 // _l3HostRoute gets the l3 host route
@@ -1134,6 +1388,16 @@ func (l L3Decoder) translateAddedRoute(route netlink_polling.RouteStruct) []inte
 	var ecmpFlag bool
 	ecmpFlag = false
 
+	route, ok := irbRoute(route)
+	if !ok {
+		return entries
+	}
+	for _, nh := range route.Nexthops {
+		if offloadedNhType(nh.NhType) {
+			nexthopRefs.retain(nh.Key, route.Key)
+		}
+	}
+
 	var ecmp EcmpDispatcher
 	if len(route.Nexthops) > 1 {
 		if !ecmp.EcmpDispatcherInit(route.Nexthops, route.Vrf) {
@@ -1143,12 +1407,16 @@ func (l L3Decoder) translateAddedRoute(route netlink_polling.RouteStruct) []inte
 		if refCount == 1 {
 			ecmp.runWebsterAlg()
 			entries = ecmp.addEcmpDispatcher(entries)
+			trackEcmpGroup(ecmp)
 		}
 		route.Nexthops = []*netlink_polling.NexthopStruct{}
 		route.Nexthops = ecmp.Nexthop
 		ecmpFlag = true
 	}
 	var ipv4Net = route.Route0.Dst
+	if isIPv6(ipv4Net.IP) {
+		return l._l3Route6(route, "False", ecmpFlag, entries, ecmp)
+	}
 	if net.IP(ipv4Net.Mask).String() == "255.255.255.255" {
 		return l._l3HostRoute(route, "False", ecmpFlag, entries, ecmp)
 	}
@@ -1162,25 +1430,35 @@ func (l L3Decoder) translateDeletedRoute(route netlink_polling.RouteStruct) []in
 	var ecmpFlag bool
 	ecmpFlag = false
 
+	route, ok := irbRoute(route)
+	if !ok {
+		return append(entries, nexthopRefs.releaseUser(route.Key)...)
+	}
 	var ecmp EcmpDispatcher
 	if len(route.Nexthops) > 1 {
 		if !ecmp.EcmpDispatcherInit(route.Nexthops, route.Vrf) {
-			return entries
+			return append(entries, nexthopRefs.releaseUser(route.Key)...)
 		}
 		ecmp.id, refCount = ecmpIndexPool.ReleaseIDWithRef(ecmp.key, route.Key)
 		if refCount == 0 {
 			ecmp.runWebsterAlg()
 			entries = ecmp.delEcmpDispatcher(entries)
+			untrackEcmpGroup(ecmp.id)
 		}
 		route.Nexthops = []*netlink_polling.NexthopStruct{}
 		route.Nexthops = ecmp.Nexthop
 		ecmpFlag = true
 	}
 	var ipv4Net = route.Route0.Dst
-	if net.IP(ipv4Net.Mask).String() == "255.255.255.255" {
-		return l._l3HostRoute(route, "True", ecmpFlag, entries, ecmp)
+	if isIPv6(ipv4Net.IP) {
+		entries = l._l3Route6(route, "True", ecmpFlag, entries, ecmp)
+	} else if net.IP(ipv4Net.Mask).String() == "255.255.255.255" {
+		entries = l._l3HostRoute(route, "True", ecmpFlag, entries, ecmp)
+	} else {
+		entries = l._l3Route(route, "True", ecmpFlag, entries, ecmp)
 	}
-	return l._l3Route(route, "True", ecmpFlag, entries, ecmp)
+	// nexthops deleted while the route still used them go now
+	return append(entries, nexthopRefs.releaseUser(route.Key)...)
 }
 
 // translateAddedNexthop translate the added nexthop to p4 entries
@@ -1191,8 +1469,9 @@ func (l L3Decoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruct)
 		var entries []interface{}
 		return entries
 	}
+	nexthopRefs.ownerAdded(nexthop.Key, l.Name())
 	key := fmt.Sprintf("%d-%s-%s-%d-%v", EntryType.l3NH, nexthop.Key.VrfName, nexthop.Key.Dst, nexthop.Key.Dev, nexthop.Key.Local)
-	var modPtr = ptrPool.GetID(key)
+	var modPtr, _ = ptrPool.GetIDWithRef(key, nexthop.Key)
 	nhID := _p4NexthopID(nexthop, Direction.Tx)
 
 	var entries = make([]interface{}, 0)
@@ -1258,6 +1537,7 @@ func (l L3Decoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruct)
 				},
 			})
 	case netlink_polling.ACC:
+		var vrfMarking, _ = qos.vrf(nexthop.Key.VrfName)
 		var dmac, _ = net.ParseMAC(nexthop.Metadata["dmac"].(string))
 		var vlanID = nexthop.Metadata["vlanID"].(uint32)
 		var vport = _toEgressVsi(nexthop.Metadata["egress_vport"].(int))
@@ -1271,7 +1551,7 @@ func (l L3Decoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruct)
 			},
 			Action: p4client.Action{
 				ActionName: "evpn_gw_control.dmac_vlan_push",
-				Params:     []interface{}{uint16(0), uint16(1), uint16(vlanID), dmac},
+				Params:     []interface{}{vrfMarking.pcp, uint16(1), uint16(vlanID), dmac},
 			},
 		},
 			p4client.TableEntry{
@@ -1324,7 +1604,7 @@ func (l L3Decoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruct)
 				},
 				Action: p4client.Action{
 					ActionName: "evpn_gw_control.update_smac_dmac_vlan",
-					Params:     []interface{}{smac, dmac, 0, 1, uint16(vlanID)},
+					Params:     []interface{}{smac, dmac, qos.sviPcp(uint16(vlanID), nexthop.Key.VrfName), uint16(1), customerVid(nexthop.Metadata["egress_vport"].(string), uint16(vlanID))},
 				},
 			},
 				p4client.TableEntry{
@@ -1408,15 +1688,22 @@ func (l L3Decoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruct)
 }
 
 // translateDeletedNexthop translate the deleted nexthop to p4 entries
-//
-//nolint:funlen
 func (l L3Decoder) translateDeletedNexthop(nexthop netlink_polling.NexthopStruct) []interface{} {
 	if nexthop.NhType == netlink_polling.VXLAN {
 		var entries []interface{}
 		return entries
 	}
+	return nexthopRefs.ownerDeleted(nexthop.Key, l.Name(), func() []interface{} {
+		return l._deletedNexthopEntries(nexthop)
+	})
+}
+
+// _deletedNexthopEntries builds the deletions of a nexthop nothing uses any more
+//
+//nolint:funlen
+func (l L3Decoder) _deletedNexthopEntries(nexthop netlink_polling.NexthopStruct) []interface{} {
 	key := fmt.Sprintf("%d-%s-%s-%d-%v", EntryType.l3NH, nexthop.Key.VrfName, nexthop.Key.Dst, nexthop.Key.Dev, nexthop.Key.Local)
-	var modPtr = ptrPool.ReleaseID(key)
+	var modPtr, modRefs = ptrPool.ReleaseIDWithRef(key, nexthop.Key)
 	nhID := _p4NexthopID(nexthop, Direction.Tx)
 	var entries = make([]interface{}, 0)
 	switch nexthop.NhType {
@@ -1560,6 +1847,10 @@ func (l L3Decoder) translateDeletedNexthop(nexthop netlink_polling.NexthopStruct
 	default:
 		return entries
 	}
+	if modRefs != 0 {
+		// the mod pointer is shared with another nexthop to the same gateway
+		entries = _withoutModEntries(entries, modPtr)
+	}
 	return entries
 }
 
@@ -1693,12 +1984,13 @@ func (l L3Decoder) StaticAdditions() []interface{} {
 			Params:     []interface{}{tidx},
 		},
 	})
+	entries = append(entries, uplinkEntries(OpAdded, l._phyPorts, l._defaultVsi)...)
 	return entries
 }
 
 // StaticDeletions do the static deletion for p4 tables
 func (l L3Decoder) StaticDeletions() []interface{} {
-	var entries = make([]interface{}, 0)
+	var entries = uplinkEntries(OpDeleted, l._phyPorts, l._defaultVsi)
 	for _, port := range l._phyPorts {
 		var portDa, _ = net.ParseMAC(port.mac)
 		entries = append(entries, p4client.TableEntry{
@@ -1788,11 +2080,31 @@ func (l L3Decoder) StaticDeletions() []interface{} {
 	return entries
 }
 
+// Name returns the decoder name
+func (l L3Decoder) Name() string {
+	return "l3"
+}
+
+// OnRoute translates an added or deleted route
+func (l L3Decoder) OnRoute(op Operation, route netlink_polling.RouteStruct) ([]interface{}, error) {
+	if op == OpDeleted {
+		return l.translateDeletedRoute(route), nil
+	}
+	return l.translateAddedRoute(route), nil
+}
+
+// OnNexthop translates an added or deleted nexthop
+func (l L3Decoder) OnNexthop(op Operation, nexthop netlink_polling.NexthopStruct) ([]interface{}, error) {
+	if op == OpDeleted {
+		return l.translateDeletedNexthop(nexthop), nil
+	}
+	return nexthopRefs.share(nexthop.Key, l.translateAddedNexthop(nexthop)), nil
+}
+
 // VxlanDecoder structure
 type VxlanDecoder struct {
-	vxlanUDPPort uint32
-	_muxVsi      int
-	_defaultVsi  int
+	_muxVsi     int
+	_defaultVsi int
 }
 
 // VxlanDecoderInit initialize vxlan decoder
@@ -1802,9 +2114,8 @@ func (v VxlanDecoder) VxlanDecoderInit(representors map[string][2]string) VxlanD
 		panic(err)
 	}
 	s := VxlanDecoder{
-		vxlanUDPPort: 4789,
-		_defaultVsi:  0xb,
-		_muxVsi:      int(muxVsi),
+		_defaultVsi: 0xb,
+		_muxVsi:     int(muxVsi),
 	}
 	return s
 }
@@ -1824,7 +2135,7 @@ func (v VxlanDecoder) translateAddedVrf(vrf *infradb.Vrf) []interface{} {
 	if err != nil {
 		return entries
 	}
-	G, _ := infradb.GetVrf(vrf.Name)
+	G, _ := objects.GetVrf(vrf.Name)
 	var detail map[string]interface{}
 	var Rmac net.HardwareAddr
 	for _, com := range G.Status.Components {
@@ -1864,7 +2175,8 @@ func (v VxlanDecoder) translateAddedVrf(vrf *infradb.Vrf) []interface{} {
 			Params:     []interface{}{ModPointer.ignorePtr, uint32(tcamPrefix), uint32(_toEgressVsi(v._defaultVsi)), *vrf.Metadata.RoutingTable[0]},
 		},
 	})
-	return entries
+	entries = append(entries, _vxlanPortEntry(encaps.vrf(vrf.Name), *vrf.Spec.Vni, false)...)
+	return _vtepEntries(vrf.Spec.VtepIP.IP, entries)
 }
 
 // translateDeletedVrf translates the deleted vrf
@@ -1873,7 +2185,7 @@ func (v VxlanDecoder) translateDeletedVrf(vrf *infradb.Vrf) []interface{} {
 	if !_isL3vpnEnabled(vrf) {
 		return entries
 	}
-	G, _ := infradb.GetVrf(vrf.Name)
+	G, _ := objects.GetVrf(vrf.Name)
 	var detail map[string]interface{}
 	var Rmac net.HardwareAddr
 	for _, com := range G.Status.Components {
@@ -1909,7 +2221,8 @@ func (v VxlanDecoder) translateDeletedVrf(vrf *infradb.Vrf) []interface{} {
 			Priority: int32(0),
 		},
 	})
-	return entries
+	entries = append(entries, _vxlanPortEntry(encaps.vrf(vrf.Name), *vrf.Spec.Vni, true)...)
+	return _vtepEntries(vrf.Spec.VtepIP.IP, entries)
 }
 
 // translateAddedLb translates the added lb
@@ -1932,7 +2245,8 @@ func (v VxlanDecoder) translateAddedLb(lb *infradb.LogicalBridge) []interface{}
 			Params:     []interface{}{ModPointer.ignorePtr, uint16(lb.Spec.VlanID), uint32(_toEgressVsi(v._defaultVsi))},
 		},
 	})
-	return entries
+	entries = append(entries, _vxlanPortEntry(encaps.logicalBridge(lb.Name), *lb.Spec.Vni, false)...)
+	return _vtepEntries(lb.Spec.VtepIP.IP, entries)
 }
 
 // translateDeletedLb translates the deleted lb
@@ -1952,7 +2266,8 @@ func (v VxlanDecoder) translateDeletedLb(lb *infradb.LogicalBridge) []interface{
 			Priority: int32(0),
 		},
 	})
-	return entries
+	entries = append(entries, _vxlanPortEntry(encaps.logicalBridge(lb.Name), *lb.Spec.Vni, true)...)
+	return _vtepEntries(lb.Spec.VtepIP.IP, entries)
 }
 
 // translateAddedNexthop translates the added nexthop
@@ -1962,8 +2277,9 @@ func (v VxlanDecoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruc
 	if nexthop.NhType != netlink_polling.VXLAN {
 		return entries
 	}
+	nexthopRefs.ownerAdded(nexthop.Key, v.Name())
 	key := fmt.Sprintf("%d-%s-%s-%d-%v", EntryType.l3NH, nexthop.Key.VrfName, nexthop.Key.Dst, nexthop.Key.Dev, nexthop.Key.Local)
-	var modPtr = ptrPool.GetID(key)
+	var modPtr, _ = ptrPool.GetIDWithRef(key, nexthop.Key)
 	var vport = nexthop.Metadata["egress_vport"].(int)
 	var smac, _ = net.ParseMAC(nexthop.Metadata["phy_smac"].(string))
 	var dmac, _ = net.ParseMAC(nexthop.Metadata["phy_dmac"].(string))
@@ -1972,6 +2288,8 @@ func (v VxlanDecoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruc
 	var vni = nexthop.Metadata["vni"]
 	var innerSmacAddr, _ = net.ParseMAC(nexthop.Metadata["inner_smac"].(string))
 	var innerDmacAddr, _ = net.ParseMAC(nexthop.Metadata["inner_dmac"].(string))
+	var vrfMarking, _ = qos.vrf(nexthop.Key.VrfName)
+	var encap = encaps.vrf(nexthop.Key.VrfName)
 	entries = append(entries, p4client.TableEntry{
 		Tablename: pushVxlanHdr,
 		TableField: p4client.TableField{
@@ -1982,7 +2300,7 @@ func (v VxlanDecoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruc
 		},
 		Action: p4client.Action{
 			ActionName: "evpn_gw_control.omac_vxlan_imac_push",
-			Params:     []interface{}{smac, dmac, net.ParseIP(srcAddr.(string)), net.ParseIP(dstAddr.(string)), v.vxlanUDPPort, vni.(uint32), innerSmacAddr, innerDmacAddr},
+			Params:     append([]interface{}{smac, dmac, net.ParseIP(srcAddr.(string)), net.ParseIP(dstAddr.(string)), uint32(encap.udpPort), vni.(uint32), innerSmacAddr, innerDmacAddr}, encaps.outerParams(vrfMarking, encap)...),
 		},
 	},
 		p4client.TableEntry{
@@ -2027,19 +2345,24 @@ func (v VxlanDecoder) translateAddedNexthop(nexthop netlink_polling.NexthopStruc
 				Params:     []interface{}{uint32(vport)},
 			},
 		})
-	return entries
+	return _vtepEntries(_nexthopVtep(nexthop.Metadata), entries)
 }
 
 // translateDeletedNexthop translates the deleted nexthop
 func (v VxlanDecoder) translateDeletedNexthop(nexthop netlink_polling.NexthopStruct) []interface{} {
-	var entries = make([]interface{}, 0)
-
 	if nexthop.NhType != netlink_polling.VXLAN {
-		return entries
+		return make([]interface{}, 0)
 	}
-	// var key []interface{}
-	key := fmt.Sprintf("%d-%s-%s-%d-%v", EntryType.l2Nh, nexthop.Key.VrfName, nexthop.Key.Dst, nexthop.Key.Dev, nexthop.Key.Local)
-	var modPtr = ptrPool.ReleaseID(key)
+	return nexthopRefs.ownerDeleted(nexthop.Key, v.Name(), func() []interface{} {
+		return v._deletedNexthopEntries(nexthop)
+	})
+}
+
+// _deletedNexthopEntries builds the deletions of a vxlan nexthop nothing uses any more
+func (v VxlanDecoder) _deletedNexthopEntries(nexthop netlink_polling.NexthopStruct) []interface{} {
+	var entries = make([]interface{}, 0)
+	key := fmt.Sprintf("%d-%s-%s-%d-%v", EntryType.l3NH, nexthop.Key.VrfName, nexthop.Key.Dst, nexthop.Key.Dev, nexthop.Key.Local)
+	var modPtr, modRefs = ptrPool.ReleaseIDWithRef(key, nexthop.Key)
 	entries = append(entries, p4client.TableEntry{
 		Tablename: pushVxlanHdr,
 		TableField: p4client.TableField{
@@ -2079,7 +2402,10 @@ func (v VxlanDecoder) translateDeletedNexthop(nexthop netlink_polling.NexthopStr
 				Priority: int32(0),
 			},
 		})
-	return entries
+	if modRefs != 0 {
+		entries = _withoutModEntries(entries, modPtr)
+	}
+	return _vtepEntries(_nexthopVtep(nexthop.Metadata), entries)
 }
 
 // translateAddedL2Nexthop translates the added l2 nexthop
@@ -2089,8 +2415,9 @@ func (v VxlanDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopS
 	if nexthop.Type != netlink_polling.VXLAN {
 		return entries
 	}
+	l2NexthopRefs.ownerAdded(nexthop.Key, v.Name())
 	key := fmt.Sprintf("%d-%s-%d-%s", EntryType.l2Nh, nexthop.Key.Dev, nexthop.Key.VlanID, nexthop.Key.Dst)
-	var modPtr = ptrPool.GetID(key)
+	var modPtr, _ = ptrPool.GetIDWithRef(key, nexthop.Key)
 	var vport = nexthop.Metadata["egress_vport"].(int)
 	var srcMac, _ = net.ParseMAC(nexthop.Metadata["phy_smac"].(string))
 	var dstMac, _ = net.ParseMAC(nexthop.Metadata["phy_dmac"].(string))
@@ -2099,6 +2426,8 @@ func (v VxlanDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopS
 	var vni = nexthop.Metadata["vni"]
 	var vsiOut = _toEgressVsi(vport)
 	var neighbor = nexthop.ID
+	var lbMarking, _ = qos.vlan(uint16(nexthop.VlanID))
+	var encap = encaps.vlan(uint16(nexthop.VlanID))
 	entries = append(entries, p4client.TableEntry{
 		Tablename: pushVxlanOutHdr,
 		TableField: p4client.TableField{
@@ -2109,7 +2438,7 @@ func (v VxlanDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopS
 		},
 		Action: p4client.Action{
 			ActionName: "evpn_gw_control.omac_vxlan_push",
-			Params:     []interface{}{srcMac, dstMac, net.ParseIP(srcIP.(string)), net.ParseIP(dstIP.(string)), v.vxlanUDPPort, vni.(uint32)},
+			Params:     append([]interface{}{srcMac, dstMac, net.ParseIP(srcIP.(string)), net.ParseIP(dstIP.(string)), uint32(encap.udpPort), vni.(uint32)}, encaps.outerParams(lbMarking, encap)...),
 		},
 	},
 		p4client.TableEntry{
@@ -2126,18 +2455,24 @@ func (v VxlanDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopS
 				Params:     []interface{}{modPtr, vsiOut},
 			},
 		})
-	return entries
+	return _vtepEntries(_nexthopVtep(nexthop.Metadata), entries)
 }
 
 // translateDeletedL2Nexthop translates the deleted l2 nexthop
 func (v VxlanDecoder) translateDeletedL2Nexthop(nexthop netlink_polling.L2NexthopStruct) []interface{} {
-	var entries = make([]interface{}, 0)
-
 	if nexthop.Type != netlink_polling.VXLAN {
-		return entries
+		return make([]interface{}, 0)
 	}
+	return l2NexthopRefs.ownerDeleted(nexthop.Key, v.Name(), func() []interface{} {
+		return v._deletedL2NexthopEntries(nexthop)
+	})
+}
+
+// _deletedL2NexthopEntries builds the deletions of an l2 nexthop nothing uses any more
+func (v VxlanDecoder) _deletedL2NexthopEntries(nexthop netlink_polling.L2NexthopStruct) []interface{} {
+	var entries = make([]interface{}, 0)
 	key := fmt.Sprintf("%d-%s-%d-%s", EntryType.l2Nh, nexthop.Key.Dev, nexthop.Key.VlanID, nexthop.Key.Dst)
-	var modPtr = ptrPool.ReleaseID(key)
+	var modPtr, modRefs = ptrPool.ReleaseIDWithRef(key, nexthop.Key)
 	var neighbor = nexthop.ID
 	entries = append(entries, p4client.TableEntry{
 		Tablename: pushVxlanOutHdr,
@@ -2158,7 +2493,10 @@ func (v VxlanDecoder) translateDeletedL2Nexthop(nexthop netlink_polling.L2Nextho
 				Priority: int32(0),
 			},
 		})
-	return entries
+	if modRefs != 0 {
+		entries = _withoutModEntries(entries, modPtr)
+	}
+	return _vtepEntries(_nexthopVtep(nexthop.Metadata), entries)
 }
 
 // translateAddedFdb translates the added fdb entry
@@ -2168,6 +2506,9 @@ func (v VxlanDecoder) translateAddedFdb(fdb netlink_polling.FdbEntryStruct) []in
 	if fdb.Type != netlink_polling.VXLAN {
 		return entries
 	}
+	if fdb.Nexthop != nil {
+		l2NexthopRefs.retain(fdb.Nexthop.Key, fdb.Key)
+	}
 	var mac, _ = net.ParseMAC(fdb.Mac)
 	var directions = _directionsOf(fdb)
 
@@ -2214,14 +2555,76 @@ func (v VxlanDecoder) translateDeletedFdb(fdb netlink_polling.FdbEntryStruct) []
 			},
 		})
 	}
+	// the l2 nexthop goes once no fdb entry points at it
+	entries = append(entries, l2NexthopRefs.releaseUser(fdb.Key)...)
 	return entries
 }
 
+// Name returns the decoder name
+func (v VxlanDecoder) Name() string {
+	return "vxlan"
+}
+
+// OnVrf translates an added or deleted vrf
+func (v VxlanDecoder) OnVrf(op Operation, vrf *infradb.Vrf) ([]interface{}, error) {
+	if op == OpDeleted {
+		return v.translateDeletedVrf(vrf), nil
+	}
+	return v.translateAddedVrf(vrf), nil
+}
+
+// OnLogicalBridge translates an added or deleted logical bridge
+func (v VxlanDecoder) OnLogicalBridge(op Operation, lb *infradb.LogicalBridge) ([]interface{}, error) {
+	trackVlanBridge(op, lb)
+	if op == OpDeleted {
+		return v.translateDeletedLb(lb), nil
+	}
+	return v.translateAddedLb(lb), nil
+}
+
+// OnNexthop translates an added or deleted nexthop
+func (v VxlanDecoder) OnNexthop(op Operation, nexthop netlink_polling.NexthopStruct) ([]interface{}, error) {
+	if op == OpDeleted {
+		return v.translateDeletedNexthop(nexthop), nil
+	}
+	return nexthopRefs.share(nexthop.Key, v.translateAddedNexthop(nexthop)), nil
+}
+
+// OnL2Nexthop translates an added or deleted l2 nexthop
+func (v VxlanDecoder) OnL2Nexthop(op Operation, nexthop netlink_polling.L2NexthopStruct) ([]interface{}, error) {
+	if aliasing {
+		aliases.nexthop(op, nexthop)
+	}
+	if op == OpDeleted {
+		return append(v.translateDeletedL2Nexthop(nexthop), v.vtepFloodEntries(op, nexthop)...), nil
+	}
+	return append(l2NexthopRefs.share(nexthop.Key, v.translateAddedL2Nexthop(nexthop)), v.vtepFloodEntries(op, nexthop)...), nil
+}
+
+// OnFdb translates an added or deleted fdb entry
+func (v VxlanDecoder) OnFdb(op Operation, fdb netlink_polling.FdbEntryStruct) ([]interface{}, error) {
+	if op == OpDeleted {
+		if aliasing {
+			aliases.fdb(op, fdb, nil)
+		}
+		return v.translateDeletedFdb(fdb), nil
+	}
+	entries := v.translateAddedFdb(fdb)
+	if aliasing && fdb.Type == netlink_polling.VXLAN {
+		// the remote mac points at its own l2 nexthop until its group is
+		// known, frr tells the segment of a new mac
+		aliases.fdb(op, fdb, entries)
+		requestFrrRead()
+	}
+	return entries, nil
+}
+
 // PodDecoder structure for pod decode
 type PodDecoder struct {
 	portMuxIDs  [2]string
 	_portMuxVsi int
 	_portMuxMac string
+	_portMuxes  []podPortMux
 	vrfMuxIDs   [2]string
 	_vrfMuxVsi  int
 	_vrfMuxMac  string
@@ -2244,6 +2647,7 @@ func (p PodDecoder) PodDecoderInit(representors map[string][2]string) PodDecoder
 	}
 	p._portMuxVsi = int(portMuxVsi)
 	p._portMuxMac = p.portMuxIDs[1]
+	p._portMuxes = portMuxesOf(representors)
 	p._vrfMuxVsi = int(vrfMuxVsi)
 	p._vrfMuxMac = p.vrfMuxIDs[1]
 	p.floodModPtr = ModPointer.l2FloodingPtr
@@ -2257,21 +2661,28 @@ func (p PodDecoder) PodDecoderInit(representors map[string][2]string) PodDecoder
 func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, error) {
 	var entries = make([]interface{}, 0)
 
-	var portMuxVsiOut = _toEgressVsi(p._portMuxVsi)
 	port, err := strconv.ParseUint(bp.Metadata.VPort, 10, 16)
 	if err != nil {
 		return entries, err
 	}
+	if err = checkVport(bp); err != nil {
+		return entries, err
+	}
 	key := fmt.Sprintf("%d-%d", EntryType.BP, port)
 	key1 := fmt.Sprintf("%d-%v", EntryType.BP, *bp.Spec.MacAddress)
 	var vsi = port
 	var vsiOut = _toEgressVsi(int(vsi))
+	muxVsi, stag, err := p.portMuxOf(bp, int(port))
+	if err != nil {
+		return entries, err
+	}
+	if muxVsi < 0 || muxVsi > math.MaxUint16 {
+		return nil, errors.New("_portMuxVsi is not in range of uint16")
+	}
+	var portMuxVsiOut = _toEgressVsi(muxVsi)
 	var modPtr = ptrPool.GetID(key)
 	var ignorePtr = ModPointer.ignorePtr
 	var mac = *bp.Spec.MacAddress
-	if p._portMuxVsi < 0 || p._portMuxVsi > math.MaxUint16 {
-		return nil, errors.New("_portMuxVsi is not in range of uint16")
-	}
 	if bp.Spec.Ptype == infradb.Trunk {
 		var modPtrD = ptrPool.GetID(key1)
 		entries = append(entries, p4client.TableEntry{
@@ -2279,8 +2690,8 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 			Tablename: portMuxIn,
 			TableField: p4client.TableField{
 				FieldValue: map[string][2]interface{}{
-					"vsi": {uint16(p._portMuxVsi), "exact"},
-					"vid": {uint16(vsi), "exact"},
+					"vsi": {uint16(muxVsi), "exact"},
+					"vid": {stag, "exact"},
 				},
 				Priority: int32(0),
 			},
@@ -2326,11 +2737,11 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 				},
 				Action: p4client.Action{
 					ActionName: "evpn_gw_control.vlan_push_trunk",
-					Params:     []interface{}{uint16(0), uint16(0), uint32(vsi)},
+					Params:     []interface{}{qos.bridgePortPcp(bp.Spec.LogicalBridges), uint16(0), uint32(stag)},
 				},
 			})
 		for _, vlan := range bp.Spec.LogicalBridges {
-			BrObj, err := infradb.GetLB(vlan)
+			BrObj, err := objects.GetLB(vlan)
 			if err != nil {
 				log.Printf("intel-e2000: unable to find key %s and error is %v\n", vlan, err)
 				return entries, err
@@ -2341,13 +2752,15 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 			}
 
 			vid := uint16(BrObj.Spec.VlanID)
+			// the tenant tags the logical bridge with its own vid
+			cvid := vlanmap.CustomerVid(bp.Name, vid)
 			entries = append(entries, p4client.TableEntry{
 				// To MUX PORT
 				Tablename: podInArpTrunk,
 				TableField: p4client.TableField{
 					FieldValue: map[string][2]interface{}{
 						"vsi": {uint16(vsi), "exact"},
-						"vid": {vid, "exact"},
+						"vid": {cvid, "exact"},
 					},
 					Priority: int32(0),
 				},
@@ -2362,7 +2775,7 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 					TableField: p4client.TableField{
 						FieldValue: map[string][2]interface{}{
 							"vsi": {uint16(vsi), "exact"},
-							"vid": {vid, "exact"},
+							"vid": {cvid, "exact"},
 						},
 						Priority: int32(0),
 					},
@@ -2373,12 +2786,12 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 				})
 
 			if BrObj.Svi != "" {
-				SviObj, err := infradb.GetSvi(BrObj.Svi)
+				SviObj, err := objects.GetSvi(BrObj.Svi)
 				if err != nil {
 					log.Printf("intel-e2000: unable to find key %s and error is %v\n", BrObj.Svi, err)
 					return entries, err
 				}
-				VrfObj, err := infradb.GetVrf(SviObj.Spec.Vrf)
+				VrfObj, err := objects.GetVrf(SviObj.Spec.Vrf)
 				if err != nil {
 					log.Printf("intel-e2000: unable to find key %s and error is %v\n", SviObj.Spec.Vrf, err)
 					return entries, err
@@ -2395,7 +2808,7 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 					TableField: p4client.TableField{
 						FieldValue: map[string][2]interface{}{
 							"vsi": {uint16(vsi), "exact"},
-							"vid": {vid, "exact"},
+							"vid": {cvid, "exact"},
 							"da":  {sviMac, "exact"},
 						},
 						Priority: int32(0),
@@ -2409,8 +2822,10 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 				log.Println("intel-e2000: no associated SVI object created")
 			}
 		}
+		// the nexthops find the port once all its entries are built
+		trunkVports[bp.Metadata.VPort] = bp.Name
 	} else if bp.Spec.Ptype == infradb.Access {
-		BrObj, err := infradb.GetLB(bp.Spec.LogicalBridges[0])
+		BrObj, err := objects.GetLB(bp.Spec.LogicalBridges[0])
 		if err != nil {
 			log.Printf("intel-e2000: unable to find key %s and error is %v\n", bp.Spec.LogicalBridges[0], err)
 			return entries, err
@@ -2422,13 +2837,14 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 		var vid = uint16(BrObj.Spec.VlanID)
 		var modPtrD = ptrPool.GetID(key1)
 		var dstMacAddr = *bp.Spec.MacAddress
+		var pcp = qos.bridgePortPcp(bp.Spec.LogicalBridges)
 		entries = append(entries, p4client.TableEntry{
 			// From MUX
 			Tablename: portMuxIn,
 			TableField: p4client.TableField{
 				FieldValue: map[string][2]interface{}{
-					"vsi": {uint16(p._portMuxVsi), "exact"},
-					"vid": {uint16(vsi), "exact"},
+					"vsi": {uint16(muxVsi), "exact"},
+					"vid": {stag, "exact"},
 				},
 				Priority: int32(0),
 			},
@@ -2475,7 +2891,7 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 				},
 				Action: p4client.Action{
 					ActionName: "evpn_gw_control.vlan_push_access",
-					Params:     []interface{}{uint16(0), uint16(0), vid, uint16(0), uint16(0), uint16(vsi)},
+					Params:     []interface{}{pcp, uint16(0), vid, pcp, uint16(0), stag},
 				},
 			},
 			p4client.TableEntry{
@@ -2508,12 +2924,12 @@ func (p PodDecoder) translateAddedBp(bp *infradb.BridgePort) ([]interface{}, err
 				},
 			})
 		if BrObj.Svi != "" {
-			SviObj, err := infradb.GetSvi(BrObj.Svi)
+			SviObj, err := objects.GetSvi(BrObj.Svi)
 			if err != nil {
 				log.Printf("intel-e2000: unable to find key %s and error is %v\n", BrObj.Svi, err)
 				return entries, err
 			}
-			VrfObj, err := infradb.GetVrf(SviObj.Spec.Vrf)
+			VrfObj, err := objects.GetVrf(SviObj.Spec.Vrf)
 			if err != nil {
 				log.Printf("intel-e2000: unable to find key %s and error is %v\n", SviObj.Spec.Vrf, err)
 				return entries, err
@@ -2557,20 +2973,25 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 	key := fmt.Sprintf("%d-%d", EntryType.BP, port)
 	key1 := fmt.Sprintf("%d-%v", EntryType.BP, *bp.Spec.MacAddress)
 	var vsi = port
+	muxVsi, stag, err := p.portMuxOf(bp, int(port))
+	if err != nil {
+		return entries, err
+	}
+	if muxVsi < 0 || muxVsi > math.MaxUint16 {
+		return nil, errors.New("_portMuxVsi is not in range of uint16")
+	}
 	var modPtr = ptrPool.ReleaseID(key)
 	var mac = *bp.Spec.MacAddress
 	var modPtrD = ptrPool.ReleaseID(key1)
-	if p._portMuxVsi < 0 || p._portMuxVsi > math.MaxUint16 {
-		return nil, errors.New("_portMuxVsi is not in range of uint16")
-	}
 	if bp.Spec.Ptype == infradb.Trunk {
+		delete(trunkVports, bp.Metadata.VPort)
 		entries = append(entries, p4client.TableEntry{
 			// From MUX
 			Tablename: portMuxIn,
 			TableField: p4client.TableField{
 				FieldValue: map[string][2]interface{}{
-					"vsi": {uint16(p._portMuxVsi), "exact"},
-					"vid": {uint16(vsi), "exact"},
+					"vsi": {uint16(muxVsi), "exact"},
+					"vid": {stag, "exact"},
 				},
 				Priority: int32(0),
 			},
@@ -2604,7 +3025,7 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 				},
 			})
 		for _, vlan := range bp.Spec.LogicalBridges {
-			BrObj, err := infradb.GetLB(vlan)
+			BrObj, err := objects.GetLB(vlan)
 			if err != nil {
 				log.Printf("intel-e2000: unable to find key %s and error is %v\n", vlan, err)
 				return entries, err
@@ -2614,13 +3035,15 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 				return entries, errors.New("VlanID value passed in Logical Bridge create is greater than 16 bit value")
 			}
 			vid := uint16(BrObj.Spec.VlanID)
+			// the tenant tags the logical bridge with its own vid
+			cvid := vlanmap.CustomerVid(bp.Name, vid)
 			entries = append(entries, p4client.TableEntry{
 				// To MUX PORT
 				Tablename: podInArpTrunk,
 				TableField: p4client.TableField{
 					FieldValue: map[string][2]interface{}{
 						"vsi": {uint16(vsi), "exact"},
-						"vid": {vid, "exact"},
+						"vid": {cvid, "exact"},
 					},
 					Priority: int32(0),
 				},
@@ -2631,14 +3054,14 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 					TableField: p4client.TableField{
 						FieldValue: map[string][2]interface{}{
 							"vsi": {uint16(vsi), "exact"},
-							"vid": {vid, "exact"},
+							"vid": {cvid, "exact"},
 						},
 						Priority: int32(0),
 					},
 				})
 
 			if BrObj.Svi != "" {
-				SviObj, err := infradb.GetSvi(BrObj.Svi)
+				SviObj, err := objects.GetSvi(BrObj.Svi)
 				if err != nil {
 					log.Printf("intel-e2000: unable to find key %s and error is %v\n", BrObj.Svi, err)
 					return entries, err
@@ -2651,7 +3074,7 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 					TableField: p4client.TableField{
 						FieldValue: map[string][2]interface{}{
 							"vsi": {uint16(vsi), "exact"},
-							"vid": {vid, "exact"},
+							"vid": {cvid, "exact"},
 							"da":  {sviMac, "exact"},
 						},
 						Priority: int32(0),
@@ -2662,7 +3085,7 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 			}
 		}
 	} else if bp.Spec.Ptype == infradb.Access {
-		BrObj, err := infradb.GetLB(bp.Spec.LogicalBridges[0])
+		BrObj, err := objects.GetLB(bp.Spec.LogicalBridges[0])
 		if err != nil {
 			log.Printf("intel-e2000: unable to find key %s and error is %v\n", bp.Spec.LogicalBridges[0], err)
 			return entries, err
@@ -2673,8 +3096,8 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 			Tablename: portMuxIn,
 			TableField: p4client.TableField{
 				FieldValue: map[string][2]interface{}{
-					"vsi": {uint16(p._portMuxVsi), "exact"},
-					"vid": {uint16(vsi), "exact"},
+					"vsi": {uint16(muxVsi), "exact"},
+					"vid": {stag, "exact"},
 				},
 				Priority: int32(0),
 			},
@@ -2730,7 +3153,7 @@ func (p PodDecoder) translateDeletedBp(bp *infradb.BridgePort) ([]interface{}, e
 				},
 			})
 		if BrObj.Svi != "" {
-			SviObj, err := infradb.GetSvi(BrObj.Svi)
+			SviObj, err := objects.GetSvi(BrObj.Svi)
 			if err != nil {
 				log.Printf("intel-e2000: unable to find key %s and error is %v\n", BrObj.Svi, err)
 				return entries, err
@@ -2760,14 +3183,14 @@ func (p PodDecoder) translateAddedSvi(svi *infradb.Svi) ([]interface{}, error) {
 	var mac = *svi.Spec.MacAddress
 	var entries = make([]interface{}, 0)
 
-	BrObj, err := infradb.GetLB(svi.Spec.LogicalBridge)
+	BrObj, err := objects.GetLB(svi.Spec.LogicalBridge)
 	if err != nil {
 		log.Printf("intel-e2000: unable to find key %s and error is %v\n", svi.Spec.LogicalBridge, err)
 		return entries, err
 	}
 	for k, v := range BrObj.BridgePorts {
 		if !v {
-			PortObj, err := infradb.GetBP(k)
+			PortObj, err := objects.GetBP(k)
 			if err != nil {
 				log.Printf("intel-e2000: unable to find key %s and error is %v\n", k, err)
 				return entries, err
@@ -2776,7 +3199,7 @@ func (p PodDecoder) translateAddedSvi(svi *infradb.Svi) ([]interface{}, error) {
 			if err != nil {
 				return entries, err
 			}
-			VrfObj, err := infradb.GetVrf(svi.Spec.Vrf)
+			VrfObj, err := objects.GetVrf(svi.Spec.Vrf)
 			if err != nil {
 				log.Printf("intel-e2000: unable to find key %s and error is %v", svi.Spec.Vrf, err)
 				return entries, err
@@ -2806,14 +3229,14 @@ func (p PodDecoder) translateAddedSvi(svi *infradb.Svi) ([]interface{}, error) {
 					TableField: p4client.TableField{
 						FieldValue: map[string][2]interface{}{
 							"vsi": {uint16(port), "exact"},
-							"vid": {uint16(BrObj.Spec.VlanID), "exact"},
+							"vid": {vlanmap.CustomerVid(PortObj.Name, uint16(BrObj.Spec.VlanID)), "exact"},
 							"da":  {mac, "exact"},
 						},
 						Priority: int32(0),
 					},
 					Action: p4client.Action{
 						ActionName: "evpn_gw_control.pop_vlan_set_vrf_id",
-						Params:     []interface{}{ignorePtr, uint32(tcamPrefix), uint32(0), uint16(*VrfObj.Spec.Vni)},
+						Params:     []interface{}{ignorePtr, uint32(tcamPrefix), uint32(0), uint16(*VrfObj.Metadata.RoutingTable[0])},
 					},
 				})
 			}
@@ -2827,7 +3250,7 @@ func (p PodDecoder) translateDeletedSvi(svi *infradb.Svi) ([]interface{}, error)
 	var mac = *svi.Spec.MacAddress
 	var entries = make([]interface{}, 0)
 
-	BrObj, err := infradb.GetLB(svi.Spec.LogicalBridge)
+	BrObj, err := objects.GetLB(svi.Spec.LogicalBridge)
 	if err != nil {
 		log.Printf("intel-e2000: unable to find key %s and error is %v\n", svi.Spec.LogicalBridge, err)
 		return entries, err
@@ -2835,7 +3258,7 @@ func (p PodDecoder) translateDeletedSvi(svi *infradb.Svi) ([]interface{}, error)
 
 	for k, v := range BrObj.BridgePorts {
 		if !v {
-			PortObj, err := infradb.GetBP(k)
+			PortObj, err := objects.GetBP(k)
 			if err != nil {
 				log.Printf("unable to find key %s and error is %v", k, err)
 				return entries, err
@@ -2861,7 +3284,7 @@ func (p PodDecoder) translateDeletedSvi(svi *infradb.Svi) ([]interface{}, error)
 					TableField: p4client.TableField{
 						FieldValue: map[string][2]interface{}{
 							"vsi": {uint16(port), "exact"},
-							"vid": {uint16(BrObj.Spec.VlanID), "exact"},
+							"vid": {vlanmap.CustomerVid(PortObj.Name, uint16(BrObj.Spec.VlanID)), "exact"},
 							"da":  {mac, "exact"},
 						},
 						Priority: int32(0),
@@ -2881,6 +3304,9 @@ func (p PodDecoder) translateAddedFdb(fdb netlink_polling.FdbEntryStruct) []inte
 	if fdb.Type != netlink_polling.BRIDGEPORT {
 		return entries
 	}
+	if fdb.Nexthop != nil {
+		l2NexthopRefs.retain(fdb.Nexthop.Key, fdb.Key)
+	}
 	for dir := range _directionsOf(fdb) {
 		entries = append(entries, p4client.TableEntry{
 			Tablename: l2Fwd,
@@ -2922,6 +3348,7 @@ func (p PodDecoder) translateDeletedFdb(fdb netlink_polling.FdbEntryStruct) []in
 			},
 		})
 	}
+	entries = append(entries, l2NexthopRefs.releaseUser(fdb.Key)...)
 	return entries
 }
 
@@ -2932,6 +3359,7 @@ func (p PodDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopStr
 	if nexthop.Type != netlink_polling.BRIDGEPORT {
 		return entries
 	}
+	l2NexthopRefs.ownerAdded(nexthop.Key, p.Name())
 	var neighbor = nexthop.ID
 	var portType = nexthop.Metadata["portType"].(infradb.BridgePortType)
 	var portID, err = strconv.Atoi(nexthop.Metadata["vport_id"].(string))
@@ -2955,7 +3383,8 @@ func (p PodDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopStr
 		})
 	} else if portType == infradb.Trunk {
 		key := fmt.Sprintf("%d-%s-%d-%s", EntryType.l2Nh, nexthop.Key.Dev, nexthop.Key.VlanID, nexthop.Key.Dst)
-		var modPtr = ptrPool.GetID(key)
+		var modPtr, _ = ptrPool.GetIDWithRef(key, nexthop.Key)
+		var lbMarking, _ = qos.vlan(uint16(nexthop.VlanID))
 		entries = append(entries, p4client.TableEntry{
 			Tablename: pushVlan,
 			TableField: p4client.TableField{
@@ -2966,7 +3395,7 @@ func (p PodDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopStr
 			},
 			Action: p4client.Action{
 				ActionName: "evpn_gw_control.vlan_push",
-				Params:     []interface{}{uint16(0), uint16(0), uint16(nexthop.VlanID)},
+				Params:     []interface{}{lbMarking.pcp, uint16(0), customerVid(nexthop.Metadata["vport_id"].(string), uint16(nexthop.VlanID))},
 			},
 		},
 			p4client.TableEntry{
@@ -2989,12 +3418,19 @@ func (p PodDecoder) translateAddedL2Nexthop(nexthop netlink_polling.L2NexthopStr
 
 // translateDeletedL2Nexthop translate the deleted l2 nexthop entry
 func (p PodDecoder) translateDeletedL2Nexthop(nexthop netlink_polling.L2NexthopStruct) []interface{} {
-	var entries = make([]interface{}, 0)
-
-	var modPtr uint32
 	if nexthop.Type != netlink_polling.BRIDGEPORT {
-		return entries
+		return make([]interface{}, 0)
 	}
+	return l2NexthopRefs.ownerDeleted(nexthop.Key, p.Name(), func() []interface{} {
+		return p._deletedL2NexthopEntries(nexthop)
+	})
+}
+
+// _deletedL2NexthopEntries builds the deletions of an l2 nexthop nothing uses any more
+func (p PodDecoder) _deletedL2NexthopEntries(nexthop netlink_polling.L2NexthopStruct) []interface{} {
+	var entries = make([]interface{}, 0)
+
+	var modPtr, modRefs uint32
 	var neighbor = nexthop.ID
 	var portType = nexthop.Metadata["portType"].(infradb.BridgePortType)
 
@@ -3011,7 +3447,7 @@ func (p PodDecoder) translateDeletedL2Nexthop(nexthop netlink_polling.L2NexthopS
 		})
 	} else if portType == infradb.Trunk {
 		key := fmt.Sprintf("%d-%s-%d-%s", EntryType.l2Nh, nexthop.Key.Dev, nexthop.Key.VlanID, nexthop.Key.Dst)
-		modPtr = ptrPool.ReleaseID(key)
+		modPtr, modRefs = ptrPool.ReleaseIDWithRef(key, nexthop.Key)
 		entries = append(entries, p4client.TableEntry{
 			Tablename: pushVlan,
 			TableField: p4client.TableField{
@@ -3032,15 +3468,20 @@ func (p PodDecoder) translateDeletedL2Nexthop(nexthop netlink_polling.L2NexthopS
 				},
 			})
 	}
+	if modRefs != 0 {
+		entries = _withoutModEntries(entries, modPtr)
+	}
 	return entries
 }
 
 // StaticAdditions static additions
 func (p PodDecoder) StaticAdditions() []interface{} {
-	var portMuxDa, _ = net.ParseMAC(p._portMuxMac)
 	var vrfMuxDa, _ = net.ParseMAC(p._vrfMuxMac)
 	var entries = make([]interface{}, 0)
 
+	// the table is keyed on a constant, its one entry sends to the first
+	// mux. The bridge ports of the other muxes are reached through the
+	// entries of their own mux.
 	entries = append(entries, p4client.TableEntry{
 		Tablename: portMuxFwd,
 		TableField: p4client.TableField{
@@ -3054,19 +3495,6 @@ func (p PodDecoder) StaticAdditions() []interface{} {
 			Params:     []interface{}{uint32(_toEgressVsi(p._portMuxVsi))},
 		},
 	},
-		p4client.TableEntry{
-			Tablename: l2FwdLoop,
-			TableField: p4client.TableField{
-				FieldValue: map[string][2]interface{}{
-					"da": {portMuxDa, "exact"},
-				},
-				Priority: int32(0),
-			},
-			Action: p4client.Action{
-				ActionName: "evpn_gw_control.l2_fwd",
-				Params:     []interface{}{uint32(_toEgressVsi(p._portMuxVsi))},
-			},
-		},
 		p4client.TableEntry{
 			Tablename: l2FwdLoop,
 			TableField: p4client.TableField{
@@ -3108,6 +3536,8 @@ func (p PodDecoder) StaticAdditions() []interface{} {
 				Params:     []interface{}{p.floodModPtr, uint32(_toEgressVsi(p._vrfMuxVsi))},
 			},
 		})
+	entries = append(entries, p.portMuxLoopEntries(OpAdded)...)
+	entries = append(entries, splitHorizonEntries(OpAdded)...)
 	return entries
 }
 
@@ -3115,7 +3545,6 @@ func (p PodDecoder) StaticAdditions() []interface{} {
 func (p PodDecoder) StaticDeletions() []interface{} {
 	var entries = make([]interface{}, 0)
 
-	var portMuxDa, _ = net.ParseMAC(p._portMuxMac)
 	var vrfMuxDa, _ = net.ParseMAC(p._vrfMuxMac)
 	entries = append(entries, p4client.TableEntry{
 		Tablename: portMuxFwd,
@@ -3126,15 +3555,6 @@ func (p PodDecoder) StaticDeletions() []interface{} {
 			Priority: int32(0),
 		},
 	},
-		p4client.TableEntry{
-			Tablename: l2FwdLoop,
-			TableField: p4client.TableField{
-				FieldValue: map[string][2]interface{}{
-					"da": {portMuxDa, "exact"},
-				},
-				Priority: int32(0),
-			},
-		},
 		p4client.TableEntry{
 			Tablename: l2FwdLoop,
 			TableField: p4client.TableField{
@@ -3164,5 +3584,56 @@ func (p PodDecoder) StaticDeletions() []interface{} {
 				Priority: int32(0),
 			},
 		})
+	entries = append(entries, p.portMuxLoopEntries(OpDeleted)...)
+	entries = append(entries, splitHorizonEntries(OpDeleted)...)
 	return entries
 }
+
+// Name returns the decoder name
+func (p PodDecoder) Name() string {
+	return "pod"
+}
+
+// OnBridgePort translates an added or deleted bridge port
+func (p PodDecoder) OnBridgePort(op Operation, bp *infradb.BridgePort) ([]interface{}, error) {
+	var entries []interface{}
+	var err error
+	if op == OpDeleted {
+		entries, err = p.translateDeletedBp(bp)
+	} else {
+		entries, err = p.translateAddedBp(bp)
+	}
+	if err != nil {
+		return entries, err
+	}
+	return append(entries, p.bpFloodEntries(op, bp)...), nil
+}
+
+// OnSvi translates an added or deleted svi
+func (p PodDecoder) OnSvi(op Operation, svi *infradb.Svi) ([]interface{}, error) {
+	if op == OpDeleted {
+		return p.translateDeletedSvi(svi)
+	}
+	return p.translateAddedSvi(svi)
+}
+
+// OnFdb translates an added or deleted fdb entry
+func (p PodDecoder) OnFdb(op Operation, fdb netlink_polling.FdbEntryStruct) ([]interface{}, error) {
+	if op == OpDeleted {
+		return p.translateDeletedFdb(fdb), nil
+	}
+	return p.translateAddedFdb(fdb), nil
+}
+
+// OnNexthop translates an added or deleted nexthop
+func (p PodDecoder) OnNexthop(op Operation, nexthop netlink_polling.NexthopStruct) ([]interface{}, error) {
+	return p.translateNeighbor(op, nexthop), nil
+}
+
+// OnL2Nexthop translates an added or deleted l2 nexthop
+func (p PodDecoder) OnL2Nexthop(op Operation, nexthop netlink_polling.L2NexthopStruct) ([]interface{}, error) {
+	if op == OpDeleted {
+		return p.translateDeletedL2Nexthop(nexthop), nil
+	}
+	return l2NexthopRefs.share(nexthop.Key, p.translateAddedL2Nexthop(nexthop)), nil
+}
@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// activeRepresentors is the representor map the decoders were set up with
+var activeRepresentors map[string][2]string
+
+// ecmpGroups are the programmed ecmp groups by id, guarded by translateMu
+var ecmpGroups = make(map[uint32]EcmpDispatcher)
+
+// DebugPort is a physical or grpc pair port of the l3 decoder
+type DebugPort struct {
+	ID      int    `json:"id,omitempty"`
+	Vsi     int    `json:"vsi"`
+	Mac     string `json:"mac"`
+	PeerVsi string `json:"peerVsi,omitempty"`
+	PeerMac string `json:"peerMac,omitempty"`
+}
+
+// DebugL3Decoder is the state of the l3 decoder
+type DebugL3Decoder struct {
+	MuxVsi     uint16             `json:"muxVsi"`
+	DefaultVsi int                `json:"defaultVsi"`
+	PhyPorts   []DebugPort        `json:"phyPorts"`
+	GrpcPorts  []DebugPort        `json:"grpcPorts"`
+	Uplinks    []DebugUplinkGroup `json:"uplinkGroups,omitempty"`
+}
+
+// DebugUplinkGroup is an uplink group with its members that are down
+type DebugUplinkGroup struct {
+	Name    string   `json:"name"`
+	Port    int      `json:"port"`
+	Members []string `json:"members"`
+	Down    []string `json:"down,omitempty"`
+}
+
+// DebugPodDecoder is the state of the pod decoder
+type DebugPodDecoder struct {
+	PortMuxVsi  int    `json:"portMuxVsi"`
+	PortMuxMac  string `json:"portMuxMac"`
+	VrfMuxVsi   int    `json:"vrfMuxVsi"`
+	VrfMuxMac   string `json:"vrfMuxMac"`
+	FloodModPtr uint32 `json:"floodModPtr"`
+	FloodNhID   uint16 `json:"floodNhId"`
+}
+
+// DebugVxlanDecoder is the state of the vxlan decoder
+type DebugVxlanDecoder struct {
+	Encaps     DebugVxlanEncaps `json:"encaps"`
+	MuxVsi     int              `json:"muxVsi"`
+	DefaultVsi int              `json:"defaultVsi"`
+}
+
+// DebugVxlanEncaps are the default encap of the tunnels and the ones of the
+// logical bridges and vrfs overriding it
+type DebugVxlanEncaps struct {
+	Default        DebugVxlanEncap            `json:"default"`
+	LogicalBridges map[string]DebugVxlanEncap `json:"logicalBridges,omitempty"`
+	Vrfs           map[string]DebugVxlanEncap `json:"vrfs,omitempty"`
+}
+
+// DebugVxlanEncap is the outer header of the tunnels of an encap
+type DebugVxlanEncap struct {
+	UDPPort uint16 `json:"udpPort"`
+	TTL     uint16 `json:"ttl"`
+	Entropy bool   `json:"entropy"`
+}
+
+// DebugEcmpMember is a nexthop of an ecmp group with its hash slots
+type DebugEcmpMember struct {
+	NexthopID int   `json:"nexthopId"`
+	Weight    int   `json:"weight"`
+	Hashes    []int `json:"hashes"`
+}
+
+// DebugEcmpGroup is a programmed ecmp group
+type DebugEcmpGroup struct {
+	ID        uint32            `json:"id"`
+	Direction int               `json:"direction"`
+	Members   []DebugEcmpMember `json:"members"`
+}
+
+// DebugState is what the plugin believes is programmed
+type DebugState struct {
+	Representors map[string][2]string              `json:"representors"`
+	Decoders     []string                          `json:"decoders"`
+	L3           DebugL3Decoder                    `json:"l3Decoder"`
+	Pod          DebugPodDecoder                   `json:"podDecoder"`
+	Vxlan        DebugVxlanDecoder                 `json:"vxlanDecoder"`
+	Tables       map[string][]p4client.EntryRecord `json:"tables"`
+	Pools        map[string]string                 `json:"pools"`
+	EcmpGroups   []DebugEcmpGroup                  `json:"ecmpGroups"`
+	FloodGroups  []p4client.MulticastGroupRecord   `json:"floodGroups"`
+	Mirrors      []MirrorSession                   `json:"mirrorSessions"`
+	Parked       map[string]int                    `json:"parkedEvents,omitempty"`
+}
+
+// trackEcmpGroup keeps the group programmed by addEcmpDispatcher
+func trackEcmpGroup(e EcmpDispatcher) {
+	ecmpGroups[e.id] = e
+}
+
+// untrackEcmpGroup drops the group removed by delEcmpDispatcher
+func untrackEcmpGroup(id uint32) {
+	delete(ecmpGroups, id)
+}
+
+// DebugDump returns the decoder state, the desired entries, the id pools
+// and the ecmp groups
+func DebugDump() *DebugState {
+	translateMu.Lock()
+	defer translateMu.Unlock()
+
+	state := &DebugState{
+		Representors: activeRepresentors,
+		L3: DebugL3Decoder{
+			MuxVsi:     L3._muxVsi,
+			DefaultVsi: L3._defaultVsi,
+			Uplinks:    uplinkGroupList(),
+		},
+		Pod: DebugPodDecoder{
+			PortMuxVsi:  Pod._portMuxVsi,
+			PortMuxMac:  Pod._portMuxMac,
+			VrfMuxVsi:   Pod._vrfMuxVsi,
+			VrfMuxMac:   Pod._vrfMuxMac,
+			FloodModPtr: Pod.floodModPtr,
+			FloodNhID:   Pod.floodNhID,
+		},
+		Vxlan: DebugVxlanDecoder{
+			Encaps:     encaps.debug(),
+			MuxVsi:     Vxlan._muxVsi,
+			DefaultVsi: Vxlan._defaultVsi,
+		},
+		Tables: make(map[string][]p4client.EntryRecord),
+		Pools: map[string]string{
+			"mod_ptr":    ptrPool.GetPoolStatus(),
+			"trie_index": trieIndexPool.GetPoolStatus(),
+			"ecmp":       ecmpIndexPool.GetPoolStatus(),
+			"mirror":     mirrorPool.GetPoolStatus(),
+		},
+		Mirrors: mirrorSessionList(),
+	}
+	if netlinkPipeline != nil {
+		state.Parked = netlinkPipeline.Parked()
+	}
+	for _, d := range Decoders() {
+		state.Decoders = append(state.Decoders, d.Name())
+	}
+	for _, p := range L3._phyPorts {
+		state.L3.PhyPorts = append(state.L3.PhyPorts, DebugPort{ID: p.id, Vsi: p.vsi, Mac: p.mac})
+	}
+	for _, p := range L3._grpcPorts {
+		state.L3.GrpcPorts = append(state.L3.GrpcPorts, DebugPort{Vsi: p.vsi, Mac: p.mac, PeerVsi: p.peer["vsi"], PeerMac: p.peer["mac"]})
+	}
+	for _, table := range desired.tableNames() {
+		for _, e := range desired.entries(table) {
+			state.Tables[table] = append(state.Tables[table], p4client.NewEntryRecord(e))
+		}
+	}
+	for _, e := range ecmpGroups {
+		group := DebugEcmpGroup{ID: e.id, Direction: e.dir}
+		for _, nh := range e.Nexthop {
+			group.Members = append(group.Members, DebugEcmpMember{NexthopID: nh.ID, Weight: nh.Weight, Hashes: nh.Hashes})
+		}
+		state.EcmpGroups = append(state.EcmpGroups, group)
+	}
+	for _, g := range desired.multicastGroups() {
+		state.FloodGroups = append(state.FloodGroups, p4client.NewMulticastGroupRecord(g))
+	}
+	sort.Slice(state.EcmpGroups, func(i, j int) bool { return state.EcmpGroups[i].ID < state.EcmpGroups[j].ID })
+	return state
+}
+
+// DebugTextproto returns the desired entries as p4runtime textproto, one
+// TableEntry per entry. It needs the p4info of a connected device, or in
+// dry run mode the p4info file of p4.config.p4infofile.
+func DebugTextproto() (string, error) {
+	var b strings.Builder
+	for _, table := range desired.tableNames() {
+		for _, e := range desired.entries(table) {
+			text, err := p4client.EntryTextproto(schema.resolve(e))
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", table, err)
+			}
+			fmt.Fprintf(&b, "# %s\n%s\n", table, text)
+		}
+	}
+	return b.String(), nil
+}
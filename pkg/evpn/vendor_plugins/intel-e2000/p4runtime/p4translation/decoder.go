@@ -0,0 +1,360 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	nm "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Operation tells a decoder hook whether the object was added or deleted
+type Operation int
+
+const (
+	// OpAdded the object has been added
+	OpAdded Operation = iota
+	// OpDeleted the object has been deleted
+	OpDeleted
+)
+
+// String returns the operation name
+func (op Operation) String() string {
+	if op == OpDeleted {
+		return "deleted"
+	}
+	return "added"
+}
+
+// Decoder is a translation unit of the intel-e2000 fast path.
+// A decoder implements any subset of the hook interfaces below;
+// the dispatcher only calls the hooks a decoder provides.
+type Decoder interface {
+	Name() string
+}
+
+// VrfHook translates vrf objects
+type VrfHook interface {
+	OnVrf(op Operation, vrf *infradb.Vrf) ([]interface{}, error)
+}
+
+// LogicalBridgeHook translates logical bridge objects
+type LogicalBridgeHook interface {
+	OnLogicalBridge(op Operation, lb *infradb.LogicalBridge) ([]interface{}, error)
+}
+
+// BridgePortHook translates bridge port objects
+type BridgePortHook interface {
+	OnBridgePort(op Operation, bp *infradb.BridgePort) ([]interface{}, error)
+}
+
+// SviHook translates svi objects
+type SviHook interface {
+	OnSvi(op Operation, svi *infradb.Svi) ([]interface{}, error)
+}
+
+// RouteHook translates netlink routes
+type RouteHook interface {
+	OnRoute(op Operation, route nm.RouteStruct) ([]interface{}, error)
+}
+
+// NexthopHook translates netlink nexthops
+type NexthopHook interface {
+	OnNexthop(op Operation, nexthop nm.NexthopStruct) ([]interface{}, error)
+}
+
+// FdbHook translates netlink fdb entries
+type FdbHook interface {
+	OnFdb(op Operation, fdb nm.FdbEntryStruct) ([]interface{}, error)
+}
+
+// L2NexthopHook translates netlink l2 nexthops
+type L2NexthopHook interface {
+	OnL2Nexthop(op Operation, nexthop nm.L2NexthopStruct) ([]interface{}, error)
+}
+
+// StaticHook provides the entries programmed at start up and removed at shut down
+type StaticHook interface {
+	StaticAdditions() []interface{}
+	StaticDeletions() []interface{}
+}
+
+// registry keeps the decoders in registration order
+var registry = struct {
+	sync.RWMutex
+	decoders []Decoder
+}{}
+
+// RegisterDecoder adds a decoder to the dispatch list.
+// A decoder registered under an existing name replaces the old one in place.
+func RegisterDecoder(d Decoder) {
+	registry.Lock()
+	defer registry.Unlock()
+	for i, old := range registry.decoders {
+		if old.Name() == d.Name() {
+			registry.decoders[i] = d
+			return
+		}
+	}
+	registry.decoders = append(registry.decoders, d)
+}
+
+// UnregisterDecoder removes the decoder with the given name
+func UnregisterDecoder(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	for i, d := range registry.decoders {
+		if d.Name() == name {
+			registry.decoders = append(registry.decoders[:i], registry.decoders[i+1:]...)
+			return
+		}
+	}
+}
+
+// Decoders returns the registered decoders in dispatch order
+func Decoders() []Decoder {
+	registry.RLock()
+	defer registry.RUnlock()
+	return append([]Decoder(nil), registry.decoders...)
+}
+
+// translateMu serialises the decoders, which share the id pools, between
+// the netlink shards and the infradb handlers
+var translateMu sync.Mutex
+
+// collect runs fn for every registered decoder and concatenates the entries.
+// Static deletions are collected in reverse order so teardown mirrors set up.
+func collect(reverse bool, fn func(d Decoder) ([]interface{}, error)) ([]interface{}, error) {
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	var entries []interface{}
+	decoders := Decoders()
+	for i := range decoders {
+		d := decoders[i]
+		if reverse {
+			d = decoders[len(decoders)-1-i]
+		}
+		e, err := fn(d)
+		if err != nil {
+			return nil, fmt.Errorf("%s decoder: %w", d.Name(), err)
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+// vrfEntries collects the vrf entries of all decoders
+func vrfEntries(op Operation, vrf *infradb.Vrf) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(VrfHook); ok {
+			return h.OnVrf(op, vrf)
+		}
+		return nil, nil
+	})
+}
+
+// lbEntries collects the logical bridge entries of all decoders
+func lbEntries(op Operation, lb *infradb.LogicalBridge) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(LogicalBridgeHook); ok {
+			return h.OnLogicalBridge(op, lb)
+		}
+		return nil, nil
+	})
+}
+
+// bpEntries collects the bridge port entries of all decoders
+func bpEntries(op Operation, bp *infradb.BridgePort) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(BridgePortHook); ok {
+			return h.OnBridgePort(op, bp)
+		}
+		return nil, nil
+	})
+}
+
+// sviEntries collects the svi entries of all decoders
+func sviEntries(op Operation, svi *infradb.Svi) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(SviHook); ok {
+			return h.OnSvi(op, svi)
+		}
+		return nil, nil
+	})
+}
+
+// routeEntries collects the route entries of all decoders
+func routeEntries(op Operation, route nm.RouteStruct) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(RouteHook); ok {
+			return h.OnRoute(op, route)
+		}
+		return nil, nil
+	})
+}
+
+// nexthopEntries collects the nexthop entries of all decoders
+func nexthopEntries(op Operation, nexthop nm.NexthopStruct) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(NexthopHook); ok {
+			return h.OnNexthop(op, nexthop)
+		}
+		return nil, nil
+	})
+}
+
+// fdbEntries collects the fdb entries of all decoders
+func fdbEntries(op Operation, fdb nm.FdbEntryStruct) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(FdbHook); ok {
+			return h.OnFdb(op, fdb)
+		}
+		return nil, nil
+	})
+}
+
+// l2NexthopEntries collects the l2 nexthop entries of all decoders
+func l2NexthopEntries(op Operation, nexthop nm.L2NexthopStruct) ([]interface{}, error) {
+	return collect(false, func(d Decoder) ([]interface{}, error) {
+		if h, ok := d.(L2NexthopHook); ok {
+			return h.OnL2Nexthop(op, nexthop)
+		}
+		return nil, nil
+	})
+}
+
+// staticEntries collects the static additions or deletions of all decoders
+func staticEntries(op Operation) []interface{} {
+	entries, _ := collect(op == OpDeleted, func(d Decoder) ([]interface{}, error) {
+		h, ok := d.(StaticHook)
+		if !ok {
+			return nil, nil
+		}
+		if op == OpDeleted {
+			return h.StaticDeletions(), nil
+		}
+		return h.StaticAdditions(), nil
+	})
+	return entries
+}
+
+// writeGroup programs a multicast group. The group carries all its replicas,
+// writing it brings the device in line whether the entries are added or
+// deleted, a group without replicas is deleted.
+func writeGroup(g p4client.MulticastGroup) {
+	var err error
+	existed := desired.setGroup(g)
+	switch {
+	case len(g.Replicas) == 0 && existed:
+		err = p4client.DelMulticastGroup(g)
+	case len(g.Replicas) == 0:
+	case existed:
+		err = p4client.ModifyMulticastGroup(g)
+	default:
+		err = p4client.AddMulticastGroup(g)
+	}
+	if err != nil {
+		log.Printf("intel-e2000: error writing multicast group %d error %v\n", g.ID, err)
+	}
+}
+
+// addEntries programs the entries into the pipeline, the desired state
+// and the recording only take the entries written
+func addEntries(entries []interface{}) error {
+	for _, entry := range entries {
+		if g, ok := entry.(p4client.MulticastGroup); ok {
+			writeGroup(g)
+			continue
+		}
+		if c, ok := entry.(p4client.CloneSession); ok {
+			if err := p4client.AddCloneSession(c); err != nil {
+				log.Printf("intel-e2000: error adding clone session %d error %v\n", c.ID, err)
+			}
+			continue
+		}
+		e, ok := entry.(p4client.TableEntry)
+		if !ok {
+			log.Printf("intel-e2000: Entry is not of type p4client.TableEntry:- %v\n", entry)
+			return fmt.Errorf("entry is not of type p4client.TableEntry:- %v", entry)
+		}
+		err := p4client.AddEntry(schema.resolve(e))
+		if status.Code(err) == codes.AlreadyExists {
+			if !sharedEntry(e) {
+				// the entry on the device is another object's, it stays desired
+				log.Printf("intel-e2000: skipping entry for %v, the key collides with an entry of another object\n", e.Tablename)
+				continue
+			}
+			// a shared entry kept alive by its users is rewritten in place
+			err = p4client.ModifyEntry(schema.resolve(e))
+		}
+		if err != nil {
+			log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, err)
+			continue
+		}
+		recorder.entry("add", e)
+		desired.add(e)
+	}
+	return nil
+}
+
+// modEntries rewrites the actions of entries that are programmed already
+func modEntries(entries []interface{}) error {
+	for _, entry := range entries {
+		e, ok := entry.(p4client.TableEntry)
+		if !ok {
+			log.Printf("intel-e2000: Entry is not of type p4client.TableEntry:- %v\n", entry)
+			return fmt.Errorf("entry is not of type p4client.TableEntry:- %v", entry)
+		}
+		if err := p4client.ModifyEntry(schema.resolve(e)); err != nil {
+			log.Printf("intel-e2000: error modifying entry for %v error %v\n", e.Tablename, err)
+			continue
+		}
+		recorder.entry("add", e)
+		desired.add(e)
+	}
+	return nil
+}
+
+// delEntries removes the entries from the pipeline
+func delEntries(entries []interface{}) error {
+	for _, entry := range entries {
+		if g, ok := entry.(p4client.MulticastGroup); ok {
+			writeGroup(g)
+			continue
+		}
+		if c, ok := entry.(p4client.CloneSession); ok {
+			if err := p4client.DelCloneSession(c); err != nil {
+				log.Printf("intel-e2000: error deleting clone session %d error %v\n", c.ID, err)
+			}
+			continue
+		}
+		e, ok := entry.(p4client.TableEntry)
+		if !ok {
+			log.Printf("intel-e2000: Entry is not of type p4client.TableEntry:- %v\n", entry)
+			return fmt.Errorf("entry is not of type p4client.TableEntry:- %v", entry)
+		}
+		recorder.entry("delete", e)
+		desired.remove(e)
+		if err := p4client.DelEntry(schema.resolve(e)); err != nil {
+			log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, err)
+		}
+	}
+	return nil
+}
+
+// applyEntries adds or deletes the entries depending on the operation
+func applyEntries(op Operation, entries []interface{}) error {
+	if op == OpDeleted {
+		return delEntries(entries)
+	}
+	return addEntries(entries)
+}
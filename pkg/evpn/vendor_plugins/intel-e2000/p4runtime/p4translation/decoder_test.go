@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	nm "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+type testDecoder struct {
+	name    string
+	entries []interface{}
+}
+
+func (d testDecoder) Name() string {
+	return d.name
+}
+
+func (d testDecoder) OnRoute(op Operation, _ nm.RouteStruct) ([]interface{}, error) {
+	return append([]interface{}{op}, d.entries...), nil
+}
+
+func (d testDecoder) StaticAdditions() []interface{} {
+	return d.entries
+}
+
+func (d testDecoder) StaticDeletions() []interface{} {
+	return d.entries
+}
+
+type failingDecoder struct{}
+
+func (failingDecoder) Name() string {
+	return "failing"
+}
+
+func (failingDecoder) OnRoute(Operation, nm.RouteStruct) ([]interface{}, error) {
+	return nil, errors.New("no vrf")
+}
+
+type nameOnlyDecoder struct{}
+
+func (nameOnlyDecoder) Name() string {
+	return "name-only"
+}
+
+func TestDecoder_Registry(t *testing.T) {
+	tests := map[string]struct {
+		register []Decoder
+		routeOp  Operation
+		route    []interface{}
+		static   []interface{}
+		teardown []interface{}
+	}{
+		"dispatch in registration order": {
+			register: []Decoder{testDecoder{"a", []interface{}{"a1"}}, testDecoder{"b", []interface{}{"b1"}}},
+			routeOp:  OpAdded,
+			route:    []interface{}{OpAdded, "a1", OpAdded, "b1"},
+			static:   []interface{}{"a1", "b1"},
+			teardown: []interface{}{"b1", "a1"},
+		},
+		"same name replaces in place": {
+			register: []Decoder{testDecoder{"a", []interface{}{"a1"}}, testDecoder{"b", []interface{}{"b1"}}, testDecoder{"a", []interface{}{"a2"}}},
+			routeOp:  OpDeleted,
+			route:    []interface{}{OpDeleted, "a2", OpDeleted, "b1"},
+			static:   []interface{}{"a2", "b1"},
+			teardown: []interface{}{"b1", "a2"},
+		},
+		"decoders without the hook are skipped": {
+			register: []Decoder{nameOnlyDecoder{}, testDecoder{"b", []interface{}{"b1"}}},
+			routeOp:  OpAdded,
+			route:    []interface{}{OpAdded, "b1"},
+			static:   []interface{}{"b1"},
+			teardown: []interface{}{"b1"},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			for _, d := range Decoders() {
+				UnregisterDecoder(d.Name())
+			}
+			for _, d := range tt.register {
+				RegisterDecoder(d)
+			}
+
+			if got, _ := routeEntries(tt.routeOp, nm.RouteStruct{}); !reflect.DeepEqual(got, tt.route) {
+				t.Errorf("Expected route entries: %v, received %v", tt.route, got)
+			}
+			if got := staticEntries(OpAdded); !reflect.DeepEqual(got, tt.static) {
+				t.Errorf("Expected static additions: %v, received %v", tt.static, got)
+			}
+			if got := staticEntries(OpDeleted); !reflect.DeepEqual(got, tt.teardown) {
+				t.Errorf("Expected static deletions: %v, received %v", tt.teardown, got)
+			}
+		})
+	}
+}
+
+func TestDecoder_RouteError(t *testing.T) {
+	for _, d := range Decoders() {
+		UnregisterDecoder(d.Name())
+	}
+	RegisterDecoder(testDecoder{"a", []interface{}{"a1"}})
+	RegisterDecoder(failingDecoder{})
+	defer UnregisterDecoder("failing")
+
+	entries, err := routeEntries(OpAdded, nm.RouteStruct{})
+	if err == nil || err.Error() != "failing decoder: no vrf" {
+		t.Errorf("Expected the error of the failing decoder, received %v", err)
+	}
+	if entries != nil {
+		t.Errorf("Expected no entries, received %v", entries)
+	}
+}
+
+func TestAddEntries_WriteFailure(t *testing.T) {
+	resetState()
+	defer resetState()
+	p4client.SetDryRun(journal.New(&failingJournal{table: "evpn_gw_control.test_failing"}))
+	defer p4client.SetDryRun(nil)
+
+	entry := func(table string) p4client.TableEntry {
+		return p4client.TableEntry{
+			Tablename: table,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{"vsi": {uint16(24), "exact"}},
+			},
+			Action: p4client.Action{ActionName: "evpn_gw_control.test_action"},
+		}
+	}
+	written, failing := entry("evpn_gw_control.test_written"), entry("evpn_gw_control.test_failing")
+	_ = addEntries([]interface{}{written, failing})
+	if n := len(desired.entries(written.Tablename)); n != 1 {
+		t.Errorf("Expected the written entry desired, received %d entries", n)
+	}
+	if n := len(desired.entries(failing.Tablename)); n != 0 {
+		t.Errorf("Expected the failed entry not desired, received %d entries", n)
+	}
+	_ = modEntries([]interface{}{failing})
+	if n := len(desired.entries(failing.Tablename)); n != 0 {
+		t.Errorf("Expected the failed modification not desired, received %d entries", n)
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// desiredState holds the entries the decoders want programmed, per table,
+// and the multicast groups by id
+type desiredState struct {
+	mu     sync.RWMutex
+	tables map[string]map[string]p4client.TableEntry
+	groups map[uint32]p4client.MulticastGroup
+}
+
+// desired is the desired state built by addEntries and delEntries
+var desired = newDesiredState()
+
+// newDesiredState returns an empty desired state
+func newDesiredState() *desiredState {
+	return &desiredState{
+		tables: make(map[string]map[string]p4client.TableEntry),
+		groups: make(map[uint32]p4client.MulticastGroup),
+	}
+}
+
+// entryKey identifies an entry by its match fields and priority
+func entryKey(e p4client.TableEntry) string {
+	rec := p4client.NewEntryRecord(e)
+	b, _ := json.Marshal(struct {
+		Match    []p4client.MatchRecord
+		Priority int32
+	}{rec.Match, rec.Priority})
+	return string(b)
+}
+
+// add stores the entry, replacing the entry with the same key
+func (d *desiredState) add(e p4client.TableEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	table, ok := d.tables[e.Tablename]
+	if !ok {
+		table = make(map[string]p4client.TableEntry)
+		d.tables[e.Tablename] = table
+	}
+	table[entryKey(e)] = e
+}
+
+// remove drops the entry with the key of e
+func (d *desiredState) remove(e p4client.TableEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.tables[e.Tablename], entryKey(e))
+}
+
+// entries returns the entries of the table in a stable order
+func (d *desiredState) entries(table string) []p4client.TableEntry {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys := make([]string, 0, len(d.tables[table]))
+	for k := range d.tables[table] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]p4client.TableEntry, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, d.tables[table][k])
+	}
+	return entries
+}
+
+// tableNames returns the tables holding entries, sorted
+func (d *desiredState) tableNames() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var names []string
+	for name, table := range d.tables {
+		if len(table) != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setGroup stores the group, a group without replicas is dropped.
+// It tells whether the group was already there.
+func (d *desiredState) setGroup(g p4client.MulticastGroup) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.groups[g.ID]
+	if len(g.Replicas) == 0 {
+		delete(d.groups, g.ID)
+	} else {
+		d.groups[g.ID] = g
+	}
+	return ok
+}
+
+// multicastGroups returns the groups sorted by id
+func (d *desiredState) multicastGroups() []p4client.MulticastGroup {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	groups := make([]p4client.MulticastGroup, 0, len(d.groups))
+	for _, g := range d.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups
+}
@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"encoding/binary"
+	"log"
+	"net"
+	"syscall"
+	"time"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+// the dhcp message types the snooping follows
+const (
+	dhcpDiscover = 1
+	dhcpRequest  = 3
+	dhcpAck      = 5
+	dhcpRelease  = 7
+)
+
+// dhcpExpiryInterval is how often the leases run out are dropped
+const dhcpExpiryInterval = 30 * time.Second
+
+// dhcpTransactionTimeout is how long an ack is awaited after the discover
+// or request of a bridge port
+const dhcpTransactionTimeout = time.Minute
+
+// packetOutgoing is the packet type of the frames the host sends
+const packetOutgoing = 4
+
+// dhcpBinding is a dhcp message of the snooping, the lease an ack hands
+// out, the address a release gives back or the transaction a discover or
+// request starts
+type dhcpBinding struct {
+	msgType byte
+	xid     uint32
+	mac     net.HardwareAddr
+	ip      net.IP
+	lease   time.Duration
+}
+
+// parseDhcp returns the binding of a dhcp discover, request, ack or
+// release in the frame, false for any other frame
+func parseDhcp(frame []byte) (dhcpBinding, bool) {
+	var b dhcpBinding
+	if len(frame) < 14 {
+		return b, false
+	}
+	etherType, data := binary.BigEndian.Uint16(frame[12:14]), frame[14:]
+	if etherType == 0x8100 && len(data) >= 4 {
+		etherType, data = binary.BigEndian.Uint16(data[2:4]), data[4:]
+	}
+	if etherType != 0x0800 || len(data) < 20 || data[9] != syscall.IPPROTO_UDP {
+		return b, false
+	}
+	ihl := int(data[0]&0x0f) * 4
+	if ihl < 20 || len(data) < ihl+8 {
+		return b, false
+	}
+	udp := data[ihl:]
+	sport, dport := binary.BigEndian.Uint16(udp[0:2]), binary.BigEndian.Uint16(udp[2:4])
+	bootp := udp[8:]
+	// the fixed bootp header and the magic cookie come before the options
+	if len(bootp) < 240 || binary.BigEndian.Uint32(bootp[236:240]) != 0x63825363 {
+		return b, false
+	}
+	hlen := int(bootp[2])
+	if bootp[1] != 1 || hlen != 6 {
+		return b, false
+	}
+	b.xid = binary.BigEndian.Uint32(bootp[4:8])
+	b.mac = net.HardwareAddr(append([]byte(nil), bootp[28:28+hlen]...))
+	for opts := bootp[240:]; len(opts) > 0 && opts[0] != 255; {
+		if opts[0] == 0 {
+			opts = opts[1:]
+			continue
+		}
+		if len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			return b, false
+		}
+		value := opts[2 : 2+int(opts[1])]
+		switch {
+		case opts[0] == 53 && len(value) == 1:
+			b.msgType = value[0]
+		case opts[0] == 51 && len(value) == 4:
+			b.lease = time.Duration(binary.BigEndian.Uint32(value)) * time.Second
+		}
+		opts = opts[2+int(opts[1]):]
+	}
+	switch {
+	case (b.msgType == dhcpDiscover || b.msgType == dhcpRequest) && sport == 68 && dport == 67:
+		return b, true
+	case b.msgType == dhcpAck && sport == 67 && dport == 68:
+		b.ip = net.IP(append([]byte(nil), bootp[16:20]...))
+	case b.msgType == dhcpRelease && sport == 68 && dport == 67:
+		b.ip = net.IP(append([]byte(nil), bootp[12:16]...))
+	default:
+		return b, false
+	}
+	return b, !b.ip.Equal(net.IPv4zero)
+}
+
+// dhcpFilter passes the udp frames from the dhcp ports 67 and 68 to the
+// snooping socket, untagged or with one vlan tag, and drops the fragments
+// and everything else in the kernel
+var dhcpFilter = []bpf.Instruction{
+	bpf.LoadAbsolute{Off: 12, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0x0800, SkipFalse: 7},
+	bpf.LoadAbsolute{Off: 23, Size: 1},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: syscall.IPPROTO_UDP, SkipFalse: 16},
+	bpf.LoadAbsolute{Off: 20, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpBitsSet, Val: 0x1fff, SkipTrue: 14},
+	bpf.LoadMemShift{Off: 14},
+	bpf.LoadIndirect{Off: 14, Size: 2},
+	bpf.Jump{Skip: 9},
+	// the vlan tagged frames
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0x8100, SkipFalse: 10},
+	bpf.LoadAbsolute{Off: 16, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0x0800, SkipFalse: 8},
+	bpf.LoadAbsolute{Off: 27, Size: 1},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: syscall.IPPROTO_UDP, SkipFalse: 6},
+	bpf.LoadAbsolute{Off: 24, Size: 2},
+	bpf.JumpIf{Cond: bpf.JumpBitsSet, Val: 0x1fff, SkipTrue: 4},
+	bpf.LoadMemShift{Off: 18},
+	bpf.LoadIndirect{Off: 18, Size: 2},
+	// the source port of the client and server messages
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: 67, SkipTrue: 2},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: 68, SkipTrue: 1},
+	bpf.RetConstant{Val: 0},
+	bpf.RetConstant{Val: 0x40000},
+}
+
+// attachDhcpFilter lets only the dhcp frames reach the socket
+func attachDhcpFilter(fd int) error {
+	raw, err := bpf.Assemble(dhcpFilter)
+	if err != nil {
+		return err
+	}
+	filter := make([]unix.SockFilter, len(raw))
+	for i, ins := range raw {
+		filter[i] = unix.SockFilter{Code: ins.Op, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	return unix.SetsockoptSockFprog(fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &prog)
+}
+
+// watchDhcp snoops the dhcp messages the links of the host receive and
+// lets the leased addresses in on the snooping bridge ports of their mac.
+// An ack counts when it comes in on a trusted link for a transaction the
+// bridge port of the mac started, see PortSecurityDecoder.snoop. In dry
+// run the interfaces of the host are not snooped and only the static
+// addresses are let in.
+func watchDhcp(done <-chan struct{}) {
+	if portSecurity == nil || !portSecurity.snooping() || p4client.IsDryRun() {
+		return
+	}
+	// unbound, the socket sees the frames of every link with the link
+	// they came in on, the filter keeps the dhcp ones
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_ALL)))
+	if err != nil {
+		log.Printf("intel-e2000: cannot snoop dhcp: %v\n", err)
+		return
+	}
+	if err := attachDhcpFilter(fd); err != nil {
+		_ = syscall.Close(fd)
+		log.Printf("intel-e2000: cannot filter the dhcp frames: %v\n", err)
+		return
+	}
+	// the read times out so the loop sees done and the leases run out
+	timeout := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		_ = syscall.Close(fd)
+		log.Printf("intel-e2000: cannot snoop dhcp: %v\n", err)
+		return
+	}
+	log.Printf("intel-e2000: snooping dhcp\n")
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, 65536)
+		expired := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if now := time.Now(); now.Sub(expired) >= dhcpExpiryInterval {
+				expired = now
+				translateMu.Lock()
+				if err := delEntries(portSecurity.expire(now)); err != nil {
+					log.Printf("intel-e2000: failed to remove the expired dhcp leases: %v\n", err)
+				}
+				translateMu.Unlock()
+			}
+			n, from, err := syscall.Recvfrom(fd, buf, 0)
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				log.Printf("intel-e2000: dhcp snooping stopped: %v\n", err)
+				return
+			}
+			ll, ok := from.(*syscall.SockaddrLinklayer)
+			if !ok || ll.Pkttype == packetOutgoing {
+				continue
+			}
+			b, ok := parseDhcp(buf[:n])
+			if !ok {
+				continue
+			}
+			ifc, err := net.InterfaceByIndex(ll.Ifindex)
+			if err != nil {
+				continue
+			}
+			translateMu.Lock()
+			dels, adds := portSecurity.snoop(ifc.Name, b, time.Now())
+			if err := delEntries(dels); err != nil {
+				log.Printf("intel-e2000: failed to remove the dhcp lease of %s: %v\n", b.mac, err)
+			}
+			if err := addEntries(adds); err != nil {
+				log.Printf("intel-e2000: failed to add the dhcp lease of %s: %v\n", b.mac, err)
+			}
+			translateMu.Unlock()
+		}
+	}()
+}
+
+// htons converts the value to network order
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
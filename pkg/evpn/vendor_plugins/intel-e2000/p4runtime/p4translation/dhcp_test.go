@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/bpf"
+)
+
+// dhcpFrame builds an untagged dhcp frame between the udp ports
+func dhcpFrame(sport uint16, dport uint16, msgType byte, ciaddr string, yiaddr string, mac string) []byte {
+	bootp := make([]byte, 240)
+	bootp[0], bootp[1], bootp[2] = 2, 1, 6
+	binary.BigEndian.PutUint32(bootp[4:8], 0x1234)
+	copy(bootp[12:16], net.ParseIP(ciaddr).To4())
+	copy(bootp[16:20], net.ParseIP(yiaddr).To4())
+	hwaddr, _ := net.ParseMAC(mac)
+	copy(bootp[28:34], hwaddr)
+	binary.BigEndian.PutUint32(bootp[236:240], 0x63825363)
+	bootp = append(bootp, 53, 1, msgType, 51, 4, 0, 0, 0x0e, 0x10, 255)
+
+	udp := make([]byte, 8)
+	binary.BigEndian.PutUint16(udp[0:2], sport)
+	binary.BigEndian.PutUint16(udp[2:4], dport)
+	ip := make([]byte, 20)
+	ip[0], ip[9] = 0x45, 17
+	frame := make([]byte, 14)
+	binary.BigEndian.PutUint16(frame[12:14], 0x0800)
+	frame = append(frame, ip...)
+	frame = append(frame, udp...)
+	return append(frame, bootp...)
+}
+
+func TestParseDhcp(t *testing.T) {
+	tests := map[string]struct {
+		frame []byte
+		ok    bool
+		ip    string
+	}{
+		"ack": {
+			frame: dhcpFrame(67, 68, dhcpAck, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66"),
+			ok:    true,
+			ip:    "10.10.0.9",
+		},
+		"release": {
+			frame: dhcpFrame(68, 67, dhcpRelease, "10.10.0.9", "0.0.0.0", "00:11:22:33:44:66"),
+			ok:    true,
+			ip:    "10.10.0.9",
+		},
+		"discover": {
+			frame: dhcpFrame(68, 67, dhcpDiscover, "0.0.0.0", "0.0.0.0", "00:11:22:33:44:66"),
+			ok:    true,
+			ip:    "<nil>",
+		},
+		"request": {
+			frame: dhcpFrame(68, 67, dhcpRequest, "0.0.0.0", "0.0.0.0", "00:11:22:33:44:66"),
+			ok:    true,
+			ip:    "<nil>",
+		},
+		"offer": {
+			frame: dhcpFrame(67, 68, 2, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66"),
+		},
+		"ack from a client": {
+			frame: dhcpFrame(68, 67, dhcpAck, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66"),
+		},
+		"truncated": {
+			frame: dhcpFrame(67, 68, dhcpAck, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66")[:200],
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			b, ok := parseDhcp(tt.frame)
+			if ok != tt.ok {
+				t.Fatalf("Expected %v, received %v", tt.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if b.ip.String() != tt.ip || b.mac.String() != "00:11:22:33:44:66" {
+				t.Errorf("Expected %s of 00:11:22:33:44:66, received %s of %s", tt.ip, b.ip, b.mac)
+			}
+			if b.xid != 0x1234 {
+				t.Errorf("Expected transaction 0x1234, received %#x", b.xid)
+			}
+			if b.lease != time.Hour {
+				t.Errorf("Expected a lease of an hour, received %v", b.lease)
+			}
+		})
+	}
+}
+
+func TestDhcpFilter(t *testing.T) {
+	vm, err := bpf.NewVM(dhcpFilter)
+	if err != nil {
+		t.Fatalf("Expected a valid filter, received %v", err)
+	}
+	tagged := func(frame []byte) []byte {
+		out := append([]byte(nil), frame[:12]...)
+		out = append(out, 0x81, 0x00, 0x00, 0x0a)
+		return append(out, frame[12:]...)
+	}
+	fragment := dhcpFrame(67, 68, dhcpAck, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66")
+	binary.BigEndian.PutUint16(fragment[20:22], 0x0010)
+	dns := dhcpFrame(53, 5353, dhcpAck, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66")
+	tcp := dhcpFrame(67, 68, dhcpAck, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66")
+	tcp[23] = 6
+	arp := make([]byte, 42)
+	binary.BigEndian.PutUint16(arp[12:14], 0x0806)
+	tests := map[string]struct {
+		frame []byte
+		pass  bool
+	}{
+		"ack": {
+			frame: dhcpFrame(67, 68, dhcpAck, "0.0.0.0", "10.10.0.9", "00:11:22:33:44:66"),
+			pass:  true,
+		},
+		"discover": {
+			frame: dhcpFrame(68, 67, dhcpDiscover, "0.0.0.0", "0.0.0.0", "00:11:22:33:44:66"),
+			pass:  true,
+		},
+		"tagged request": {
+			frame: tagged(dhcpFrame(68, 67, dhcpRequest, "0.0.0.0", "0.0.0.0", "00:11:22:33:44:66")),
+			pass:  true,
+		},
+		"other udp": {
+			frame: dns,
+		},
+		"tagged other udp": {
+			frame: tagged(dns),
+		},
+		"tcp": {
+			frame: tcp,
+		},
+		"fragment": {
+			frame: fragment,
+		},
+		"arp": {
+			frame: arp,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			n, err := vm.Run(tt.frame)
+			if err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			if pass := n != 0; pass != tt.pass {
+				t.Errorf("Expected pass: %v, received %v", tt.pass, pass)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/config"
+	"github.com/opiproject/opi-evpn-bridge/pkg/utils"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// setUpMultihoming checks the ethernet segments of the config, the pipeline
+// needs the source vtep table to pick the fabric flood groups
+func setUpMultihoming() {
+	segments := multihoming.Segments()
+	if len(segments) == 0 {
+		return
+	}
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, bumSrcVtep) {
+		log.Fatalf("intel-e2000: multihoming needs the %s table in pipeline %s\n",
+			schema.tableName(bumSrcVtep), schema.Pipeline)
+	}
+	for _, peer := range multihoming.Peers() {
+		if isIPv6(net.ParseIP(peer)) && pipelineInfo != nil && !schema.hasTables(pipelineInfo, bumSrcVtep6) {
+			log.Fatalf("intel-e2000: multihoming with the ipv6 peer %s needs the %s table in pipeline %s\n",
+				peer, schema.tableName(bumSrcVtep6), schema.Pipeline)
+		}
+	}
+	log.Printf("intel-e2000: %d ethernet segments shared with %v\n", len(segments), multihoming.Peers())
+}
+
+// splitHorizonEntries map the peers of the ethernet segments to the index
+// of their fabric flood groups, BUM from any other vtep uses index 0. The
+// ipv6 peers go to the table of the ipv6 underlay.
+func splitHorizonEntries(op Operation) []interface{} {
+	var entries = make([]interface{}, 0)
+	for i, peer := range multihoming.Peers() {
+		ip := net.ParseIP(peer)
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		entry := p4client.TableEntry{
+			Tablename: bumSrcVtep,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"src_ip": {ip, "exact"},
+				},
+				Priority: int32(0),
+			},
+		}
+		if op == OpAdded {
+			entry.Action = p4client.Action{
+				ActionName: "evpn_gw_control.set_flood_peer",
+				Params:     []interface{}{uint32(i + 1)},
+			}
+		}
+		entries = append(entries, _vtepEntries(ip, []interface{}{entry})...)
+	}
+	return entries
+}
+
+// frrReadDelay is how long frr is read after the vteps, the segment ports
+// or the remote macs changed, frr holds the election for its df-delay of 3s
+const frrReadDelay = 4 * time.Second
+
+// frrReadTimeout bounds a read of frr
+const frrReadTimeout = 10 * time.Second
+
+// frrDone stops following the segments in frr
+var frrDone chan struct{}
+
+// frrReads holds a pending read of frr
+var frrReads = make(chan struct{}, 1)
+
+// frrZebra is the zebra vty the segments are read from, the one the frr
+// module configures
+var frrZebra utils.Frr
+
+// readElections returns the designated forwarder state frr elected for the
+// local segments
+var readElections = func() (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), frrReadTimeout)
+	defer cancel()
+	out, err := frrZebra.FrrZebraCmd(ctx, "show evpn es detail json", true)
+	if err != nil {
+		return nil, err
+	}
+	return multihoming.ParseElections([]byte(out))
+}
+
+// followsFrr tells whether the segments are read from frr, for the
+// elections of the local segments or the aliasing of the remote ones
+func followsFrr() bool {
+	return len(multihoming.Segments()) != 0 || aliasing
+}
+
+// requestFrrRead asks for the segments to be read from frr again, the
+// requests made before the read are served by it. It does not block, the
+// decoders call it under translateMu.
+func requestFrrRead() {
+	if !followsFrr() {
+		return
+	}
+	select {
+	case frrReads <- struct{}{}:
+	default:
+	}
+}
+
+// updateElections takes the designated forwarder state from frr and
+// rewrites the flood groups of the vlans where it changed
+func updateElections() error {
+	df, err := readElections()
+	if err != nil {
+		return err
+	}
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	if !multihoming.SetElections(df) {
+		return nil
+	}
+	log.Printf("intel-e2000: designated forwarder state of the segments %v\n", df)
+	return addEntries(floodGroups.reelect())
+}
+
+// updateFrr reads the elections of the local segments and, with aliasing,
+// the remote segments from frr
+func updateFrr() error {
+	if len(multihoming.Segments()) != 0 {
+		if err := updateElections(); err != nil {
+			return err
+		}
+	}
+	if aliasing {
+		return updateAliases()
+	}
+	return nil
+}
+
+// watchFrr reads the segments from frr at start and whenever the vteps of
+// a vlan, the ports of a segment or the remote macs change, frr elects
+// again and learns the segments of the macs then. A failed read is retried.
+// In dry run there is no frr, the service carving stays and nothing is
+// aliased.
+func watchFrr(done <-chan struct{}) {
+	if !followsFrr() || p4client.IsDryRun() {
+		return
+	}
+	frrZebra = utils.NewFrrWrapperWithArgs("localhost", config.GlobalConfig.Tracer)
+	requestFrrRead()
+	go func() {
+		failing := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-frrReads:
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(frrReadDelay):
+			}
+			// the requests made while waiting are served by this read
+			select {
+			case <-frrReads:
+			default:
+			}
+			err := updateFrr()
+			if err != nil && !failing {
+				log.Printf("intel-e2000: cannot read the ethernet segments from frr: %v\n", err)
+			}
+			failing = err != nil
+			if failing {
+				requestFrrRead()
+			}
+		}
+	}()
+}
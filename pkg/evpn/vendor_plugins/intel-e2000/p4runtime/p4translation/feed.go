@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FeedNetlink publishes the netlink events of a recording in recorded
+// order. It stands in for the netlink watcher in the simulation build env,
+// where the routes, nexthops and fdb entries of the host are not read.
+// Events of other sources are skipped. FeedNetlink returns the number of
+// events published.
+func FeedNetlink(path string, publish func(eventType string, data interface{})) (int, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	fed := 0
+	dec := json.NewDecoder(f)
+	for {
+		var rec eventRecord
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fed, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if rec.Source != sourceNetlink {
+			continue
+		}
+		event := rec.netlinkEvent()
+		if event == nil {
+			return fed, fmt.Errorf("event %d: netlink event %s without data", rec.Seq, rec.EventType)
+		}
+		publish(rec.EventType, event)
+		fed++
+	}
+	return fed, nil
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	nm "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestFeedNetlink(t *testing.T) {
+	table := uint32(1001)
+	vrf := &infradb.Vrf{
+		Name:     "//network.opiproject.org/vrfs/blue",
+		Spec:     &infradb.VrfSpec{},
+		Metadata: &infradb.VrfMetadata{RoutingTable: []*uint32{&table}},
+	}
+	nexthop := &nm.NexthopStruct{
+		Key:    nm.NexthopKey{VrfName: vrf.Name, Dst: "10.10.10.1", Dev: 3},
+		Vrf:    vrf,
+		ID:     5,
+		NhType: nm.PHY,
+		Metadata: map[interface{}]interface{}{
+			"smac":         "00:10:00:00:03:14",
+			"dmac":         "00:aa:bb:cc:dd:ee",
+			"egress_vport": 16,
+		},
+	}
+	_, dst, _ := net.ParseCIDR("192.168.1.0/24")
+	route := &nm.RouteStruct{
+		Key:      nm.RouteKey{Table: int(table), Dst: dst.String()},
+		Vrf:      vrf,
+		Nexthops: []*nm.NexthopStruct{nexthop},
+		Metadata: map[interface{}]interface{}{"direction": nm.RXTX},
+	}
+	route.Route0.Dst = dst
+
+	var recording bytes.Buffer
+	enc := json.NewEncoder(&recording)
+	for _, rec := range []*eventRecord{
+		{Source: sourceInit, Op: OpAdded.String()},
+		newNetlinkRecord(nm.RouteAdded, route),
+		newNetlinkRecord(nm.NexthopAdded, nexthop),
+	} {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "netlink.json")
+	if err := os.WriteFile(path, recording.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l3, pod, vxlan := L3, Pod, Vxlan
+	defer func() { L3, Pod, Vxlan = l3, pod, vxlan }()
+	resetState()
+	var buf bytes.Buffer
+	p4client.SetDryRun(journal.New(&buf))
+	defer p4client.SetDryRun(nil)
+	setUpDecoders(testRepresentors)
+	defer tearDownDecoders()
+
+	// the route comes first and waits in the pipeline for its nexthop
+	p := NewPipeline(handleNetlinkEvent)
+	fed, err := FeedNetlink(path, p.Dispatch)
+	p.Stop()
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if fed != 2 {
+		t.Errorf("Expected the 2 netlink events fed, received %d", fed)
+	}
+	if !strings.Contains(buf.String(), "l3_routing_table") {
+		t.Errorf("Expected l3_routing_table entries in the journal, received %s", buf.String())
+	}
+}
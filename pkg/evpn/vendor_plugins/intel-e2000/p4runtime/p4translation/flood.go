@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// floodLists keeps the BUM replication members of the logical bridges.
+// Every logical bridge with members has a multicast group, its id is the
+// vlan id, replicating to the local bridge ports, the remote vteps and the
+// vrf mux for the slow path. With ethernet segments configured the BUM
+// coming in from the fabric has groups of its own, see fabricGroupID.
+type floodLists struct {
+	vlans map[uint16]map[string]floodMember
+}
+
+// floodMember is a replica of a flood list, bridge ports of an ethernet
+// segment remember the segment, the local vtep of the vlan and whether they
+// are its designated forwarder in the vlan
+type floodMember struct {
+	replica p4client.Replica
+	vtep    bool
+	esi     string
+	local   string
+	df      bool
+}
+
+// floodGroups are the flood lists of the logical bridges, guarded by translateMu
+var floodGroups = newFloodLists()
+
+// newFloodLists returns empty flood lists
+func newFloodLists() *floodLists {
+	return &floodLists{vlans: make(map[uint16]map[string]floodMember)}
+}
+
+// fabricGroupID is the group of the BUM of the vlan coming in from the
+// fabric. Peer 0 is used for vteps sharing no segment, peer n for the n-th
+// vtep of multihoming.Peers, the pipeline picks it with bumSrcVtep.
+func fabricGroupID(vid uint16, peer int) uint32 {
+	return uint32(vid) + maxVlans*uint32(1+peer)
+}
+
+// maxVlans is the size of the vlan id space, the stride of the fabric groups
+const maxVlans = 4096
+
+// bpMember names a bridge port in the flood lists
+func bpMember(vport string) string {
+	return fmt.Sprintf("bp-%s", vport)
+}
+
+// vtepMember names a remote vtep of a vlan in the flood lists
+func vtepMember(key netlink_polling.L2NexthopKey) string {
+	return fmt.Sprintf("vtep-%d-%s", key.VlanID, key.Dst)
+}
+
+// join adds the member to the flood list of the vlan and returns the groups
+func (f *floodLists) join(vid uint16, name string, member floodMember) []interface{} {
+	members, ok := f.vlans[vid]
+	if !ok {
+		members = make(map[string]floodMember)
+		f.vlans[vid] = members
+	}
+	if old, ok := members[name]; ok && old == member {
+		return nil
+	}
+	members[name] = member
+	return f.vlanGroups(vid)
+}
+
+// leave removes the member from every flood list and returns the changed
+// groups, a group left without members has no replicas and is deleted
+func (f *floodLists) leave(name string) []interface{} {
+	var groups []interface{}
+	for _, vid := range f.vids() {
+		if _, ok := f.vlans[vid][name]; !ok {
+			continue
+		}
+		delete(f.vlans[vid], name)
+		groups = append(groups, f.vlanGroups(vid)...)
+		if len(f.vlans[vid]) == 0 {
+			delete(f.vlans, vid)
+		}
+	}
+	return groups
+}
+
+// vlanGroups returns the local group of the vlan followed by its fabric
+// groups when ethernet segments are configured
+func (f *floodLists) vlanGroups(vid uint16) []interface{} {
+	groups := []interface{}{f.group(vid)}
+	if len(multihoming.Segments()) == 0 {
+		return groups
+	}
+	groups = append(groups, f.fabricGroup(vid, 0, ""))
+	for i, peer := range multihoming.Peers() {
+		groups = append(groups, f.fabricGroup(vid, i+1, peer))
+	}
+	return groups
+}
+
+// reelect runs the designated forwarder election of the segment members
+// again and returns the groups of the vlans where it changed
+func (f *floodLists) reelect() []interface{} {
+	var groups []interface{}
+	for _, vid := range f.vids() {
+		changed := false
+		for name, m := range f.vlans[vid] {
+			segment, ok := multihoming.Lookup(m.esi)
+			if m.esi == "" || !ok {
+				continue
+			}
+			if df := segment.DesignatedForwarder(vid, net.ParseIP(m.local)); df != m.df {
+				m.df = df
+				f.vlans[vid][name] = m
+				changed = true
+			}
+		}
+		if changed {
+			groups = append(groups, f.vlanGroups(vid)...)
+		}
+	}
+	return groups
+}
+
+// group builds the multicast group of the vlan, replicas in a stable order
+func (f *floodLists) group(vid uint16) p4client.MulticastGroup {
+	return f.build(uint32(vid), vid, func(floodMember) bool { return true })
+}
+
+// fabricGroup builds the group of the BUM the peer sends into the vlan.
+// It never goes back to the fabric, the ports of a segment only get it from
+// their designated forwarder and never when the peer shares the segment, the
+// peer delivered it there already.
+func (f *floodLists) fabricGroup(vid uint16, index int, peer string) p4client.MulticastGroup {
+	shared := make(map[string]bool)
+	for _, s := range multihoming.Segments() {
+		shared[s.ESI] = peer != "" && s.SharedWith(peer)
+	}
+	return f.build(fabricGroupID(vid, index), vid, func(m floodMember) bool {
+		return !m.vtep && (m.esi == "" || m.df && !shared[m.esi])
+	})
+}
+
+// build makes the group of the members of the vlan passing the filter
+func (f *floodLists) build(id uint32, vid uint16, filter func(floodMember) bool) p4client.MulticastGroup {
+	group := p4client.MulticastGroup{ID: id}
+	if len(f.vlans[vid]) == 0 {
+		return group
+	}
+	for _, m := range f.vlans[vid] {
+		if filter(m) {
+			group.Replicas = append(group.Replicas, m.replica)
+		}
+	}
+	// a copy goes to the slow path through the flooding nexthop
+	group.Replicas = append(group.Replicas, p4client.Replica{Port: uint32(_toEgressVsi(Pod._vrfMuxVsi)), Instance: uint32(Pod.floodNhID)})
+	sort.Slice(group.Replicas, func(i, j int) bool {
+		if group.Replicas[i].Port != group.Replicas[j].Port {
+			return group.Replicas[i].Port < group.Replicas[j].Port
+		}
+		return group.Replicas[i].Instance < group.Replicas[j].Instance
+	})
+	return group
+}
+
+// groups returns the groups of all vlans with members
+func (f *floodLists) groups() []p4client.MulticastGroup {
+	var groups []p4client.MulticastGroup
+	for _, vid := range f.vids() {
+		for _, g := range f.vlanGroups(vid) {
+			groups = append(groups, g.(p4client.MulticastGroup))
+		}
+	}
+	return groups
+}
+
+// vids returns the vlans with members, sorted
+func (f *floodLists) vids() []uint16 {
+	vids := make([]uint16, 0, len(f.vlans))
+	for vid := range f.vlans {
+		vids = append(vids, vid)
+	}
+	sort.Slice(vids, func(i, j int) bool { return vids[i] < vids[j] })
+	return vids
+}
+
+// bpFloodEntries puts the bridge port into or takes it out of the flood
+// lists of its logical bridges
+func (p PodDecoder) bpFloodEntries(op Operation, bp *infradb.BridgePort) []interface{} {
+	member := bpMember(bp.Metadata.VPort)
+	segment, multihomed := multihoming.SegmentOf(bp.Name)
+	if multihomed {
+		// frr elects again with the port attached to the segment or gone
+		requestFrrRead()
+	}
+	if op == OpDeleted {
+		return floodGroups.leave(member)
+	}
+	var entries []interface{}
+	vsi, _ := strconv.Atoi(bp.Metadata.VPort)
+	vsiOut := uint32(_toEgressVsi(vsi))
+	for _, name := range bp.Spec.LogicalBridges {
+		lb, err := objects.GetLB(name)
+		if err != nil || lb.Spec.VlanID > math.MaxUint16 {
+			log.Printf("intel-e2000: bridge port %s not flooded in %s\n", bp.Name, name)
+			continue
+		}
+		vid := uint16(lb.Spec.VlanID)
+		m := floodMember{replica: p4client.Replica{Port: vsiOut}, df: true}
+		if multihomed && lb.Spec.VtepIP != nil {
+			m.esi = segment.ESI
+			m.local = lb.Spec.VtepIP.IP.String()
+			m.df = segment.DesignatedForwarder(vid, lb.Spec.VtepIP.IP)
+		}
+		entries = append(entries, floodGroups.join(vid, member, m)...)
+		if bp.Spec.Ptype == infradb.Access {
+			break
+		}
+	}
+	return entries
+}
+
+// vtepFloodEntries puts the remote vtep of a vxlan l2 nexthop into or takes
+// it out of the flood list of the vlan. The netlink watcher does not pass on
+// the all zero mac entries of the type-3 routes, a remote vtep joins with its
+// first l2 nexthop in the vlan. The replica instance is the l2 nexthop whose
+// entry pushes the vxlan header.
+func (v VxlanDecoder) vtepFloodEntries(op Operation, nexthop netlink_polling.L2NexthopStruct) []interface{} {
+	if nexthop.Type != netlink_polling.VXLAN || nexthop.VlanID <= 0 || nexthop.VlanID > math.MaxUint16 {
+		return nil
+	}
+	member := vtepMember(nexthop.Key)
+	// a vtep coming or going may be a peer of the segments, frr elects again
+	requestFrrRead()
+	if op == OpDeleted {
+		return floodGroups.leave(member)
+	}
+	vport, ok := nexthop.Metadata["egress_vport"].(int)
+	if !ok {
+		return nil
+	}
+	replica := p4client.Replica{Port: uint32(_toEgressVsi(vport)), Instance: uint32(nexthop.ID)}
+	return floodGroups.join(uint16(nexthop.VlanID), member, floodMember{replica: replica, vtep: true})
+}
@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/multihoming"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestFloodLists(t *testing.T) {
+	slowPath := p4client.Replica{Port: uint32(_toEgressVsi(Pod._vrfMuxVsi)), Instance: uint32(Pod.floodNhID)}
+	bp := p4client.Replica{Port: 40}
+	vtep := p4client.Replica{Port: 30, Instance: 17}
+	group := func(replicas ...p4client.Replica) []interface{} {
+		return []interface{}{p4client.MulticastGroup{ID: 10, Replicas: replicas}}
+	}
+	f := newFloodLists()
+	// the steps run in order on the same flood lists
+	steps := []struct {
+		name   string
+		step   func() []interface{}
+		groups []interface{}
+	}{
+		{
+			name:   "first member creates the group",
+			step:   func() []interface{} { return f.join(10, "bp-24", floodMember{replica: bp}) },
+			groups: group(slowPath, bp),
+		},
+		{
+			name:   "remote vtep joins",
+			step:   func() []interface{} { return f.join(10, "vtep-10-10.0.0.2", floodMember{replica: vtep, vtep: true}) },
+			groups: group(slowPath, vtep, bp),
+		},
+		{
+			name: "joining again changes nothing",
+			step: func() []interface{} { return f.join(10, "vtep-10-10.0.0.2", floodMember{replica: vtep, vtep: true}) },
+		},
+		{
+			name:   "bridge port leaves",
+			step:   func() []interface{} { return f.leave("bp-24") },
+			groups: group(slowPath, vtep),
+		},
+		{
+			name:   "last member deletes the group",
+			step:   func() []interface{} { return f.leave("vtep-10-10.0.0.2") },
+			groups: []interface{}{p4client.MulticastGroup{ID: 10}},
+		},
+	}
+	for _, tt := range steps {
+		if got := tt.step(); !reflect.DeepEqual(got, tt.groups) {
+			t.Errorf("%s: Expected groups: %v, received %v", tt.name, tt.groups, got)
+		}
+	}
+	if groups := f.groups(); len(groups) != 0 {
+		t.Errorf("Expected no groups left, received %v", groups)
+	}
+}
+
+func TestFloodLists_Multihoming(t *testing.T) {
+	err := multihoming.Set([]multihoming.Segment{{
+		ESI:         "00:11:22:33:44:55:66:77:88:99",
+		BridgePorts: []string{"bp-a"},
+		Peers:       []string{"10.0.0.2"},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = multihoming.Set(nil) }()
+	esi := "00:11:22:33:44:55:66:77:88:99"
+	slowPath := p4client.Replica{Port: uint32(_toEgressVsi(Pod._vrfMuxVsi)), Instance: uint32(Pod.floodNhID)}
+	vtep := p4client.Replica{Port: 30, Instance: 17}
+	multihomed := p4client.Replica{Port: 40}
+	single := p4client.Replica{Port: 41}
+	tests := map[string]struct {
+		df     bool
+		groups []interface{}
+	}{
+		"designated forwarder": {
+			df: true,
+			groups: []interface{}{
+				p4client.MulticastGroup{ID: 10, Replicas: []p4client.Replica{slowPath, vtep, multihomed, single}},
+				p4client.MulticastGroup{ID: fabricGroupID(10, 0), Replicas: []p4client.Replica{slowPath, multihomed, single}},
+				p4client.MulticastGroup{ID: fabricGroupID(10, 1), Replicas: []p4client.Replica{slowPath, single}},
+			},
+		},
+		"not the designated forwarder": {
+			df: false,
+			groups: []interface{}{
+				p4client.MulticastGroup{ID: 10, Replicas: []p4client.Replica{slowPath, vtep, multihomed, single}},
+				p4client.MulticastGroup{ID: fabricGroupID(10, 0), Replicas: []p4client.Replica{slowPath, single}},
+				p4client.MulticastGroup{ID: fabricGroupID(10, 1), Replicas: []p4client.Replica{slowPath, single}},
+			},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			f := newFloodLists()
+			f.join(10, "vtep-10-10.0.0.3", floodMember{replica: vtep, vtep: true})
+			f.join(10, "bp-25", floodMember{replica: single, df: true})
+			groups := f.join(10, "bp-24", floodMember{replica: multihomed, esi: esi, df: tt.df})
+			if !reflect.DeepEqual(groups, tt.groups) {
+				t.Errorf("Expected groups: %v, received %v", tt.groups, groups)
+			}
+		})
+	}
+}
+
+func TestUpdateElections(t *testing.T) {
+	esi := "00:11:22:33:44:55:66:77:88:99"
+	err := multihoming.Set([]multihoming.Segment{{ESI: esi, BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = multihoming.Set(nil) }()
+	resetState()
+	defer resetState()
+	var buf bytes.Buffer
+	p4client.SetDryRun(journal.New(&buf))
+	defer p4client.SetDryRun(nil)
+	read := readElections
+	defer func() { readElections = read }()
+
+	// the carving elects 10.0.0.1 in the even vlans
+	floodGroups.join(10, "bp-24", floodMember{replica: p4client.Replica{Port: 40}, esi: esi, local: "10.0.0.1", df: true})
+	floodGroups.join(11, "bp-24", floodMember{replica: p4client.Replica{Port: 40}, esi: esi, local: "10.0.0.1", df: false})
+	tests := []struct {
+		name string
+		df   map[string]bool
+		want map[uint16]bool
+	}{
+		{name: "frr elects the peer", df: map[string]bool{esi: false}, want: map[uint16]bool{10: false, 11: false}},
+		{name: "same state again", df: map[string]bool{esi: false}, want: map[uint16]bool{10: false, 11: false}},
+		{name: "frr elects the local vtep", df: map[string]bool{esi: true}, want: map[uint16]bool{10: true, 11: true}},
+		{name: "frr no longer reports the segment", df: map[string]bool{}, want: map[uint16]bool{10: true, 11: false}},
+	}
+	for _, tt := range tests {
+		readElections = func() (map[string]bool, error) { return tt.df, nil }
+		if err := updateElections(); err != nil {
+			t.Fatalf("%s: Expected no error, received %v", tt.name, err)
+		}
+		for vid, df := range tt.want {
+			if m := floodGroups.vlans[vid]["bp-24"]; m.df != df {
+				t.Errorf("%s: Expected designated forwarder %v in vlan %d, received %v", tt.name, df, vid, m.df)
+			}
+		}
+	}
+	// only the changes of the election rewrite the fabric groups
+	if n := strings.Count(buf.String(), fmt.Sprintf(`"id":%d,`, fabricGroupID(10, 0))); n != 2 {
+		t.Errorf("Expected the fabric group of vlan 10 written 2 times, received %d\n%s", n, buf.String())
+	}
+}
+
+func TestRequestFrrRead(t *testing.T) {
+	drain := func() int {
+		n := 0
+		for {
+			select {
+			case <-frrReads:
+				n++
+			default:
+				return n
+			}
+		}
+	}
+	drain()
+	requestFrrRead()
+	if n := drain(); n != 0 {
+		t.Errorf("Expected no request without segments, received %d", n)
+	}
+	err := multihoming.Set([]multihoming.Segment{{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2"}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = multihoming.Set(nil) }()
+	// a vtep joining a vlan asks for the elections, the requests are served at once
+	v := VxlanDecoder{}
+	nexthop := netlink_polling.L2NexthopStruct{Type: netlink_polling.VXLAN, VlanID: 10, ID: 17,
+		Key: netlink_polling.L2NexthopKey{VlanID: 10, Dst: "10.0.0.2"}, Metadata: map[interface{}]interface{}{"egress_vport": 40}}
+	resetState()
+	defer resetState()
+	v.vtepFloodEntries(OpAdded, nexthop)
+	v.vtepFloodEntries(OpDeleted, nexthop)
+	if n := drain(); n != 1 {
+		t.Errorf("Expected 1 pending request, received %d", n)
+	}
+}
+
+func TestSplitHorizonEntries(t *testing.T) {
+	err := multihoming.Set([]multihoming.Segment{
+		{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.3"}},
+		{ESI: "00:11:22:33:44:55:66:77:88:aa", BridgePorts: []string{"bp-b"}, Peers: []string{"10.0.0.2", "10.0.0.3"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = multihoming.Set(nil) }()
+	entries := splitHorizonEntries(OpAdded)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, received %v", entries)
+	}
+	for i, peer := range []string{"10.0.0.2", "10.0.0.3"} {
+		e := entries[i].(p4client.TableEntry)
+		if ip := e.FieldValue["src_ip"][0].(net.IP); !ip.Equal(net.ParseIP(peer)) {
+			t.Errorf("Expected peer %s, received %v", peer, ip)
+		}
+		if !reflect.DeepEqual(e.Params, []interface{}{uint32(i + 1)}) {
+			t.Errorf("Expected peer index %d, received %v", i+1, e.Params)
+		}
+	}
+	for _, entry := range splitHorizonEntries(OpDeleted) {
+		if e := entry.(p4client.TableEntry); e.ActionName != "" {
+			t.Errorf("Expected deletion without action, received %v", e.ActionName)
+		}
+	}
+}
+
+func TestSplitHorizonEntries_IPv6(t *testing.T) {
+	err := multihoming.Set([]multihoming.Segment{
+		{ESI: "00:11:22:33:44:55:66:77:88:99", BridgePorts: []string{"bp-a"}, Peers: []string{"10.0.0.2", "2001:db8::2"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = multihoming.Set(nil) }()
+	entries := splitHorizonEntries(OpAdded)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, received %v", entries)
+	}
+	tests := []struct {
+		table string
+		peer  string
+	}{
+		{table: bumSrcVtep, peer: "10.0.0.2"},
+		{table: bumSrcVtep6, peer: "2001:db8::2"},
+	}
+	for i, tt := range tests {
+		e := entries[i].(p4client.TableEntry)
+		if e.Tablename != tt.table {
+			t.Errorf("Expected table %s, received %s", tt.table, e.Tablename)
+		}
+		if ip := e.FieldValue["src_ip"][0].(net.IP); !ip.Equal(net.ParseIP(tt.peer)) {
+			t.Errorf("Expected peer %s, received %v", tt.peer, ip)
+		}
+		if !reflect.DeepEqual(e.Params, []interface{}{uint32(i + 1)}) {
+			t.Errorf("Expected peer index %d, received %v", i+1, e.Params)
+		}
+	}
+}
+
+func TestWriteGroup(t *testing.T) {
+	resetState()
+	var buf bytes.Buffer
+	p4client.SetDryRun(journal.New(&buf))
+	defer p4client.SetDryRun(nil)
+
+	replicas := []p4client.Replica{{Port: 30, Instance: 17}}
+	_ = addEntries([]interface{}{p4client.MulticastGroup{ID: 10, Replicas: replicas}})
+	_ = addEntries([]interface{}{p4client.MulticastGroup{ID: 10, Replicas: append(replicas, p4client.Replica{Port: 40})}})
+	_ = delEntries([]interface{}{p4client.MulticastGroup{ID: 10}})
+
+	want := `{"seq":1,"component":"pre","op":"add","object":{"id":10,"replicas":["30/17"]}}
+{"seq":2,"component":"pre","op":"modify","object":{"id":10,"replicas":["30/17","40/0"]}}
+{"seq":3,"component":"pre","op":"delete","object":{"id":10}}
+`
+	if buf.String() != want {
+		t.Errorf("Expected journal: %s, received %s", want, buf.String())
+	}
+	if groups := desired.multicastGroups(); len(groups) != 0 {
+		t.Errorf("Expected no desired groups, received %v", groups)
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"log"
+	"net"
+	"path"
+
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// ipv6Tables are the tables of the ipv6 underlay by their ipv4 counterpart
+var ipv6Tables = map[string]string{
+	phyInVxlan:      phyInVxlan6,
+	phyInVxlanL2:    phyInVxlanL26,
+	pushVxlanHdr:    pushVxlan6Hdr,
+	pushVxlanOutHdr: pushVxlan6OutHdr,
+	bumSrcVtep:      bumSrcVtep6,
+}
+
+// ipv6Actions are the actions of the ipv6 underlay by their ipv4 counterpart
+var ipv6Actions = map[string]string{
+	"evpn_gw_control.omac_vxlan_imac_push":                  "evpn_gw_control.omac_vxlan6_imac_push",
+	"evpn_gw_control.omac_vxlan_push":                       "evpn_gw_control.omac_vxlan6_push",
+	"evpn_gw_control.push_outermac_vxlan_innermac":          "evpn_gw_control.push_outermac_vxlan6_innermac",
+	"evpn_gw_control.send_p2p_push_outermac_vxlan_innermac": "evpn_gw_control.send_p2p_push_outermac_vxlan6_innermac",
+	"evpn_gw_control.push_outermac_vxlan":                   "evpn_gw_control.push_outermac_vxlan6",
+}
+
+// isIPv6 tells whether the address is an ipv6 address
+func isIPv6(ip net.IP) bool {
+	return ip != nil && ip.To4() == nil
+}
+
+// hasIPv6Tables tells whether the pipeline has the tables of the ipv6
+// underlay, assumed in dry run when the p4info could not be read
+func hasIPv6Tables(tables ...string) bool {
+	return pipelineInfo == nil || schema.hasTables(pipelineInfo, tables...)
+}
+
+// _vtepEntries moves the vxlan entries of an ipv6 vtep to the tables and
+// actions of the ipv6 underlay, ipv4 vteps keep them
+func _vtepEntries(vtep net.IP, entries []interface{}) []interface{} {
+	if !isIPv6(vtep) {
+		return entries
+	}
+	var tables []string
+	for _, entry := range entries {
+		if e, ok := entry.(p4client.TableEntry); ok && ipv6Tables[e.Tablename] != "" {
+			tables = append(tables, ipv6Tables[e.Tablename])
+		}
+	}
+	if !hasIPv6Tables(tables...) {
+		log.Printf("intel-e2000: pipeline %s has no ipv6 underlay, ignoring vtep %s\n", schema.Pipeline, vtep)
+		return make([]interface{}, 0)
+	}
+	var moved = make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		e, ok := entry.(p4client.TableEntry)
+		if !ok {
+			moved = append(moved, entry)
+			continue
+		}
+		if table, ok := ipv6Tables[e.Tablename]; ok {
+			e.Tablename = table
+		}
+		if action, ok := ipv6Actions[e.ActionName]; ok {
+			e.ActionName = action
+		}
+		fields := make(map[string][2]interface{}, len(e.FieldValue))
+		for name, value := range e.FieldValue {
+			if ip, ok := value[0].(net.IP); ok {
+				value[0] = ip.To16()
+			}
+			fields[name] = value
+		}
+		e.FieldValue = fields
+		moved = append(moved, e)
+	}
+	return moved
+}
+
+// _nexthopVtep returns the remote vtep of a vxlan nexthop
+func _nexthopVtep(metadata map[interface{}]interface{}) net.IP {
+	vtep, _ := metadata["remote_vtep_ip"].(string)
+	return net.ParseIP(vtep)
+}
+
+// _l3Route6 generates the entries of an ipv6 route, host routes go to the
+// lem tables and the others to the lpm tables of the ipv6 destinations
+func (l L3Decoder) _l3Route6(route netlink_polling.RouteStruct, delete string, ecmpFlag bool, entries []interface{}, e EcmpDispatcher) []interface{} {
+	var vrfID = l.getVrfID(route)
+	var dst = route.Route0.Dst
+	var ones, bits = dst.Mask.Size()
+	var host = ones == bits
+	var table, p2pTable = l3Rt6, l3P2PRt6
+	if host {
+		table, p2pTable = l3RtHost6, l3P2PRtHost6
+	}
+	if !hasIPv6Tables(table, p2pTable) {
+		log.Printf("intel-e2000: pipeline %s has no ipv6 routing tables, ignoring route %s\n", schema.Pipeline, dst)
+		return entries
+	}
+	var ec uint16
+	if ecmpFlag {
+		ec = uint16(1)
+	}
+	// dst_ip matches exactly on host routes and by prefix on the others
+	var dstIP = [2]interface{}{dst, "lpm"}
+	if host {
+		dstIP = [2]interface{}{dst.IP.To16(), "exact"}
+	}
+	neighborOf := func(dir int) uint16 {
+		if ecmpFlag {
+			return uint16(e._p4NexthopID(dir))
+		}
+		return uint16(_p4NexthopID(*route.Nexthops[0], dir))
+	}
+	for _, dir := range _directionsOf(route) {
+		entry := p4client.TableEntry{
+			Tablename: table,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"vrf":       {bigEndian16(vrfID), "exact"},
+					"direction": {uint16(dir), "exact"},
+					"dst_ip":    dstIP,
+				},
+				Priority: int32(0),
+			},
+		}
+		if delete != trueStr {
+			entry.Action = p4client.Action{
+				ActionName: "evpn_gw_control.set_neighbor",
+				Params:     []interface{}{neighborOf(dir), ec},
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if path.Base(route.Vrf.Name) != grdStr || route.Nexthops[0].NhType != netlink_polling.PHY {
+		return entries
+	}
+	// the vxlan packets of the ipv6 underlay take the p2p tables
+	entry := p4client.TableEntry{
+		Tablename: p2pTable,
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{
+				"dst_ip": dstIP,
+			},
+			Priority: int32(0),
+		},
+	}
+	if host {
+		entry.FieldValue["vrf"] = [2]interface{}{bigEndian16(vrfID), "exact"}
+		entry.FieldValue["direction"] = [2]interface{}{uint16(Direction.Rx), "exact"}
+	}
+	if delete != trueStr {
+		entry.Action = p4client.Action{
+			ActionName: "evpn_gw_control.set_p2p_neighbor",
+			Params:     []interface{}{neighborOf(Direction.Rx), ec},
+		}
+	}
+	return append(entries, entry)
+}
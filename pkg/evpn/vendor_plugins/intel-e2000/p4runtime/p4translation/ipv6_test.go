@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"github.com/vishvananda/netlink"
+)
+
+func TestVtepEntries(t *testing.T) {
+	entries := func() []interface{} {
+		return []interface{}{
+			p4client.TableEntry{
+				Tablename: pushVxlanHdr,
+				TableField: p4client.TableField{
+					FieldValue: map[string][2]interface{}{"meta.common.mod_blob_ptr": {uint32(2), "exact"}},
+				},
+				Action: p4client.Action{ActionName: "evpn_gw_control.omac_vxlan_imac_push"},
+			},
+			p4client.TableEntry{
+				Tablename: l3NhTx,
+				Action:    p4client.Action{ActionName: "evpn_gw_control.push_outermac_vxlan_innermac"},
+			},
+		}
+	}
+	tests := map[string]struct {
+		vtep    string
+		tables  []string
+		actions []string
+	}{
+		"ipv4 vtep": {
+			vtep:    "10.0.0.1",
+			tables:  []string{pushVxlanHdr, l3NhTx},
+			actions: []string{"evpn_gw_control.omac_vxlan_imac_push", "evpn_gw_control.push_outermac_vxlan_innermac"},
+		},
+		"ipv6 vtep": {
+			vtep:    "fd00::1",
+			tables:  []string{pushVxlan6Hdr, l3NhTx},
+			actions: []string{"evpn_gw_control.omac_vxlan6_imac_push", "evpn_gw_control.push_outermac_vxlan6_innermac"},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			got := _vtepEntries(net.ParseIP(tt.vtep), entries())
+			if len(got) != len(tt.tables) {
+				t.Fatalf("Expected %d entries, received %v", len(tt.tables), got)
+			}
+			for i, entry := range got {
+				e := entry.(p4client.TableEntry)
+				if e.Tablename != tt.tables[i] || e.ActionName != tt.actions[i] {
+					t.Errorf("Expected %s with %s, received %s with %s", tt.tables[i], tt.actions[i], e.Tablename, e.ActionName)
+				}
+			}
+		})
+	}
+}
+
+func TestL3Route6(t *testing.T) {
+	grd := &infradb.Vrf{Name: "//network.opiproject.org/vrfs/GRD", Spec: &infradb.VrfSpec{}}
+	nexthop := &netlink_polling.NexthopStruct{NhType: netlink_polling.PHY, ID: 5}
+	tests := map[string]struct {
+		dst    string
+		tables []string
+		match  interface{}
+	}{
+		"host route": {
+			dst:    "fd00::2/128",
+			tables: []string{l3RtHost6, l3RtHost6, l3P2PRtHost6},
+			match:  net.ParseIP("fd00::2").To16(),
+		},
+		"prefix route": {
+			dst:    "fd00::/64",
+			tables: []string{l3Rt6, l3Rt6, l3P2PRt6},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, dst, _ := net.ParseCIDR(tt.dst)
+			route := netlink_polling.RouteStruct{
+				Route0:   netlink.Route{Dst: dst},
+				Vrf:      grd,
+				Nexthops: []*netlink_polling.NexthopStruct{nexthop},
+				Metadata: map[interface{}]interface{}{"direction": netlink_polling.RXTX},
+			}
+			entries := L3Decoder{}._l3Route6(route, "False", false, nil, EcmpDispatcher{})
+			if len(entries) != len(tt.tables) {
+				t.Fatalf("Expected %d entries, received %v", len(tt.tables), entries)
+			}
+			for i, entry := range entries {
+				e := entry.(p4client.TableEntry)
+				if e.Tablename != tt.tables[i] {
+					t.Errorf("Expected table %s, received %s", tt.tables[i], e.Tablename)
+				}
+				if ip, ok := e.FieldValue["dst_ip"][0].(net.IP); tt.match != nil && (!ok || !ip.Equal(tt.match.(net.IP))) {
+					t.Errorf("Expected dst_ip %v, received %v", tt.match, e.FieldValue["dst_ip"][0])
+				}
+			}
+			p2p := entries[len(entries)-1].(p4client.TableEntry)
+			if p2p.ActionName != "evpn_gw_control.set_p2p_neighbor" || p2p.Params[0] != uint16(11) {
+				t.Errorf("Expected the p2p neighbor 11, received %v", p2p.Action)
+			}
+			deletions := L3Decoder{}._l3Route6(route, trueStr, false, nil, EcmpDispatcher{})
+			for _, entry := range deletions {
+				if e := entry.(p4client.TableEntry); e.ActionName != "" {
+					t.Errorf("Expected deletions without action, received %v", e)
+				}
+			}
+		})
+	}
+}
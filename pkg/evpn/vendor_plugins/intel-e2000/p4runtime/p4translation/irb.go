@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"log"
+	"path"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+)
+
+// irb modes of a vrf
+const (
+	irbSymmetric  = "symmetric"
+	irbAsymmetric = "asymmetric"
+)
+
+// irbModes are the vrfs routing asymmetrically by the base of their name,
+// the others route symmetrically through their l3vni
+var irbModes struct {
+	mu         sync.RWMutex
+	asymmetric map[string]bool
+}
+
+// setUpIrb applies the asymmetric vrfs of the config
+func setUpIrb(c irbSection) {
+	setAsymmetricVrfs(c.Asymmetric)
+	if len(c.Asymmetric) != 0 {
+		log.Printf("intel-e2000: vrfs %v route asymmetrically\n", c.Asymmetric)
+	}
+}
+
+// setAsymmetricVrfs sets the vrfs routing asymmetrically
+func setAsymmetricVrfs(vrfs []string) {
+	asymmetric := make(map[string]bool)
+	for _, vrf := range vrfs {
+		asymmetric[path.Base(vrf)] = true
+	}
+	irbModes.mu.Lock()
+	defer irbModes.mu.Unlock()
+	irbModes.asymmetric = asymmetric
+}
+
+// irbMode returns the irb mode of the vrf
+func irbMode(vrf *infradb.Vrf) string {
+	irbModes.mu.RLock()
+	defer irbModes.mu.RUnlock()
+	if irbModes.asymmetric[path.Base(vrf.Name)] {
+		return irbAsymmetric
+	}
+	return irbSymmetric
+}
+
+// intoL2vni tells whether the vxlan nexthop routes into the l2vni of a
+// remote host, it then resolved through a neighbor of the svi and carries
+// the svi mac, the host mac and the l2vni. Nexthops through the l3vni
+// resolve on the underlay and carry the router macs.
+func intoL2vni(nexthop *netlink_polling.NexthopStruct) bool {
+	return nexthop.NhType == netlink_polling.VXLAN && nexthop.Neighbor != nil &&
+		nexthop.Neighbor.Type == netlink_polling.VXLAN
+}
+
+// irbRoute adapts the route to the irb mode of its vrf and tells whether
+// the decoders program it. Symmetric vrfs take every route, the l3vni host
+// routes of frr go before the neighbors in the l2vni. Asymmetric vrfs route
+// into the l2vni of the remote hosts and leave the l3vni out, they need no
+// l3vni and then route in both directions like symmetric vrfs.
+func irbRoute(route netlink_polling.RouteStruct) (netlink_polling.RouteStruct, bool) {
+	if route.Vrf == nil || irbMode(route.Vrf) != irbAsymmetric {
+		return route, true
+	}
+	for _, nh := range route.Nexthops {
+		if nh.NhType == netlink_polling.VXLAN && !intoL2vni(nh) {
+			log.Printf("intel-e2000: asymmetric vrf %s skips route %v through the l3vni\n", route.Vrf.Name, route.Key)
+			return route, false
+		}
+	}
+	if route.Vrf.Spec.Vni != nil || len(route.Nexthops) == 0 {
+		return route, true
+	}
+	metadata := make(map[interface{}]interface{}, len(route.Metadata)+1)
+	for k, v := range route.Metadata {
+		metadata[k] = v
+	}
+	switch route.Nexthops[0].NhType {
+	case netlink_polling.VXLAN, netlink_polling.SVI, netlink_polling.ACC:
+		metadata["direction"] = netlink_polling.RXTX
+	}
+	route.Metadata = metadata
+	return route, true
+}
+
+// irbVrfID returns the vrf id of the route, vrfs without l3vni share id 0
+// with the grd unless they route asymmetrically
+func irbVrfID(vrf *infradb.Vrf) uint32 {
+	if vrf.Spec.Vni == nil && irbMode(vrf) != irbAsymmetric {
+		return 0
+	}
+	return *vrf.Metadata.RoutingTable[0]
+}
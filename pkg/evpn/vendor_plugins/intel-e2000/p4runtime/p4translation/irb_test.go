@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestIrbRoute(t *testing.T) {
+	vni, table := uint32(1000), uint32(1001)
+	l3vni := &netlink_polling.NexthopStruct{
+		NhType:   netlink_polling.VXLAN,
+		Neighbor: &netlink_polling.NeighStruct{Type: netlink_polling.PHY},
+	}
+	l2vni := &netlink_polling.NexthopStruct{
+		NhType:   netlink_polling.VXLAN,
+		Neighbor: &netlink_polling.NeighStruct{Type: netlink_polling.VXLAN},
+	}
+	tests := map[string]struct {
+		vrf        *infradb.Vrf
+		nexthop    *netlink_polling.NexthopStruct
+		programmed bool
+		directions []int
+		vrfID      uint32
+	}{
+		"symmetric through the l3vni": {
+			vrf:        &infradb.Vrf{Name: "//network.opiproject.org/vrfs/vrf-blue", Spec: &infradb.VrfSpec{Vni: &vni}},
+			nexthop:    l3vni,
+			programmed: true,
+			directions: []int{Direction.Tx, Direction.Rx},
+			vrfID:      table,
+		},
+		"asymmetric through the l3vni": {
+			vrf:     &infradb.Vrf{Name: "//network.opiproject.org/vrfs/vrf-green", Spec: &infradb.VrfSpec{Vni: &vni}},
+			nexthop: l3vni,
+		},
+		"asymmetric into the l2vni": {
+			vrf:        &infradb.Vrf{Name: "//network.opiproject.org/vrfs/vrf-green", Spec: &infradb.VrfSpec{Vni: &vni}},
+			nexthop:    l2vni,
+			programmed: true,
+			directions: []int{Direction.Tx, Direction.Rx},
+			vrfID:      table,
+		},
+		"asymmetric without l3vni": {
+			vrf:        &infradb.Vrf{Name: "//network.opiproject.org/vrfs/vrf-green", Spec: &infradb.VrfSpec{}},
+			nexthop:    l2vni,
+			programmed: true,
+			directions: []int{Direction.Tx, Direction.Rx},
+			vrfID:      table,
+		},
+		"symmetric without l3vni": {
+			vrf:        &infradb.Vrf{Name: "//network.opiproject.org/vrfs/vrf-blue", Spec: &infradb.VrfSpec{}},
+			nexthop:    l2vni,
+			programmed: true,
+		},
+	}
+	setAsymmetricVrfs([]string{"vrf-green"})
+	t.Cleanup(func() { setAsymmetricVrfs(nil) })
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			tt.vrf.Metadata = &infradb.VrfMetadata{RoutingTable: []*uint32{&table}}
+			direction := netlink_polling.None
+			if tt.vrf.Spec.Vni != nil {
+				direction = netlink_polling.RXTX
+			}
+			route := netlink_polling.RouteStruct{
+				Vrf:      tt.vrf,
+				Nexthops: []*netlink_polling.NexthopStruct{tt.nexthop},
+				Metadata: map[interface{}]interface{}{"direction": direction},
+			}
+			got, programmed := irbRoute(route)
+			if programmed != tt.programmed {
+				t.Fatalf("Expected programmed %v, received %v", tt.programmed, programmed)
+			}
+			if !programmed {
+				return
+			}
+			if directions := _directionsOf(got); !reflect.DeepEqual(directions, tt.directions) {
+				t.Errorf("Expected directions %v, received %v", tt.directions, directions)
+			}
+			if route.Metadata["direction"] != direction {
+				t.Errorf("Expected the route of netlink unchanged, received %v", route.Metadata)
+			}
+			if vrfID := irbVrfID(tt.vrf); vrfID != tt.vrfID {
+				t.Errorf("Expected vrf id %d, received %d", tt.vrfID, vrfID)
+			}
+		})
+	}
+}
+
+func TestPodDecoder_TranslateAddedSviVrfID(t *testing.T) {
+	vni, table := uint32(1000), uint32(1001)
+	mac, _ := net.ParseMAC("00:aa:00:00:03:14")
+	store := newSnapshotStore()
+	store.load(&objectSnapshot{
+		Vrfs: []*infradb.Vrf{{
+			Name:     "//network.opiproject.org/vrfs/vrf-blue",
+			Spec:     &infradb.VrfSpec{Vni: &vni},
+			Metadata: &infradb.VrfMetadata{RoutingTable: []*uint32{&table}},
+		}},
+		LogicalBridges: []*infradb.LogicalBridge{{
+			Name:        "//network.opiproject.org/bridges/lb-blue",
+			Spec:        &infradb.LogicalBridgeSpec{VlanID: 10},
+			BridgePorts: map[string]bool{"//network.opiproject.org/ports/bp-access": false, "//network.opiproject.org/ports/bp-trunk": false},
+		}},
+		BridgePorts: []*infradb.BridgePort{{
+			Name:     "//network.opiproject.org/ports/bp-access",
+			Spec:     &infradb.BridgePortSpec{Ptype: infradb.Access},
+			Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+		}, {
+			Name:     "//network.opiproject.org/ports/bp-trunk",
+			Spec:     &infradb.BridgePortSpec{Ptype: infradb.Trunk},
+			Metadata: &infradb.BridgePortMetadata{VPort: "25"},
+		}},
+	})
+	objects = store
+	t.Cleanup(func() { objects = infradbStore{} })
+
+	entries, err := PodDecoder{}.translateAddedSvi(&infradb.Svi{
+		Name: "//network.opiproject.org/svis/svi-blue",
+		Spec: &infradb.SviSpec{
+			Vrf:           "//network.opiproject.org/vrfs/vrf-blue",
+			LogicalBridge: "//network.opiproject.org/bridges/lb-blue",
+			MacAddress:    &mac,
+		},
+	})
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected the access and the trunk entry, received %v %v", entries, err)
+	}
+	// access and trunk ports enter the vrf of the routing table, as the
+	// routes and the trunk bridge port entries do, not the vni
+	for _, entry := range entries {
+		e := entry.(p4client.TableEntry)
+		if vrfID := e.Params[len(e.Params)-1]; vrfID != uint16(table) {
+			t.Errorf("Expected vrf id %d in %s, received %v", table, e.Tablename, vrfID)
+		}
+	}
+}
@@ -0,0 +1,523 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-evpn-bridge/pkg/utils"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// mirror source kinds and directions
+const (
+	mirrorStr        = "mirror"
+	mirrorBridgePort = "bridge-port"
+	mirrorSvi        = "svi"
+	mirrorVrf        = "vrf"
+	mirrorRx         = "rx"
+	mirrorTx         = "tx"
+	mirrorBoth       = "both"
+	maxErspanID      = 1023
+)
+
+// errors of the mirror sessions, the mirror service maps them to its codes
+var (
+	// ErrMirroringDisabled is returned while the config does not enable mirroring
+	ErrMirroringDisabled = errors.New("mirroring is not enabled")
+	// ErrErspanUnsupported is returned when the pipeline has no erspan encap
+	ErrErspanUnsupported = errors.New("erspan is not supported")
+	// ErrInvalidMirrorSession is returned for a session that does not validate
+	ErrInvalidMirrorSession = errors.New("invalid mirror session")
+	// ErrMirrorSessionExists is returned when the name is taken
+	ErrMirrorSessionExists = errors.New("mirror session already exists")
+	// ErrMirrorSessionNotFound is returned for an unknown session
+	ErrMirrorSessionNotFound = errors.New("mirror session not found")
+	// ErrMirrorSourceNotFound is returned when the source is not in infradb
+	ErrMirrorSourceNotFound = errors.New("mirror source not found")
+	// ErrMirrorSourceNotReady is returned when the source has no match value yet
+	ErrMirrorSourceNotReady = errors.New("mirror source not ready")
+	// ErrMirrorIDsExhausted is returned when the clone session ids run out
+	ErrMirrorIDsExhausted = errors.New("no clone session id left")
+)
+
+// MirrorSession copies the traffic of a bridge port, svi or vrf to a
+// representor, plain or in an erspan tunnel to a collector
+type MirrorSession struct {
+	Name        string        `json:"name"`
+	Source      string        `json:"source"`
+	SourceKind  string        `json:"sourceKind"`
+	Direction   string        `json:"direction"`
+	Representor string        `json:"representor"`
+	Erspan      *ErspanTunnel `json:"erspan,omitempty"`
+	// ID is the clone session id given by the plugin
+	ID uint32 `json:"id"`
+	// Detached is set while the source is deleted, its traffic is copied
+	// again once the source is created anew
+	Detached bool `json:"detached,omitempty"`
+}
+
+// ErspanTunnel is the erspan type II tunnel of a mirror session, the copies
+// leave through the representor to the nexthop towards the collector
+type ErspanTunnel struct {
+	Collector  string `json:"collector"`
+	Source     string `json:"source"`
+	NexthopMac string `json:"nexthopMac"`
+	SessionID  uint32 `json:"sessionId"`
+}
+
+// mirroring is set when the config enables the mirror sessions
+var mirroring bool
+
+// mirrorFile is the file the sessions are saved to, none when empty
+var mirrorFile string
+
+// setUpMirroring enables the mirror sessions when the config asks for it
+// and the pipeline has the mirror tables
+func setUpMirroring() {
+	mirroring = pluginCfg.P4.Mirroring
+	if !mirroring {
+		return
+	}
+	mirrorFile = pluginCfg.P4.MirrorSessions
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, mirrorBp, mirrorSviTable, mirrorVrfTable) {
+		log.Fatalf("intel-e2000: mirroring needs the %s, %s and %s tables in pipeline %s\n",
+			schema.tableName(mirrorBp), schema.tableName(mirrorSviTable), schema.tableName(mirrorVrfTable), schema.Pipeline)
+	}
+	log.Printf("intel-e2000: mirroring enabled\n")
+}
+
+// mirrorSessions are the created sessions by name, guarded by translateMu
+var mirrorSessions = make(map[string]mirrorState)
+
+// mirrorState is a session with the entries programmed for it, the clone
+// session with its encap and the clone entries of the source
+type mirrorState struct {
+	session MirrorSession
+	entries []interface{}
+	clones  []interface{}
+}
+
+// mirrorPool hands out the clone session ids
+var mirrorPool, _ = utils.IDPoolInit("mirror", 1, 1023)
+
+// CreateMirrorSession validates the session and programs the clone session,
+// the tunnel encap and the clone entries of its source
+func CreateMirrorSession(session MirrorSession) (MirrorSession, error) {
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	session, err := createMirrorSession(session, false)
+	if err == nil {
+		saveMirrorSessions()
+	}
+	return session, err
+}
+
+// createMirrorSession creates the session, translateMu held. A restored
+// session whose source is not in infradb yet waits for it detached.
+func createMirrorSession(session MirrorSession, restore bool) (MirrorSession, error) {
+	if !mirroring {
+		return session, ErrMirroringDisabled
+	}
+	if session.Name == "" {
+		return session, fmt.Errorf("%w: no name", ErrInvalidMirrorSession)
+	}
+	if _, ok := mirrorSessions[session.Name]; ok {
+		return session, fmt.Errorf("%w: %s", ErrMirrorSessionExists, session.Name)
+	}
+	if err := session.check(); err != nil {
+		return session, err
+	}
+	session.ID = mirrorPool.GetID(session.Name)
+	if session.ID == 0 {
+		return session, fmt.Errorf("%w for %s", ErrMirrorIDsExhausted, session.Name)
+	}
+	table, field, key, err := session.sourceKey()
+	session.Detached = restore && errors.Is(err, ErrMirrorSourceNotFound)
+	if err != nil && !session.Detached {
+		mirrorPool.ReleaseID(session.Name)
+		return session, err
+	}
+	entries, err := session.entries()
+	if err != nil {
+		mirrorPool.ReleaseID(session.Name)
+		return session, err
+	}
+	var clones []interface{}
+	if session.Detached {
+		log.Printf("intel-e2000: Mirror session %s waits for %s %s to be created\n", session.Name, session.SourceKind, session.Source)
+	} else {
+		clones = session.clones(table, field, key)
+		log.Printf("intel-e2000: Mirroring %s %s %s with clone session %d\n", session.SourceKind, session.Source, session.Direction, session.ID)
+	}
+	if err := addMirrorEntries(append(entries, clones...)); err != nil {
+		mirrorPool.ReleaseID(session.Name)
+		return session, err
+	}
+	mirrorSessions[session.Name] = mirrorState{session: session, entries: entries, clones: clones}
+	return session, nil
+}
+
+// addMirrorEntries programs the entries of a session in order. A failed
+// write removes the entries written before it so no half session is left.
+func addMirrorEntries(entries []interface{}) error {
+	for i, entry := range entries {
+		var err error
+		switch e := entry.(type) {
+		case p4client.CloneSession:
+			err = p4client.AddCloneSession(e)
+		case p4client.TableEntry:
+			err = p4client.AddEntry(schema.resolve(e))
+			if err == nil {
+				recorder.entry("add", e)
+				desired.add(e)
+			}
+		default:
+			err = fmt.Errorf("entry is not of type p4client.TableEntry:- %v", entry)
+		}
+		if err != nil {
+			log.Printf("intel-e2000: error adding mirror entry %d error %v\n", i, err)
+			if delErr := delEntries(mirrorDeletions(entries[:i])); delErr != nil {
+				log.Printf("intel-e2000: error removing the mirror entries error %v\n", delErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteMirrorSession removes the session and its entries
+func DeleteMirrorSession(name string) error {
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	if !mirroring {
+		return ErrMirroringDisabled
+	}
+	state, ok := mirrorSessions[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrMirrorSessionNotFound, name)
+	}
+	delete(mirrorSessions, name)
+	mirrorPool.ReleaseID(name)
+	saveMirrorSessions()
+	// the clone entries go first, the session they point to last
+	return delEntries(mirrorDeletions(append(state.entries, state.clones...)))
+}
+
+// saveMirrorSessions writes the sessions to the mirror file, translateMu held.
+// The file is replaced whole so a crash leaves the previous sessions.
+func saveMirrorSessions() {
+	if mirrorFile == "" {
+		return
+	}
+	b, err := json.MarshalIndent(mirrorSessionList(), "", "  ")
+	if err != nil {
+		log.Printf("intel-e2000: Failed to encode the mirror sessions: %v\n", err)
+		return
+	}
+	tmp := mirrorFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Printf("intel-e2000: Failed to save the mirror sessions: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, mirrorFile); err != nil {
+		log.Printf("intel-e2000: Failed to save the mirror sessions: %v\n", err)
+	}
+}
+
+// restoreMirrorSessions creates the sessions of the mirror file again once
+// the representors are known
+func restoreMirrorSessions() {
+	if !mirroring || mirrorFile == "" {
+		return
+	}
+	b, err := os.ReadFile(mirrorFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var sessions []MirrorSession
+	if err == nil {
+		err = json.Unmarshal(b, &sessions)
+	}
+	if err != nil {
+		log.Printf("intel-e2000: Failed to read the mirror sessions: %v\n", err)
+		return
+	}
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	for _, s := range sessions {
+		s.ID, s.Detached = 0, false
+		if _, err := createMirrorSession(s, true); err != nil {
+			log.Printf("intel-e2000: Failed to restore mirror session %s: %v\n", s.Name, err)
+		}
+	}
+}
+
+// mirrorDeletions returns the deletions of the entries in reverse order
+func mirrorDeletions(entries []interface{}) []interface{} {
+	var dels []interface{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		switch e := entries[i].(type) {
+		case p4client.TableEntry:
+			dels = append(dels, p4client.TableEntry{Tablename: e.Tablename, TableField: e.TableField})
+		default:
+			dels = append(dels, e)
+		}
+	}
+	return dels
+}
+
+// MirrorSessions returns the sessions sorted by name
+func MirrorSessions() []MirrorSession {
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	return mirrorSessionList()
+}
+
+// mirrorSessionList returns the sessions sorted by name, translateMu held
+func mirrorSessionList() []MirrorSession {
+	sessions := make([]MirrorSession, 0, len(mirrorSessions))
+	for _, s := range mirrorSessions {
+		sessions = append(sessions, s.session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+	return sessions
+}
+
+// check validates the session against the pipeline and the representors
+func (s MirrorSession) check() error {
+	switch s.SourceKind {
+	case mirrorBridgePort, mirrorSvi, mirrorVrf:
+	default:
+		return fmt.Errorf("%w: unknown source kind %q", ErrInvalidMirrorSession, s.SourceKind)
+	}
+	switch s.Direction {
+	case mirrorRx, mirrorTx, mirrorBoth:
+	default:
+		return fmt.Errorf("%w: unknown direction %q", ErrInvalidMirrorSession, s.Direction)
+	}
+	if _, ok := activeRepresentors[s.Representor]; !ok {
+		return fmt.Errorf("%w: unknown representor %q", ErrInvalidMirrorSession, s.Representor)
+	}
+	if s.Erspan == nil {
+		return nil
+	}
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, erspanEncap) {
+		return fmt.Errorf("%w: pipeline %s has no %s table", ErrErspanUnsupported, schema.Pipeline, schema.tableName(erspanEncap))
+	}
+	if net.ParseIP(s.Erspan.Collector).To4() == nil || net.ParseIP(s.Erspan.Source).To4() == nil {
+		return fmt.Errorf("%w: erspan needs ipv4 collector and source addresses", ErrInvalidMirrorSession)
+	}
+	if _, err := net.ParseMAC(s.Erspan.NexthopMac); err != nil {
+		return fmt.Errorf("%w: erspan nexthop mac: %v", ErrInvalidMirrorSession, err)
+	}
+	if s.Erspan.SessionID > maxErspanID {
+		return fmt.Errorf("%w: erspan session id %d above %d", ErrInvalidMirrorSession, s.Erspan.SessionID, maxErspanID)
+	}
+	return nil
+}
+
+// sourceKey resolves the source to its table and the match value
+func (s MirrorSession) sourceKey() (string, string, uint16, error) {
+	table, field, key, err := s.lookUpSource()
+	switch {
+	case errors.Is(err, infradb.ErrKeyNotFound):
+		err = fmt.Errorf("%w: %v", ErrMirrorSourceNotFound, err)
+	case err != nil:
+		err = fmt.Errorf("%w: %v", ErrMirrorSourceNotReady, err)
+	}
+	return table, field, key, err
+}
+
+// lookUpSource reads the source from infradb and returns its match value
+func (s MirrorSession) lookUpSource() (string, string, uint16, error) {
+	switch s.SourceKind {
+	case mirrorBridgePort:
+		bp, err := objects.GetBP(s.Source)
+		if err != nil {
+			return "", "", 0, err
+		}
+		return bpMirrorKey(bp)
+	case mirrorSvi:
+		svi, err := objects.GetSvi(s.Source)
+		if err != nil {
+			return "", "", 0, err
+		}
+		return sviMirrorKey(svi)
+	default:
+		vrf, err := objects.GetVrf(s.Source)
+		if err != nil {
+			return "", "", 0, err
+		}
+		return vrfMirrorKey(vrf)
+	}
+}
+
+// bpMirrorKey is the clone entry key of a bridge port
+func bpMirrorKey(bp *infradb.BridgePort) (string, string, uint16, error) {
+	vsi, err := strconv.ParseUint(bp.Metadata.VPort, 10, 16)
+	return mirrorBp, "vsi", uint16(vsi), err
+}
+
+// sviMirrorKey is the clone entry key of a svi, the vlan of its logical bridge
+func sviMirrorKey(svi *infradb.Svi) (string, string, uint16, error) {
+	lb, err := objects.GetLB(svi.Spec.LogicalBridge)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if lb.Spec.VlanID > math.MaxUint16 {
+		return "", "", 0, fmt.Errorf("vlan %d out of range", lb.Spec.VlanID)
+	}
+	return mirrorSviTable, "vlan_id", uint16(lb.Spec.VlanID), nil
+}
+
+// vrfMirrorKey is the clone entry key of a vrf, its routing table
+func vrfMirrorKey(vrf *infradb.Vrf) (string, string, uint16, error) {
+	if vrf.Metadata == nil || len(vrf.Metadata.RoutingTable) == 0 || vrf.Metadata.RoutingTable[0] == nil {
+		return "", "", 0, fmt.Errorf("vrf %s has no routing table", vrf.Name)
+	}
+	return mirrorVrfTable, "vrf", uint16(*vrf.Metadata.RoutingTable[0]), nil
+}
+
+// entries builds the clone session and the erspan encap of its copies, in
+// the order they are added
+func (s MirrorSession) entries() ([]interface{}, error) {
+	vsi, err := strconv.Atoi(activeRepresentors[s.Representor][0])
+	if err != nil {
+		return nil, fmt.Errorf("representor %s has no vsi", s.Representor)
+	}
+	replica := p4client.Replica{Port: uint32(_toEgressVsi(vsi))}
+	if s.Erspan != nil {
+		// the instance selects the encap of the copies
+		replica.Instance = s.ID
+	}
+	entries := []interface{}{p4client.CloneSession{ID: s.ID, Replicas: []p4client.Replica{replica}}}
+	if s.Erspan != nil {
+		smac, err := net.ParseMAC(activeRepresentors[s.Representor][1])
+		if err != nil {
+			return nil, fmt.Errorf("representor %s has no mac", s.Representor)
+		}
+		dmac, _ := net.ParseMAC(s.Erspan.NexthopMac)
+		entries = append(entries, p4client.TableEntry{
+			Tablename: erspanEncap,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"session": {s.ID, "exact"},
+				},
+				Priority: int32(0),
+			},
+			Action: p4client.Action{
+				ActionName: "evpn_gw_control.push_erspan",
+				Params:     []interface{}{smac, dmac, net.ParseIP(s.Erspan.Source).To4(), net.ParseIP(s.Erspan.Collector).To4(), s.Erspan.SessionID},
+			},
+		})
+	}
+	return entries, nil
+}
+
+// clones builds the clone entries of the source
+func (s MirrorSession) clones(table, field string, key uint16) []interface{} {
+	var entries []interface{}
+	for _, dir := range s.directions() {
+		entries = append(entries, p4client.TableEntry{
+			Tablename: table,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					field:       {key, "exact"},
+					"direction": {uint16(dir), "exact"},
+				},
+				Priority: int32(0),
+			},
+			Action: p4client.Action{
+				ActionName: "evpn_gw_control.mirror_to_session",
+				Params:     []interface{}{s.ID},
+			},
+		})
+	}
+	return entries
+}
+
+// directions returns the pipeline directions of the session
+func (s MirrorSession) directions() []int {
+	switch s.Direction {
+	case mirrorRx:
+		return []int{Direction.Rx}
+	case mirrorTx:
+		return []int{Direction.Tx}
+	}
+	return []int{Direction.Rx, Direction.Tx}
+}
+
+// MirrorDecoder ties the clone entries of the mirror sessions to their
+// sources. The entries go with a deleted source, so a new object reusing
+// its vsi, vlan or table is not mirrored, and come back when the source is
+// created again.
+type MirrorDecoder struct{}
+
+// Name returns the decoder name
+func (MirrorDecoder) Name() string {
+	return mirrorStr
+}
+
+// OnBridgePort follows the bridge ports mirrored by a session
+func (MirrorDecoder) OnBridgePort(op Operation, bp *infradb.BridgePort) ([]interface{}, error) {
+	return mirrorSource(op, mirrorBridgePort, bp.Name, func() (string, string, uint16, error) {
+		return bpMirrorKey(bp)
+	})
+}
+
+// OnSvi follows the svis mirrored by a session
+func (MirrorDecoder) OnSvi(op Operation, svi *infradb.Svi) ([]interface{}, error) {
+	return mirrorSource(op, mirrorSvi, svi.Name, func() (string, string, uint16, error) {
+		return sviMirrorKey(svi)
+	})
+}
+
+// OnVrf follows the vrfs mirrored by a session
+func (MirrorDecoder) OnVrf(op Operation, vrf *infradb.Vrf) ([]interface{}, error) {
+	return mirrorSource(op, mirrorVrf, vrf.Name, func() (string, string, uint16, error) {
+		return vrfMirrorKey(vrf)
+	})
+}
+
+// mirrorSource detaches the sessions of a deleted source and attaches them
+// again to the source once it is added, translateMu held
+func mirrorSource(op Operation, kind, name string, key func() (string, string, uint16, error)) ([]interface{}, error) {
+	var entries []interface{}
+	for _, s := range mirrorSessionList() {
+		if s.SourceKind != kind || s.Source != name {
+			continue
+		}
+		state := mirrorSessions[s.Name]
+		switch {
+		case op == OpDeleted && !s.Detached:
+			log.Printf("intel-e2000: Mirror session %s waits for %s %s to be created again\n", s.Name, kind, name)
+			entries = append(entries, mirrorDeletions(state.clones)...)
+			state.clones = nil
+			state.session.Detached = true
+		case op == OpAdded && s.Detached:
+			table, field, k, err := key()
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("intel-e2000: Mirror session %s follows %s %s again\n", s.Name, kind, name)
+			state.clones = s.clones(table, field, k)
+			state.session.Detached = false
+			entries = append(entries, state.clones...)
+		}
+		mirrorSessions[s.Name] = state
+	}
+	return entries, nil
+}
@@ -0,0 +1,303 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	p4config "github.com/p4lang/p4runtime/go/p4/config/v1"
+)
+
+// setUpMirrorTest runs the plugin dry with a bridge port and a representor
+func setUpMirrorTest(t *testing.T) *bytes.Buffer {
+	resetState()
+	var buf bytes.Buffer
+	p4client.SetDryRun(journal.New(&buf))
+	store := newSnapshotStore()
+	store.load(&objectSnapshot{BridgePorts: []*infradb.BridgePort{{
+		Name:     "//network.opiproject.org/ports/bp-a",
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}}})
+	objects = store
+	activeRepresentors = map[string][2]string{"phy0_rep": {"16", "00:01:00:00:03:14"}}
+	mirroring = true
+	t.Cleanup(func() {
+		p4client.SetDryRun(nil)
+		mirroring = false
+		objects = infradbStore{}
+		activeRepresentors = nil
+		pipelineInfo = nil
+	})
+	return &buf
+}
+
+func TestCreateMirrorSession(t *testing.T) {
+	session := func() MirrorSession {
+		return MirrorSession{
+			Name:        "capture",
+			Source:      "//network.opiproject.org/ports/bp-a",
+			SourceKind:  "bridge-port",
+			Direction:   "both",
+			Representor: "phy0_rep",
+		}
+	}
+	tests := map[string]struct {
+		change  func(*MirrorSession)
+		entries int
+		errMsg  string
+		err     error
+	}{
+		"local representor": {
+			change:  func(*MirrorSession) {},
+			entries: 3,
+		},
+		"erspan tunnel": {
+			change: func(s *MirrorSession) {
+				s.Direction = "rx"
+				s.Erspan = &ErspanTunnel{Collector: "192.168.1.10", Source: "192.168.1.1", NexthopMac: "00:aa:bb:cc:dd:ee", SessionID: 7}
+			},
+			entries: 3,
+		},
+		"unknown representor": {
+			change: func(s *MirrorSession) { s.Representor = "phy9_rep" },
+			errMsg: `unknown representor "phy9_rep"`,
+			err:    ErrInvalidMirrorSession,
+		},
+		"unknown direction": {
+			change: func(s *MirrorSession) { s.Direction = "in" },
+			errMsg: `unknown direction "in"`,
+			err:    ErrInvalidMirrorSession,
+		},
+		"unknown source": {
+			change: func(s *MirrorSession) { s.Source = "//network.opiproject.org/ports/bp-b" },
+			errMsg: "bridge port //network.opiproject.org/ports/bp-b",
+			err:    ErrMirrorSourceNotFound,
+		},
+		"mirroring disabled": {
+			change: func(*MirrorSession) { mirroring = false },
+			errMsg: "mirroring is not enabled",
+			err:    ErrMirroringDisabled,
+		},
+		"erspan without the encap table": {
+			change: func(s *MirrorSession) {
+				pipelineInfo = &p4config.P4Info{}
+				s.Erspan = &ErspanTunnel{Collector: "192.168.1.10", Source: "192.168.1.1", NexthopMac: "00:aa:bb:cc:dd:ee", SessionID: 7}
+			},
+			errMsg: "erspan is not supported",
+			err:    ErrErspanUnsupported,
+		},
+		"erspan id out of range": {
+			change: func(s *MirrorSession) {
+				s.Erspan = &ErspanTunnel{Collector: "192.168.1.10", Source: "192.168.1.1", NexthopMac: "00:aa:bb:cc:dd:ee", SessionID: 1024}
+			},
+			errMsg: "erspan session id 1024 above 1023",
+			err:    ErrInvalidMirrorSession,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			setUpMirrorTest(t)
+			s := session()
+			tt.change(&s)
+			created, err := CreateMirrorSession(s)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Fatalf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg) || !errors.Is(err, tt.err)):
+				t.Fatalf("Expected error: %v, received %v", tt.errMsg, err)
+			case tt.errMsg != "":
+				if len(MirrorSessions()) != 0 {
+					t.Errorf("Expected no sessions, received %v", MirrorSessions())
+				}
+				return
+			}
+			if created.ID == 0 {
+				t.Errorf("Expected a clone session id, received 0")
+			}
+			state := mirrorSessions["capture"]
+			if got := len(state.entries) + len(state.clones); got != tt.entries {
+				t.Errorf("Expected %d entries, received %d", tt.entries, got)
+			}
+		})
+	}
+}
+
+func TestDeleteMirrorSession(t *testing.T) {
+	buf := setUpMirrorTest(t)
+	_, err := CreateMirrorSession(MirrorSession{
+		Name:        "capture",
+		Source:      "//network.opiproject.org/ports/bp-a",
+		SourceKind:  "bridge-port",
+		Direction:   "tx",
+		Representor: "phy0_rep",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if err := DeleteMirrorSession("capture"); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	ops := []string{`"op":"add-clone"`, `"op":"add"`, `"op":"delete"`, `"op":"delete-clone"`}
+	if len(lines) != len(ops) {
+		t.Fatalf("Expected %d journal lines, received %v", len(ops), lines)
+	}
+	for i, op := range ops {
+		if !strings.Contains(lines[i], op) {
+			t.Errorf("Expected %s in line %d, received %s", op, i+1, lines[i])
+		}
+	}
+	if err := DeleteMirrorSession("capture"); !errors.Is(err, ErrMirrorSessionNotFound) {
+		t.Errorf("Expected an error deleting an unknown session, received %v", err)
+	}
+}
+
+// failingJournal fails the writes naming the table, the journal keeps
+// failing from then on
+type failingJournal struct {
+	bytes.Buffer
+	table string
+}
+
+func (j *failingJournal) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte(`"table":"`+j.table+`"`)) {
+		return 0, errors.New("write failed")
+	}
+	return j.Buffer.Write(p)
+}
+
+func TestCreateMirrorSession_WriteFailure(t *testing.T) {
+	setUpMirrorTest(t)
+	buf := &failingJournal{table: schema.tableName(mirrorBp)}
+	p4client.SetDryRun(journal.New(buf))
+	mirrorFile = filepath.Join(t.TempDir(), "mirror-sessions.json")
+	t.Cleanup(func() { mirrorFile = "" })
+	_, err := CreateMirrorSession(MirrorSession{
+		Name:        "capture",
+		Source:      "//network.opiproject.org/ports/bp-a",
+		SourceKind:  "bridge-port",
+		Direction:   "tx",
+		Representor: "phy0_rep",
+		Erspan:      &ErspanTunnel{Collector: "192.168.1.10", Source: "192.168.1.1", NexthopMac: "00:aa:bb:cc:dd:ee", SessionID: 7},
+	})
+	if err == nil {
+		t.Fatalf("Expected the write error, received none")
+	}
+	if len(MirrorSessions()) != 0 {
+		t.Errorf("Expected no sessions, received %v", MirrorSessions())
+	}
+	if id := mirrorPool.ReleaseID("capture"); id != 0 {
+		t.Errorf("Expected the clone session id released, received %d still in use", id)
+	}
+	// the encap written ahead of the failed clone entry is removed again
+	if entries := desired.entries(erspanEncap); len(entries) != 0 {
+		t.Errorf("Expected no erspan encap, received %v", entries)
+	}
+	if !strings.Contains(buf.String(), `"op":"add-clone"`) {
+		t.Errorf("Expected the clone session added first, received %s", buf.String())
+	}
+	if _, err := os.Stat(mirrorFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no mirror file, received %v", err)
+	}
+}
+
+func TestMirrorDecoder_SourceDeleted(t *testing.T) {
+	buf := setUpMirrorTest(t)
+	_, err := CreateMirrorSession(MirrorSession{
+		Name:        "capture",
+		Source:      "//network.opiproject.org/ports/bp-a",
+		SourceKind:  "bridge-port",
+		Direction:   "tx",
+		Representor: "phy0_rep",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	d := MirrorDecoder{}
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-a",
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	other := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-b",
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	tests := []struct {
+		name     string
+		op       Operation
+		bp       *infradb.BridgePort
+		entries  int
+		vsi      uint16
+		detached bool
+	}{
+		{name: "source deleted", op: OpDeleted, bp: bp, entries: 1, detached: true},
+		{name: "new port on the same vsi", op: OpAdded, bp: other, detached: true},
+		{name: "source created on another vsi", op: OpAdded, bp: &infradb.BridgePort{Name: bp.Name, Metadata: &infradb.BridgePortMetadata{VPort: "25"}}, entries: 1, vsi: 25},
+		{name: "source added again", op: OpAdded, bp: bp},
+	}
+	for _, tt := range tests {
+		entries, err := d.OnBridgePort(tt.op, tt.bp)
+		if err != nil {
+			t.Fatalf("%s: Expected no error, received %v", tt.name, err)
+		}
+		if len(entries) != tt.entries {
+			t.Fatalf("%s: Expected %d entries, received %v", tt.name, tt.entries, entries)
+		}
+		if tt.vsi != 0 && entries[0].(p4client.TableEntry).FieldValue["vsi"][0] != tt.vsi {
+			t.Errorf("%s: Expected the clone entry of vsi %d, received %v", tt.name, tt.vsi, entries[0])
+		}
+		if detached := MirrorSessions()[0].Detached; detached != tt.detached {
+			t.Errorf("%s: Expected detached %v, received %v", tt.name, tt.detached, detached)
+		}
+	}
+	buf.Reset()
+	if err := DeleteMirrorSession("capture"); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if !strings.Contains(buf.String(), `"vsi","value":"25"`) {
+		t.Errorf("Expected the clone entry of the new vsi deleted, received %s", buf.String())
+	}
+}
+
+func TestRestoreMirrorSessions(t *testing.T) {
+	setUpMirrorTest(t)
+	mirrorFile = filepath.Join(t.TempDir(), "mirror-sessions.json")
+	t.Cleanup(func() { mirrorFile = "" })
+	for _, s := range []MirrorSession{
+		{Name: "capture", Source: "//network.opiproject.org/ports/bp-a", SourceKind: "bridge-port", Direction: "rx", Representor: "phy0_rep"},
+		{Name: "gone", Source: "//network.opiproject.org/ports/bp-a", SourceKind: "bridge-port", Direction: "tx", Representor: "phy0_rep"},
+	} {
+		if _, err := CreateMirrorSession(s); err != nil {
+			t.Fatalf("Expected no error, received %v", err)
+		}
+	}
+	if err := DeleteMirrorSession("gone"); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+
+	// the bridge starts again before infradb has the port
+	resetState()
+	objects = newSnapshotStore()
+	restoreMirrorSessions()
+	sessions := MirrorSessions()
+	if len(sessions) != 1 || sessions[0].Name != "capture" || !sessions[0].Detached || sessions[0].ID == 0 {
+		t.Fatalf("Expected capture restored detached, received %v", sessions)
+	}
+	entries, err := MirrorDecoder{}.OnBridgePort(OpAdded, &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-a",
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected the clone entry once the port is created, received %v %v", entries, err)
+	}
+}
@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+)
+
+// objectStore looks up the infradb objects a decoder depends on
+type objectStore interface {
+	GetVrf(name string) (*infradb.Vrf, error)
+	GetLB(name string) (*infradb.LogicalBridge, error)
+	GetBP(name string) (*infradb.BridgePort, error)
+	GetSvi(name string) (*infradb.Svi, error)
+	GetAllBPs() ([]*infradb.BridgePort, error)
+	GetAllSvis() ([]*infradb.Svi, error)
+}
+
+// infradbStore reads the objects from infradb
+type infradbStore struct{}
+
+// GetVrf gets the vrf from infradb
+func (infradbStore) GetVrf(name string) (*infradb.Vrf, error) {
+	return infradb.GetVrf(name)
+}
+
+// GetLB gets the logical bridge from infradb
+func (infradbStore) GetLB(name string) (*infradb.LogicalBridge, error) {
+	return infradb.GetLB(name)
+}
+
+// GetBP gets the bridge port from infradb
+func (infradbStore) GetBP(name string) (*infradb.BridgePort, error) {
+	return infradb.GetBP(name)
+}
+
+// GetSvi gets the svi from infradb
+func (infradbStore) GetSvi(name string) (*infradb.Svi, error) {
+	return infradb.GetSvi(name)
+}
+
+// GetAllBPs gets the bridge ports from infradb, none when it has none
+func (infradbStore) GetAllBPs() ([]*infradb.BridgePort, error) {
+	bps, err := infradb.GetAllBPs()
+	if errors.Is(err, infradb.ErrKeyNotFound) {
+		return nil, nil
+	}
+	return bps, err
+}
+
+// GetAllSvis gets the svis from infradb, none when it has none
+func (infradbStore) GetAllSvis() ([]*infradb.Svi, error) {
+	svis, err := infradb.GetAllSvis()
+	if errors.Is(err, infradb.ErrKeyNotFound) {
+		return nil, nil
+	}
+	return svis, err
+}
+
+// objects is the store the decoders read from
+var objects objectStore = infradbStore{}
+
+// snapshotStore serves the object snapshots of a recording
+type snapshotStore struct {
+	mu   sync.Mutex
+	vrfs map[string]*infradb.Vrf
+	lbs  map[string]*infradb.LogicalBridge
+	bps  map[string]*infradb.BridgePort
+	svis map[string]*infradb.Svi
+}
+
+// newSnapshotStore creates an empty snapshot store
+func newSnapshotStore() *snapshotStore {
+	return &snapshotStore{
+		vrfs: make(map[string]*infradb.Vrf),
+		lbs:  make(map[string]*infradb.LogicalBridge),
+		bps:  make(map[string]*infradb.BridgePort),
+		svis: make(map[string]*infradb.Svi),
+	}
+}
+
+// load stores the snapshots, replacing older versions of the same objects
+func (s *snapshotStore) load(snap *objectSnapshot) {
+	if snap == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, vrf := range snap.Vrfs {
+		s.vrfs[vrf.Name] = vrf
+	}
+	for _, lb := range snap.LogicalBridges {
+		s.lbs[lb.Name] = lb
+	}
+	for _, bp := range snap.BridgePorts {
+		s.bps[bp.Name] = bp
+	}
+	for _, svi := range snap.Svis {
+		s.svis[svi.Name] = svi
+	}
+}
+
+// GetVrf gets the vrf snapshot
+func (s *snapshotStore) GetVrf(name string) (*infradb.Vrf, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if vrf, ok := s.vrfs[name]; ok {
+		return vrf, nil
+	}
+	return nil, fmt.Errorf("vrf %s not in the recording: %w", name, infradb.ErrKeyNotFound)
+}
+
+// GetLB gets the logical bridge snapshot
+func (s *snapshotStore) GetLB(name string) (*infradb.LogicalBridge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lb, ok := s.lbs[name]; ok {
+		return lb, nil
+	}
+	return nil, fmt.Errorf("logical bridge %s not in the recording: %w", name, infradb.ErrKeyNotFound)
+}
+
+// GetBP gets the bridge port snapshot
+func (s *snapshotStore) GetBP(name string) (*infradb.BridgePort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bp, ok := s.bps[name]; ok {
+		return bp, nil
+	}
+	return nil, fmt.Errorf("bridge port %s not in the recording: %w", name, infradb.ErrKeyNotFound)
+}
+
+// GetSvi gets the svi snapshot
+func (s *snapshotStore) GetSvi(name string) (*infradb.Svi, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if svi, ok := s.svis[name]; ok {
+		return svi, nil
+	}
+	return nil, fmt.Errorf("svi %s not in the recording: %w", name, infradb.ErrKeyNotFound)
+}
+
+// GetAllBPs gets the bridge port snapshots
+func (s *snapshotStore) GetAllBPs() ([]*infradb.BridgePort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bps := make([]*infradb.BridgePort, 0, len(s.bps))
+	for _, bp := range s.bps {
+		bps = append(bps, bp)
+	}
+	return bps, nil
+}
+
+// GetAllSvis gets the svi snapshots
+func (s *snapshotStore) GetAllSvis() ([]*infradb.Svi, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	svis := make([]*infradb.Svi, 0, len(s.svis))
+	for _, svi := range s.svis {
+		svis = append(svis, svi)
+	}
+	return svis, nil
+}
+
+// missingBridgePorts returns the names of the config no bridge port of
+// infradb has, compared by the base of the names
+func missingBridgePorts(names []string) ([]string, error) {
+	bps, err := objects.GetAllBPs()
+	if err != nil {
+		return nil, err
+	}
+	known := make([]string, 0, len(bps))
+	for _, bp := range bps {
+		known = append(known, bp.Name)
+	}
+	return missingNames(names, known), nil
+}
+
+// missingSvis returns the names of the config no svi of infradb has
+func missingSvis(names []string) ([]string, error) {
+	svis, err := objects.GetAllSvis()
+	if err != nil {
+		return nil, err
+	}
+	known := make([]string, 0, len(svis))
+	for _, svi := range svis {
+		known = append(known, svi.Name)
+	}
+	return missingNames(names, known), nil
+}
+
+// missingNames returns the sorted names whose base is not among the known ones
+func missingNames(names []string, known []string) []string {
+	bases := make(map[string]bool)
+	for _, name := range known {
+		bases[path.Base(name)] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !bases[path.Base(name)] && !contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// checkConfiguredObjects logs the bridge ports and svis a config section
+// names that infradb does not have. The config may name an object ahead of
+// its creation, it applies once one of that name shows up, but a mistyped
+// or renamed object would otherwise go unnoticed.
+func checkConfiguredObjects(section string, bps []string, svis []string) {
+	missing, err := missingBridgePorts(bps)
+	if err != nil {
+		log.Printf("intel-e2000: %s: bridge ports not checked against infradb: %v\n", section, err)
+	} else if len(missing) != 0 {
+		log.Printf("intel-e2000: %s: bridge ports %v are not in infradb\n", section, missing)
+	}
+	missing, err = missingSvis(svis)
+	if err != nil {
+		log.Printf("intel-e2000: %s: svis not checked against infradb: %v\n", section, err)
+	} else if len(missing) != 0 {
+		log.Printf("intel-e2000: %s: svis %v are not in infradb\n", section, missing)
+	}
+}
+
+// requireConfiguredObjects rejects the bridge ports and svis a policy file
+// names that infradb does not have. The infradb objects carry no policy, the
+// file attaches the policies by name and may only name existing objects.
+func requireConfiguredObjects(section string, bps []string, svis []string) error {
+	missing, err := missingBridgePorts(bps)
+	if err != nil {
+		return fmt.Errorf("%s: bridge ports not checked against infradb: %w", section, err)
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("%s: bridge ports %v are not in infradb", section, missing)
+	}
+	missing, err = missingSvis(svis)
+	if err != nil {
+		return fmt.Errorf("%s: svis not checked against infradb: %w", section, err)
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("%s: svis %v are not in infradb", section, missing)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+)
+
+func TestMissingObjects(t *testing.T) {
+	store := newSnapshotStore()
+	store.load(&objectSnapshot{
+		BridgePorts: []*infradb.BridgePort{{Name: "//network.opiproject.org/ports/bp-vm1"}},
+		Svis:        []*infradb.Svi{{Name: "//network.opiproject.org/svis/svi-blue"}},
+	})
+	objects = store
+	t.Cleanup(func() { objects = infradbStore{} })
+
+	tests := map[string]struct {
+		bps     []string
+		svis    []string
+		missing []string
+	}{
+		"known by base name": {
+			bps: []string{"bp-vm1"},
+		},
+		"known by full name": {
+			bps: []string{"//network.opiproject.org/ports/bp-vm1"},
+		},
+		"mistyped bridge port": {
+			bps:     []string{"bp-vm2", "bp-vm1", "bp-vm2"},
+			missing: []string{"bp-vm2"},
+		},
+		"mistyped svi": {
+			svis:    []string{"svi-blue", "svi-bleu"},
+			missing: []string{"svi-bleu"},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			missing, err := missingBridgePorts(tt.bps)
+			if err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			svis, err := missingSvis(tt.svis)
+			if err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			missing = append(missing, svis...)
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("Expected missing: %v, received %v", tt.missing, missing)
+			}
+		})
+	}
+}
@@ -11,11 +11,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"os/exec"
 	"path"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/opiproject/opi-evpn-bridge/pkg/config"
@@ -23,8 +23,9 @@ import (
 	"github.com/opiproject/opi-evpn-bridge/pkg/infradb/common"
 	"github.com/opiproject/opi-evpn-bridge/pkg/infradb/subscriberframework/eventbus"
 	nm "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
-	eb "github.com/opiproject/opi-evpn-bridge/pkg/netlink/eventbus"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
 	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vport"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -74,22 +75,28 @@ func getMac(dev string) string {
 	return ""
 }
 
-// vportFromMac get the vport from the mac
+// vportFromMac get the vport of the representor with the mac
 func vportFromMac(mac string) int {
-	mbyte := strings.Split(mac, ":")
-	if len(mbyte) < 5 {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
 		return -1
 	}
-	byte0, _ := strconv.ParseInt(mbyte[0], 16, 64)
-	byte1, _ := strconv.ParseInt(mbyte[1], 16, 64)
-
-	return int(byte0<<8 + byte1)
+	id, err := vport.OfRepresentor(hw)
+	if err != nil {
+		log.Printf("intel-e2000: %v\n", err)
+		return -1
+	}
+	return id
 }
 
 // idsOf  get the mac vsi from nexthop id
 func idsOf(value string) (string, string, error) {
 	if isValidMAC(value) {
-		return strconv.Itoa(vportFromMac(value)), value, nil
+		vsi := vportFromMac(value)
+		if vsi == -1 {
+			return "", "", fmt.Errorf("failed to get id")
+		}
+		return strconv.Itoa(vsi), value, nil
 	}
 
 	mac := getMac(value)
@@ -108,456 +115,178 @@ var (
 	Conn *grpc.ClientConn
 )
 
-// startSubscriber  set the subscriber handlers
-func startSubscriber(eventBus *eb.EventBus, eventType string) {
-	subscriber := eventBus.Subscribe(eventType)
-
-	go func() {
-		for {
-			select {
-			case event := <-subscriber.Ch:
-				log.Printf("intel-e2000: Subscriber for %s received event\n", eventType)
-				switch eventType {
-				case "route_added":
-					handleRouteAdded(event)
-				case "route_updated":
-					handleRouteUpdated(event)
-				case "route_deleted":
-					handleRouteDeleted(event)
-				case "nexthop_added":
-					handleNexthopAdded(event)
-				case "nexthop_updated":
-					handleNexthopUpdated(event)
-				case "nexthop_deleted":
-					handleNexthopDeleted(event)
-				case "fdb_entry_added":
-					handleFbdEntryAdded(event)
-				case "fdb_entry_updated":
-					handleFbdEntryUpdated(event)
-				case "fdb_entry_deleted":
-					handleFbdEntryDeleted(event)
-				case "l2_nexthop_added":
-					handleL2NexthopAdded(event)
-				case "l2_nexthop_updated":
-					handleL2NexthopUpdated(event)
-				case "l2_nexthop_deleted":
-					handleL2NexthopDeleted(event)
-				}
-			case <-subscriber.Quit:
-				return
-			}
-		}
-	}()
+// netlinkPipeline orders the netlink events before they reach the decoders
+var netlinkPipeline *Pipeline
+
+// handleNetlinkEvent programs a netlink event released by the pipeline
+func handleNetlinkEvent(eventType string, event interface{}) {
+	if recorder == nil {
+		dispatchNetlinkEvent(eventType, event)
+		return
+	}
+	recorder.track(newNetlinkRecord(eventType, event), func() {
+		dispatchNetlinkEvent(eventType, event)
+	})
+}
+
+// dispatchNetlinkEvent calls the handler of the netlink event type
+func dispatchNetlinkEvent(eventType string, event interface{}) {
+	switch eventType {
+	case nm.RouteAdded:
+		handleRouteAdded(event)
+	case nm.RouteUpdated:
+		handleRouteUpdated(event)
+	case nm.RouteDeleted:
+		handleRouteDeleted(event)
+	case nm.NexthopAdded:
+		handleNexthopAdded(event)
+	case nm.NexthopUpdated:
+		handleNexthopUpdated(event)
+	case nm.NexthopDeleted:
+		handleNexthopDeleted(event)
+	case nm.FdbEntryAdded:
+		handleFbdEntryAdded(event)
+	case nm.FdbEntryUpdated:
+		handleFbdEntryUpdated(event)
+	case nm.FdbEntryDeleted:
+		handleFbdEntryDeleted(event)
+	case nm.L2NexthopAdded:
+		handleL2NexthopAdded(event)
+	case nm.L2NexthopUpdated:
+		handleL2NexthopUpdated(event)
+	case nm.L2NexthopDeleted:
+		handleL2NexthopDeleted(event)
+	}
+	if syncsAliases(eventType) {
+		syncAliases()
+	}
 }
 
 // handleRouteAdded  handles the added route
 func handleRouteAdded(route interface{}) {
-	var entries []interface{}
 	routeData, _ := route.(*nm.RouteStruct)
 	if routeData != nil {
-		entries = L3.translateAddedRoute(*routeData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Printf("intel-e2000: Entry is not of type p4client.TableEntry:- %v\n", e)
-			}
-		}
+		entries, err := routeEntries(OpAdded, *routeData)
+		applyNetlinkEntries(OpAdded, "route", entries, err)
 	}
 }
 
 // handleRouteUpdated  handles the updated route
 func handleRouteUpdated(route interface{}) {
-	var entries []interface{}
 	routeData, _ := route.(*nm.RouteStruct)
 	if routeData != nil {
-		entries = L3.translateDeletedRoute(*routeData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				err := p4client.DelEntry(e)
-				if err != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, err)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = L3.translateAddedRoute(*routeData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := routeEntries(OpDeleted, *routeData)
+		applyNetlinkEntries(OpDeleted, "route", entries, err)
+		entries, err = routeEntries(OpAdded, *routeData)
+		applyNetlinkEntries(OpAdded, "route", entries, err)
 	}
 }
 
 // handleRouteDeleted  handles the deleted route
 func handleRouteDeleted(route interface{}) {
-	var entries []interface{}
 	routeData, _ := route.(*nm.RouteStruct)
 	if routeData != nil {
-		entries = L3.translateDeletedRoute(*routeData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := routeEntries(OpDeleted, *routeData)
+		applyNetlinkEntries(OpDeleted, "route", entries, err)
 	}
 }
 
 // handleNexthopAdded  handles the added nexthop
 func handleNexthopAdded(nexthop interface{}) {
-	var entries []interface{}
 	nexthopData, _ := nexthop.(*nm.NexthopStruct)
 	if nexthopData != nil {
-		entries = L3.translateAddedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Vxlan.translateAddedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := nexthopEntries(OpAdded, *nexthopData)
+		applyNetlinkEntries(OpAdded, "nexthop", entries, err)
 	}
 }
 
 // handleNexthopUpdated  handles the updated nexthop
-//
-//gocognit:ignore
 func handleNexthopUpdated(nexthop interface{}) {
-	var entries []interface{}
 	nexthopData, _ := nexthop.(*nm.NexthopStruct)
 	if nexthopData != nil {
-		entries = L3.translateDeletedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Vxlan.translateDeletedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = L3.translateAddedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Vxlan.translateAddedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := nexthopEntries(OpDeleted, *nexthopData)
+		applyNetlinkEntries(OpDeleted, "nexthop", entries, err)
+		entries, err = nexthopEntries(OpAdded, *nexthopData)
+		applyNetlinkEntries(OpAdded, "nexthop", entries, err)
 	}
 }
 
 // handleNexthopDeleted  handles the deleted nexthop
 func handleNexthopDeleted(nexthop interface{}) {
-	var entries []interface{}
 	nexthopData, _ := nexthop.(*nm.NexthopStruct)
 	if nexthopData != nil {
-		entries = L3.translateDeletedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Vxlan.translateDeletedNexthop(*nexthopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := nexthopEntries(OpDeleted, *nexthopData)
+		applyNetlinkEntries(OpDeleted, "nexthop", entries, err)
 	}
 }
 
 // handleFbdEntryAdded  handles the added fdb entry
 func handleFbdEntryAdded(fbdEntry interface{}) {
-	var entries []interface{}
 	fbdEntryData, _ := fbdEntry.(*nm.FdbEntryStruct)
 	if fbdEntryData != nil {
-		entries = Vxlan.translateAddedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateAddedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := fdbEntries(OpAdded, *fbdEntryData)
+		applyNetlinkEntries(OpAdded, "fdb entry", entries, err)
 	}
 }
 
 // handleFbdEntryUpdated  handles the updated fdb entry
-//
-//gocognit:ignore
 func handleFbdEntryUpdated(fdbEntry interface{}) {
-	var entries []interface{}
 	fbdEntryData, _ := fdbEntry.(*nm.FdbEntryStruct)
 	if fbdEntryData != nil {
-		entries = Vxlan.translateDeletedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateDeletedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-
-		entries = Vxlan.translateAddedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateAddedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := fdbEntries(OpDeleted, *fbdEntryData)
+		applyNetlinkEntries(OpDeleted, "fdb entry", entries, err)
+		entries, err = fdbEntries(OpAdded, *fbdEntryData)
+		applyNetlinkEntries(OpAdded, "fdb entry", entries, err)
 	}
 }
 
 // handleFbdEntryDeleted  handles the deleted fdb entry
 func handleFbdEntryDeleted(fdbEntry interface{}) {
-	var entries []interface{}
 	fbdEntryData, _ := fdbEntry.(*nm.FdbEntryStruct)
 	if fbdEntryData != nil {
-		entries = Vxlan.translateDeletedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateDeletedFdb(*fbdEntryData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := fdbEntries(OpDeleted, *fbdEntryData)
+		applyNetlinkEntries(OpDeleted, "fdb entry", entries, err)
 	}
 }
 
 // handleL2NexthopAdded  handles the added l2 nexthop
 func handleL2NexthopAdded(l2NextHop interface{}) {
-	var entries []interface{}
 	l2NextHopData, _ := l2NextHop.(*nm.L2NexthopStruct)
 	if l2NextHopData != nil {
-		entries = Vxlan.translateAddedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateAddedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := l2NexthopEntries(OpAdded, *l2NextHopData)
+		applyNetlinkEntries(OpAdded, "l2 nexthop", entries, err)
 	}
 }
 
 // handleL2NexthopUpdated  handles the updated l2 nexthop
-//
-//gocognit:ignore
 func handleL2NexthopUpdated(l2NextHop interface{}) {
-	var entries []interface{}
 	l2NextHopData, _ := l2NextHop.(*nm.L2NexthopStruct)
 	if l2NextHopData != nil {
-		entries = Vxlan.translateDeletedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateDeletedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Vxlan.translateAddedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateAddedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.AddEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("iintel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := l2NexthopEntries(OpDeleted, *l2NextHopData)
+		applyNetlinkEntries(OpDeleted, "l2 nexthop", entries, err)
+		entries, err = l2NexthopEntries(OpAdded, *l2NextHopData)
+		applyNetlinkEntries(OpAdded, "l2 nexthop", entries, err)
 	}
 }
 
 // handleL2NexthopDeleted  handles the deleted l2 nexthop
 func handleL2NexthopDeleted(l2NextHop interface{}) {
-	var entries []interface{}
 	l2NextHopData, _ := l2NextHop.(*nm.L2NexthopStruct)
 	if l2NextHopData != nil {
-		entries = Vxlan.translateDeletedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				err := p4client.DelEntry(e)
-				if err != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, err)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
-		entries = Pod.translateDeletedL2Nexthop(*l2NextHopData)
-		for _, entry := range entries {
-			if e, ok := entry.(p4client.TableEntry); ok {
-				er := p4client.DelEntry(e)
-				if er != nil {
-					log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-				}
-			} else {
-				log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			}
-		}
+		entries, err := l2NexthopEntries(OpDeleted, *l2NextHopData)
+		applyNetlinkEntries(OpDeleted, "l2 nexthop", entries, err)
+	}
+}
+
+// applyNetlinkEntries programs the entries the decoders produced for a
+// netlink event, or logs why the decoders failed to translate it
+func applyNetlinkEntries(op Operation, kind string, entries []interface{}, err error) {
+	if err != nil {
+		log.Printf("intel-e2000: failed to translate the %s %s: %v\n", op, kind, err)
+		return
+	}
+	if err := applyEntries(op, entries); err != nil {
+		log.Printf("intel-e2000: failed to program the %s %s: %v\n", op, kind, err)
 	}
 }
 
@@ -915,82 +644,62 @@ func handlesvi(objectData *eventbus.ObjectData) {
 	}
 }
 
+// applyObjectEntries programs the entries the decoders produced for an infradb object
+func applyObjectEntries(op Operation, caller string, entries []interface{}, err error) (string, bool) {
+	if err != nil {
+		return err.Error(), false
+	}
+	if err := applyEntries(op, entries); err != nil {
+		return fmt.Sprintf("intel-e2000 %s: %v", caller, err), false
+	}
+	return "", true
+}
+
+// translateObject collects and programs the entries of an infradb object
+func translateObject(op Operation, eventType string, caller string, obj interface{}, collectFn func() ([]interface{}, error)) (string, bool) {
+	var details string
+	var status bool
+	handle := func() {
+		entries, err := collectFn()
+		details, status = applyObjectEntries(op, caller, entries, err)
+	}
+	if recorder == nil {
+		handle()
+	} else {
+		recorder.track(newObjectRecord(eventType, op, obj), handle)
+	}
+	return details, status
+}
+
 // offloadVrf  offload the vrf events
 func offloadVrf(vrf *infradb.Vrf) (string, bool) {
 	if path.Base(vrf.Name) == grdStr {
 		return "", true
 	}
-
-	entries := Vxlan.translateAddedVrf(vrf)
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.AddEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry:-", e)
-			return fmt.Sprintf("intel-e2000: Entry is not of type p4client.TableEntry:-%v", e), false
-		}
-	}
-	return "", true
+	return translateObject(OpAdded, "vrf", "offloadVrf", vrf, func() ([]interface{}, error) {
+		return vrfEntries(OpAdded, vrf)
+	})
 }
 
 // setUpLb  set up the logical bridge
 func setUpLb(lb *infradb.LogicalBridge) (string, bool) {
-	entries := Vxlan.translateAddedLb(lb)
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.AddEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry:-", e)
-			return fmt.Sprintf("intel-e2000 setUpLb: Entry is not of type p4client.TableEntry:-%v", e), false
-		}
-	}
-	return "", true
+	return translateObject(OpAdded, "logical-bridge", "setUpLb", lb, func() ([]interface{}, error) {
+		return lbEntries(OpAdded, lb)
+	})
 }
 
 // setUpBp  set up the bridge port
 func setUpBp(bp *infradb.BridgePort) (string, bool) {
-	entries, err := Pod.translateAddedBp(bp)
-	if err != nil {
-		return err.Error(), false
-	}
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.AddEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry:-", e)
-			return fmt.Sprintf("intel-e2000 setUpBp: Entry is not of type p4client.TableEntry:-%v", e), false
-		}
-	}
-	return "", true
+	return translateObject(OpAdded, "bridge-port", "setUpBp", bp, func() ([]interface{}, error) {
+		return bpEntries(OpAdded, bp)
+	})
 }
 
 // setUpSvi  set up the svi
 func setUpSvi(svi *infradb.Svi) (string, bool) {
-	entries, err := Pod.translateAddedSvi(svi)
-	if err != nil {
-		return err.Error(), false
-	}
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.AddEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry:-", e)
-			return fmt.Sprintf("intel-e2000 setUpBp: Entry is not of type p4client.TableEntry:-%v", e), false
-		}
-	}
-	return "", true
+	return translateObject(OpAdded, "svi", "setUpSvi", svi, func() ([]interface{}, error) {
+		return sviEntries(OpAdded, svi)
+	})
 }
 
 // tearDownVrf  tear down the vrf
@@ -998,96 +707,49 @@ func tearDownVrf(vrf *infradb.Vrf) (string, bool) {
 	if path.Base(vrf.Name) == grdStr {
 		return "", true
 	}
-	// var entries []interface{}
-	entries := Vxlan.translateDeletedVrf(vrf)
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.DelEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			return fmt.Sprintf("intel-e2000 tearDownVrf: Entry is not of type p4client.TableEntry"), false
-		}
-	}
-	return "", true
+	return translateObject(OpDeleted, "vrf", "tearDownVrf", vrf, func() ([]interface{}, error) {
+		return vrfEntries(OpDeleted, vrf)
+	})
 }
 
 // tearDownLb  tear down the logical bridge
 func tearDownLb(lb *infradb.LogicalBridge) (string, bool) {
-	entries := Vxlan.translateDeletedLb(lb)
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.DelEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			return fmt.Sprintf("intel-e2000 tearDownLb: Entry is not of type p4client.TableEntry"), false
-		}
-	}
-	return "", true
+	return translateObject(OpDeleted, "logical-bridge", "tearDownLb", lb, func() ([]interface{}, error) {
+		return lbEntries(OpDeleted, lb)
+	})
 }
 
 // tearDownBp  tear down the bridge port
 func tearDownBp(bp *infradb.BridgePort) (string, bool) {
-	entries, err := Pod.translateDeletedBp(bp)
-	if err != nil {
-		return err.Error(), false
-	}
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.DelEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			return fmt.Sprintf("intel-e2000 tearDownBp: Entry is not of type p4client.TableEntry"), false
-		}
-	}
-	return "", true
+	return translateObject(OpDeleted, "bridge-port", "tearDownBp", bp, func() ([]interface{}, error) {
+		return bpEntries(OpDeleted, bp)
+	})
 }
 
 // tearDownSvi  tear down the svi
 func tearDownSvi(svi *infradb.Svi) (string, bool) {
-	entries, err := Pod.translateDeletedSvi(svi)
-	if err != nil {
-		return err.Error(), false
-	}
-	for _, entry := range entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.DelEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-			return fmt.Sprintf("intel-e2000 tearDownSvi: Entry is not of type p4client.TableEntry"), false
-		}
-	}
-	return "", true
+	return translateObject(OpDeleted, "svi", "tearDownSvi", svi, func() ([]interface{}, error) {
+		return sviEntries(OpDeleted, svi)
+	})
 }
 
 // Initialize function handles init functionality
 //
 //gocognit:ignore
 func Initialize() {
+	if err := loadPluginConfig(); err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
+	}
+	if file := pluginCfg.P4.RecordFile; file != "" {
+		if err := startRecording(file); err != nil {
+			log.Printf("intel-e2000: Failed to start recording into %s: %v\n", file, err)
+		} else {
+			log.Printf("intel-e2000: Recording events into %s\n", file)
+		}
+	}
 	// Netlink Listener
-	startSubscriber(nm.EventBus, nm.RouteAdded)
-	startSubscriber(nm.EventBus, nm.RouteUpdated)
-	startSubscriber(nm.EventBus, nm.RouteDeleted)
-	startSubscriber(nm.EventBus, nm.NexthopAdded)
-	startSubscriber(nm.EventBus, nm.NexthopUpdated)
-	startSubscriber(nm.EventBus, nm.NexthopDeleted)
-	startSubscriber(nm.EventBus, nm.FdbEntryAdded)
-	startSubscriber(nm.EventBus, nm.FdbEntryUpdated)
-	startSubscriber(nm.EventBus, nm.FdbEntryDeleted)
-	startSubscriber(nm.EventBus, nm.L2NexthopAdded)
-	startSubscriber(nm.EventBus, nm.L2NexthopUpdated)
-	startSubscriber(nm.EventBus, nm.L2NexthopDeleted)
+	netlinkPipeline = NewPipeline(handleNetlinkEvent)
+	netlinkPipeline.Subscribe(nm.EventBus)
 	// InfraDB Listener
 
 	eb := eventbus.EBus
@@ -1098,22 +760,44 @@ func Initialize() {
 			}
 		}
 	}
-	// Setup p4runtime connection
-	Conn, err := grpc.Dial(defaultAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Fatalf("intel-e2000: Cannot connect to server: %v\n", err)
-	}
-
-	err1 := p4client.NewP4RuntimeClient(config.GlobalConfig.P4.Config.BinFile, config.GlobalConfig.P4.Config.P4infoFile, Conn)
-	if err1 != nil {
-		log.Printf("intel-e2000: Failed to create P4Runtime client: %v\n", err1)
+	setUpSchema()
+	setUpArpSuppression()
+	setUpMirroring()
+	setUpMultihoming()
+	setUpAliasing()
+	setUpQos(pluginCfg.Qos)
+	setUpIrb(pluginCfg.Irb)
+	setUpVxlan(pluginCfg.Vxlan)
+	setUpUplinks(pluginCfg.Uplinks)
+	setUpStaticFile()
+	setUpAcls()
+	setUpPbr()
+	setUpPortSecurity(pluginCfg.PortSecurity)
+	setUpPortMacs()
+	setUpVlanMap()
+	setUpPortMux()
+	if dryRun() {
+		// Record the entries instead of programming the device
+		log.Printf("intel-e2000: p4 disabled, running in dry run mode\n")
+		p4client.SetDryRun(journal.Default)
+		if pipelineInfo != nil {
+			// the debug textproto dump takes its ids from the p4info file
+			if err := p4client.SetP4Info(pipelineInfo); err != nil {
+				log.Printf("intel-e2000: Failed to load the p4info for dry run: %v\n", err)
+			}
+		}
+	} else {
+		connectP4Runtime()
 	}
-	time.Sleep(time.Second * 60)
 	// add static rules into the pipeline of representators read from config
 	representors := make(map[string][2]string)
 
 	// Add the physical port representors
 	for i, port := range config.GlobalConfig.Interfaces.PhyPorts {
+		if isUplinkGroup(port.Rep) {
+			// the bond of an uplink group egresses on its members
+			continue
+		}
 		key := fmt.Sprintf("phy%d_rep", i)
 		vsi, mac, err := idsOf(port.Rep)
 		if err != nil {
@@ -1146,65 +830,212 @@ func Initialize() {
 		representors["vrf_mux"] = [2]string{vrfMuxVsi, vrfMuxMac}
 	}
 
-	portMuxVsi, portMuxMac, err := idsOf(config.GlobalConfig.Interfaces.PortMux)
-	if err != nil {
-		log.Printf("Error getting ids for port_mux: %v", err)
-	} else {
-		representors["port_mux"] = [2]string{portMuxVsi, portMuxMac}
-	}
+	portMuxRepresentors(representors)
 	log.Printf("intel-e2000: REPRESENTORS %+v\n", representors)
+	setUpDecoders(representors)
+	restoreMirrorSessions()
+	uplinkDone = make(chan struct{})
+	watchUplinks(uplinkDone)
+	portSecurityDone = make(chan struct{})
+	watchDhcp(portSecurityDone)
+	portMacsDone = make(chan struct{})
+	watchPortMacs(portMacsDone)
+	frrDone = make(chan struct{})
+	watchFrr(frrDone)
+}
+
+// setUpDecoders initializes the decoders and programs their static entries
+func setUpDecoders(representors map[string][2]string) {
+	recorder.track(&eventRecord{Source: sourceInit, Op: OpAdded.String(), Representors: representors}, func() {
+		initDecoders(representors)
+		_ = addEntries(staticEntries(OpAdded))
+	})
+}
+
+// tearDownDecoders removes the static entries of the decoders
+func tearDownDecoders() {
+	recorder.track(&eventRecord{Source: sourceInit, Op: OpDeleted.String()}, func() {
+		_ = delEntries(staticEntries(OpDeleted))
+	})
+}
+
+// initDecoders sets up the decoders for the representors and registers them
+func initDecoders(representors map[string][2]string) {
+	activeRepresentors = representors
 	L3 = L3.L3DecoderInit(representors)
 	Pod = Pod.PodDecoderInit(representors)
 	Vxlan = Vxlan.VxlanDecoderInit(representors)
-	L3entries := L3.StaticAdditions()
-	for _, entry := range L3entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.AddEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
-		}
+	RegisterDecoder(&L3)
+	RegisterDecoder(&Vxlan)
+	RegisterDecoder(&Pod)
+	if portSecurity != nil {
+		RegisterDecoder(portSecurity)
 	}
-	Podentries := Pod.StaticAdditions()
-	for _, entry := range Podentries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.AddEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error adding entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
+	if portMacs != nil {
+		RegisterDecoder(portMacs)
+	}
+	if acls != nil {
+		RegisterDecoder(acls)
+	}
+	if pbr != nil {
+		RegisterDecoder(pbr)
+	}
+	if mirroring {
+		RegisterDecoder(MirrorDecoder{})
+	}
+	if staticFile != nil {
+		// registered last so its entries go in after the built-in ones
+		RegisterDecoder(staticFile)
+	}
+}
+
+// setUpSchema loads the pipeline schema given in the config and checks it
+// against the p4info of the pipeline
+func setUpSchema() {
+	if file := pluginCfg.P4.Schema; file != "" {
+		s, err := loadSchema(file)
+		if err != nil {
+			log.Fatalf("intel-e2000: Failed to load the pipeline schema: %v\n", err)
 		}
+		schema = s
+	}
+	info, err := loadPipelineInfo(config.GlobalConfig.P4.Config.P4infoFile, dryRun())
+	if err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
+	}
+	if info == nil {
+		log.Printf("intel-e2000: Pipeline schema %s not checked in dry run\n", schema.Pipeline)
+		return
+	}
+	warnings, err := schema.check(info)
+	if err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
 	}
+	for _, w := range warnings {
+		log.Printf("intel-e2000: Pipeline %s: %s, refusing the features that need it\n", schema.Pipeline, w)
+	}
+	pipelineInfo = info
+	log.Printf("intel-e2000: Pipeline schema %s matches the p4info\n", schema.Pipeline)
 }
 
-// DeInitialize function handles stops functionality
-func DeInitialize() {
-	L3entries := L3.StaticDeletions()
-	for _, entry := range L3entries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.DelEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
+// dryRun tells whether the entries are recorded instead of programmed
+func dryRun() bool {
+	return journal.Enabled() || !config.GlobalConfig.P4.Enabled
+}
+
+// setUpStaticFile loads the static entries file given in the config
+func setUpStaticFile() {
+	file := pluginCfg.P4.StaticEntries
+	if file == "" {
+		return
+	}
+	d, err := NewStaticFileDecoder(file)
+	if err != nil {
+		log.Fatalf("intel-e2000: Failed to load the static entries: %v\n", err)
+	}
+	staticFile = d
+	log.Printf("intel-e2000: Loaded %d static entries from %s\n", len(d.entries), file)
+}
+
+// setUpAcls loads the acl policy file given in the config, the pipeline
+// needs the acl tables to enforce them
+func setUpAcls() {
+	file := pluginCfg.P4.AclPolicy
+	if file == "" {
+		return
+	}
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, aclBp, aclSvi) {
+		log.Fatalf("intel-e2000: acls need the %s and %s tables in pipeline %s\n",
+			schema.tableName(aclBp), schema.tableName(aclSvi), schema.Pipeline)
+	}
+	d, err := NewAclDecoder(file)
+	if err != nil {
+		log.Fatalf("intel-e2000: Failed to load the acl policies: %v\n", err)
+	}
+	acls = d
+	log.Printf("intel-e2000: Loaded %d acl policies from %s\n", len(d.policies.rules), file)
+}
+
+// setUpPbr loads the pbr policy file given in the config, the pipeline
+// needs the pbr table to steer the flows
+func setUpPbr() {
+	file := pluginCfg.P4.PbrPolicy
+	if file == "" {
+		return
+	}
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, pbrTable) {
+		log.Fatalf("intel-e2000: pbr needs the %s table in pipeline %s\n",
+			schema.tableName(pbrTable), schema.Pipeline)
+	}
+	d, err := NewPbrDecoder(file)
+	if err != nil {
+		log.Fatalf("intel-e2000: Failed to load the pbr policies: %v\n", err)
+	}
+	pbr = d
+	log.Printf("intel-e2000: Loaded %d pbr policies from %s\n", len(d.policies.rules), file)
+}
+
+// Reload reconciles the static entries, the acl entries and the pbr entries
+// with the files given in the config
+func Reload() error {
+	if staticFile == nil && acls == nil && pbr == nil {
+		return fmt.Errorf("no static entries, acl or pbr policy file configured")
+	}
+	if staticFile != nil {
+		if err := ReloadStaticEntries(); err != nil {
+			return err
 		}
 	}
-	Podentries := Pod.StaticDeletions()
-	for _, entry := range Podentries {
-		if e, ok := entry.(p4client.TableEntry); ok {
-			er := p4client.DelEntry(e)
-			if er != nil {
-				log.Printf("intel-e2000: error deleting entry for %v error %v\n", e.Tablename, er)
-			}
-		} else {
-			log.Println("intel-e2000: Entry is not of type p4client.TableEntry")
+	if acls != nil {
+		if err := ReloadAclPolicies(); err != nil {
+			return err
 		}
 	}
+	if pbr != nil {
+		return ReloadPbrPolicies()
+	}
+	return nil
+}
+
+// connectP4Runtime sets up the p4runtime connection to infrap4d
+func connectP4Runtime() {
+	var err error
+	Conn, err = grpc.Dial(defaultAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("intel-e2000: Cannot connect to server: %v\n", err)
+	}
+
+	err1 := p4client.NewP4RuntimeClient(config.GlobalConfig.P4.Config.BinFile, config.GlobalConfig.P4.Config.P4infoFile, Conn)
+	if err1 != nil {
+		log.Printf("intel-e2000: Failed to create P4Runtime client: %v\n", err1)
+	}
+	time.Sleep(time.Second * 60)
+}
 
+// DeInitialize function handles stops functionality
+func DeInitialize() {
 	// unsubscriber all the events
 	nm.EventBus.Unsubscribe()
+	if netlinkPipeline != nil {
+		netlinkPipeline.Stop()
+	}
+
+	if uplinkDone != nil {
+		close(uplinkDone)
+		uplinkDone = nil
+	}
+	if portSecurityDone != nil {
+		close(portSecurityDone)
+		portSecurityDone = nil
+	}
+	if portMacsDone != nil {
+		close(portMacsDone)
+		portMacsDone = nil
+	}
+	if frrDone != nil {
+		close(frrDone)
+		frrDone = nil
+	}
+	tearDownDecoders()
+	stopRecording()
 }
@@ -0,0 +1,388 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"gopkg.in/yaml.v3"
+)
+
+// pbrStr is the name of the pbr decoder
+const pbrStr = "pbr"
+
+// pbrRule is a rule of a pbr policy. The five tuple is matched like the acl
+// rules, the fields left out match anything. The flow is steered to the
+// bridge port or to the nexthop of the vrf, a firewall vsi or a remote vtep.
+type pbrRule struct {
+	SrcIP      string `yaml:"srcip"`
+	DstIP      string `yaml:"dstip"`
+	Protocol   string `yaml:"protocol"`
+	SrcPort    string `yaml:"srcport"`
+	DstPort    string `yaml:"dstport"`
+	BridgePort string `yaml:"bridgeport"`
+	Vrf        string `yaml:"vrf"`
+	Nexthop    string `yaml:"nexthop"`
+}
+
+// pbrPolicy is an ordered list of rules, the first matching rule wins.
+// Packets matching no rule take the vrf lookup.
+type pbrPolicy struct {
+	Name  string    `yaml:"name"`
+	Rules []pbrRule `yaml:"rules"`
+}
+
+// pbrPolicyFile is the layout of the pbr policy file, the ingress bridge
+// ports are given by name with their policy
+type pbrPolicyFile struct {
+	Policies    []pbrPolicy       `yaml:"policies"`
+	BridgePorts map[string]string `yaml:"bridgeports"`
+}
+
+// pbrMatch is a validated rule, the target is the base name of a bridge
+// port or the vrf and address of a nexthop
+type pbrMatch struct {
+	fields     map[string][2]interface{}
+	bridgePort string
+	vrf        string
+	nexthop    string
+}
+
+// pbrPolicies are the validated policies of the file
+type pbrPolicies struct {
+	rules       map[string][]pbrMatch
+	bridgePorts map[string]string
+}
+
+// pbrEntry is a programmed entry with the nexthop it points to, nil when it
+// steers to a bridge port
+type pbrEntry struct {
+	entry   p4client.TableEntry
+	nexthop interface{}
+}
+
+// PbrDecoder steers the flows of the bridge ports matching the pbr policies
+// to a bridge port or nexthop ahead of the vrf lookup. The infradb objects
+// carry no policy, the file attaches the policies to the bridge ports by
+// name. A rule is programmed while its ingress port, its target port or vrf
+// are in infradb and its nexthop is known, and removed when one of them is
+// deleted.
+type PbrDecoder struct {
+	mu       sync.Mutex
+	path     string
+	policies pbrPolicies
+	vports   map[string]uint16
+	vrfs     map[string]bool
+	nexthops map[string]netlink_polling.NexthopStruct
+	entries  map[string]pbrEntry
+}
+
+// pbr is the pbr decoder, nil when no policy file is configured
+var pbr *PbrDecoder
+
+// NewPbrDecoder loads and validates the pbr policy file
+func NewPbrDecoder(path string) (*PbrDecoder, error) {
+	policies, err := readPbrPolicies(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := policies.check(); err != nil {
+		return nil, err
+	}
+	return &PbrDecoder{
+		path:     path,
+		policies: policies,
+		vports:   make(map[string]uint16),
+		vrfs:     make(map[string]bool),
+		nexthops: make(map[string]netlink_polling.NexthopStruct),
+		entries:  make(map[string]pbrEntry),
+	}, nil
+}
+
+// Name returns the decoder name
+func (p *PbrDecoder) Name() string {
+	return pbrStr
+}
+
+// OnBridgePort steers the flows of the bridge port and to it
+func (p *PbrDecoder) OnBridgePort(op Operation, bp *infradb.BridgePort) ([]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	name := path.Base(bp.Name)
+	if op == OpDeleted {
+		delete(p.vports, name)
+		dels, _ := p.sync(true, false)
+		return dels, nil
+	}
+	vsi, err := strconv.ParseUint(bp.Metadata.VPort, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	p.vports[name] = uint16(vsi)
+	_, adds := p.sync(false, true)
+	return adds, nil
+}
+
+// OnVrf steers the flows to the nexthops of the vrf, its deletion removes
+// the rules targeting them ahead of the nexthops going away
+func (p *PbrDecoder) OnVrf(op Operation, vrf *infradb.Vrf) ([]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	name := path.Base(vrf.Name)
+	if op == OpDeleted {
+		delete(p.vrfs, name)
+		dels, _ := p.sync(true, false)
+		return dels, nil
+	}
+	p.vrfs[name] = true
+	_, adds := p.sync(false, true)
+	return adds, nil
+}
+
+// OnNexthop steers the flows of the rules targeting the nexthop
+func (p *PbrDecoder) OnNexthop(op Operation, nexthop netlink_polling.NexthopStruct) ([]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	target := pbrNexthop(nexthop.Key.VrfName, nexthop.Key.Dst)
+	if op == OpDeleted {
+		if nh, ok := p.nexthops[target]; !ok || nh.Key != nexthop.Key {
+			return nil, nil
+		}
+		delete(p.nexthops, target)
+		dels, _ := p.sync(true, false)
+		return dels, nil
+	}
+	p.nexthops[target] = nexthop
+	_, adds := p.sync(false, true)
+	return adds, nil
+}
+
+// sync brings the entries in line with the policies and the known targets.
+// It returns the deletions of the entries that went away when dels is set
+// and the entries that are new or rewritten when adds is set, the others
+// are left for a later sync.
+func (p *PbrDecoder) sync(dels bool, adds bool) ([]interface{}, []interface{}) {
+	wanted := p.wanted()
+	var removed, added []interface{}
+	for _, key := range sortedEntryKeys(p.entries) {
+		if _, ok := wanted[key]; ok || !dels {
+			continue
+		}
+		e := p.entries[key]
+		delete(p.entries, key)
+		removed = append(removed, p4client.TableEntry{Tablename: e.entry.Tablename, TableField: e.entry.TableField})
+		removed = append(removed, nexthopRefs.releaseUser(pbrStr+"/"+key)...)
+	}
+	for _, key := range sortedEntryKeys(wanted) {
+		w := wanted[key]
+		old, ok := p.entries[key]
+		if !adds || (ok && staticEntryString(old.entry) == staticEntryString(w.entry)) {
+			continue
+		}
+		if ok {
+			// a rewritten entry lets go of its old target, which is still
+			// there: a deleted one took the entry out with it
+			removed = append(removed, nexthopRefs.releaseUser(pbrStr+"/"+key)...)
+		}
+		if w.nexthop != nil {
+			// the nexthop entries stay while the rule points at them
+			nexthopRefs.retain(w.nexthop, pbrStr+"/"+key)
+		}
+		p.entries[key] = w
+		added = append(added, w.entry)
+	}
+	return removed, added
+}
+
+// wanted builds the entries of the rules whose ingress port and target are
+// known, by their match. A nexthop target also needs its vrf in infradb. The first rule gets the highest priority.
+func (p *PbrDecoder) wanted() map[string]pbrEntry {
+	wanted := make(map[string]pbrEntry)
+	for name, vsi := range p.vports {
+		rules := p.policies.rules[p.policies.bridgePorts[name]]
+		for i, r := range rules {
+			fields := map[string][2]interface{}{
+				"vsi": {exactTernary(uint16toBytes(vsi)), "ternary"},
+			}
+			for f, v := range r.fields {
+				fields[f] = v
+			}
+			e := pbrEntry{entry: p4client.TableEntry{
+				Tablename: pbrTable,
+				TableField: p4client.TableField{
+					FieldValue: fields,
+					Priority:   int32(len(rules) - i),
+				},
+			}}
+			if r.bridgePort != "" {
+				port, ok := p.vports[r.bridgePort]
+				if !ok {
+					continue
+				}
+				e.entry.Action = p4client.Action{
+					ActionName: "evpn_gw_control.pbr_fwd_to_port",
+					Params:     []interface{}{uint32(_toEgressVsi(int(port)))},
+				}
+			} else {
+				nexthop, ok := p.nexthops[r.nexthop]
+				if !ok || !p.vrfs[r.vrf] {
+					continue
+				}
+				e.entry.Action = p4client.Action{
+					ActionName: "evpn_gw_control.pbr_set_neighbor",
+					Params:     []interface{}{uint16(_p4NexthopID(nexthop, Direction.Tx))},
+				}
+				e.nexthop = nexthop.Key
+			}
+			wanted[staticEntryString(p4client.TableEntry{Tablename: e.entry.Tablename, TableField: e.entry.TableField})] = e
+		}
+	}
+	return wanted
+}
+
+// reload reads the file again and returns the deletions and additions that
+// bring the entries in line with it
+func (p *PbrDecoder) reload() ([]interface{}, []interface{}, error) {
+	policies, err := readPbrPolicies(p.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := policies.check(); err != nil {
+		return nil, nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.policies = policies
+	dels, adds := p.sync(true, true)
+	return dels, adds, nil
+}
+
+// ReloadPbrPolicies reconciles the pbr entries with the pbr policy file
+func ReloadPbrPolicies() error {
+	if pbr == nil {
+		return fmt.Errorf("no pbr policy file configured")
+	}
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	dels, adds, err := pbr.reload()
+	if err != nil {
+		return err
+	}
+	log.Printf("intel-e2000: Reloaded %s, %d pbr entries removed, %d added\n", pbr.path, len(dels), len(adds))
+	if err := delEntries(dels); err != nil {
+		return err
+	}
+	return addEntries(adds)
+}
+
+// check rejects the ingress bridge ports and the bridge ports the rules
+// steer to that infradb does not have, the vrfs of the nexthops are matched
+// as they are added
+func (p pbrPolicies) check() error {
+	var bps []string
+	for name := range p.bridgePorts {
+		bps = append(bps, name)
+	}
+	for _, rules := range p.rules {
+		for _, rule := range rules {
+			if rule.bridgePort != "" {
+				bps = append(bps, rule.bridgePort)
+			}
+		}
+	}
+	return requireConfiguredObjects("pbrpolicy", bps, nil)
+}
+
+// readPbrPolicies reads and validates the pbr policy file
+func readPbrPolicies(path string) (pbrPolicies, error) {
+	policies := pbrPolicies{rules: make(map[string][]pbrMatch)}
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return policies, err
+	}
+	var file pbrPolicyFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return policies, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for _, policy := range file.Policies {
+		if policy.Name == "" {
+			return policies, fmt.Errorf("%s: policy without name", path)
+		}
+		if _, ok := policies.rules[policy.Name]; ok {
+			return policies, fmt.Errorf("%s: duplicate policy %s", path, policy.Name)
+		}
+		var rules []pbrMatch
+		for i, r := range policy.Rules {
+			m, err := r.match()
+			if err != nil {
+				return policies, fmt.Errorf("%s: policy %s rule %d: %w", path, policy.Name, i+1, err)
+			}
+			rules = append(rules, m)
+		}
+		policies.rules[policy.Name] = rules
+	}
+	for port, policy := range file.BridgePorts {
+		if _, ok := policies.rules[policy]; !ok {
+			return policies, fmt.Errorf("%s: bridge port %s has unknown policy %s", path, port, policy)
+		}
+	}
+	policies.bridgePorts = file.BridgePorts
+	return policies, nil
+}
+
+// match validates the rule and converts it to ternary match fields, the
+// five tuple goes through the acl rule checks and pbr routes ipv4 only
+func (r pbrRule) match() (pbrMatch, error) {
+	m := pbrMatch{bridgePort: r.BridgePort}
+	switch {
+	case (r.BridgePort == "") == (r.Nexthop == ""):
+		return m, fmt.Errorf("needs either a bridge port or a nexthop")
+	case r.Nexthop != "" && r.Vrf == "":
+		return m, fmt.Errorf("nexthop %s needs a vrf", r.Nexthop)
+	case r.Nexthop != "" && net.ParseIP(r.Nexthop) == nil:
+		return m, fmt.Errorf("invalid nexthop %s", r.Nexthop)
+	}
+	if r.Nexthop != "" {
+		m.vrf, m.nexthop = path.Base(r.Vrf), pbrNexthop(r.Vrf, r.Nexthop)
+	}
+	acl, err := aclRule{Action: "permit", EtherType: "0x0800", SrcIP: r.SrcIP, DstIP: r.DstIP,
+		Protocol: r.Protocol, SrcPort: r.SrcPort, DstPort: r.DstPort}.match()
+	if err != nil {
+		return m, err
+	}
+	m.fields = acl.fields
+	return m, nil
+}
+
+// pbrNexthop names the nexthop of the vrf, the nexthops give the linux name
+// of the vrf which is the base of its name
+func pbrNexthop(vrf string, ip string) string {
+	if addr := net.ParseIP(ip); addr != nil {
+		ip = addr.String()
+	}
+	return path.Base(vrf) + "/" + ip
+}
+
+// sortedEntryKeys returns the keys of the entries in order
+func sortedEntryKeys(entries map[string]pbrEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
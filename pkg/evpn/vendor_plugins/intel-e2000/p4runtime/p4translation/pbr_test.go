@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestReadPbrPolicies(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		errMsg  string
+	}{
+		"bridge port and nexthop": {
+			content: `policies:
+  - name: chain
+    rules:
+      - {protocol: tcp, dstport: "80", bridgeport: bp-fw}
+      - {dstip: 10.20.0.0/16, vrf: vrf-blue, nexthop: 192.168.100.2}
+bridgeports:
+  bp-a: chain
+`,
+		},
+		"no target": {
+			content: "policies:\n  - name: chain\n    rules:\n      - {protocol: tcp}\n",
+			errMsg:  "policy chain rule 1: needs either a bridge port or a nexthop",
+		},
+		"both targets": {
+			content: "policies:\n  - name: chain\n    rules:\n      - {bridgeport: bp-fw, vrf: vrf-blue, nexthop: 10.0.0.1}\n",
+			errMsg:  "policy chain rule 1: needs either a bridge port or a nexthop",
+		},
+		"nexthop without vrf": {
+			content: "policies:\n  - name: chain\n    rules:\n      - {nexthop: 10.0.0.1}\n",
+			errMsg:  "nexthop 10.0.0.1 needs a vrf",
+		},
+		"invalid nexthop": {
+			content: "policies:\n  - name: chain\n    rules:\n      - {vrf: vrf-blue, nexthop: vtep1}\n",
+			errMsg:  "invalid nexthop vtep1",
+		},
+		"ipv6 prefix": {
+			content: "policies:\n  - name: chain\n    rules:\n      - {dstip: \"2001:db8::/64\", bridgeport: bp-fw}\n",
+			errMsg:  "invalid ipv4 prefix 2001:db8::/64",
+		},
+		"ports without protocol": {
+			content: "policies:\n  - name: chain\n    rules:\n      - {dstport: \"80\", bridgeport: bp-fw}\n",
+			errMsg:  "ports need protocol tcp or udp",
+		},
+		"duplicate policy": {
+			content: "policies:\n  - name: chain\n  - name: chain\n",
+			errMsg:  "duplicate policy chain",
+		},
+		"unknown policy": {
+			content: "bridgeports:\n  bp-a: chain\n",
+			errMsg:  "bridge port bp-a has unknown policy chain",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := readPbrPolicies(writeStaticFile(t, tt.content))
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestReadPbrPolicies_Example(t *testing.T) {
+	policies, err := readPbrPolicies("../../../../../../pbr-policy-example.yaml")
+	if err != nil {
+		t.Fatalf("Loading the shipped example failed: %v", err)
+	}
+	if len(policies.rules) != 2 {
+		t.Errorf("Expected 2 policies, received %d", len(policies.rules))
+	}
+}
+
+func TestPbrDecoder_OnBridgePort(t *testing.T) {
+	bpA := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-a",
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	bpFw := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-fw",
+		Metadata: &infradb.BridgePortMetadata{VPort: "30"},
+	}
+	store := newSnapshotStore()
+	store.load(&objectSnapshot{BridgePorts: []*infradb.BridgePort{bpA, bpFw}})
+	objects = store
+	t.Cleanup(func() { objects = infradbStore{} })
+	path := writeStaticFile(t, `policies:
+  - name: chain
+    rules:
+      - {protocol: tcp, dstport: "80", bridgeport: bp-fw}
+bridgeports:
+  bp-a: chain
+`)
+	d, err := NewPbrDecoder(path)
+	if err != nil {
+		t.Fatalf("Loading the pbr policies failed: %v", err)
+	}
+	if entries, _ := d.OnBridgePort(OpAdded, bpA); len(entries) != 0 {
+		t.Errorf("Expected the rule to wait for bp-fw, received %v", entries)
+	}
+	entries, err := d.OnBridgePort(OpAdded, bpFw)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, received %v", entries)
+	}
+	e := entries[0].(p4client.TableEntry)
+	if e.Tablename != pbrTable || e.ActionName != "evpn_gw_control.pbr_fwd_to_port" ||
+		e.Params[0] != uint32(_toEgressVsi(30)) {
+		t.Errorf("Expected the flow steered to vsi 30, received %v", e)
+	}
+	if vsi := e.FieldValue["vsi"][0].(p4client.Ternary); vsi.String() != "0x0018&&&0xffff" {
+		t.Errorf("Expected the rule to match vsi 24, received %v", vsi)
+	}
+	if entries, _ := d.OnBridgePort(OpAdded, bpFw); len(entries) != 0 {
+		t.Errorf("Expected nothing for a known port, received %v", entries)
+	}
+
+	// a port unknown to infradb rejects the file, the policies stay
+	if err := os.WriteFile(path, []byte(`policies:
+  - name: chain
+    rules:
+      - {protocol: tcp, dstport: "80", bridgeport: bp-gone}
+bridgeports:
+  bp-a: chain
+`), 0o600); err != nil {
+		t.Fatalf("Writing the pbr policies failed: %v", err)
+	}
+	if _, _, err := d.reload(); err == nil || err.Error() != "pbrpolicy: bridge ports [bp-gone] are not in infradb" {
+		t.Errorf("Expected the unknown bridge port rejected, received %v", err)
+	}
+	if _, err := NewPbrDecoder(path); err == nil {
+		t.Errorf("Expected the unknown bridge port rejected on load, received no error")
+	}
+
+	// the ingress port goes away and takes the rule with it
+	entries, _ = d.OnBridgePort(OpDeleted, bpA)
+	if len(entries) != 1 || entries[0].(p4client.TableEntry).ActionName != "" {
+		t.Errorf("Expected the deletion of the rule, received %v", entries)
+	}
+	if entries, _ := d.OnBridgePort(OpAdded, bpA); len(entries) != 1 {
+		t.Fatalf("Expected the rule back with the port, received %v", entries)
+	}
+
+	// so does the firewall port
+	entries, _ = d.OnBridgePort(OpDeleted, bpFw)
+	if len(entries) != 1 || entries[0].(p4client.TableEntry).ActionName != "" {
+		t.Errorf("Expected the deletion of the rule, received %v", entries)
+	}
+	if entries, _ := d.OnBridgePort(OpDeleted, bpA); len(entries) != 0 {
+		t.Errorf("Expected nothing left to delete, received %v", entries)
+	}
+}
+
+func TestPbrDecoder_OnNexthop(t *testing.T) {
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-a",
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	vrf := &infradb.Vrf{Name: "//network.opiproject.org/vrfs/vrf-blue"}
+	store := newSnapshotStore()
+	store.load(&objectSnapshot{BridgePorts: []*infradb.BridgePort{bp}, Vrfs: []*infradb.Vrf{vrf}})
+	nexthopRefs = newRefTable()
+	objects = store
+	t.Cleanup(func() {
+		nexthopRefs = newRefTable()
+		objects = infradbStore{}
+	})
+	path := writeStaticFile(t, `policies:
+  - name: backup
+    rules:
+      - {dstip: 10.20.0.0/16, vrf: vrf-blue, nexthop: 192.168.100.2}
+bridgeports:
+  bp-a: backup
+`)
+	d, err := NewPbrDecoder(path)
+	if err != nil {
+		t.Fatalf("Loading the pbr policies failed: %v", err)
+	}
+	if _, err := d.OnBridgePort(OpAdded, bp); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	nexthop := netlink_polling.NexthopStruct{ID: 5, Key: netlink_polling.NexthopKey{VrfName: "vrf-blue", Dst: "192.168.100.2"}}
+	other := netlink_polling.NexthopStruct{ID: 6, Key: netlink_polling.NexthopKey{VrfName: "vrf-red", Dst: "192.168.100.2"}}
+	if entries, _ := d.OnNexthop(OpAdded, other); len(entries) != 0 {
+		t.Errorf("Expected nothing for a nexthop of another vrf, received %v", entries)
+	}
+	if entries, _ := d.OnNexthop(OpAdded, nexthop); len(entries) != 0 {
+		t.Errorf("Expected the rule to wait for the vrf, received %v", entries)
+	}
+	entries, _ := d.OnVrf(OpAdded, vrf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, received %v", entries)
+	}
+	if e := entries[0].(p4client.TableEntry); e.ActionName != "evpn_gw_control.pbr_set_neighbor" ||
+		e.Params[0] != uint16(_p4NexthopID(nexthop, Direction.Tx)) {
+		t.Errorf("Expected the flow steered to neighbor %d, received %v", _p4NexthopID(nexthop, Direction.Tx), e)
+	}
+	if n := nexthopRefs.count(nexthop.Key); n != 1 {
+		t.Errorf("Expected the rule to hold the nexthop, received %d users", n)
+	}
+	if entries, _ := d.OnNexthop(OpDeleted, other); len(entries) != 0 {
+		t.Errorf("Expected nothing for the deletion of another nexthop, received %v", entries)
+	}
+
+	// the target resolves to a nexthop of another link, the rule moves to it
+	moved := netlink_polling.NexthopStruct{ID: 7, Key: netlink_polling.NexthopKey{VrfName: "vrf-blue", Dst: "192.168.100.2", Dev: 3}}
+	if entries, _ := d.OnNexthop(OpAdded, moved); len(entries) != 1 {
+		t.Fatalf("Expected the rule rewritten, received %v", entries)
+	}
+	if n := nexthopRefs.count(nexthop.Key); n != 0 {
+		t.Errorf("Expected the old nexthop released, received %d users", n)
+	}
+	if n := nexthopRefs.count(moved.Key); n != 1 {
+		t.Errorf("Expected the rule to hold the new nexthop, received %d users", n)
+	}
+	nexthop = moved
+
+	// the vrf goes away ahead of its nexthops and takes the rule with it
+	if entries, _ := d.OnVrf(OpDeleted, vrf); len(entries) != 1 {
+		t.Errorf("Expected the deletion of the rule, received %v", entries)
+	}
+	if n := nexthopRefs.count(nexthop.Key); n != 0 {
+		t.Errorf("Expected the nexthop released with the vrf, received %d users", n)
+	}
+	if entries, _ := d.OnVrf(OpAdded, vrf); len(entries) != 1 {
+		t.Fatalf("Expected the rule back with the vrf, received %v", entries)
+	}
+
+	// the rule goes away with the policy and lets go of the nexthop
+	if err := os.WriteFile(path, []byte("policies:\n  - name: backup\nbridgeports:\n  bp-a: backup\n"), 0o600); err != nil {
+		t.Fatalf("Writing the pbr policies failed: %v", err)
+	}
+	dels, adds, err := d.reload()
+	if err != nil {
+		t.Fatalf("Reloading the pbr policies failed: %v", err)
+	}
+	if len(dels) != 1 || len(adds) != 0 {
+		t.Errorf("Expected 1 deletion and no addition, received %d and %d", len(dels), len(adds))
+	}
+	if n := nexthopRefs.count(nexthop.Key); n != 0 {
+		t.Errorf("Expected the nexthop released, received %d users", n)
+	}
+}
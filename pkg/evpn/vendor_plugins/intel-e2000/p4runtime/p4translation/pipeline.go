@@ -0,0 +1,324 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	nm "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	eb "github.com/opiproject/opi-evpn-bridge/pkg/netlink/eventbus"
+)
+
+// netlinkEventTypes lists the netlink events the translation layer consumes
+var netlinkEventTypes = []string{
+	nm.RouteAdded, nm.RouteUpdated, nm.RouteDeleted,
+	nm.NexthopAdded, nm.NexthopUpdated, nm.NexthopDeleted,
+	nm.FdbEntryAdded, nm.FdbEntryUpdated, nm.FdbEntryDeleted,
+	nm.L2NexthopAdded, nm.L2NexthopUpdated, nm.L2NexthopDeleted,
+}
+
+// shardQueueLen is the number of events a shard buffers before the
+// publisher is blocked
+const shardQueueLen = 1024
+
+// parkedWarnAfter is how long a route or fdb entry waits for its nexthops
+// before the shard warns that it is still parked
+var parkedWarnAfter = time.Minute
+
+// netlinkEvent is a netlink notification queued for translation
+type netlinkEvent struct {
+	eventType string
+	data      interface{}
+}
+
+// eventHandler programs a single netlink event
+type eventHandler func(eventType string, data interface{})
+
+// Pipeline serialises the netlink events of a VRF or logical bridge and
+// processes them in publish order, while independent shards run in parallel
+type Pipeline struct {
+	mu      sync.Mutex
+	shards  map[string]*shard
+	handler eventHandler
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+// NewPipeline creates a pipeline that programs the events with handler
+func NewPipeline(handler eventHandler) *Pipeline {
+	return &Pipeline{
+		shards:  make(map[string]*shard),
+		handler: handler,
+	}
+}
+
+// shardKey returns the ordering domain of the event.
+// Routes and nexthops are ordered per vrf, fdb entries and l2 nexthops per vlan.
+func shardKey(data interface{}) (string, error) {
+	switch d := data.(type) {
+	case *nm.RouteStruct:
+		if d.Vrf == nil {
+			return "", fmt.Errorf("route %v has no vrf", d.Key)
+		}
+		return "vrf:" + d.Vrf.Name, nil
+	case *nm.NexthopStruct:
+		return "vrf:" + d.Key.VrfName, nil
+	case *nm.FdbEntryStruct:
+		return fmt.Sprintf("vlan:%d", d.VlanID), nil
+	case *nm.L2NexthopStruct:
+		return fmt.Sprintf("vlan:%d", d.VlanID), nil
+	}
+	return "", fmt.Errorf("unexpected type %T", data)
+}
+
+// Dispatch queues the event on the shard owning it
+func (p *Pipeline) Dispatch(eventType string, data interface{}) {
+	key, err := shardKey(data)
+	if err != nil {
+		log.Printf("intel-e2000: dropping %s event: %v\n", eventType, err)
+		return
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	s, ok := p.shards[key]
+	if !ok {
+		s = newShard(key, p.handler)
+		p.shards[key] = s
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			s.run()
+		}()
+	}
+	p.mu.Unlock()
+	// the queue stays open, a dispatch racing Stop gives up on done
+	select {
+	case s.queue <- netlinkEvent{eventType: eventType, data: data}:
+	case <-s.done:
+		log.Printf("intel-e2000: dropping %s event, the pipeline is stopped\n", eventType)
+	}
+}
+
+// Stop drains the shards and waits for their workers to finish
+func (p *Pipeline) Stop() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	for _, s := range p.shards {
+		close(s.done)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// Parked returns the number of routes and fdb entries waiting for their
+// nexthops by shard
+func (p *Pipeline) Parked() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	parked := make(map[string]int)
+	for key, s := range p.shards {
+		if n := s.parked(); n != 0 {
+			parked[key] = n
+		}
+	}
+	return parked
+}
+
+// Subscribe feeds the netlink events of the event bus into the pipeline.
+// A single reader keeps the publish order across event types, since the
+// event bus delivers one event at a time on unbuffered channels.
+func (p *Pipeline) Subscribe(eventBus *eb.EventBus) {
+	cases := make([]reflect.SelectCase, 0, len(netlinkEventTypes))
+	for _, eventType := range netlinkEventTypes {
+		subscriber := eventBus.Subscribe(eventType)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(subscriber.Ch)})
+	}
+	go func() {
+		for {
+			chosen, value, ok := reflect.Select(cases)
+			if !ok {
+				// the event bus closes all channels on unsubscribe
+				return
+			}
+			log.Printf("intel-e2000: Subscriber for %s received event\n", netlinkEventTypes[chosen])
+			p.Dispatch(netlinkEventTypes[chosen], value.Interface())
+		}
+	}()
+}
+
+// shard processes the events of one ordering domain
+type shard struct {
+	key     string
+	queue   chan netlinkEvent
+	done    chan struct{}
+	handler eventHandler
+}
+
+// newShard creates a shard
+func newShard(key string, handler eventHandler) *shard {
+	return &shard{
+		key:     key,
+		queue:   make(chan netlinkEvent, shardQueueLen),
+		done:    make(chan struct{}),
+		handler: handler,
+	}
+}
+
+// run processes the queued events until the shard is stopped, and warns
+// about the entries parked for longer than parkedWarnAfter. The events
+// queued before the stop are drained first.
+func (s *shard) run() {
+	ticker := time.NewTicker(parkedWarnAfter)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev := <-s.queue:
+			s.process(ev)
+		case <-s.done:
+			s.drain()
+			return
+		case now := <-ticker.C:
+			nexthopRefs.warnParked(s.key, now, parkedWarnAfter)
+			l2NexthopRefs.warnParked(s.key, now, parkedWarnAfter)
+		}
+	}
+}
+
+// drain processes the events left in the queue
+func (s *shard) drain() {
+	for {
+		select {
+		case ev := <-s.queue:
+			s.process(ev)
+		default:
+			return
+		}
+	}
+}
+
+// parked returns the number of routes and fdb entries the shard parked
+func (s *shard) parked() int {
+	return nexthopRefs.parkedCount(s.key) + l2NexthopRefs.parkedCount(s.key)
+}
+
+// process programs the event once the nexthops it needs exist. The
+// reference table of the nexthops tells whether they do, and holds the
+// deletion of a nexthop while routes or fdb entries still use it.
+func (s *shard) process(ev netlinkEvent) {
+	refs := refsOf(ev.eventType)
+	switch ev.eventType {
+	case nm.RouteAdded, nm.FdbEntryAdded, nm.RouteUpdated, nm.FdbEntryUpdated:
+		s.addDependant(refs, ev)
+	case nm.RouteDeleted, nm.FdbEntryDeleted:
+		key, _ := dependantOf(ev.data)
+		if refs.unpark(key) {
+			// never programmed
+			return
+		}
+		s.handler(ev.eventType, ev.data)
+	case nm.NexthopAdded, nm.L2NexthopAdded, nm.NexthopUpdated, nm.L2NexthopUpdated:
+		s.handler(ev.eventType, ev.data)
+		for _, parked := range refs.releaseParked(s.key) {
+			added, _, _ := opEvents(parked.eventType)
+			s.handler(added, parked.data)
+		}
+	default:
+		s.handler(ev.eventType, ev.data)
+	}
+}
+
+// addDependant programs a route or fdb entry when its nexthops exist and
+// parks it otherwise
+func (s *shard) addDependant(refs *refTable, ev netlinkEvent) {
+	key, deps := dependantOf(ev.data)
+	added, updated, deleted := opEvents(ev.eventType)
+	// an update, or an add announcing a programmed entry again, replaces
+	// the programmed version
+	programmed := ev.eventType == updated || refs.retains(key)
+	if !refs.satisfied(deps) {
+		if refs.unpark(key) {
+			programmed = false
+		}
+		if programmed {
+			s.handler(deleted, ev.data)
+		}
+		refs.park(s.key, key, deps, ev)
+		return
+	}
+	if refs.unpark(key) || !programmed {
+		s.handler(added, ev.data)
+		return
+	}
+	s.handler(updated, ev.data)
+}
+
+// refsOf returns the reference table of the nexthops the event needs or is
+func refsOf(eventType string) *refTable {
+	switch eventType {
+	case nm.FdbEntryAdded, nm.FdbEntryUpdated, nm.FdbEntryDeleted,
+		nm.L2NexthopAdded, nm.L2NexthopUpdated, nm.L2NexthopDeleted:
+		return l2NexthopRefs
+	}
+	return nexthopRefs
+}
+
+// offloadedNhType reports whether nexthops of the type are published to the decoders
+func offloadedNhType(nhType int) bool {
+	switch nhType {
+	case nm.PHY, nm.SVI, nm.ACC, nm.VXLAN:
+		return true
+	}
+	return false
+}
+
+// offloadedL2NhType reports whether l2 nexthops of the type are owned by a decoder
+func offloadedL2NhType(nhType int) bool {
+	return nhType == nm.BRIDGEPORT || nhType == nm.VXLAN
+}
+
+// dependantOf returns the key of a route or fdb entry and the nexthops it needs
+func dependantOf(data interface{}) (interface{}, []interface{}) {
+	switch d := data.(type) {
+	case *nm.RouteStruct:
+		var deps []interface{}
+		for _, nh := range d.Nexthops {
+			if nh != nil && offloadedNhType(nh.NhType) {
+				deps = append(deps, nh.Key)
+			}
+		}
+		return d.Key, deps
+	case *nm.FdbEntryStruct:
+		if d.Nexthop == nil || !offloadedL2NhType(d.Nexthop.Type) {
+			return d.Key, nil
+		}
+		return d.Key, []interface{}{d.Nexthop.Key}
+	}
+	return nil, nil
+}
+
+// opEvents returns the added, updated and deleted event types matching the event
+func opEvents(eventType string) (string, string, string) {
+	switch eventType {
+	case nm.RouteAdded, nm.RouteUpdated, nm.RouteDeleted:
+		return nm.RouteAdded, nm.RouteUpdated, nm.RouteDeleted
+	case nm.NexthopAdded, nm.NexthopUpdated, nm.NexthopDeleted:
+		return nm.NexthopAdded, nm.NexthopUpdated, nm.NexthopDeleted
+	case nm.FdbEntryAdded, nm.FdbEntryUpdated, nm.FdbEntryDeleted:
+		return nm.FdbEntryAdded, nm.FdbEntryUpdated, nm.FdbEntryDeleted
+	}
+	return nm.L2NexthopAdded, nm.L2NexthopUpdated, nm.L2NexthopDeleted
+}
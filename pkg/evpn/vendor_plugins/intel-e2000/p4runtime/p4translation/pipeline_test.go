@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	nm "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+)
+
+var (
+	testVrf = &infradb.Vrf{Name: "//network.opiproject.org/vrfs/blue"}
+	testNh1 = &nm.NexthopStruct{NhType: nm.PHY, Key: nm.NexthopKey{VrfName: testVrf.Name, Dst: "10.0.0.1"}}
+	testNh2 = &nm.NexthopStruct{NhType: nm.PHY, Key: nm.NexthopKey{VrfName: testVrf.Name, Dst: "10.0.0.2"}}
+	testRt1 = &nm.RouteStruct{Vrf: testVrf, Key: nm.RouteKey{Table: 1000, Dst: "192.168.1.0/24"}, Nexthops: []*nm.NexthopStruct{testNh1}}
+	testRt2 = &nm.RouteStruct{Vrf: testVrf, Key: nm.RouteKey{Table: 1000, Dst: "192.168.1.0/24"}, Nexthops: []*nm.NexthopStruct{testNh2}}
+)
+
+type recordedEvent struct {
+	eventType string
+	key       interface{}
+}
+
+func TestPipeline_DependencyOrder(t *testing.T) {
+	tests := map[string]struct {
+		in  []netlinkEvent
+		out []recordedEvent
+	}{
+		"route waits for its nexthop": {
+			in: []netlinkEvent{
+				{nm.RouteAdded, testRt1},
+				{nm.NexthopAdded, testNh1},
+			},
+			out: []recordedEvent{
+				{nm.NexthopAdded, testNh1.Key},
+				{nm.RouteAdded, testRt1.Key},
+			},
+		},
+		"nexthop delete is held while a route uses it": {
+			in: []netlinkEvent{
+				{nm.NexthopAdded, testNh1},
+				{nm.RouteAdded, testRt1},
+				{nm.NexthopDeleted, testNh1},
+				{nm.RouteDeleted, testRt1},
+			},
+			out: []recordedEvent{
+				{nm.NexthopAdded, testNh1.Key},
+				{nm.RouteAdded, testRt1.Key},
+				{nm.RouteDeleted, testRt1.Key},
+				{nm.NexthopDeleted, testNh1.Key},
+			},
+		},
+		"parked route deleted before its nexthop shows up": {
+			in: []netlinkEvent{
+				{nm.RouteAdded, testRt1},
+				{nm.RouteDeleted, testRt1},
+				{nm.NexthopAdded, testNh1},
+			},
+			out: []recordedEvent{
+				{nm.NexthopAdded, testNh1.Key},
+			},
+		},
+		"route moved to a new nexthop releases the old one": {
+			in: []netlinkEvent{
+				{nm.NexthopAdded, testNh1},
+				{nm.NexthopAdded, testNh2},
+				{nm.RouteAdded, testRt1},
+				{nm.NexthopDeleted, testNh1},
+				{nm.RouteUpdated, testRt2},
+			},
+			out: []recordedEvent{
+				{nm.NexthopAdded, testNh1.Key},
+				{nm.NexthopAdded, testNh2.Key},
+				{nm.RouteAdded, testRt1.Key},
+				{nm.RouteDeleted, testRt2.Key},
+				{nm.NexthopDeleted, testNh1.Key},
+				{nm.RouteAdded, testRt2.Key},
+			},
+		},
+		"route moved to a missing nexthop waits for it": {
+			in: []netlinkEvent{
+				{nm.NexthopAdded, testNh1},
+				{nm.RouteAdded, testRt1},
+				{nm.RouteUpdated, testRt2},
+				{nm.NexthopAdded, testNh2},
+			},
+			out: []recordedEvent{
+				{nm.NexthopAdded, testNh1.Key},
+				{nm.RouteAdded, testRt1.Key},
+				{nm.RouteDeleted, testRt2.Key},
+				{nm.NexthopAdded, testNh2.Key},
+				{nm.RouteAdded, testRt2.Key},
+			},
+		},
+		"deleted nexthop parks the routes added later": {
+			in: []netlinkEvent{
+				{nm.NexthopAdded, testNh1},
+				{nm.NexthopDeleted, testNh1},
+				{nm.RouteAdded, testRt1},
+			},
+			out: []recordedEvent{
+				{nm.NexthopAdded, testNh1.Key},
+				{nm.NexthopDeleted, testNh1.Key},
+			},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			resetRefTables(t)
+			var mu sync.Mutex
+			var got []recordedEvent
+			p := NewPipeline(func(eventType string, data interface{}) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, testDecoderEvents(eventType, data)...)
+			})
+			for _, ev := range tt.in {
+				p.Dispatch(ev.eventType, ev.data)
+			}
+			p.Stop()
+
+			if !reflect.DeepEqual(got, tt.out) {
+				t.Errorf("Expected events: %v, received %v", tt.out, got)
+			}
+		})
+	}
+}
+
+func TestPipeline_Parked(t *testing.T) {
+	resetRefTables(t)
+	p := NewPipeline(func(eventType string, data interface{}) {})
+	p.Dispatch(nm.RouteAdded, testRt1)
+	p.Dispatch(nm.RouteAdded, &nm.RouteStruct{Key: nm.RouteKey{Table: 1000, Dst: "192.168.2.0/24"}})
+	p.Stop()
+
+	parked := p.Parked()
+	if !reflect.DeepEqual(parked, map[string]int{"vrf:" + testVrf.Name: 1}) {
+		t.Errorf("Expected the route parked on its vrf shard, received %v", parked)
+	}
+}
+
+func TestPipeline_DispatchRacingStop(t *testing.T) {
+	// a slow handler keeps the dispatchers blocked on a full queue
+	resetRefTables(t)
+	p := NewPipeline(func(eventType string, data interface{}) { time.Sleep(10 * time.Microsecond) })
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2*shardQueueLen; j++ {
+				p.Dispatch(nm.NexthopAdded, testNh1)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	p.Stop()
+	// a send on a closed queue would panic here
+	wg.Wait()
+}
+
+func TestRefTable_WarnParked(t *testing.T) {
+	tests := map[string]struct {
+		wait   time.Duration
+		warned int
+		again  int
+	}{
+		"parked for less than the period": {
+			wait: time.Second,
+		},
+		"parked for longer than the period": {
+			wait:   2 * time.Minute,
+			warned: 1,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			r := newRefTable()
+			key, deps := dependantOf(testRt1)
+			r.park("vrf:blue", key, deps, netlinkEvent{nm.RouteAdded, testRt1})
+			now := r.parked[key].since.Add(tt.wait)
+			if warned := r.warnParked("vrf:red", now, time.Minute); warned != 0 {
+				t.Errorf("Expected no warnings for another shard, received %d", warned)
+			}
+			if warned := r.warnParked("vrf:blue", now, time.Minute); warned != tt.warned {
+				t.Errorf("Expected %d warnings, received %d", tt.warned, warned)
+			}
+			if warned := r.warnParked("vrf:blue", now, time.Minute); warned != tt.again {
+				t.Errorf("Expected %d warnings in the same period, received %d", tt.again, warned)
+			}
+		})
+	}
+}
+
+// resetRefTables gives the test empty reference tables
+func resetRefTables(t *testing.T) {
+	nexthopRefs = newRefTable()
+	l2NexthopRefs = newRefTable()
+	t.Cleanup(func() {
+		nexthopRefs = newRefTable()
+		l2NexthopRefs = newRefTable()
+	})
+}
+
+// testDecoderEvents plays a decoder owning the nexthops and retaining them
+// for its routes, and returns the events whose entries it programs
+func testDecoderEvents(eventType string, data interface{}) []recordedEvent {
+	switch d := data.(type) {
+	case *nm.NexthopStruct:
+		if eventType == nm.NexthopDeleted {
+			return recordedEvents(nexthopRefs.ownerDeleted(d.Key, "test", func() []interface{} {
+				return []interface{}{recordedEvent{eventType, d.Key}}
+			}))
+		}
+		nexthopRefs.ownerAdded(d.Key, "test")
+		return []recordedEvent{{eventType, d.Key}}
+	case *nm.RouteStruct:
+		switch eventType {
+		case nm.RouteAdded:
+			for _, nh := range d.Nexthops {
+				nexthopRefs.retain(nh.Key, d.Key)
+			}
+			return []recordedEvent{{eventType, d.Key}}
+		case nm.RouteDeleted:
+			events := []recordedEvent{{eventType, d.Key}}
+			return append(events, recordedEvents(nexthopRefs.releaseUser(d.Key))...)
+		}
+		// an update deletes the route and adds it again
+		return append(testDecoderEvents(nm.RouteDeleted, data), testDecoderEvents(nm.RouteAdded, data)...)
+	}
+	return nil
+}
+
+// recordedEvents returns the recorded events among the entries
+func recordedEvents(entries []interface{}) []recordedEvent {
+	var events []recordedEvent
+	for _, e := range entries {
+		events = append(events, e.(recordedEvent))
+	}
+	return events
+}
@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmacs"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"github.com/vishvananda/netlink"
+)
+
+// portMacsStr is the name of the secondary mac decoder
+const portMacsStr = "portmacs"
+
+// portMacsBp is a bridge port with secondary macs known to the decoder
+type portMacsBp struct {
+	vsi     uint16
+	primary string
+}
+
+// portMacsEntry is a programmed entry with the l2 nexthop it points to, nil
+// for the entries of the recirculation
+type portMacsEntry struct {
+	entry   p4client.TableEntry
+	nexthop interface{}
+}
+
+// PortMacDecoder forwards to the secondary macs of the bridge ports, the
+// allowed ones of the config on every vlan of the port and the learned
+// ones on the vlan they are learned on. The evpn bridge only follows the
+// mac of the port itself.
+type PortMacDecoder struct {
+	mu       sync.Mutex
+	bps      map[string]portMacsBp
+	nexthops map[string]netlink_polling.L2NexthopStruct
+	learned  map[string]map[string]bool
+	entries  map[string]portMacsEntry
+}
+
+// portMacs is the secondary mac decoder, nil when no bridge port has any
+var portMacs *PortMacDecoder
+
+// portMacsDone stops following the learned macs
+var portMacsDone chan struct{}
+
+// setUpPortMacs follows the secondary macs of the bridge ports
+func setUpPortMacs() {
+	ports := portmacs.Ports()
+	if len(ports) == 0 {
+		return
+	}
+	portMacs = NewPortMacDecoder()
+	log.Printf("intel-e2000: secondary macs on %d bridge ports\n", len(ports))
+}
+
+// NewPortMacDecoder returns a decoder for the secondary macs of the config
+func NewPortMacDecoder() *PortMacDecoder {
+	return &PortMacDecoder{
+		bps:      make(map[string]portMacsBp),
+		nexthops: make(map[string]netlink_polling.L2NexthopStruct),
+		learned:  make(map[string]map[string]bool),
+		entries:  make(map[string]portMacsEntry),
+	}
+}
+
+// Name returns the decoder name
+func (d *PortMacDecoder) Name() string {
+	return portMacsStr
+}
+
+// OnBridgePort forwards the secondary macs of the bridge port
+func (d *PortMacDecoder) OnBridgePort(op Operation, bp *infradb.BridgePort) ([]interface{}, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	name := path.Base(bp.Name)
+	if _, ok := portmacs.Of(name); !ok {
+		return nil, nil
+	}
+	if op == OpDeleted {
+		delete(d.bps, name)
+		dels, _ := d.sync(true, false)
+		return dels, nil
+	}
+	vsi, err := strconv.ParseUint(bp.Metadata.VPort, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	var primary string
+	if bp.Spec.MacAddress != nil {
+		primary = bp.Spec.MacAddress.String()
+	}
+	d.bps[name] = portMacsBp{vsi: uint16(vsi), primary: primary}
+	_, adds := d.sync(false, true)
+	return adds, nil
+}
+
+// OnL2Nexthop forwards the secondary macs on the vlan of the l2 nexthop
+// of a bridge port
+func (d *PortMacDecoder) OnL2Nexthop(op Operation, nexthop netlink_polling.L2NexthopStruct) ([]interface{}, error) {
+	if nexthop.Type != netlink_polling.BRIDGEPORT {
+		return nil, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := portMacsVlan(nexthop.Key.Dev, nexthop.VlanID)
+	if op == OpDeleted {
+		if nh, ok := d.nexthops[key]; !ok || nh.Key != nexthop.Key {
+			return nil, nil
+		}
+		delete(d.nexthops, key)
+		dels, _ := d.sync(true, false)
+		return dels, nil
+	}
+	d.nexthops[key] = nexthop
+	_, adds := d.sync(false, true)
+	return adds, nil
+}
+
+// learn records the mac learned on the vlan of the link, or forgets it,
+// and returns the entries of the change
+func (d *PortMacDecoder) learn(link string, vlan int, mac string, learned bool) ([]interface{}, []interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := portMacsVlan(link, vlan)
+	if d.learned[key][mac] == learned {
+		return nil, nil
+	}
+	if !learned {
+		delete(d.learned[key], mac)
+		if len(d.learned[key]) == 0 {
+			delete(d.learned, key)
+		}
+		return d.sync(true, false)
+	}
+	if d.learned[key] == nil {
+		d.learned[key] = make(map[string]bool)
+	}
+	d.learned[key][mac] = true
+	return d.sync(false, true)
+}
+
+// sync brings the entries in line with the known ports, l2 nexthops and
+// macs. It returns the deletions of the entries that went away when dels
+// is set and the new entries when adds is set, the others are left for a
+// later sync.
+func (d *PortMacDecoder) sync(dels bool, adds bool) ([]interface{}, []interface{}) {
+	wanted := d.wanted()
+	var removed, added []interface{}
+	for _, key := range sortedPortMacsKeys(d.entries) {
+		if _, ok := wanted[key]; ok || !dels {
+			continue
+		}
+		e := d.entries[key]
+		delete(d.entries, key)
+		removed = append(removed, p4client.TableEntry{Tablename: e.entry.Tablename, TableField: e.entry.TableField})
+		removed = append(removed, l2NexthopRefs.releaseUser(portMacsStr+"/"+key)...)
+	}
+	for _, key := range sortedPortMacsKeys(wanted) {
+		w := wanted[key]
+		old, ok := d.entries[key]
+		if !adds || (ok && staticEntryString(old.entry) == staticEntryString(w.entry)) {
+			continue
+		}
+		if ok {
+			// a rewritten entry lets go of its old target, which is still
+			// there: a deleted one took the entry out with it
+			removed = append(removed, l2NexthopRefs.releaseUser(portMacsStr+"/"+key)...)
+		}
+		if w.nexthop != nil {
+			// the l2 nexthop stays while a secondary mac points at it
+			l2NexthopRefs.retain(w.nexthop, portMacsStr+"/"+key)
+		}
+		d.entries[key] = w
+		added = append(added, w.entry)
+	}
+	return removed, added
+}
+
+// wanted builds the entries of the secondary macs of the known ports on
+// the vlans they have an l2 nexthop on, by their match
+func (d *PortMacDecoder) wanted() map[string]portMacsEntry {
+	wanted := make(map[string]portMacsEntry)
+	add := func(e portMacsEntry) {
+		wanted[staticEntryString(p4client.TableEntry{Tablename: e.entry.Tablename, TableField: e.entry.TableField})] = e
+	}
+	for name, bp := range d.bps {
+		port, _ := portmacs.Of(name)
+		link := fmt.Sprintf("vport-%d", bp.vsi)
+		for key, nexthop := range d.nexthops {
+			if nexthop.Key.Dev != link {
+				continue
+			}
+			macs := port.Macs
+			if port.Learn {
+				for mac := range d.learned[key] {
+					macs = append(macs, mac)
+				}
+			}
+			for _, m := range macs {
+				mac, err := net.ParseMAC(m)
+				if err != nil || m == bp.primary {
+					continue
+				}
+				for _, dir := range []int{Direction.Tx, Direction.Rx} {
+					add(portMacsEntry{entry: p4client.TableEntry{
+						Tablename: l2Fwd,
+						TableField: p4client.TableField{
+							FieldValue: map[string][2]interface{}{
+								"vlan_id":   {uint16(nexthop.VlanID), "exact"},
+								"da":        {mac, "exact"},
+								"direction": {uint16(dir), "exact"},
+							},
+							Priority: int32(0),
+						},
+						Action: p4client.Action{
+							ActionName: "evpn_gw_control.set_neighbor",
+							Params:     []interface{}{uint16(nexthop.ID)},
+						},
+					}, nexthop: nexthop.Key})
+				}
+				// From Rx-to-Tx-recirculate (pass 3) entry
+				add(portMacsEntry{entry: p4client.TableEntry{
+					Tablename: l2FwdLoop,
+					TableField: p4client.TableField{
+						FieldValue: map[string][2]interface{}{
+							"da": {mac, "exact"},
+						},
+						Priority: int32(0),
+					},
+					Action: p4client.Action{
+						ActionName: "evpn_gw_control.l2_fwd",
+						Params:     []interface{}{uint32(_toEgressVsi(int(bp.vsi)))},
+					},
+				}})
+			}
+		}
+	}
+	return wanted
+}
+
+// watchPortMacs follows the macs the linux bridge learns on the bridge
+// port links and forwards to them while they are known, in dry run the
+// bridge of the host is not followed and only the allowed macs are known
+func watchPortMacs(done <-chan struct{}) {
+	if portMacs == nil || p4client.IsDryRun() {
+		return
+	}
+	updates := make(chan netlink.NeighUpdate)
+	// the existing entries come first and catch the macs learned before start
+	options := netlink.NeighSubscribeOptions{ListExisting: true}
+	if err := netlink.NeighSubscribeWithOptions(updates, done, options); err != nil {
+		log.Printf("intel-e2000: cannot follow the learned macs: %v\n", err)
+		return
+	}
+	go func() {
+		for u := range updates {
+			if u.Family != syscall.AF_BRIDGE || u.Vlan == 0 || u.HardwareAddr == nil {
+				continue
+			}
+			link, err := netlink.LinkByIndex(u.LinkIndex)
+			if err != nil || !strings.HasPrefix(link.Attrs().Name, "vport-") {
+				continue
+			}
+			translateMu.Lock()
+			dels, adds := portMacs.learn(link.Attrs().Name, u.Vlan, u.HardwareAddr.String(), u.Type == syscall.RTM_NEWNEIGH)
+			_ = delEntries(dels)
+			_ = addEntries(adds)
+			translateMu.Unlock()
+		}
+	}()
+}
+
+// portMacsVlan names the vlan of the link
+func portMacsVlan(link string, vlan int) string {
+	return fmt.Sprintf("%s/%d", link, vlan)
+}
+
+// sortedPortMacsKeys returns the keys of the entries in order
+func sortedPortMacsKeys(entries map[string]portMacsEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmacs"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestPortMacDecoder(t *testing.T) {
+	err := portmacs.Set([]portmacs.Port{{
+		BridgePort: "bp-vm1",
+		Macs:       []string{"00:11:22:33:44:55", "00:11:22:33:44:77"},
+		Learn:      true,
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = portmacs.Set(nil) }()
+	l2NexthopRefs = newRefTable()
+	defer func() { l2NexthopRefs = newRefTable() }()
+
+	d := NewPortMacDecoder()
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm1",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	if entries, err := d.OnBridgePort(OpAdded, bp); err != nil || len(entries) != 0 {
+		t.Errorf("Expected nothing before the port has a vlan, received %v %v", entries, err)
+	}
+	nexthop := netlink_polling.L2NexthopStruct{
+		Key:    netlink_polling.L2NexthopKey{Dev: "vport-24", VlanID: 10},
+		VlanID: 10,
+		ID:     17,
+		Type:   netlink_polling.BRIDGEPORT,
+	}
+	// the primary mac is left to the pod decoder
+	entries, _ := d.OnL2Nexthop(OpAdded, nexthop)
+	if len(entries) != 3 {
+		t.Fatalf("Expected the tx, rx and loop entries of the secondary mac, received %v", entries)
+	}
+	for _, entry := range entries {
+		e := entry.(p4client.TableEntry)
+		if e.FieldValue["da"][0].(net.HardwareAddr).String() != "00:11:22:33:44:77" {
+			t.Errorf("Expected an entry of 00:11:22:33:44:77, received %v", e)
+		}
+	}
+	// by the tx and the rx entry
+	if l2NexthopRefs.count(nexthop.Key) != 2 {
+		t.Errorf("Expected the l2 nexthop retained twice, received %d users", l2NexthopRefs.count(nexthop.Key))
+	}
+	// the vlan of the port moved to another l2 nexthop, the entries follow
+	moved := nexthop
+	moved.Key.Dst = "10.0.0.5"
+	moved.ID = 18
+	if entries, _ := d.OnL2Nexthop(OpAdded, moved); len(entries) != 2 {
+		t.Fatalf("Expected the tx and rx entries rewritten, received %v", entries)
+	}
+	if l2NexthopRefs.count(nexthop.Key) != 0 {
+		t.Errorf("Expected the old l2 nexthop released, received %d users", l2NexthopRefs.count(nexthop.Key))
+	}
+	if entries, _ := d.OnL2Nexthop(OpDeleted, nexthop); len(entries) != 0 {
+		t.Errorf("Expected nothing for the deletion of the old l2 nexthop, received %v", entries)
+	}
+	nexthop = moved
+	other := nexthop
+	other.Type = netlink_polling.VXLAN
+	if entries, _ := d.OnL2Nexthop(OpAdded, other); len(entries) != 0 {
+		t.Errorf("Expected nothing for a vxlan l2 nexthop, received %v", entries)
+	}
+
+	dels, adds := d.learn("vport-24", 10, "00:11:22:33:44:88", true)
+	if len(dels) != 0 || len(adds) != 3 {
+		t.Errorf("Expected the entries of the learned mac, received %v %v", dels, adds)
+	}
+	if dels, adds := d.learn("vport-24", 10, "00:11:22:33:44:88", true); len(dels) != 0 || len(adds) != 0 {
+		t.Errorf("Expected nothing for a mac learned again, received %v %v", dels, adds)
+	}
+	dels, adds = d.learn("vport-24", 10, "00:11:22:33:44:88", false)
+	if len(dels) != 3 || len(adds) != 0 {
+		t.Errorf("Expected the deletion of the forgotten mac, received %v %v", dels, adds)
+	}
+
+	entries, _ = d.OnBridgePort(OpDeleted, bp)
+	if len(entries) != 3 || entries[0].(p4client.TableEntry).ActionName != "" {
+		t.Errorf("Expected the deletion of the secondary mac entries, received %v", entries)
+	}
+	if l2NexthopRefs.count(nexthop.Key) != 0 {
+		t.Errorf("Expected the l2 nexthop released, received %d users", l2NexthopRefs.count(nexthop.Key))
+	}
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmux"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// podPortMux is a port mux representor of the pod decoder
+type podPortMux struct {
+	vsi int
+	mac string
+}
+
+// setUpPortMux logs the port mux interfaces the bridge ports are spread on
+func setUpPortMux() {
+	if muxes := portmux.Interfaces(); len(muxes) > 1 {
+		log.Printf("intel-e2000: bridge ports on the port muxes %v\n", muxes)
+	}
+}
+
+// portMuxKey names the representor of the mux at index i, the first one
+// keeps the name of the single mux
+func portMuxKey(i int) string {
+	if i == 0 {
+		return "port_mux"
+	}
+	return fmt.Sprintf("port_mux%d", i)
+}
+
+// portMuxRepresentors adds the representors of the port muxes
+func portMuxRepresentors(representors map[string][2]string) {
+	for i, name := range portmux.Interfaces() {
+		vsi, mac, err := idsOf(name)
+		if err != nil {
+			log.Printf("Error getting ids for %s: %v", portMuxKey(i), err)
+			continue
+		}
+		representors[portMuxKey(i)] = [2]string{vsi, mac}
+	}
+}
+
+// portMuxesOf returns the port mux representors in the order of the config
+func portMuxesOf(representors map[string][2]string) []podPortMux {
+	var muxes []podPortMux
+	for i := 0; ; i++ {
+		ids, ok := representors[portMuxKey(i)]
+		if !ok {
+			return muxes
+		}
+		vsi, err := strconv.ParseInt(ids[0], 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		muxes = append(muxes, podPortMux{vsi: int(vsi), mac: ids[1]})
+	}
+}
+
+// portMuxOf returns the vsi of the mux the bridge port is on and the s-tag
+// of the port there. Without the port mux config, as in a replay, the port
+// is on the first mux with its vport as s-tag.
+func (p PodDecoder) portMuxOf(bp *infradb.BridgePort, vport int) (int, uint16, error) {
+	if len(portmux.Interfaces()) == 0 {
+		return p._portMuxVsi, uint16(vport), nil
+	}
+	mux, err := portmux.Assign(bp.Name, vport)
+	if err != nil {
+		return 0, 0, err
+	}
+	if mux.Index >= len(p._portMuxes) {
+		return 0, 0, fmt.Errorf("no representor for port mux %s", mux.Interface)
+	}
+	return p._portMuxes[mux.Index].vsi, mux.Stag, nil
+}
+
+// portMuxLoopEntries forward the packets recirculated to the mac of each
+// mux to that mux, the single mux of the pod decoder when it has no list
+func (p PodDecoder) portMuxLoopEntries(op Operation) []interface{} {
+	var entries = make([]interface{}, 0)
+	muxes := p._portMuxes
+	if len(muxes) == 0 {
+		muxes = []podPortMux{{vsi: p._portMuxVsi, mac: p._portMuxMac}}
+	}
+	for _, mux := range muxes {
+		da, _ := net.ParseMAC(mux.mac)
+		entry := p4client.TableEntry{
+			Tablename: l2FwdLoop,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"da": {da, "exact"},
+				},
+				Priority: int32(0),
+			},
+		}
+		if op == OpAdded {
+			entry.Action = p4client.Action{
+				ActionName: "evpn_gw_control.l2_fwd",
+				Params:     []interface{}{uint32(_toEgressVsi(mux.vsi))},
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
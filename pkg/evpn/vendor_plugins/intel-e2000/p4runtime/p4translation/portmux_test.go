@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmux"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestPortMuxesOf(t *testing.T) {
+	muxes := portMuxesOf(map[string][2]string{
+		"port_mux":  {"4", "00:04:00:00:03:14"},
+		"port_mux1": {"5", "00:05:00:00:03:14"},
+		"port_mux3": {"7", "00:07:00:00:03:14"},
+		"vrf_mux":   {"6", "00:06:00:00:03:14"},
+	})
+	// the list stops at the first missing mux
+	if len(muxes) != 2 || muxes[0].vsi != 4 || muxes[1].vsi != 5 || muxes[1].mac != "00:05:00:00:03:14" {
+		t.Errorf("Expected the muxes 4 and 5, received %v", muxes)
+	}
+}
+
+func TestPodDecoder_PortMuxOf(t *testing.T) {
+	mac, _ := net.ParseMAC("13:88:00:00:03:14")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm1",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "5000"},
+	}
+
+	tests := map[string]struct {
+		config   *portmux.Config
+		muxes    []podPortMux
+		vsi      int
+		stag     uint16
+		errorMsg string
+	}{
+		"no port mux config": {
+			muxes: []podPortMux{{vsi: 4}},
+			vsi:   4,
+			stag:  5000,
+		},
+		"range policy": {
+			config: &portmux.Config{Interfaces: []string{"mux0", "mux1"}, Policy: portmux.PolicyRange},
+			muxes:  []podPortMux{{vsi: 4}, {vsi: 5}},
+			vsi:    5,
+			stag:   906,
+		},
+		"static policy": {
+			config: &portmux.Config{
+				Interfaces:  []string{"mux0", "mux1"},
+				Policy:      portmux.PolicyStatic,
+				Assignments: []portmux.StaticAssignment{{BridgePort: "bp-vm1", Interface: "mux0"}},
+			},
+			muxes: []podPortMux{{vsi: 4}, {vsi: 5}},
+			vsi:   4,
+			stag:  1,
+		},
+		"mux without a representor": {
+			config:   &portmux.Config{Interfaces: []string{"mux0", "mux1"}, Policy: portmux.PolicyRange},
+			muxes:    []podPortMux{{vsi: 4}},
+			errorMsg: "no representor for port mux mux1",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			p := PodDecoder{_portMuxVsi: tt.muxes[0].vsi, _portMuxes: tt.muxes}
+			if tt.config != nil {
+				if err := portmux.Set(*tt.config); err != nil {
+					t.Fatalf("Expected no error, received %v", err)
+				}
+				defer func() { _ = portmux.Set(portmux.Config{}) }()
+			}
+			vsi, stag, err := p.portMuxOf(bp, 5000)
+			if tt.errorMsg != "" {
+				if err == nil || err.Error() != tt.errorMsg {
+					t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil || vsi != tt.vsi || stag != tt.stag {
+				t.Errorf("Expected mux %d with s-tag %d, received %d %d %v", tt.vsi, tt.stag, vsi, stag, err)
+			}
+		})
+	}
+}
+
+func TestPodDecoder_PortMuxStaticEntries(t *testing.T) {
+	p := PodDecoder{
+		_portMuxVsi: 4,
+		_portMuxMac: "00:04:00:00:03:14",
+		_portMuxes:  []podPortMux{{vsi: 4, mac: "00:04:00:00:03:14"}, {vsi: 5, mac: "00:05:00:00:03:14"}},
+		_vrfMuxVsi:  6,
+		_vrfMuxMac:  "00:06:00:00:03:14",
+	}
+	loops := make(map[string]interface{})
+	var fwd []p4client.TableEntry
+	for _, entry := range p.StaticAdditions() {
+		e := entry.(p4client.TableEntry)
+		switch e.Tablename {
+		case l2FwdLoop:
+			loops[e.FieldValue["da"][0].(net.HardwareAddr).String()] = e.Params[0]
+		case portMuxFwd:
+			fwd = append(fwd, e)
+		}
+	}
+	want := map[string]interface{}{
+		"00:04:00:00:03:14": uint32(_toEgressVsi(4)),
+		"00:05:00:00:03:14": uint32(_toEgressVsi(5)),
+		"00:06:00:00:03:14": uint32(_toEgressVsi(6)),
+	}
+	if !reflect.DeepEqual(loops, want) {
+		t.Errorf("Expected the mac of each mux forwarded to it, received %v", loops)
+	}
+	// the table has a single key, one entry for all muxes
+	if len(fwd) != 1 || fwd[0].Params[0] != uint32(_toEgressVsi(4)) {
+		t.Errorf("Expected one port mux forward entry to the first mux, received %v", fwd)
+	}
+	deletions := 0
+	for _, entry := range p.StaticDeletions() {
+		if e := entry.(p4client.TableEntry); e.Tablename == l2FwdLoop {
+			deletions++
+		}
+	}
+	if deletions != len(want) {
+		t.Errorf("Expected the deletion of %d loop entries, received %d", len(want), deletions)
+	}
+}
+
+func TestPodDecoder_PortMuxLoopEntries(t *testing.T) {
+	// a pod decoder without the mux list loops to its single mux
+	p := PodDecoder{_portMuxVsi: 4, _portMuxMac: "00:04:00:00:03:14"}
+	entries := p.portMuxLoopEntries(OpAdded)
+	if len(entries) != 1 {
+		t.Fatalf("Expected the entry of the single mux, received %v", entries)
+	}
+	e := entries[0].(p4client.TableEntry)
+	if e.FieldValue["da"][0].(net.HardwareAddr).String() != "00:04:00:00:03:14" || e.Params[0] != uint32(_toEgressVsi(4)) {
+		t.Errorf("Expected the mac of the mux forwarded to it, received %v", e)
+	}
+	if entries := p.portMuxLoopEntries(OpDeleted); len(entries) != 1 || entries[0].(p4client.TableEntry).ActionName != "" {
+		t.Errorf("Expected the deletion of the entry, received %v", entries)
+	}
+}
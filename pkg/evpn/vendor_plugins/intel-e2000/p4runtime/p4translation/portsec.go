@@ -0,0 +1,529 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmacs"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// portSecurityStr is the name of the port security decoder
+const portSecurityStr = "portsecurity"
+
+// the priorities of the port security entries of a bridge port, the ipv4
+// and arp packets of its mac from an allowed address go first, then the
+// other ipv4 and arp packets are dropped, with the ipv6 ones when the port
+// asks for it, then the other packets of its mac pass and the rest is
+// dropped
+const (
+	portSecurityDropAll = iota + 1
+	portSecurityAllowMac
+	portSecurityDropIP
+	portSecurityAllowIP
+)
+
+// the ether types the port security checks the source address of, the sip
+// of an arp packet is its sender address. The table cannot match ipv6
+// sources, a port may drop ipv6 altogether instead.
+const (
+	portSecurityIPv4 = 0x0800
+	portSecurityArp  = 0x0806
+	portSecurityIPv6 = 0x86dd
+)
+
+// portSecurityConfig is a bridge port of the portsecurity.bridgeports
+// config. The port sends ipv4 and arp from its mac and the allowed ipv4
+// addresses or prefixes, or the addresses dhcp leases to its mac when
+// snooping. Its ipv6 packets pass from its mac unless DropIPv6 is set.
+type portSecurityConfig struct {
+	BridgePort   string   `mapstructure:"bridgeport"`
+	AllowedIPs   []string `mapstructure:"allowedips"`
+	DhcpSnooping bool     `mapstructure:"dhcpsnooping"`
+	DropIPv6     bool     `mapstructure:"dropipv6"`
+}
+
+// portSecurityPort is the parsed config of a bridge port
+type portSecurityPort struct {
+	allowed  []p4client.Ternary
+	snooping bool
+	dropIPv6 bool
+}
+
+// portSecurityBp is a secured bridge port known to the decoder, its macs
+// are its own and the secondary ones of the portmacs config
+type portSecurityBp struct {
+	vsi  uint16
+	macs []net.HardwareAddr
+}
+
+// dhcpTransaction is a dhcp exchange a snooping bridge port started for
+// one of its macs
+type dhcpTransaction struct {
+	mac    string
+	expiry time.Time
+}
+
+// PortSecurityDecoder drops the packets of the secured bridge ports sent
+// from another mac or ipv4 address than theirs, the pipeline counts the
+// drops of the port security table
+type PortSecurityDecoder struct {
+	mu      sync.Mutex
+	ports   map[string]portSecurityPort
+	bps     map[string]portSecurityBp
+	leases  map[string]map[string]time.Time
+	servers map[string]bool
+	trusted []string
+	serving map[string]uint16
+	pending map[uint32]dhcpTransaction
+}
+
+// portSecurity is the port security decoder, nil when no bridge port is
+// secured
+var portSecurity *PortSecurityDecoder
+
+// portSecurityDone stops the dhcp snooping
+var portSecurityDone chan struct{}
+
+// defaultTrustedLinks are the links the acks of remote dhcp servers come
+// in on, the vxlan links of the logical bridges
+var defaultTrustedLinks = []string{"vxlan-*"}
+
+// setUpPortSecurity applies the secured bridge ports of the config, the
+// pipeline needs the port security table to check the packets
+func setUpPortSecurity(c portSecuritySection) {
+	if len(c.BridgePorts) == 0 {
+		return
+	}
+	d, err := NewPortSecurityDecoder(c.BridgePorts)
+	if err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
+	}
+	if err := d.trust(c.DhcpServers, c.trustedLinks()); err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
+	}
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, portSecTable) {
+		log.Fatalf("intel-e2000: port security needs the %s table in pipeline %s\n",
+			schema.tableName(portSecTable), schema.Pipeline)
+	}
+	portSecurity = d
+	log.Printf("intel-e2000: port security on %d bridge ports\n", len(c.BridgePorts))
+	bps := append([]string(nil), c.DhcpServers...)
+	for _, p := range c.BridgePorts {
+		bps = append(bps, p.BridgePort)
+	}
+	checkConfiguredObjects("portsecurity", bps, nil)
+}
+
+// NewPortSecurityDecoder validates the secured bridge ports
+func NewPortSecurityDecoder(config []portSecurityConfig) (*PortSecurityDecoder, error) {
+	ports := make(map[string]portSecurityPort)
+	for i, c := range config {
+		if c.BridgePort == "" {
+			return nil, fmt.Errorf("portsecurity: bridge port %d has no name", i+1)
+		}
+		name := path.Base(c.BridgePort)
+		if _, ok := ports[name]; ok {
+			return nil, fmt.Errorf("portsecurity: duplicate bridge port %s", c.BridgePort)
+		}
+		port := portSecurityPort{snooping: c.DhcpSnooping, dropIPv6: c.DropIPv6}
+		for _, ip := range c.AllowedIPs {
+			t, err := ipTernary(ip)
+			if err != nil {
+				return nil, fmt.Errorf("portsecurity: bridge port %s: %w", c.BridgePort, err)
+			}
+			port.allowed = append(port.allowed, t)
+		}
+		if c.DhcpSnooping {
+			// the dhcp discover and request go out before the port has an address
+			port.allowed = append(port.allowed, exactTernary(net.IPv4zero.To4()))
+		}
+		ports[name] = port
+	}
+	return &PortSecurityDecoder{
+		ports:   ports,
+		bps:     make(map[string]portSecurityBp),
+		leases:  make(map[string]map[string]time.Time),
+		servers: make(map[string]bool),
+		serving: make(map[string]uint16),
+		pending: make(map[uint32]dhcpTransaction),
+	}, nil
+}
+
+// trust sets the bridge ports of the dhcp servers and the links, or link
+// patterns, the acks of remote servers come in on
+func (s *PortSecurityDecoder) trust(servers []string, links []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, link := range links {
+		if _, err := path.Match(link, ""); err != nil {
+			return fmt.Errorf("portsecurity: trusted link %s: %w", link, err)
+		}
+	}
+	s.servers = make(map[string]bool)
+	for _, name := range servers {
+		if _, ok := s.ports[path.Base(name)]; ok {
+			return fmt.Errorf("portsecurity: dhcp server %s is a secured bridge port", name)
+		}
+		s.servers[path.Base(name)] = true
+	}
+	s.trusted = links
+	return nil
+}
+
+// Name returns the decoder name
+func (s *PortSecurityDecoder) Name() string {
+	return portSecurityStr
+}
+
+// OnBridgePort secures the bridge port when the config asks for it
+func (s *PortSecurityDecoder) OnBridgePort(op Operation, bp *infradb.BridgePort) ([]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := path.Base(bp.Name)
+	if s.servers[name] {
+		return nil, s.serve(op, name, bp)
+	}
+	port, ok := s.ports[name]
+	if !ok {
+		return nil, nil
+	}
+	if op == OpDeleted {
+		b, ok := s.bps[name]
+		if !ok {
+			return nil, nil
+		}
+		delete(s.bps, name)
+		entries := s.entries(op, b, port)
+		s.release(b)
+		return entries, nil
+	}
+	vsi, err := strconv.ParseUint(bp.Metadata.VPort, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	if bp.Spec.MacAddress == nil {
+		return nil, fmt.Errorf("portsecurity: bridge port %s has no mac", bp.Name)
+	}
+	b := portSecurityBp{vsi: uint16(vsi), macs: []net.HardwareAddr{*bp.Spec.MacAddress}}
+	if secondary, ok := portmacs.Of(bp.Name); ok {
+		for _, m := range secondary.Macs {
+			mac, _ := net.ParseMAC(m)
+			b.macs = append(b.macs, mac)
+		}
+	}
+	s.bps[name] = b
+	return s.entries(op, b, port), nil
+}
+
+// release drops the leases and the transactions of the macs of the deleted
+// bridge port, a port created again with them has to lease its addresses
+// anew. The macs another snooping bridge port has keep theirs.
+func (s *PortSecurityDecoder) release(b portSecurityBp) {
+	for _, mac := range b.macs {
+		var held bool
+		for name, other := range s.bps {
+			if s.ports[name].snooping && hasMac(other.macs, mac) {
+				held = true
+				break
+			}
+		}
+		if held {
+			continue
+		}
+		delete(s.leases, mac.String())
+		for xid, t := range s.pending {
+			if t.mac == mac.String() {
+				delete(s.pending, xid)
+			}
+		}
+	}
+}
+
+// serve follows the vport of a dhcp server bridge port, its link is trusted
+func (s *PortSecurityDecoder) serve(op Operation, name string, bp *infradb.BridgePort) error {
+	if op == OpDeleted {
+		delete(s.serving, name)
+		return nil
+	}
+	vsi, err := strconv.ParseUint(bp.Metadata.VPort, 10, 16)
+	if err != nil {
+		return err
+	}
+	s.serving[name] = uint16(vsi)
+	return nil
+}
+
+// snooping tells whether a bridge port learns its addresses from dhcp
+func (s *PortSecurityDecoder) snooping() bool {
+	for _, port := range s.ports {
+		if port.snooping {
+			return true
+		}
+	}
+	return false
+}
+
+// entries builds the entries of the bridge port with the addresses leased
+// to its macs
+func (s *PortSecurityDecoder) entries(op Operation, b portSecurityBp, port portSecurityPort) []interface{} {
+	var entries = make([]interface{}, 0)
+	vsi := exactTernary(uint16toBytes(b.vsi))
+	for _, m := range b.macs {
+		mac := exactTernary(m)
+		allowed := port.allowed
+		if port.snooping {
+			for _, ip := range sortedLeases(s.leases[m.String()]) {
+				if !port.allows(ip) {
+					allowed = append(allowed, exactTernary(ip))
+				}
+			}
+		}
+		for _, ip := range allowed {
+			entries = append(entries,
+				portSecurityAllowEntry(op, b.vsi, m, portSecurityIPv4, ip),
+				portSecurityAllowEntry(op, b.vsi, m, portSecurityArp, ip))
+		}
+		if !port.allows(net.IPv4zero) {
+			// an arp probe has no sender address yet
+			entries = append(entries, portSecurityAllowEntry(op, b.vsi, m, portSecurityArp, exactTernary(net.IPv4zero.To4())))
+		}
+		entries = append(entries, portSecurityEntry(op, map[string][2]interface{}{
+			"vsi": {vsi, "ternary"}, "smac": {mac, "ternary"},
+		}, portSecurityAllowMac, true))
+	}
+	etherTypes := []uint16{portSecurityIPv4, portSecurityArp}
+	if port.dropIPv6 {
+		etherTypes = append(etherTypes, portSecurityIPv6)
+	}
+	for _, etherType := range etherTypes {
+		entries = append(entries, portSecurityEntry(op, map[string][2]interface{}{
+			"vsi": {vsi, "ternary"}, "ether_type": {exactTernary(uint16toBytes(etherType)), "ternary"},
+		}, portSecurityDropIP, false))
+	}
+	entries = append(entries, portSecurityEntry(op, map[string][2]interface{}{
+		"vsi": {vsi, "ternary"},
+	}, portSecurityDropAll, false))
+	return entries
+}
+
+// snoop follows a dhcp message received on the link. A discover or
+// request starts a transaction when it comes from a snooping bridge port
+// of its mac. An ack is learned only when it comes in on the link of a
+// dhcp server bridge port or a trusted link, for the mac of a transaction
+// started before. A release is forgotten only when it comes from a
+// snooping bridge port of its mac. Anything else is dropped, so a port
+// cannot lease itself an address.
+func (s *PortSecurityDecoder) snoop(link string, b dhcpBinding, now time.Time) ([]interface{}, []interface{}) {
+	s.mu.Lock()
+	switch b.msgType {
+	case dhcpDiscover, dhcpRequest:
+		if s.snoopedBy(link, b.mac) {
+			s.pending[b.xid] = dhcpTransaction{mac: b.mac.String(), expiry: now.Add(dhcpTransactionTimeout)}
+		}
+		s.mu.Unlock()
+		return nil, nil
+	case dhcpAck:
+		t, ok := s.pending[b.xid]
+		if !s.trustedLink(link) || !ok || t.mac != b.mac.String() {
+			s.mu.Unlock()
+			log.Printf("intel-e2000: ignoring the dhcp ack of %s for %s received on %s\n", b.ip, b.mac, link)
+			return nil, nil
+		}
+		delete(s.pending, b.xid)
+		s.mu.Unlock()
+		return nil, s.learn(b.mac, b.ip, now.Add(b.lease))
+	case dhcpRelease:
+		ok := s.snoopedBy(link, b.mac)
+		s.mu.Unlock()
+		if !ok {
+			log.Printf("intel-e2000: ignoring the dhcp release of %s for %s received on %s\n", b.ip, b.mac, link)
+			return nil, nil
+		}
+		return s.forget(b.mac, b.ip), nil
+	}
+	s.mu.Unlock()
+	return nil, nil
+}
+
+// snoopedBy tells whether the link is a snooping bridge port of the mac
+func (s *PortSecurityDecoder) snoopedBy(link string, mac net.HardwareAddr) bool {
+	for name, b := range s.bps {
+		if fmt.Sprintf("vport-%d", b.vsi) == link && s.ports[name].snooping && hasMac(b.macs, mac) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedLink tells whether dhcp acks are taken from the link, the link of
+// a dhcp server bridge port or one matching the trusted links
+func (s *PortSecurityDecoder) trustedLink(link string) bool {
+	for _, vsi := range s.serving {
+		if fmt.Sprintf("vport-%d", vsi) == link {
+			return true
+		}
+	}
+	for _, pattern := range s.trusted {
+		if ok, _ := path.Match(pattern, link); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// learn records the address dhcp leased to the mac until the lease runs
+// out and returns the entries letting it in on the snooping bridge ports
+// of the mac
+func (s *PortSecurityDecoder) learn(mac net.HardwareAddr, ip net.IP, expiry time.Time) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leases := s.leases[mac.String()]
+	if leases == nil {
+		leases = make(map[string]time.Time)
+		s.leases[mac.String()] = leases
+	}
+	_, known := leases[ip.String()]
+	leases[ip.String()] = expiry
+	if known {
+		return nil
+	}
+	return s.leaseEntries(OpAdded, mac, ip)
+}
+
+// forget drops the lease of the mac and returns the deletion of its entries
+func (s *PortSecurityDecoder) forget(mac net.HardwareAddr, ip net.IP) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leases[mac.String()][ip.String()]; !ok {
+		return nil
+	}
+	delete(s.leases[mac.String()], ip.String())
+	if len(s.leases[mac.String()]) == 0 {
+		delete(s.leases, mac.String())
+	}
+	return s.leaseEntries(OpDeleted, mac, ip)
+}
+
+// expire drops the leases and the transactions run out by now and returns
+// the deletion of the entries of the leases
+func (s *PortSecurityDecoder) expire(now time.Time) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for xid, t := range s.pending {
+		if !now.Before(t.expiry) {
+			delete(s.pending, xid)
+		}
+	}
+	var entries []interface{}
+	for m, leases := range s.leases {
+		mac, _ := net.ParseMAC(m)
+		for ip, expiry := range leases {
+			if now.Before(expiry) {
+				continue
+			}
+			delete(leases, ip)
+			entries = append(entries, s.leaseEntries(OpDeleted, mac, net.ParseIP(ip))...)
+		}
+		if len(leases) == 0 {
+			delete(s.leases, m)
+		}
+	}
+	return entries
+}
+
+// leaseEntries builds the entries of the leased address on the snooping
+// bridge ports of the mac
+func (s *PortSecurityDecoder) leaseEntries(op Operation, mac net.HardwareAddr, ip net.IP) []interface{} {
+	var entries []interface{}
+	for name, b := range s.bps {
+		port := s.ports[name]
+		// an address the config allows keeps its entries when the lease goes
+		if !port.snooping || !hasMac(b.macs, mac) || port.allows(ip) {
+			continue
+		}
+		entries = append(entries,
+			portSecurityAllowEntry(op, b.vsi, mac, portSecurityIPv4, exactTernary(ip.To4())),
+			portSecurityAllowEntry(op, b.vsi, mac, portSecurityArp, exactTernary(ip.To4())))
+	}
+	return entries
+}
+
+// allows tells whether the config allows the address on its own, as an
+// exact address and not through a prefix
+func (port portSecurityPort) allows(ip net.IP) bool {
+	exact := exactTernary(ip.To4())
+	for _, t := range port.allowed {
+		if bytes.Equal(t.Value, exact.Value) && bytes.Equal(t.Mask, exact.Mask) {
+			return true
+		}
+	}
+	return false
+}
+
+// portSecurityAllowEntry builds the permit of the packets of the ether type
+// from the mac and the source address on the bridge port
+func portSecurityAllowEntry(op Operation, vsi uint16, mac net.HardwareAddr, etherType uint16, sip p4client.Ternary) p4client.TableEntry {
+	return portSecurityEntry(op, map[string][2]interface{}{
+		"vsi":        {exactTernary(uint16toBytes(vsi)), "ternary"},
+		"smac":       {exactTernary(mac), "ternary"},
+		"ether_type": {exactTernary(uint16toBytes(etherType)), "ternary"},
+		"sip":        {sip, "ternary"},
+	}, portSecurityAllowIP, true)
+}
+
+// portSecurityEntry builds a permit or drop entry of the port security table
+func portSecurityEntry(op Operation, fields map[string][2]interface{}, priority int32, permit bool) p4client.TableEntry {
+	entry := p4client.TableEntry{
+		Tablename: portSecTable,
+		TableField: p4client.TableField{
+			FieldValue: fields,
+			Priority:   priority,
+		},
+	}
+	if op == OpAdded {
+		action := "evpn_gw_control.port_security_drop"
+		if permit {
+			action = "evpn_gw_control.port_security_permit"
+		}
+		entry.Action = p4client.Action{ActionName: action}
+	}
+	return entry
+}
+
+// hasMac tells whether the mac is one of the macs
+func hasMac(macs []net.HardwareAddr, mac net.HardwareAddr) bool {
+	for _, m := range macs {
+		if m.String() == mac.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedLeases returns the leased addresses in order
+func sortedLeases(leases map[string]time.Time) []net.IP {
+	keys := make([]string, 0, len(leases))
+	for ip := range leases {
+		keys = append(keys, ip)
+	}
+	sort.Strings(keys)
+	ips := make([]net.IP, 0, len(keys))
+	for _, ip := range keys {
+		ips = append(ips, net.ParseIP(ip).To4())
+	}
+	return ips
+}
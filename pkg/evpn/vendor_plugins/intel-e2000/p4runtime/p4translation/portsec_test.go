@@ -0,0 +1,358 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/portmacs"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestNewPortSecurityDecoder(t *testing.T) {
+	tests := map[string]struct {
+		config []portSecurityConfig
+		errMsg string
+	}{
+		"allowed ips and snooping": {
+			config: []portSecurityConfig{
+				{BridgePort: "bp-vm1", AllowedIPs: []string{"10.10.0.5", "10.10.1.0/28"}},
+				{BridgePort: "//network.opiproject.org/ports/bp-vm2", DhcpSnooping: true},
+			},
+		},
+		"unnamed bridge port": {
+			config: []portSecurityConfig{{DhcpSnooping: true}},
+			errMsg: "portsecurity: bridge port 1 has no name",
+		},
+		"duplicate bridge port": {
+			config: []portSecurityConfig{{BridgePort: "bp-vm1"}, {BridgePort: "//network.opiproject.org/ports/bp-vm1"}},
+			errMsg: "portsecurity: duplicate bridge port //network.opiproject.org/ports/bp-vm1",
+		},
+		"ipv6 address": {
+			config: []portSecurityConfig{{BridgePort: "bp-vm1", AllowedIPs: []string{"2001:db8::5"}}},
+			errMsg: "portsecurity: bridge port bp-vm1: invalid ipv4 prefix 2001:db8::5/32",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := NewPortSecurityDecoder(tt.config)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestPortSecurityDecoder_OnBridgePort(t *testing.T) {
+	d, err := NewPortSecurityDecoder([]portSecurityConfig{
+		{BridgePort: "bp-vm1", AllowedIPs: []string{"10.10.0.5"}, DropIPv6: true},
+		{BridgePort: "bp-vm3", AllowedIPs: []string{"10.10.0.6"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm1",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	entries, err := d.OnBridgePort(OpAdded, bp)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	// the allowed address as ipv4 and arp sender, the arp probe, the other
+	// packets of the mac, the other ipv4, arp and ipv6 and the rest
+	if len(entries) != 8 {
+		t.Fatalf("Expected 8 entries, received %v", entries)
+	}
+	allow := entries[0].(p4client.TableEntry)
+	if sip := allow.FieldValue["sip"][0].(p4client.Ternary); sip.String() != "0x0a0a0005&&&0xffffffff" ||
+		allow.ActionName != "evpn_gw_control.port_security_permit" {
+		t.Errorf("Expected 10.10.0.5 permitted, received %v", allow)
+	}
+	for i, sender := range []string{"0x0a0a0005&&&0xffffffff", "0x00000000&&&0xffffffff"} {
+		arp := entries[i+1].(p4client.TableEntry)
+		if arp.FieldValue["ether_type"][0].(p4client.Ternary).String() != "0x0806&&&0xffff" ||
+			arp.FieldValue["sip"][0].(p4client.Ternary).String() != sender || arp.Priority != allow.Priority {
+			t.Errorf("Expected the arp sender %s permitted, received %v", sender, arp)
+		}
+	}
+	for i, action := range []string{"permit", "drop", "drop", "drop", "drop"} {
+		e := entries[i+3].(p4client.TableEntry)
+		if e.ActionName != "evpn_gw_control.port_security_"+action || e.Priority >= allow.Priority {
+			t.Errorf("Expected a %s below the allowed address, received %v", action, e)
+		}
+	}
+	if ipv6 := entries[6].(p4client.TableEntry); ipv6.FieldValue["ether_type"][0].(p4client.Ternary).String() != "0x86dd&&&0xffff" {
+		t.Errorf("Expected the ipv6 packets dropped, received %v", ipv6)
+	}
+	other := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm2",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "25"},
+	}
+	if entries, _ := d.OnBridgePort(OpAdded, other); len(entries) != 0 {
+		t.Errorf("Expected nothing for an unsecured port, received %v", entries)
+	}
+	entries, _ = d.OnBridgePort(OpDeleted, bp)
+	if len(entries) != 8 || entries[0].(p4client.TableEntry).ActionName != "" {
+		t.Errorf("Expected the deletion of the 8 entries, received %v", entries)
+	}
+	// the ipv6 packets of a port not dropping them pass from its mac
+	ipv6 := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm3",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "26"},
+	}
+	entries, _ = d.OnBridgePort(OpAdded, ipv6)
+	if len(entries) != 7 {
+		t.Fatalf("Expected 7 entries, received %v", entries)
+	}
+	for _, entry := range entries {
+		if e := entry.(p4client.TableEntry); e.FieldValue["ether_type"][0] != nil &&
+			e.FieldValue["ether_type"][0].(p4client.Ternary).String() == "0x86dd&&&0xffff" {
+			t.Errorf("Expected the ipv6 packets left alone, received %v", e)
+		}
+	}
+}
+
+func TestPortSecurityDecoder_SecondaryMacs(t *testing.T) {
+	err := portmacs.Set([]portmacs.Port{{BridgePort: "bp-vm1", Macs: []string{"00:11:22:33:44:77"}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = portmacs.Set(nil) }()
+	d, err := NewPortSecurityDecoder([]portSecurityConfig{{BridgePort: "bp-vm1", AllowedIPs: []string{"10.10.0.5"}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm1",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "24"},
+	}
+	entries, _ := d.OnBridgePort(OpAdded, bp)
+	// the allowed address, the arp probe and the other packets of both
+	// macs, then the drops
+	if len(entries) != 11 {
+		t.Fatalf("Expected 12 entries, received %v", entries)
+	}
+	secondary := entries[4].(p4client.TableEntry)
+	if smac := secondary.FieldValue["smac"][0].(p4client.Ternary); smac.String() != "0x001122334477&&&0xffffffffffff" ||
+		secondary.ActionName != "evpn_gw_control.port_security_permit" {
+		t.Errorf("Expected the secondary mac permitted, received %v", secondary)
+	}
+}
+
+func TestPortSecurityDecoder_Leases(t *testing.T) {
+	d, err := NewPortSecurityDecoder([]portSecurityConfig{{BridgePort: "bp-vm2", DhcpSnooping: true}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	mac, _ := net.ParseMAC("00:11:22:33:44:66")
+	ip := net.ParseIP("10.10.0.9")
+	now := time.Now()
+	if entries := d.learn(mac, ip, now.Add(time.Hour)); len(entries) != 0 {
+		t.Errorf("Expected nothing before the port is known, received %v", entries)
+	}
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm2",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "25"},
+	}
+	// the unspecified address for the dhcp exchange and the lease, as ipv4
+	// and arp sender, then the other packets of the mac and the drops
+	if entries, _ := d.OnBridgePort(OpAdded, bp); len(entries) != 8 {
+		t.Errorf("Expected 9 entries, received %v", entries)
+	}
+	if entries := d.learn(mac, ip, now.Add(2*time.Hour)); len(entries) != 0 {
+		t.Errorf("Expected nothing for a renewed lease, received %v", entries)
+	}
+	other := net.ParseIP("10.10.0.10")
+	entries := d.learn(mac, other, now.Add(time.Minute))
+	if len(entries) != 2 || entries[0].(p4client.TableEntry).ActionName != "evpn_gw_control.port_security_permit" {
+		t.Errorf("Expected the new lease permitted, received %v", entries)
+	}
+	if entries := d.expire(now.Add(time.Hour)); len(entries) != 2 {
+		t.Errorf("Expected the short lease run out, received %v", entries)
+	}
+	if entries := d.forget(mac, ip); len(entries) != 2 || entries[0].(p4client.TableEntry).ActionName != "" {
+		t.Errorf("Expected the released lease deleted, received %v", entries)
+	}
+	if entries := d.forget(mac, ip); len(entries) != 0 {
+		t.Errorf("Expected nothing for a lease already released, received %v", entries)
+	}
+}
+
+func TestPortSecurityDecoder_Delete(t *testing.T) {
+	d, err := NewPortSecurityDecoder([]portSecurityConfig{{BridgePort: "bp-vm2", DhcpSnooping: true}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if err := d.trust([]string{"bp-dhcp"}, defaultTrustedLinks); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	mac, _ := net.ParseMAC("00:11:22:33:44:66")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm2",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "25"},
+	}
+	added, err := d.OnBridgePort(OpAdded, bp)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	now := time.Now()
+	ack := dhcpBinding{msgType: dhcpAck, mac: mac, xid: 0x1234, lease: time.Hour, ip: net.ParseIP("10.10.0.9")}
+	d.snoop("vport-25", dhcpBinding{msgType: dhcpDiscover, mac: mac, xid: 0x1234}, now)
+	if _, adds := d.snoop("vxlan-10", ack, now); len(adds) != 2 {
+		t.Fatalf("Expected the lease permitted, received %v", adds)
+	}
+	// a second exchange is still open when the port goes away
+	d.snoop("vport-25", dhcpBinding{msgType: dhcpRequest, mac: mac, xid: 0x5678}, now)
+
+	// the port takes the entries of its lease with it
+	entries, _ := d.OnBridgePort(OpDeleted, bp)
+	if len(entries) != len(added)+2 {
+		t.Errorf("Expected the deletion of the %d entries and the lease, received %v", len(added), entries)
+	}
+	for _, entry := range entries {
+		if e := entry.(p4client.TableEntry); e.ActionName != "" {
+			t.Errorf("Expected only deletions, received %v", e)
+		}
+	}
+	if len(d.leases) != 0 || len(d.pending) != 0 {
+		t.Errorf("Expected the leases and transactions released, received %v %v", d.leases, d.pending)
+	}
+	if entries, _ := d.OnBridgePort(OpDeleted, bp); len(entries) != 0 {
+		t.Errorf("Expected nothing for a deleted port, received %v", entries)
+	}
+
+	// the port created again does not get the released lease back
+	entries, _ = d.OnBridgePort(OpAdded, bp)
+	if len(entries) != len(added) {
+		t.Errorf("Expected the %d entries without the lease, received %v", len(added), entries)
+	}
+	ack.xid = 0x5678
+	if _, adds := d.snoop("vxlan-10", ack, now); len(adds) != 0 {
+		t.Errorf("Expected the ack of the released transaction ignored, received %v", adds)
+	}
+}
+
+func TestPortSecurityDecoder_StaticLease(t *testing.T) {
+	d, err := NewPortSecurityDecoder([]portSecurityConfig{{BridgePort: "bp-vm2", AllowedIPs: []string{"10.10.0.9"}, DhcpSnooping: true}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	mac, _ := net.ParseMAC("00:11:22:33:44:66")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm2",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+		Metadata: &infradb.BridgePortMetadata{VPort: "25"},
+	}
+	if _, err := d.OnBridgePort(OpAdded, bp); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	ip := net.ParseIP("10.10.0.9")
+	// the allowed address keeps its entries, the lease neither adds nor removes them
+	if entries := d.learn(mac, ip, time.Now().Add(time.Hour)); len(entries) != 0 {
+		t.Errorf("Expected nothing for the lease of an allowed address, received %v", entries)
+	}
+	if entries := d.forget(mac, ip); len(entries) != 0 {
+		t.Errorf("Expected the allowed address kept after its release, received %v", entries)
+	}
+}
+
+func TestPortSecurityDecoder_Snoop(t *testing.T) {
+	type step struct {
+		link    string
+		msgType byte
+		mac     string
+	}
+	tests := map[string]struct {
+		steps []step
+		dels  int
+		adds  int
+	}{
+		"ack of the dhcp server port": {
+			steps: []step{{"vport-25", dhcpDiscover, "00:11:22:33:44:66"}, {"vport-30", dhcpAck, "00:11:22:33:44:66"}},
+			adds:  2,
+		},
+		"ack of a trusted link": {
+			steps: []step{{"vport-25", dhcpRequest, "00:11:22:33:44:66"}, {"vxlan-10", dhcpAck, "00:11:22:33:44:66"}},
+			adds:  2,
+		},
+		"ack forged by the secured port": {
+			steps: []step{{"vport-25", dhcpDiscover, "00:11:22:33:44:66"}, {"vport-25", dhcpAck, "00:11:22:33:44:66"}},
+		},
+		"ack without a transaction": {
+			steps: []step{{"vport-30", dhcpAck, "00:11:22:33:44:66"}},
+		},
+		"ack for another mac": {
+			steps: []step{{"vport-25", dhcpDiscover, "00:11:22:33:44:66"}, {"vport-30", dhcpAck, "00:11:22:33:44:77"}},
+		},
+		"transaction of a foreign mac": {
+			steps: []step{{"vport-25", dhcpDiscover, "00:11:22:33:44:77"}, {"vport-30", dhcpAck, "00:11:22:33:44:77"}},
+		},
+		"release of the port": {
+			steps: []step{
+				{"vport-25", dhcpDiscover, "00:11:22:33:44:66"},
+				{"vport-30", dhcpAck, "00:11:22:33:44:66"},
+				{"vport-25", dhcpRelease, "00:11:22:33:44:66"},
+			},
+			dels: 2,
+		},
+		"release of another link": {
+			steps: []step{
+				{"vport-25", dhcpDiscover, "00:11:22:33:44:66"},
+				{"vport-30", dhcpAck, "00:11:22:33:44:66"},
+				{"vport-30", dhcpRelease, "00:11:22:33:44:66"},
+			},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			d, err := NewPortSecurityDecoder([]portSecurityConfig{{BridgePort: "bp-vm2", DhcpSnooping: true}})
+			if err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			if err := d.trust([]string{"bp-dhcp"}, defaultTrustedLinks); err != nil {
+				t.Fatalf("Expected no error, received %v", err)
+			}
+			mac, _ := net.ParseMAC("00:11:22:33:44:66")
+			for _, bp := range []*infradb.BridgePort{{
+				Name:     "//network.opiproject.org/ports/bp-vm2",
+				Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+				Metadata: &infradb.BridgePortMetadata{VPort: "25"},
+			}, {
+				Name:     "//network.opiproject.org/ports/bp-dhcp",
+				Spec:     &infradb.BridgePortSpec{},
+				Metadata: &infradb.BridgePortMetadata{VPort: "30"},
+			}} {
+				if _, err := d.OnBridgePort(OpAdded, bp); err != nil {
+					t.Fatalf("Expected no error, received %v", err)
+				}
+			}
+			var dels, adds []interface{}
+			for _, st := range tt.steps {
+				b := dhcpBinding{msgType: st.msgType, xid: 0x1234, lease: time.Hour, ip: net.ParseIP("10.10.0.9")}
+				b.mac, _ = net.ParseMAC(st.mac)
+				dels, adds = d.snoop(st.link, b, time.Now())
+			}
+			if len(dels) != tt.dels || len(adds) != tt.adds {
+				t.Errorf("Expected %d deletions and %d additions, received %v %v", tt.dels, tt.adds, dels, adds)
+			}
+		})
+	}
+}
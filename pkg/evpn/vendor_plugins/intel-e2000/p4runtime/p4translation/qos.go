@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+)
+
+// dscpCopy marks the outer vxlan header with the dscp of the inner packet
+const dscpCopy = "copy"
+
+// qosConfig is a marking of the qos.markings config, given for a logical
+// bridge or a vrf. Pcp is the 802.1p priority of the pushed vlan tags, dscp
+// is the dscp of the outer vxlan header as a number or copy.
+type qosConfig struct {
+	LogicalBridge string `mapstructure:"logicalbridge"`
+	Vrf           string `mapstructure:"vrf"`
+	Pcp           uint16 `mapstructure:"pcp"`
+	Dscp          string `mapstructure:"dscp"`
+}
+
+// qosMarking is the parsed marking of a logical bridge or vrf
+type qosMarking struct {
+	pcp      uint16
+	dscp     uint16
+	dscpCopy bool
+}
+
+// qosMarkings are the markings of the logical bridges and vrfs by the base
+// of their name
+type qosMarkings struct {
+	mu    sync.RWMutex
+	lbs   map[string]qosMarking
+	vrfs  map[string]qosMarking
+	dscps bool
+}
+
+// qos are the configured markings, all traffic is sent with pcp and dscp 0
+// when empty
+var qos qosMarkings
+
+// setUpQos applies the markings of the config, the pipeline needs the dscp
+// params of the vxlan push actions to mark the outer header
+func setUpQos(c qosSection) {
+	if err := qos.set(c.Markings); err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
+	}
+	if !qos.dscps {
+		return
+	}
+	for _, action := range []string{"omac_vxlan_imac_push", "omac_vxlan_push"} {
+		if order := schema.Actions[action].Params; len(order) != 0 && !contains(order, "dscp") {
+			log.Fatalf("intel-e2000: dscp marking needs the schema to map the dscp params of %s\n", action)
+		}
+		if pipelineInfo != nil && !schema.hasActionParams(pipelineInfo, action, paramsThrough(action, "dscp_copy")) {
+			log.Fatalf("intel-e2000: dscp marking needs the dscp params of %s in pipeline %s\n",
+				schema.actionName(action), schema.Pipeline)
+		}
+	}
+	log.Printf("intel-e2000: qos markings of %d logical bridges and %d vrfs\n", len(qos.lbs), len(qos.vrfs))
+}
+
+// newQosMarkings validates and parses the markings
+func newQosMarkings(markings []qosConfig) (*qosMarkings, error) {
+	lbs := make(map[string]qosMarking)
+	vrfs := make(map[string]qosMarking)
+	var dscps bool
+	for i, m := range markings {
+		if (m.LogicalBridge == "") == (m.Vrf == "") {
+			return nil, fmt.Errorf("qos: marking %d needs either a logical bridge or a vrf", i+1)
+		}
+		if m.Pcp > 7 {
+			return nil, fmt.Errorf("qos: marking %d: pcp %d above 7", i+1, m.Pcp)
+		}
+		marking := qosMarking{pcp: m.Pcp}
+		switch m.Dscp {
+		case "":
+		case dscpCopy:
+			marking.dscpCopy = true
+			dscps = true
+		default:
+			dscp, err := strconv.ParseUint(m.Dscp, 10, 6)
+			if err != nil {
+				return nil, fmt.Errorf("qos: marking %d: dscp %s is neither 0 to 63 nor copy", i+1, m.Dscp)
+			}
+			marking.dscp = uint16(dscp)
+			dscps = true
+		}
+		markings, name := lbs, m.LogicalBridge
+		if m.Vrf != "" {
+			markings, name = vrfs, m.Vrf
+		}
+		if _, ok := markings[path.Base(name)]; ok {
+			return nil, fmt.Errorf("qos: duplicate marking of %s", name)
+		}
+		markings[path.Base(name)] = marking
+	}
+	return &qosMarkings{lbs: lbs, vrfs: vrfs, dscps: dscps}, nil
+}
+
+// set validates and stores the markings
+func (q *qosMarkings) set(markings []qosConfig) error {
+	parsed, err := newQosMarkings(markings)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.lbs, q.vrfs, q.dscps = parsed.lbs, parsed.vrfs, parsed.dscps
+	return nil
+}
+
+// logicalBridge returns the marking of the logical bridge
+func (q *qosMarkings) logicalBridge(name string) (qosMarking, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	m, ok := q.lbs[path.Base(name)]
+	return m, ok
+}
+
+// vrf returns the marking of the vrf, the nexthops give the linux name of
+// the vrf which is the base of its name
+func (q *qosMarkings) vrf(name string) (qosMarking, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	m, ok := q.vrfs[path.Base(name)]
+	return m, ok
+}
+
+// vlan returns the marking of the logical bridge of the vlan
+func (q *qosMarkings) vlan(vid uint16) (qosMarking, bool) {
+	if name, ok := vlanBridge(vid); ok {
+		return q.logicalBridge(name)
+	}
+	return qosMarking{}, false
+}
+
+// vlanBridges are the logical bridges by vlan, kept by the logical bridge
+// hook of the vxlan decoder so the nexthops find the marking and encap of
+// their vlan without reading infradb
+var vlanBridges struct {
+	mu  sync.RWMutex
+	lbs map[uint16]string
+}
+
+// trackVlanBridge records the logical bridge of its vlan when added and
+// forgets it when deleted
+func trackVlanBridge(op Operation, lb *infradb.LogicalBridge) {
+	if lb.Spec == nil || lb.Spec.VlanID == 0 {
+		return
+	}
+	vid, name := uint16(lb.Spec.VlanID), path.Base(lb.Name)
+	vlanBridges.mu.Lock()
+	defer vlanBridges.mu.Unlock()
+	if op == OpDeleted {
+		if vlanBridges.lbs[vid] == name {
+			delete(vlanBridges.lbs, vid)
+		}
+		return
+	}
+	if vlanBridges.lbs == nil {
+		vlanBridges.lbs = make(map[uint16]string)
+	}
+	vlanBridges.lbs[vid] = name
+}
+
+// resetVlanBridges forgets the logical bridges of the vlans
+func resetVlanBridges() {
+	vlanBridges.mu.Lock()
+	defer vlanBridges.mu.Unlock()
+	vlanBridges.lbs = nil
+}
+
+// vlanBridge returns the base of the name of the logical bridge of the vlan
+func vlanBridge(vid uint16) (string, bool) {
+	vlanBridges.mu.RLock()
+	defer vlanBridges.mu.RUnlock()
+	name, ok := vlanBridges.lbs[vid]
+	return name, ok
+}
+
+// sviPcp returns the pcp of the traffic routed into the vlan, the marking of
+// the logical bridge goes before the one of the vrf
+func (q *qosMarkings) sviPcp(vid uint16, vrf string) uint16 {
+	if m, ok := q.vlan(vid); ok {
+		return m.pcp
+	}
+	m, _ := q.vrf(vrf)
+	return m.pcp
+}
+
+// bridgePortPcp returns the pcp of the logical bridges of the bridge port,
+// none when they are marked differently
+func (q *qosMarkings) bridgePortPcp(lbs []string) uint16 {
+	var pcp uint16
+	var marked bool
+	for _, lb := range lbs {
+		m, ok := q.logicalBridge(lb)
+		if !ok {
+			continue
+		}
+		if marked && m.pcp != pcp {
+			log.Printf("intel-e2000: logical bridges %v are marked with different pcps, pushing 0\n", lbs)
+			return 0
+		}
+		pcp, marked = m.pcp, true
+	}
+	return pcp
+}
+
+// dscpParams returns the dscp params of the vxlan push actions, none when
+// no marking sets a dscp so pipelines without them keep working
+func (q *qosMarkings) dscpParams(m qosMarking) []interface{} {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if !q.dscps {
+		return nil
+	}
+	var copyInner uint16
+	if m.dscpCopy {
+		copyInner = 1
+	}
+	return []interface{}{m.dscp, copyInner}
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+)
+
+func TestQosMarkings_Set(t *testing.T) {
+	tests := map[string]struct {
+		markings []qosConfig
+		errMsg   string
+	}{
+		"logical bridge and vrf": {
+			markings: []qosConfig{
+				{LogicalBridge: "lb-voice", Pcp: 5, Dscp: "46"},
+				{Vrf: "//network.opiproject.org/vrfs/vrf-blue", Pcp: 3, Dscp: dscpCopy},
+			},
+		},
+		"neither logical bridge nor vrf": {
+			markings: []qosConfig{{Pcp: 5}},
+			errMsg:   "qos: marking 1 needs either a logical bridge or a vrf",
+		},
+		"both logical bridge and vrf": {
+			markings: []qosConfig{{LogicalBridge: "lb-voice", Vrf: "vrf-blue"}},
+			errMsg:   "qos: marking 1 needs either a logical bridge or a vrf",
+		},
+		"pcp out of range": {
+			markings: []qosConfig{{LogicalBridge: "lb-voice", Pcp: 8}},
+			errMsg:   "qos: marking 1: pcp 8 above 7",
+		},
+		"dscp out of range": {
+			markings: []qosConfig{{Vrf: "vrf-blue", Dscp: "64"}},
+			errMsg:   "qos: marking 1: dscp 64 is neither 0 to 63 nor copy",
+		},
+		"duplicate marking": {
+			markings: []qosConfig{{Vrf: "vrf-blue"}, {Vrf: "//network.opiproject.org/vrfs/vrf-blue"}},
+			errMsg:   "qos: duplicate marking of //network.opiproject.org/vrfs/vrf-blue",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var q qosMarkings
+			err := q.set(tt.markings)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestQosMarkings_Lookup(t *testing.T) {
+	trackVlanBridge(OpAdded, &infradb.LogicalBridge{
+		Name: "//network.opiproject.org/bridges/lb-voice",
+		Spec: &infradb.LogicalBridgeSpec{VlanID: 20},
+	})
+	t.Cleanup(resetVlanBridges)
+
+	var q qosMarkings
+	if err := q.set([]qosConfig{
+		{LogicalBridge: "lb-voice", Pcp: 5, Dscp: "46"},
+		{LogicalBridge: "lb-data", Pcp: 1},
+		{Vrf: "vrf-blue", Pcp: 3, Dscp: dscpCopy},
+	}); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if m, ok := q.vlan(20); !ok || m.pcp != 5 {
+		t.Errorf("Expected pcp 5 on vlan 20, received %v", m)
+	}
+	if _, ok := q.vlan(30); ok {
+		t.Errorf("Expected no marking on vlan 30")
+	}
+	if pcp := q.sviPcp(20, "vrf-blue"); pcp != 5 {
+		t.Errorf("Expected the logical bridge pcp 5, received %d", pcp)
+	}
+	if pcp := q.sviPcp(30, "vrf-blue"); pcp != 3 {
+		t.Errorf("Expected the vrf pcp 3, received %d", pcp)
+	}
+	if pcp := q.bridgePortPcp([]string{"//network.opiproject.org/bridges/lb-voice", "lb-other"}); pcp != 5 {
+		t.Errorf("Expected pcp 5 on the bridge port, received %d", pcp)
+	}
+	if pcp := q.bridgePortPcp([]string{"lb-voice", "lb-data"}); pcp != 0 {
+		t.Errorf("Expected pcp 0 for differently marked logical bridges, received %d", pcp)
+	}
+	vrf, _ := q.vrf("vrf-blue")
+	if params := q.dscpParams(vrf); !reflect.DeepEqual(params, []interface{}{uint16(0), uint16(1)}) {
+		t.Errorf("Expected the dscp copied, received %v", params)
+	}
+	lb, _ := q.logicalBridge("lb-voice")
+	if params := q.dscpParams(lb); !reflect.DeepEqual(params, []interface{}{uint16(46), uint16(0)}) {
+		t.Errorf("Expected dscp 46, received %v", params)
+	}
+
+	// without dscp markings the vxlan push actions keep their params
+	if err := q.set([]qosConfig{{LogicalBridge: "lb-voice", Pcp: 5}}); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if params := q.dscpParams(lb); params != nil {
+		t.Errorf("Expected no dscp params, received %v", params)
+	}
+
+	// the vlan of a deleted logical bridge is no longer marked
+	trackVlanBridge(OpDeleted, &infradb.LogicalBridge{
+		Name: "//network.opiproject.org/bridges/lb-voice",
+		Spec: &infradb.LogicalBridgeSpec{VlanID: 20},
+	})
+	if _, ok := q.vlan(20); ok {
+		t.Errorf("Expected no marking on vlan 20 once lb-voice is deleted")
+	}
+}
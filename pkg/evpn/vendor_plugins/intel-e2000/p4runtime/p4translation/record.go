@@ -0,0 +1,482 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	nm "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+const (
+	// sourceInit records the decoder set up
+	sourceInit = "init"
+	// sourceInfradb records an infradb object event
+	sourceInfradb = "infradb"
+	// sourceNetlink records a netlink event
+	sourceNetlink = "netlink"
+)
+
+// metaRecord is a netlink metadata value tagged with its go type
+type metaRecord struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// encodeMetadata tags the metadata values with their types, sorted by key
+func encodeMetadata(metadata map[interface{}]interface{}) []metaRecord {
+	var recs []metaRecord
+	for k, v := range metadata {
+		rec := metaRecord{Key: fmt.Sprint(k), Value: fmt.Sprint(v)}
+		switch val := v.(type) {
+		case string:
+			rec.Type = "string"
+		case int:
+			rec.Type = "int"
+		case uint32:
+			rec.Type = "uint32"
+		case uint16:
+			rec.Type = "uint16"
+		case bool:
+			rec.Type = "bool"
+		case infradb.BridgePortType:
+			rec.Type = "bridgeporttype"
+			rec.Value = strconv.Itoa(int(val))
+		case net.IP:
+			rec.Type = "ip"
+		case net.HardwareAddr:
+			rec.Type = "mac"
+		case net.IPNet:
+			rec.Type = "ipnet"
+			rec.Value = val.String()
+		case *net.IPNet:
+			rec.Type = "*ipnet"
+		default:
+			rec.Type = "unknown"
+			log.Printf("intel-e2000: recording metadata %v of unknown type %T as string\n", k, v)
+		}
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Key < recs[j].Key })
+	return recs
+}
+
+// decodeMetadata restores the metadata values from their type tags
+func decodeMetadata(recs []metaRecord) map[interface{}]interface{} {
+	metadata := make(map[interface{}]interface{})
+	for _, rec := range recs {
+		var v interface{} = rec.Value
+		switch rec.Type {
+		case "int":
+			i, _ := strconv.Atoi(rec.Value)
+			v = i
+		case "uint32":
+			i, _ := strconv.ParseUint(rec.Value, 10, 32)
+			v = uint32(i)
+		case "uint16":
+			i, _ := strconv.ParseUint(rec.Value, 10, 16)
+			v = uint16(i)
+		case "bool":
+			v, _ = strconv.ParseBool(rec.Value)
+		case "bridgeporttype":
+			i, _ := strconv.Atoi(rec.Value)
+			v = infradb.BridgePortType(i)
+		case "ip":
+			v = net.ParseIP(rec.Value)
+		case "mac":
+			v, _ = net.ParseMAC(rec.Value)
+		case "ipnet", "*ipnet":
+			ip, ipnet, _ := net.ParseCIDR(rec.Value)
+			if ipnet != nil {
+				if ipnet.IP = ip.To4(); ipnet.IP == nil {
+					ipnet.IP = ip
+				}
+				v = ipnet
+				if rec.Type == "ipnet" {
+					v = *ipnet
+				}
+			}
+		}
+		metadata[rec.Key] = v
+	}
+	return metadata
+}
+
+// nexthopRecord is the recorded form of a netlink nexthop
+type nexthopRecord struct {
+	Key      nm.NexthopKey `json:"key"`
+	Vrf      *infradb.Vrf  `json:"vrf,omitempty"`
+	Local    bool          `json:"local,omitempty"`
+	Weight   int           `json:"weight,omitempty"`
+	Metric   int           `json:"metric,omitempty"`
+	ID       int           `json:"id"`
+	Scope    int           `json:"scope,omitempty"`
+	Protocol int           `json:"protocol,omitempty"`
+	Resolved bool          `json:"resolved"`
+	NhType   int           `json:"nhtype"`
+	Metadata []metaRecord  `json:"metadata,omitempty"`
+	Dir      int           `json:"dir,omitempty"`
+	Divisor  int           `json:"divisor,omitempty"`
+	Value    float64       `json:"value,omitempty"`
+	Hashes   []int         `json:"hashes,omitempty"`
+}
+
+// newNexthopRecord records a nexthop, its routes and neighbor are left out
+func newNexthopRecord(nh *nm.NexthopStruct) *nexthopRecord {
+	return &nexthopRecord{
+		Key: nh.Key, Vrf: nh.Vrf, Local: nh.Local, Weight: nh.Weight, Metric: nh.Metric,
+		ID: nh.ID, Scope: nh.Scope, Protocol: nh.Protocol, Resolved: nh.Resolved, NhType: nh.NhType,
+		Metadata: encodeMetadata(nh.Metadata), Dir: nh.Dir, Divisor: nh.Divisor, Value: nh.Value, Hashes: nh.Hashes,
+	}
+}
+
+// nexthop restores the nexthop
+func (r *nexthopRecord) nexthop() *nm.NexthopStruct {
+	return &nm.NexthopStruct{
+		Key: r.Key, Vrf: r.Vrf, Local: r.Local, Weight: r.Weight, Metric: r.Metric,
+		ID: r.ID, Scope: r.Scope, Protocol: r.Protocol, Resolved: r.Resolved, NhType: r.NhType,
+		Metadata: decodeMetadata(r.Metadata), Dir: r.Dir, Divisor: r.Divisor, Value: r.Value, Hashes: r.Hashes,
+	}
+}
+
+// routeRecord is the recorded form of a netlink route
+type routeRecord struct {
+	Key      nm.RouteKey      `json:"key"`
+	Vrf      *infradb.Vrf     `json:"vrf,omitempty"`
+	Dst      string           `json:"dst,omitempty"`
+	Nexthops []*nexthopRecord `json:"nexthops,omitempty"`
+	Metadata []metaRecord     `json:"metadata,omitempty"`
+	NlType   string           `json:"nltype,omitempty"`
+}
+
+// newRouteRecord records a route with its nexthops
+func newRouteRecord(route *nm.RouteStruct) *routeRecord {
+	rec := &routeRecord{Key: route.Key, Vrf: route.Vrf, Metadata: encodeMetadata(route.Metadata), NlType: route.NlType}
+	if route.Route0.Dst != nil {
+		rec.Dst = route.Route0.Dst.String()
+	}
+	for _, nh := range route.Nexthops {
+		rec.Nexthops = append(rec.Nexthops, newNexthopRecord(nh))
+	}
+	return rec
+}
+
+// route restores the route
+func (r *routeRecord) route() *nm.RouteStruct {
+	route := &nm.RouteStruct{Key: r.Key, Vrf: r.Vrf, Metadata: decodeMetadata(r.Metadata), NlType: r.NlType}
+	if r.Dst != "" {
+		_, route.Route0.Dst, _ = net.ParseCIDR(r.Dst)
+	}
+	for _, nh := range r.Nexthops {
+		route.Nexthops = append(route.Nexthops, nh.nexthop())
+	}
+	return route
+}
+
+// l2NexthopRecord is the recorded form of a netlink l2 nexthop
+type l2NexthopRecord struct {
+	Key      nm.L2NexthopKey `json:"key"`
+	Dev      string          `json:"dev"`
+	VlanID   int             `json:"vlanid"`
+	Dst      net.IP          `json:"dst,omitempty"`
+	ID       int             `json:"id"`
+	Resolved bool            `json:"resolved"`
+	Type     int             `json:"type"`
+	Metadata []metaRecord    `json:"metadata,omitempty"`
+}
+
+// newL2NexthopRecord records an l2 nexthop, its fdb entries are left out
+func newL2NexthopRecord(nh *nm.L2NexthopStruct) *l2NexthopRecord {
+	return &l2NexthopRecord{
+		Key: nh.Key, Dev: nh.Dev, VlanID: nh.VlanID, Dst: nh.Dst, ID: nh.ID,
+		Resolved: nh.Resolved, Type: nh.Type, Metadata: encodeMetadata(nh.Metadata),
+	}
+}
+
+// l2Nexthop restores the l2 nexthop
+func (r *l2NexthopRecord) l2Nexthop() *nm.L2NexthopStruct {
+	return &nm.L2NexthopStruct{
+		Key: r.Key, Dev: r.Dev, VlanID: r.VlanID, Dst: r.Dst, ID: r.ID,
+		Resolved: r.Resolved, Type: r.Type, Metadata: decodeMetadata(r.Metadata),
+	}
+}
+
+// fdbRecord is the recorded form of a netlink fdb entry
+type fdbRecord struct {
+	Key      nm.FdbKey        `json:"key"`
+	VlanID   int              `json:"vlanid"`
+	Mac      string           `json:"mac"`
+	State    string           `json:"state,omitempty"`
+	Nexthop  *l2NexthopRecord `json:"nexthop,omitempty"`
+	Type     int              `json:"type"`
+	Metadata []metaRecord     `json:"metadata,omitempty"`
+}
+
+// newFdbRecord records an fdb entry with its l2 nexthop
+func newFdbRecord(fdb *nm.FdbEntryStruct) *fdbRecord {
+	rec := &fdbRecord{
+		Key: fdb.Key, VlanID: fdb.VlanID, Mac: fdb.Mac, State: fdb.State,
+		Type: fdb.Type, Metadata: encodeMetadata(fdb.Metadata),
+	}
+	if fdb.Nexthop != nil {
+		rec.Nexthop = newL2NexthopRecord(fdb.Nexthop)
+	}
+	return rec
+}
+
+// fdb restores the fdb entry
+func (r *fdbRecord) fdb() *nm.FdbEntryStruct {
+	fdb := &nm.FdbEntryStruct{
+		Key: r.Key, VlanID: r.VlanID, Mac: r.Mac, State: r.State,
+		Type: r.Type, Metadata: decodeMetadata(r.Metadata),
+	}
+	if r.Nexthop != nil {
+		fdb.Nexthop = r.Nexthop.l2Nexthop()
+	}
+	return fdb
+}
+
+// objectSnapshot holds the infradb objects a decoder looked up
+type objectSnapshot struct {
+	Vrfs           []*infradb.Vrf           `json:"vrfs,omitempty"`
+	LogicalBridges []*infradb.LogicalBridge `json:"logicalbridges,omitempty"`
+	BridgePorts    []*infradb.BridgePort    `json:"bridgeports,omitempty"`
+	Svis           []*infradb.Svi           `json:"svis,omitempty"`
+}
+
+// entryOp is an entry added or deleted while handling an event
+type entryOp struct {
+	Op    string               `json:"op"`
+	Entry p4client.EntryRecord `json:"entry"`
+}
+
+// eventRecord is an event with the lookups and entries it caused
+type eventRecord struct {
+	Seq          uint64               `json:"seq"`
+	Source       string               `json:"source"`
+	EventType    string               `json:"eventtype,omitempty"`
+	Op           string               `json:"op,omitempty"`
+	Representors map[string][2]string `json:"representors,omitempty"`
+	Object       *objectSnapshot      `json:"object,omitempty"`
+	Route        *routeRecord         `json:"route,omitempty"`
+	Nexthop      *nexthopRecord       `json:"nexthop,omitempty"`
+	Fdb          *fdbRecord           `json:"fdb,omitempty"`
+	L2Nexthop    *l2NexthopRecord     `json:"l2nexthop,omitempty"`
+	Lookups      *objectSnapshot      `json:"lookups,omitempty"`
+	Entries      []entryOp            `json:"entries,omitempty"`
+}
+
+// newNetlinkRecord records a netlink event
+func newNetlinkRecord(eventType string, event interface{}) *eventRecord {
+	rec := &eventRecord{Source: sourceNetlink, EventType: eventType}
+	switch data := event.(type) {
+	case *nm.RouteStruct:
+		rec.Route = newRouteRecord(data)
+	case *nm.NexthopStruct:
+		rec.Nexthop = newNexthopRecord(data)
+	case *nm.FdbEntryStruct:
+		rec.Fdb = newFdbRecord(data)
+	case *nm.L2NexthopStruct:
+		rec.L2Nexthop = newL2NexthopRecord(data)
+	}
+	return rec
+}
+
+// netlinkEvent restores the netlink event data
+func (r *eventRecord) netlinkEvent() interface{} {
+	switch {
+	case r.Route != nil:
+		return r.Route.route()
+	case r.Nexthop != nil:
+		return r.Nexthop.nexthop()
+	case r.Fdb != nil:
+		return r.Fdb.fdb()
+	case r.L2Nexthop != nil:
+		return r.L2Nexthop.l2Nexthop()
+	}
+	return nil
+}
+
+// newObjectRecord records an infradb object event
+func newObjectRecord(eventType string, op Operation, obj interface{}) *eventRecord {
+	rec := &eventRecord{Source: sourceInfradb, EventType: eventType, Op: op.String(), Object: &objectSnapshot{}}
+	rec.Object.add(obj)
+	return rec
+}
+
+// add appends obj to the snapshot
+func (s *objectSnapshot) add(obj interface{}) {
+	switch o := obj.(type) {
+	case *infradb.Vrf:
+		s.Vrfs = append(s.Vrfs, o)
+	case *infradb.LogicalBridge:
+		s.LogicalBridges = append(s.LogicalBridges, o)
+	case *infradb.BridgePort:
+		s.BridgePorts = append(s.BridgePorts, o)
+	case *infradb.Svi:
+		s.Svis = append(s.Svis, o)
+	}
+}
+
+// eventRecorder writes every event the plugin handles together with the
+// infradb lookups and the entries it caused. Events are handled one at a
+// time while recording so the file has the order the entries were applied.
+type eventRecorder struct {
+	mu      sync.Mutex
+	emit    func(rec *eventRecord) error
+	closer  io.Closer
+	seq     uint64
+	curMu   sync.Mutex
+	current *eventRecord
+}
+
+// recorder is the active event recorder, nil when not recording
+var recorder *eventRecorder
+
+// newEventRecorder creates a recorder writing json lines to w
+func newEventRecorder(w io.Writer) *eventRecorder {
+	enc := json.NewEncoder(w)
+	r := &eventRecorder{emit: func(rec *eventRecord) error { return enc.Encode(rec) }}
+	if c, ok := w.(io.Closer); ok {
+		r.closer = c
+	}
+	return r
+}
+
+// startRecording records the events into the file at path
+func startRecording(path string) error {
+	f, err := os.OpenFile(filepath.Clean(path), os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	recorder = newEventRecorder(f)
+	objects = recordingStore{next: objects}
+	return nil
+}
+
+// stopRecording closes the recording file
+func stopRecording() {
+	if recorder == nil {
+		return
+	}
+	if recorder.closer != nil {
+		_ = recorder.closer.Close()
+	}
+	recorder = nil
+}
+
+// track runs fn as the handling of rec and writes rec with what fn caused
+func (r *eventRecorder) track(rec *eventRecord, fn func()) {
+	if r == nil {
+		fn()
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	rec.Seq = r.seq
+	r.curMu.Lock()
+	r.current = rec
+	r.curMu.Unlock()
+	fn()
+	r.curMu.Lock()
+	r.current = nil
+	r.curMu.Unlock()
+	if err := r.emit(rec); err != nil {
+		log.Printf("intel-e2000: error recording event %d: %v\n", rec.Seq, err)
+	}
+}
+
+// entry records an entry applied by the current event
+func (r *eventRecorder) entry(op string, e p4client.TableEntry) {
+	if r == nil {
+		return
+	}
+	r.curMu.Lock()
+	defer r.curMu.Unlock()
+	if r.current != nil {
+		r.current.Entries = append(r.current.Entries, entryOp{Op: op, Entry: p4client.NewEntryRecord(e)})
+	}
+}
+
+// lookup records an infradb object read by the current event
+func (r *eventRecorder) lookup(obj interface{}) {
+	if r == nil {
+		return
+	}
+	r.curMu.Lock()
+	defer r.curMu.Unlock()
+	if r.current != nil {
+		if r.current.Lookups == nil {
+			r.current.Lookups = &objectSnapshot{}
+		}
+		r.current.Lookups.add(obj)
+	}
+}
+
+// recordingStore records the lookups of the decoders
+type recordingStore struct {
+	next objectStore
+}
+
+// GetVrf gets and records the vrf
+func (s recordingStore) GetVrf(name string) (*infradb.Vrf, error) {
+	vrf, err := s.next.GetVrf(name)
+	if err == nil {
+		recorder.lookup(vrf)
+	}
+	return vrf, err
+}
+
+// GetLB gets and records the logical bridge
+func (s recordingStore) GetLB(name string) (*infradb.LogicalBridge, error) {
+	lb, err := s.next.GetLB(name)
+	if err == nil {
+		recorder.lookup(lb)
+	}
+	return lb, err
+}
+
+// GetBP gets and records the bridge port
+func (s recordingStore) GetBP(name string) (*infradb.BridgePort, error) {
+	bp, err := s.next.GetBP(name)
+	if err == nil {
+		recorder.lookup(bp)
+	}
+	return bp, err
+}
+
+// GetSvi gets and records the svi
+func (s recordingStore) GetSvi(name string) (*infradb.Svi, error) {
+	svi, err := s.next.GetSvi(name)
+	if err == nil {
+		recorder.lookup(svi)
+	}
+	return svi, err
+}
+
+// GetAllBPs gets the bridge ports, only the config checks list them so
+// they are not recorded
+func (s recordingStore) GetAllBPs() ([]*infradb.BridgePort, error) {
+	return s.next.GetAllBPs()
+}
+
+// GetAllSvis gets the svis, not recorded either
+func (s recordingStore) GetAllSvis() ([]*infradb.Svi, error) {
+	return s.next.GetAllSvis()
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+var testRepresentors = map[string][2]string{
+	"phy0_rep":  {"16", "00:10:00:00:03:14"},
+	"phy1_rep":  {"17", "00:11:00:00:03:14"},
+	"grpc_acc":  {"21", "00:15:00:00:03:14"},
+	"grpc_host": {"13", "00:0d:00:03:09:64"},
+	"vrf_mux":   {"23", "00:17:00:00:03:14"},
+	"port_mux":  {"24", "00:18:00:00:03:14"},
+}
+
+func TestRecord_Metadata(t *testing.T) {
+	_, vtep, _ := net.ParseCIDR("10.0.0.1/32")
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	tests := map[string]struct {
+		in map[interface{}]interface{}
+	}{
+		"scalar values": {
+			in: map[interface{}]interface{}{"direction": 1, "vlanID": uint32(4089), "dmac": "00:11:22:33:44:55"},
+		},
+		"infradb and net values": {
+			in: map[interface{}]interface{}{
+				"portType":       infradb.Trunk,
+				"remote_vtep_ip": net.ParseIP("10.0.0.2"),
+				"local_vtep_ip":  *vtep,
+				"mac":            mac,
+			},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if got := decodeMetadata(encodeMetadata(tt.in)); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("Expected metadata: %v, received %v", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestRecord_Replay(t *testing.T) {
+	tests := map[string]struct {
+		tamper bool
+		diffs  int
+	}{
+		"replay matches the recording": {
+			diffs: 0,
+		},
+		"changed entry is reported": {
+			tamper: true,
+			diffs:  1,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			resetState()
+			p4client.SetDryRun(nil)
+			var buf bytes.Buffer
+			recorder = newEventRecorder(&buf)
+			setUpDecoders(testRepresentors)
+			tearDownDecoders()
+			recorder = nil
+
+			recording := buf.String()
+			if !strings.Contains(recording, `"entries"`) {
+				t.Fatalf("Expected entries in the recording, received %s", recording)
+			}
+			if tt.tamper {
+				recording = strings.Replace(recording, `"op":"add"`, `"op":"delete"`, 1)
+			}
+			path := filepath.Join(t.TempDir(), "recording.json")
+			if err := os.WriteFile(path, []byte(recording), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			diffs, err := Replay(path, nil, &out)
+			if err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+			if diffs != tt.diffs {
+				t.Errorf("Expected diffs: %d, received %d: %s", tt.diffs, diffs, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// ownerRef marks the decoder that owns the entries of a shared key
+type ownerRef string
+
+// parkedEvent is a route or fdb entry waiting for its nexthops
+type parkedEvent struct {
+	shard  string
+	ev     netlinkEvent
+	deps   []interface{}
+	since  time.Time
+	warned time.Time
+}
+
+// refTable tracks the nexthops shared between translated objects. A
+// nexthop exists while a decoder owns it, that is writes its entries, and
+// is used by the routes, fdb entries or rules pointing at it. Deleting the
+// owner while users remain parks the deletion, which runs when the last
+// user lets go. A route or fdb entry whose nexthops do not exist yet is
+// parked until they show up. The netlink shards and the decoders share
+// the table, it is the only place holding this state.
+type refTable struct {
+	mu       sync.Mutex
+	users    map[interface{}]map[interface{}]bool
+	keys     map[interface{}][]interface{}
+	deferred map[interface{}]map[ownerRef]func() []interface{}
+	parked   map[interface{}]*parkedEvent
+	shared   map[string]map[interface{}]bool
+	sharedBy map[interface{}][]string
+}
+
+// newRefTable creates a reference table
+func newRefTable() *refTable {
+	return &refTable{
+		users:    make(map[interface{}]map[interface{}]bool),
+		keys:     make(map[interface{}][]interface{}),
+		deferred: make(map[interface{}]map[ownerRef]func() []interface{}),
+		parked:   make(map[interface{}]*parkedEvent),
+		shared:   make(map[string]map[interface{}]bool),
+		sharedBy: make(map[interface{}][]string),
+	}
+}
+
+// nexthopRefs counts the users of l3 nexthop entries, keyed by nexthop key
+var nexthopRefs = newRefTable()
+
+// l2NexthopRefs counts the users of l2 nexthop entries, keyed by l2 nexthop key
+var l2NexthopRefs = newRefTable()
+
+// count returns the number of users of key
+func (r *refTable) count(key interface{}) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.users[key])
+}
+
+// add records user against key
+func (r *refTable) add(key interface{}, user interface{}) {
+	if r.users[key] == nil {
+		r.users[key] = make(map[interface{}]bool)
+	}
+	r.users[key][user] = true
+}
+
+// remove drops user from key and returns the parked deletions once key is unused
+func (r *refTable) remove(key interface{}, user interface{}) []func() []interface{} {
+	delete(r.users[key], user)
+	if len(r.users[key]) != 0 {
+		return nil
+	}
+	delete(r.users, key)
+	var dels []func() []interface{}
+	for _, del := range r.deferred[key] {
+		dels = append(dels, del)
+	}
+	delete(r.deferred, key)
+	if len(dels) != 0 {
+		r.unshare(key)
+	}
+	return dels
+}
+
+// retain records that user points at key
+func (r *refTable) retain(key interface{}, user interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[key][user]; ok {
+		return
+	}
+	r.add(key, user)
+	r.keys[user] = append(r.keys[user], key)
+}
+
+// retains reports whether user points at a key
+func (r *refTable) retains(user interface{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.keys[user]) != 0
+}
+
+// releaseUser drops every key user retained and returns the deletions that became due
+func (r *refTable) releaseUser(user interface{}) []interface{} {
+	r.mu.Lock()
+	var dels []func() []interface{}
+	for _, key := range r.keys[user] {
+		dels = append(dels, r.remove(key, user)...)
+	}
+	delete(r.keys, user)
+	r.mu.Unlock()
+	// the deletions build their entries with the table unlocked
+	var entries []interface{}
+	for _, del := range dels {
+		entries = append(entries, del()...)
+	}
+	return entries
+}
+
+// ownerAdded registers owner as user of key. A deletion of owner still
+// parked on key is dropped, since the entries are about to be rewritten.
+func (r *refTable) ownerAdded(key interface{}, owner string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.deferred[key], ownerRef(owner))
+	r.add(key, ownerRef(owner))
+}
+
+// ownerDeleted releases owner from key. The deletion runs right away when
+// nothing else uses key, otherwise it is parked until the last user goes.
+func (r *refTable) ownerDeleted(key interface{}, owner string, del func() []interface{}) []interface{} {
+	r.mu.Lock()
+	delete(r.users[key], ownerRef(owner))
+	if len(r.users[key]) != 0 {
+		if r.deferred[key] == nil {
+			r.deferred[key] = make(map[ownerRef]func() []interface{})
+		}
+		r.deferred[key][ownerRef(owner)] = del
+		r.mu.Unlock()
+		return nil
+	}
+	delete(r.users, key)
+	r.unshare(key)
+	r.mu.Unlock()
+	return del()
+}
+
+// share records the table entries an owner writes for key. They are
+// rewritten in place when written again while key still exists, e.g. when
+// the owner comes back before its deferred deletion ran or when another
+// nexthop shares the mod pointer.
+func (r *refTable) share(key interface{}, entries []interface{}) []interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range entries {
+		e, ok := entry.(p4client.TableEntry)
+		if !ok {
+			continue
+		}
+		id := e.Tablename + entryKey(e)
+		if r.shared[id] == nil {
+			r.shared[id] = make(map[interface{}]bool)
+		}
+		if !r.shared[id][key] {
+			r.shared[id][key] = true
+			r.sharedBy[key] = append(r.sharedBy[key], id)
+		}
+	}
+	return entries
+}
+
+// unshare forgets the entries of key once its deletion runs, with the
+// table locked
+func (r *refTable) unshare(key interface{}) {
+	for _, id := range r.sharedBy[key] {
+		delete(r.shared[id], key)
+		if len(r.shared[id]) == 0 {
+			delete(r.shared, id)
+		}
+	}
+	delete(r.sharedBy, key)
+}
+
+// isShared reports whether the entry belongs to a shared key
+func (r *refTable) isShared(e p4client.TableEntry) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.shared[e.Tablename+entryKey(e)]) != 0
+}
+
+// sharedEntry reports whether the entry belongs to an l3 or l2 nexthop
+func sharedEntry(e p4client.TableEntry) bool {
+	return nexthopRefs.isShared(e) || l2NexthopRefs.isShared(e)
+}
+
+// exists reports whether a decoder owns key
+func (r *refTable) exists(key interface{}) bool {
+	for user := range r.users[key] {
+		if _, ok := user.(ownerRef); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// satisfied reports whether all the nexthops exist
+func (r *refTable) satisfied(deps []interface{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.allExist(deps)
+}
+
+// allExist reports whether all the keys exist, with the table locked
+func (r *refTable) allExist(deps []interface{}) bool {
+	for _, d := range deps {
+		if !r.exists(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// park keeps the route or fdb entry of the shard until its nexthops exist,
+// a newer version replaces the parked one
+func (r *refTable) park(shard string, key interface{}, deps []interface{}, ev netlinkEvent) {
+	log.Printf("intel-e2000: parking %s %v until its nexthops are programmed\n", ev.eventType, key)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.parked[key]; ok {
+		p.ev, p.deps = ev, deps
+		return
+	}
+	r.parked[key] = &parkedEvent{shard: shard, ev: ev, deps: deps, since: time.Now()}
+}
+
+// unpark forgets a parked route or fdb entry and reports whether it was parked
+func (r *refTable) unpark(key interface{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.parked[key]
+	delete(r.parked, key)
+	return ok
+}
+
+// releaseParked unparks the entries of the shard whose nexthops now exist
+// and returns their events
+func (r *refTable) releaseParked(shard string) []netlinkEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []netlinkEvent
+	for key, p := range r.parked {
+		if p.shard == shard && r.allExist(p.deps) {
+			delete(r.parked, key)
+			events = append(events, p.ev)
+		}
+	}
+	return events
+}
+
+// parkedCount returns the number of entries the shard parked
+func (r *refTable) parkedCount(shard string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, p := range r.parked {
+		if p.shard == shard {
+			n++
+		}
+	}
+	return n
+}
+
+// warnParked logs the entries of the shard parked for longer than after,
+// once per period
+func (r *refTable) warnParked(shard string, now time.Time, after time.Duration) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	warned := 0
+	for key, p := range r.parked {
+		if p.shard != shard || now.Sub(p.since) < after || now.Sub(p.warned) < after {
+			continue
+		}
+		log.Printf("intel-e2000: %s %v still waits for nexthops %v after %v\n", shard, key, p.deps, now.Sub(p.since).Round(time.Second))
+		p.warned = now
+		warned++
+	}
+	return warned
+}
+
+// _withoutModEntries drops the mod table entries of modPtr, used when
+// the mod pointer is still referenced by another nexthop
+func _withoutModEntries(entries []interface{}, modPtr uint32) []interface{} {
+	var kept []interface{}
+	for _, entry := range entries {
+		if e, ok := entry.(p4client.TableEntry); ok {
+			if ptr, ok := e.FieldValue["meta.common.mod_blob_ptr"]; ok && ptr[0] == modPtr {
+				continue
+			}
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
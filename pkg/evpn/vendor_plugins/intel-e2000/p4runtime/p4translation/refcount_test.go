@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"reflect"
+	"testing"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestRefTable_DeferredDeletion(t *testing.T) {
+	del := func() []interface{} { return []interface{}{"nh"} }
+	tests := map[string]struct {
+		run    func(r *refTable) []interface{}
+		out    []interface{}
+		unused bool
+	}{
+		"owner without users deletes right away": {
+			run: func(r *refTable) []interface{} {
+				r.ownerAdded(testNh1.Key, "l3")
+				return r.ownerDeleted(testNh1.Key, "l3", del)
+			},
+			out:    []interface{}{"nh"},
+			unused: true,
+		},
+		"owner deleted while a route uses it is deferred": {
+			run: func(r *refTable) []interface{} {
+				r.ownerAdded(testNh1.Key, "l3")
+				r.retain(testNh1.Key, testRt1.Key)
+				return r.ownerDeleted(testNh1.Key, "l3", del)
+			},
+			out: nil,
+		},
+		"last user releases the deferred deletion": {
+			run: func(r *refTable) []interface{} {
+				r.ownerAdded(testNh1.Key, "l3")
+				r.retain(testNh1.Key, testRt1.Key)
+				r.ownerDeleted(testNh1.Key, "l3", del)
+				return r.releaseUser(testRt1.Key)
+			},
+			out:    []interface{}{"nh"},
+			unused: true,
+		},
+		"re-added owner drops the deferred deletion": {
+			run: func(r *refTable) []interface{} {
+				r.ownerAdded(testNh1.Key, "l3")
+				r.retain(testNh1.Key, testRt1.Key)
+				r.ownerDeleted(testNh1.Key, "l3", del)
+				r.ownerAdded(testNh1.Key, "l3")
+				return r.releaseUser(testRt1.Key)
+			},
+			out: nil,
+		},
+		"retaining twice counts once": {
+			run: func(r *refTable) []interface{} {
+				r.retain(testNh1.Key, testRt1.Key)
+				r.retain(testNh1.Key, testRt1.Key)
+				return r.releaseUser(testRt1.Key)
+			},
+			out:    nil,
+			unused: true,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			r := newRefTable()
+			if got := tt.run(r); !reflect.DeepEqual(got, tt.out) {
+				t.Errorf("Expected entries: %v, received %v", tt.out, got)
+			}
+			if unused := r.count(testNh1.Key) == 0; unused != tt.unused {
+				t.Errorf("Expected unused: %v, received %v", tt.unused, unused)
+			}
+		})
+	}
+}
+
+func TestRefTable_Shared(t *testing.T) {
+	mod := p4client.TableEntry{
+		Tablename: "mod",
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{"meta.common.mod_blob_ptr": {uint32(7), "exact"}},
+		},
+	}
+	del := func() []interface{} { return nil }
+	tests := map[string]struct {
+		run    func(r *refTable)
+		shared bool
+	}{
+		"entries of an owned nexthop are shared": {
+			run: func(r *refTable) {
+				r.ownerAdded(testNh1.Key, "l3")
+				r.share(testNh1.Key, []interface{}{mod})
+			},
+			shared: true,
+		},
+		"entries of a deferred deletion stay shared": {
+			run: func(r *refTable) {
+				r.ownerAdded(testNh1.Key, "l3")
+				r.share(testNh1.Key, []interface{}{mod})
+				r.retain(testNh1.Key, testRt1.Key)
+				r.ownerDeleted(testNh1.Key, "l3", del)
+			},
+			shared: true,
+		},
+		"entries of a deleted nexthop are not shared": {
+			run: func(r *refTable) {
+				r.ownerAdded(testNh1.Key, "l3")
+				r.share(testNh1.Key, []interface{}{mod})
+				r.retain(testNh1.Key, testRt1.Key)
+				r.ownerDeleted(testNh1.Key, "l3", del)
+				r.releaseUser(testRt1.Key)
+			},
+			shared: false,
+		},
+		"entries of another nexthop stay shared": {
+			run: func(r *refTable) {
+				r.ownerAdded(testNh1.Key, "l3")
+				r.share(testNh1.Key, []interface{}{mod})
+				r.share(testRt1.Key, []interface{}{mod})
+				r.ownerDeleted(testNh1.Key, "l3", del)
+			},
+			shared: true,
+		},
+		"unknown entries are not shared": {
+			run:    func(r *refTable) {},
+			shared: false,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			r := newRefTable()
+			tt.run(r)
+			if shared := r.isShared(mod); shared != tt.shared {
+				t.Errorf("Expected shared: %v, received %v", tt.shared, shared)
+			}
+		})
+	}
+}
+
+func TestWithoutModEntries(t *testing.T) {
+	mod := p4client.TableEntry{
+		Tablename: "mod",
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{"meta.common.mod_blob_ptr": {uint32(7), "exact"}},
+		},
+	}
+	other := p4client.TableEntry{
+		Tablename: "other",
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{"meta.common.mod_blob_ptr": {uint32(8), "exact"}},
+		},
+	}
+	tests := map[string]struct {
+		in  []interface{}
+		ptr uint32
+		out []interface{}
+	}{
+		"drops the entries of the pointer": {
+			in:  []interface{}{mod, other},
+			ptr: 7,
+			out: []interface{}{other},
+		},
+		"keeps entries of other pointers": {
+			in:  []interface{}{mod, other},
+			ptr: 9,
+			out: []interface{}{mod, other},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if got := _withoutModEntries(tt.in, tt.ptr); !reflect.DeepEqual(got, tt.out) {
+				t.Errorf("Expected entries: %v, received %v", tt.out, got)
+			}
+		})
+	}
+}
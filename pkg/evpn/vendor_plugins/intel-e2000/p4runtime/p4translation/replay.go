@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/utils"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// Replay feeds a recording back through the decoders against the dry run
+// driver, writing the programmed entries to j. The entries of every event
+// are compared with the recorded ones and the differences are reported to
+// out. Replay returns the number of events that differ.
+func Replay(path string, j *journal.Journal, out io.Writer) (int, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	p4client.SetDryRun(j)
+	resetState()
+	store := newSnapshotStore()
+	objects = store
+	var replayed *eventRecord
+	recorder = &eventRecorder{emit: func(rec *eventRecord) error {
+		replayed = rec
+		return nil
+	}}
+	defer func() {
+		recorder = nil
+		objects = infradbStore{}
+	}()
+
+	diffs := 0
+	dec := json.NewDecoder(f)
+	for {
+		var rec eventRecord
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return diffs, fmt.Errorf("reading %s: %w", path, err)
+		}
+		store.load(rec.Lookups)
+		store.load(rec.Object)
+
+		replayed = nil
+		if err := replayEvent(&rec); err != nil {
+			return diffs, fmt.Errorf("event %d: %w", rec.Seq, err)
+		}
+		var entries []entryOp
+		if replayed != nil {
+			entries = replayed.Entries
+		}
+		if missing, extra := diffEntries(rec.Entries, entries); len(missing) != 0 || len(extra) != 0 {
+			diffs++
+			fmt.Fprintf(out, "event %d %s %s %s:\n", rec.Seq, rec.Source, rec.EventType, rec.Op)
+			for _, e := range missing {
+				fmt.Fprintf(out, "  - %s\n", e)
+			}
+			for _, e := range extra {
+				fmt.Fprintf(out, "  + %s\n", e)
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// resetState brings the decoders back to the state of a freshly started plugin
+func resetState() {
+	for _, d := range Decoders() {
+		UnregisterDecoder(d.Name())
+	}
+	ptrPool, _ = utils.IDPoolInit("mod_ptr", ModPointer.ptrMinRange, ModPointer.ptrMaxRange)
+	trieIndexPool, _ = utils.IDPoolInit("trie_index", TrieIndex.triIdxMinRange, TrieIndex.triIdxMaxRange)
+	ecmpIndexPool, _ = utils.IDPoolInit("ecmp", EcmpIndex.ecmpIdxMinRange, EcmpIndex.ecmpIdxMaxRange)
+	nexthopRefs = newRefTable()
+	l2NexthopRefs = newRefTable()
+	desired = newDesiredState()
+	ecmpGroups = make(map[uint32]EcmpDispatcher)
+	floodGroups = newFloodLists()
+	aliases = newAliasTable()
+	mirrorSessions = make(map[string]mirrorState)
+	mirrorPool, _ = utils.IDPoolInit("mirror", 1, 1023)
+	resetVlanBridges()
+}
+
+// replayEvent handles a recorded event the way the plugin handled it
+func replayEvent(rec *eventRecord) error {
+	op := OpAdded
+	if rec.Op == OpDeleted.String() {
+		op = OpDeleted
+	}
+	switch rec.Source {
+	case sourceInit:
+		if op == OpDeleted {
+			tearDownDecoders()
+		} else {
+			setUpDecoders(rec.Representors)
+		}
+	case sourceNetlink:
+		event := rec.netlinkEvent()
+		if event == nil {
+			return fmt.Errorf("netlink event %s without data", rec.EventType)
+		}
+		handleNetlinkEvent(rec.EventType, event)
+	case sourceInfradb:
+		return replayObject(rec.EventType, op, rec.Object)
+	default:
+		return fmt.Errorf("unknown source %s", rec.Source)
+	}
+	return nil
+}
+
+// replayObject sets up or tears down the recorded infradb object
+func replayObject(eventType string, op Operation, obj *objectSnapshot) error {
+	if obj == nil {
+		return fmt.Errorf("%s event without object", eventType)
+	}
+	switch {
+	case eventType == "vrf" && len(obj.Vrfs) == 1:
+		if op == OpDeleted {
+			tearDownVrf(obj.Vrfs[0])
+		} else {
+			offloadVrf(obj.Vrfs[0])
+		}
+	case eventType == "logical-bridge" && len(obj.LogicalBridges) == 1:
+		if op == OpDeleted {
+			tearDownLb(obj.LogicalBridges[0])
+		} else {
+			setUpLb(obj.LogicalBridges[0])
+		}
+	case eventType == "bridge-port" && len(obj.BridgePorts) == 1:
+		if op == OpDeleted {
+			tearDownBp(obj.BridgePorts[0])
+		} else {
+			setUpBp(obj.BridgePorts[0])
+		}
+	case eventType == "svi" && len(obj.Svis) == 1:
+		if op == OpDeleted {
+			tearDownSvi(obj.Svis[0])
+		} else {
+			setUpSvi(obj.Svis[0])
+		}
+	default:
+		return fmt.Errorf("%s event without matching object", eventType)
+	}
+	return nil
+}
+
+// diffEntries returns the recorded entries that were not replayed and the
+// replayed entries that were not recorded
+func diffEntries(recorded, replayed []entryOp) ([]string, []string) {
+	count := make(map[string]int)
+	for _, e := range recorded {
+		count[entryString(e)]++
+	}
+	var extra []string
+	for _, e := range replayed {
+		s := entryString(e)
+		if count[s] == 0 {
+			extra = append(extra, s)
+			continue
+		}
+		count[s]--
+	}
+	var missing []string
+	for _, e := range recorded {
+		s := entryString(e)
+		if count[s] > 0 {
+			missing = append(missing, s)
+			count[s]--
+		}
+	}
+	return missing, extra
+}
+
+// entryString is the one line form of an entry used to compare entries
+func entryString(e entryOp) string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
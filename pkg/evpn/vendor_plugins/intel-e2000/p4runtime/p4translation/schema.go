@@ -0,0 +1,495 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	p4config "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// modPtrField is the match field of the mod tables
+	modPtrField = "meta.common.mod_blob_ptr"
+	// tcamPrefixField is the match field of the lpm root tables
+	tcamPrefixField = "user_meta.cmeta.tcam_prefix"
+	// controlPrefix prefixes the logical table and action names
+	controlPrefix = "evpn_gw_control."
+)
+
+// logicalTables lists the tables the decoders program with their match fields.
+// The decoders use the names of the evpn_gw program, the schema maps them to
+// the tables of the loaded pipeline.
+var logicalTables = map[string][]string{
+	phyInVxlan:       {"dst_ip", "vni", "da"},
+	phyInVxlanL2:     {"dst_ip", "vni"},
+	phyInArp:         {"port_id", "bit32_zeros"},
+	phyInIP:          {"port_id", "da"},
+	podInArpAccess:   {"vsi", "bit32_zeros"},
+	podInArpTrunk:    {"vsi", "vid"},
+	podInIPAccess:    {"vsi", "bit32_zeros"},
+	podInIPTrunk:     {"vsi", "vid"},
+	portInSviAccess:  {"vsi", "da"},
+	portInSviTrunk:   {"vsi", "vid", "da"},
+	portMuxIn:        {"vsi", "vid"},
+	portMuxFwd:       {"bit32_zeros"},
+	l2FwdLoop:        {"da"},
+	l2Fwd:            {"vlan_id", "da", "direction"},
+	l2Nh:             {"neighbor", "bit32_zeros"},
+	tcamEntries:      {tcamPrefixField},
+	tcamEntries2:     {tcamPrefixField},
+	l3Rt:             {"ipv4_table_lpm_root1", "dst_ip"},
+	l3RtHost:         {"vrf", "direction", "dst_ip"},
+	l3P2PRt:          {"ipv4_table_lpm_root2", "dst_ip"},
+	l3P2PRtHost:      {"vrf", "direction", "dst_ip"},
+	l3EcmpSel:        {"neighbor", "hash", "bit32_zeros"},
+	l2EcmpSel:        {"neighbor", "hash", "bit32_zeros"},
+	l3NhRx:           {"neighbor", "bit32_zeros"},
+	l3NhTx:           {"neighbor", "bit32_zeros"},
+	p2pIn:            {"neighbor", "bit32_zeros"},
+	pushVlan:         {modPtrField},
+	pushMacVlan:      {modPtrField},
+	pushDmacVlan:     {modPtrField},
+	macMod:           {modPtrField},
+	pushVxlanHdr:     {modPtrField},
+	podOutAccess:     {modPtrField},
+	podOutTrunk:      {modPtrField},
+	popCtagStag:      {modPtrField},
+	popStag:          {modPtrField},
+	pushQnQFlood:     {modPtrField},
+	pushVxlanOutHdr:  {modPtrField},
+	arpProxy:         {"vlan_id", "target_ip"},
+	ndProxy:          {"vlan_id", "target_ip"},
+	bumSrcVtep:       {"src_ip"},
+	bumSrcVtep6:      {"src_ip"},
+	aclBp:            {"vsi", "smac", "dmac", "ether_type", "sip", "dip", "ip_proto", "sport", "dport"},
+	aclSvi:           {"vlan_id", "smac", "dmac", "ether_type", "sip", "dip", "ip_proto", "sport", "dport"},
+	mirrorBp:         {"vsi", "direction"},
+	mirrorSviTable:   {"vlan_id", "direction"},
+	mirrorVrfTable:   {"vrf", "direction"},
+	erspanEncap:      {"session"},
+	phyInVxlan6:      {"dst_ip", "vni", "da"},
+	phyInVxlanL26:    {"dst_ip", "vni"},
+	pushVxlan6Hdr:    {modPtrField},
+	pushVxlan6OutHdr: {modPtrField},
+	l3Rt6:            {"vrf", "direction", "dst_ip"},
+	l3RtHost6:        {"vrf", "direction", "dst_ip"},
+	l3P2PRt6:         {"dst_ip"},
+	l3P2PRtHost6:     {"vrf", "direction", "dst_ip"},
+	vxlanPort:        {"dst_port", "vni"},
+	uplinkGroupTable: {"port", "slot"},
+	pbrTable:         {"vsi", "ether_type", "sip", "dip", "ip_proto", "sport", "dport"},
+	portSecTable:     {"vsi", "smac", "ether_type", "sip"},
+}
+
+// logicalActions names the params of the actions in the order the decoders
+// give them, params feeding a later lookup are named after the match field
+// they feed
+var logicalActions = map[string][]string{
+	"pop_vxlan_set_vrf_id":                   {modPtrField, tcamPrefixField, "vport", "vrf"},
+	"pop_vxlan_set_vlan_id":                  {modPtrField, "vlan_id", "vport"},
+	"set_vrf_id":                             {tcamPrefixField, "vport", "vrf"},
+	"set_vrf_id_tx":                          {tcamPrefixField, "vport", "vrf"},
+	"fwd_to_port":                            {"port"},
+	"l2_fwd":                                 {"port"},
+	"send_to_port_mux":                       {"vport"},
+	"send_to_port_mux_access":                {modPtrField, "vport"},
+	"send_to_port_mux_trunk":                 {modPtrField, "vport"},
+	"set_vlan":                               {"vlan_id", "vport"},
+	"set_vlan_and_pop_vlan":                  {modPtrField, "vlan_id", "vport"},
+	"pop_vlan_set_vrfid":                     {modPtrField, "vport", tcamPrefixField, "vrf"},
+	"pop_vlan_set_vrf_id":                    {modPtrField, tcamPrefixField, "vport", "vrf"},
+	"pop_ctag_stag_vlan":                     {modPtrField, "vport"},
+	"pop_stag_vlan":                          {modPtrField, "vport"},
+	"set_neighbor":                           {"neighbor", "ecmp_on"},
+	"set_p2p_neighbor":                       {"neighbor", "ecmp_on"},
+	"set_neighbor_withoutrec":                {"neighbor"},
+	"set_l2_ecmp_neighbor":                   {"neighbor"},
+	"ecmp_lpm_root_lut1_action":              {"ipv4_table_lpm_root1"},
+	"ecmp_lpm_root_lut2_action":              {"ipv4_table_lpm_root2"},
+	"push_mac":                               {modPtrField, "vport"},
+	"push_dmac_vlan":                         {modPtrField, "vport"},
+	"push_mac_vlan":                          {modPtrField, "vport"},
+	"push_outermac_vxlan_innermac":           {modPtrField, "vport"},
+	"push_outermac_vxlan":                    {modPtrField, "vport"},
+	"push_vlan_l2":                           {modPtrField, "vport"},
+	"push_stag_ctag":                         {modPtrField, "vport"},
+	"send_p2p_push_mac":                      {modPtrField, "port", "qid"},
+	"send_p2p_push_outermac_vxlan_innermac":  {modPtrField, "port", "qid"},
+	"update_smac_dmac":                       {"smac", "dmac"},
+	"dmac_vlan_push":                         {"pcp", "dei", "vid", "dmac"},
+	"update_smac_dmac_vlan":                  {"smac", "dmac", "pcp", "dei", "vid"},
+	"omac_vxlan_imac_push":                   {"osmac", "odmac", "sip", "dip", "dst_port", "vni", "ismac", "idmac", "dscp", "dscp_copy", "ttl", "entropy"},
+	"omac_vxlan_push":                        {"osmac", "odmac", "sip", "dip", "dst_port", "vni", "dscp", "dscp_copy", "ttl", "entropy"},
+	"vlan_push":                              {"pcp", "dei", "vid"},
+	"vlan_push_access":                       {"pcp", "dei", "ctag_id", "pcp_s", "dei_s", "stag_id"},
+	"vlan_push_trunk":                        {"pcp", "dei", "stag_id"},
+	"vlan_ctag_stag_pop":                     {"dmac"},
+	"vlan_stag_pop":                          {"dmac"},
+	"vlan_push_stag_ctag_flood":              {"flood"},
+	"arp_reply":                              {"mac"},
+	"nd_reply":                               {"mac"},
+	"set_flood_peer":                         {"peer"},
+	"acl_permit":                             {},
+	"acl_deny":                               {},
+	"acl_count":                              {},
+	"mirror_to_session":                      {"session"},
+	"push_erspan":                            {"smac", "dmac", "sip", "dip", "erspan_id"},
+	"omac_vxlan6_imac_push":                  {"osmac", "odmac", "sip", "dip", "dst_port", "vni", "ismac", "idmac", "dscp", "dscp_copy", "ttl", "entropy"},
+	"omac_vxlan6_push":                       {"osmac", "odmac", "sip", "dip", "dst_port", "vni", "dscp", "dscp_copy", "ttl", "entropy"},
+	"push_outermac_vxlan6_innermac":          {modPtrField, "vport"},
+	"send_p2p_push_outermac_vxlan6_innermac": {modPtrField, "port", "qid"},
+	"push_outermac_vxlan6":                   {modPtrField, "vport"},
+	"accept_vxlan_port":                      {},
+	"set_uplink_port":                        {"port"},
+	"pbr_fwd_to_port":                        {"port"},
+	"pbr_set_neighbor":                       {"neighbor"},
+	"port_security_permit":                   {},
+	"port_security_drop":                     {},
+}
+
+// optionalTables and optionalActions belong to features the evpn_gw program
+// may lack, they are only checked when the pipeline has them
+var (
+	optionalTables = map[string]bool{arpProxy: true, ndProxy: true, bumSrcVtep: true, bumSrcVtep6: true, aclBp: true, aclSvi: true,
+		mirrorBp: true, mirrorSviTable: true, mirrorVrfTable: true, erspanEncap: true,
+		phyInVxlan6: true, phyInVxlanL26: true, pushVxlan6Hdr: true, pushVxlan6OutHdr: true,
+		l3Rt6: true, l3RtHost6: true, l3P2PRt6: true, l3P2PRtHost6: true, vxlanPort: true,
+		uplinkGroupTable: true, pbrTable: true, portSecTable: true, l2EcmpSel: true}
+	optionalActions = map[string]bool{"arp_reply": true, "nd_reply": true, "set_flood_peer": true,
+		"acl_permit": true, "acl_deny": true, "acl_count": true, "mirror_to_session": true, "push_erspan": true,
+		"omac_vxlan6_imac_push": true, "omac_vxlan6_push": true, "push_outermac_vxlan6_innermac": true,
+		"send_p2p_push_outermac_vxlan6_innermac": true, "push_outermac_vxlan6": true, "accept_vxlan_port": true,
+		"set_uplink_port": true, "pbr_fwd_to_port": true, "pbr_set_neighbor": true,
+		"port_security_permit": true, "port_security_drop": true, "set_l2_ecmp_neighbor": true}
+	// optionalActionTables are the tables of the feature an optional action
+	// belongs to, a mismatch of the action refuses them too
+	optionalActionTables = map[string][]string{"arp_reply": {arpProxy}, "nd_reply": {ndProxy},
+		"acl_permit": {aclBp, aclSvi}, "acl_deny": {aclBp, aclSvi}, "acl_count": {aclBp, aclSvi},
+		"mirror_to_session": {mirrorBp, mirrorSviTable, mirrorVrfTable}, "push_erspan": {erspanEncap},
+		"omac_vxlan6_imac_push": ipv6UnderlayTables, "omac_vxlan6_push": ipv6UnderlayTables,
+		"push_outermac_vxlan6_innermac": ipv6UnderlayTables, "send_p2p_push_outermac_vxlan6_innermac": ipv6UnderlayTables,
+		"push_outermac_vxlan6": ipv6UnderlayTables, "accept_vxlan_port": {vxlanPort},
+		"set_uplink_port": {uplinkGroupTable}, "pbr_fwd_to_port": {pbrTable}, "pbr_set_neighbor": {pbrTable},
+		"port_security_permit": {portSecTable}, "port_security_drop": {portSecTable}, "set_flood_peer": {bumSrcVtep, bumSrcVtep6},
+		"set_l2_ecmp_neighbor": {l2EcmpSel}}
+	ipv6UnderlayTables = []string{phyInVxlan6, phyInVxlanL26, pushVxlan6Hdr, pushVxlan6OutHdr}
+	// optionalParams counts the trailing params of an action the evpn_gw
+	// program may lack, the decoders only give them when a feature needs them
+	optionalParams = map[string]int{"omac_vxlan_imac_push": 4, "omac_vxlan_push": 4,
+		"omac_vxlan6_imac_push": 4, "omac_vxlan6_push": 4}
+)
+
+// tableSchema maps a logical table to the pipeline table
+type tableSchema struct {
+	Name   string            `yaml:"name"`
+	Fields map[string]string `yaml:"fields"`
+}
+
+// actionSchema maps a logical action to the pipeline action, params lists
+// the logical params in the order the pipeline action takes them
+type actionSchema struct {
+	Name   string   `yaml:"name"`
+	Params []string `yaml:"params"`
+}
+
+// pipelineSchema maps the logical tables and actions to a pipeline. Tables,
+// fields and actions it does not list keep their logical names under the
+// control block.
+type pipelineSchema struct {
+	Pipeline string                  `yaml:"pipeline"`
+	Control  string                  `yaml:"control"`
+	Tables   map[string]tableSchema  `yaml:"tables"`
+	Actions  map[string]actionSchema `yaml:"actions"`
+	// mismatched are the optional tables and actions the p4info has with
+	// other fields or params, the features using them are refused
+	mismatched map[string]bool
+}
+
+// schema is the pipeline schema the entries are programmed with
+var schema = defaultSchema()
+
+// pipelineInfo is the p4info the schema was checked against, nil in dry run
+// when it could not be read
+var pipelineInfo *p4config.P4Info
+
+// defaultSchema maps the logical names to the evpn_gw program
+func defaultSchema() *pipelineSchema {
+	return &pipelineSchema{
+		Pipeline: "evpn_gw",
+		Control:  strings.TrimSuffix(controlPrefix, "."),
+	}
+}
+
+// loadSchema reads the schema file and checks it maps known names only
+func loadSchema(path string) (*pipelineSchema, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	s := defaultSchema()
+	if err := yaml.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// validate checks the schema against the logical tables and actions
+func (s *pipelineSchema) validate() error {
+	for name, t := range s.Tables {
+		fields, ok := logicalTables[controlPrefix+name]
+		if !ok {
+			return fmt.Errorf("unknown table %s", name)
+		}
+		for field := range t.Fields {
+			if !contains(fields, field) {
+				return fmt.Errorf("unknown field %s of table %s", field, name)
+			}
+		}
+	}
+	for name, a := range s.Actions {
+		params, ok := logicalActions[name]
+		if !ok {
+			return fmt.Errorf("unknown action %s", name)
+		}
+		if len(a.Params) == 0 {
+			continue
+		}
+		if len(a.Params) > len(params) || len(a.Params) < len(params)-optionalParams[name] {
+			return fmt.Errorf("action %s takes %d params, the schema lists %d", name, len(params), len(a.Params))
+		}
+		for _, p := range a.Params {
+			if !contains(params, p) {
+				return fmt.Errorf("unknown param %s of action %s", p, name)
+			}
+		}
+	}
+	return nil
+}
+
+// tableName returns the pipeline name of the logical table
+func (s *pipelineSchema) tableName(table string) string {
+	logical := strings.TrimPrefix(table, controlPrefix)
+	if t, ok := s.Tables[logical]; ok && t.Name != "" {
+		return t.Name
+	}
+	return s.Control + "." + logical
+}
+
+// fieldName returns the pipeline name of a match field of the logical table
+func (s *pipelineSchema) fieldName(table, field string) string {
+	if name, ok := s.Tables[strings.TrimPrefix(table, controlPrefix)].Fields[field]; ok {
+		return name
+	}
+	return field
+}
+
+// actionName returns the pipeline name of the logical action
+func (s *pipelineSchema) actionName(action string) string {
+	logical := strings.TrimPrefix(action, controlPrefix)
+	if a, ok := s.Actions[logical]; ok && a.Name != "" {
+		return a.Name
+	}
+	return s.Control + "." + logical
+}
+
+// resolve converts an entry given with the logical names to the pipeline
+func (s *pipelineSchema) resolve(e p4client.TableEntry) p4client.TableEntry {
+	out := p4client.TableEntry{Tablename: s.tableName(e.Tablename)}
+	out.Priority = e.Priority
+	out.FieldValue = make(map[string][2]interface{}, len(e.FieldValue))
+	for field, value := range e.FieldValue {
+		out.FieldValue[s.fieldName(e.Tablename, field)] = value
+	}
+	if e.ActionName == "" {
+		return out
+	}
+	logical := strings.TrimPrefix(e.ActionName, controlPrefix)
+	out.ActionName = s.actionName(logical)
+	out.Params = e.Params
+	if order := s.Actions[logical].Params; len(order) != 0 {
+		// the decoders may leave trailing params out, keep the ones given
+		index := make(map[string]int)
+		for i, p := range logicalActions[logical] {
+			index[p] = i
+		}
+		out.Params = make([]interface{}, 0, len(e.Params))
+		for _, p := range order {
+			if i := index[p]; i < len(e.Params) {
+				out.Params = append(out.Params, e.Params[i])
+			}
+		}
+	}
+	return out
+}
+
+// readP4Info reads a p4info file in text or binary form
+func readP4Info(path string) (*p4config.P4Info, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	info := &p4config.P4Info{}
+	if err := prototext.Unmarshal(b, info); err != nil {
+		if err := proto.Unmarshal(b, info); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+	return info, nil
+}
+
+// loadPipelineInfo reads the p4info the schema is checked against. The
+// features the pipeline may lack are only assumed in dry run, nothing is
+// written to a pipeline that was not checked.
+func loadPipelineInfo(path string, dryRun bool) (*p4config.P4Info, error) {
+	info, err := readP4Info(path)
+	if err == nil {
+		return info, nil
+	}
+	if dryRun {
+		log.Printf("intel-e2000: Failed to read the p4info %s: %v\n", path, err)
+		return nil, nil
+	}
+	return nil, fmt.Errorf("pipeline schema %s cannot be checked without the p4info: %w", schema.Pipeline, err)
+}
+
+// check verifies every logical table, field and action maps to the p4info.
+// The tables and actions the core decoders use must match, a mismatch of an
+// optional one is returned as a warning and refuses the feature using it.
+func (s *pipelineSchema) check(info *p4config.P4Info) ([]string, error) {
+	actions := make(map[string]*p4config.Action)
+	for _, a := range info.GetActions() {
+		actions[a.GetPreamble().GetName()] = a
+	}
+	tables := make(map[string]*p4config.Table)
+	for _, t := range info.GetTables() {
+		tables[t.GetPreamble().GetName()] = t
+	}
+
+	s.mismatched = make(map[string]bool)
+	var problems, warnings []string
+	report := func(logical string, optional bool, problem string) {
+		if optional {
+			s.mismatched[logical] = true
+			for _, table := range optionalActionTables[logical] {
+				s.mismatched[table] = true
+			}
+			warnings = append(warnings, problem)
+			return
+		}
+		problems = append(problems, problem)
+	}
+	for _, table := range sortedKeys(logicalTables) {
+		name := s.tableName(table)
+		t, ok := tables[name]
+		if !ok && optionalTables[table] {
+			continue
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("table %s is not in the p4info", name))
+			continue
+		}
+		var fields []string
+		for _, f := range t.GetMatchFields() {
+			fields = append(fields, f.GetName())
+		}
+		for _, field := range logicalTables[table] {
+			if name := s.fieldName(table, field); !contains(fields, name) {
+				report(table, optionalTables[table], fmt.Sprintf("table %s has no field %s", t.GetPreamble().GetName(), name))
+			}
+		}
+	}
+	for _, action := range sortedKeys(logicalActions) {
+		name := s.actionName(action)
+		a, ok := actions[name]
+		if !ok && optionalActions[action] {
+			continue
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("action %s is not in the p4info", name))
+			continue
+		}
+		// the decoders may leave trailing params out but never give more
+		if given := len(logicalActions[action]) - optionalParams[action]; len(a.GetParams()) < given {
+			report(action, optionalActions[action], fmt.Sprintf("action %s takes %d params, the decoders give %d", name, len(a.GetParams()), given))
+		}
+	}
+	if len(problems) != 0 {
+		return warnings, fmt.Errorf("pipeline %s does not match: %s", s.Pipeline, strings.Join(problems, "; "))
+	}
+	return warnings, nil
+}
+
+// hasTables tells whether the p4info holds the pipeline tables of the logical tables
+func (s *pipelineSchema) hasTables(info *p4config.P4Info, tables ...string) bool {
+	var names []string
+	for _, t := range info.GetTables() {
+		names = append(names, t.GetPreamble().GetName())
+	}
+	for _, table := range tables {
+		if !contains(names, s.tableName(table)) || s.mismatched[table] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasActionParams tells whether the pipeline action of the logical action
+// takes at least n params
+func (s *pipelineSchema) hasActionParams(info *p4config.P4Info, action string, n int) bool {
+	if s.mismatched[action] {
+		return false
+	}
+	name := s.actionName(action)
+	for _, a := range info.GetActions() {
+		if a.GetPreamble().GetName() == name {
+			return len(a.GetParams()) >= n
+		}
+	}
+	return false
+}
+
+// paramsThrough counts the params of the logical action up to the param
+func paramsThrough(action string, param string) int {
+	for i, p := range logicalActions[action] {
+		if p == param {
+			return i + 1
+		}
+	}
+	return len(logicalActions[action])
+}
+
+// contains tells whether the list holds the name
+func contains(list []string, name string) bool {
+	for _, n := range list {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of the map in order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	p4config "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+// testP4Info builds a p4info holding the tables and actions of the schema
+func testP4Info(s *pipelineSchema) *p4config.P4Info {
+	info := &p4config.P4Info{}
+	for table, fields := range logicalTables {
+		t := &p4config.Table{Preamble: &p4config.Preamble{Name: s.tableName(table)}}
+		for _, f := range fields {
+			t.MatchFields = append(t.MatchFields, &p4config.MatchField{Name: s.fieldName(table, f)})
+		}
+		info.Tables = append(info.Tables, t)
+	}
+	for action, params := range logicalActions {
+		a := &p4config.Action{Preamble: &p4config.Preamble{Name: s.actionName(action)}}
+		for _, p := range params {
+			a.Params = append(a.Params, &p4config.Action_Param{Name: p})
+		}
+		info.Actions = append(info.Actions, a)
+	}
+	return info
+}
+
+// withoutTable drops the table from the p4info
+func withoutTable(info *p4config.P4Info, table string) *p4config.P4Info {
+	for i, t := range info.Tables {
+		if t.GetPreamble().GetName() == table {
+			info.Tables = append(info.Tables[:i], info.Tables[i+1:]...)
+			break
+		}
+	}
+	return info
+}
+
+func TestSchema_Resolve(t *testing.T) {
+	renamed := &pipelineSchema{
+		Pipeline: "evpn_gw_v2",
+		Control:  "gw",
+		Tables: map[string]tableSchema{
+			"l3_nexthop_table_rx": {Name: "gw.nexthop_rx", Fields: map[string]string{"neighbor": "nh_id"}},
+		},
+		Actions: map[string]actionSchema{
+			"push_dmac_vlan": {Params: []string{"vport", modPtrField}},
+		},
+	}
+	entry := p4client.TableEntry{
+		Tablename: l3NhRx,
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{
+				"neighbor":    {uint16(8), "exact"},
+				"bit32_zeros": {uint32(0), "exact"},
+			},
+		},
+		Action: p4client.Action{
+			ActionName: "evpn_gw_control.push_dmac_vlan",
+			Params:     []interface{}{uint32(3), uint32(21)},
+		},
+	}
+	tests := map[string]struct {
+		schema *pipelineSchema
+		out    p4client.TableEntry
+	}{
+		"default schema keeps the entry": {
+			schema: defaultSchema(),
+			out:    entry,
+		},
+		"renamed pipeline": {
+			schema: renamed,
+			out: p4client.TableEntry{
+				Tablename: "gw.nexthop_rx",
+				TableField: p4client.TableField{
+					FieldValue: map[string][2]interface{}{
+						"nh_id":       {uint16(8), "exact"},
+						"bit32_zeros": {uint32(0), "exact"},
+					},
+				},
+				Action: p4client.Action{
+					ActionName: "gw.push_dmac_vlan",
+					Params:     []interface{}{uint32(21), uint32(3)},
+				},
+			},
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if got := tt.schema.resolve(entry); !reflect.DeepEqual(got, tt.out) {
+				t.Errorf("Expected entry: %v, received %v", tt.out, got)
+			}
+		})
+	}
+}
+
+func TestSchema_Check(t *testing.T) {
+	shipped, err := loadSchema("../../../../../../pipeline-schema-evpn-gw.yaml")
+	if err != nil {
+		t.Fatalf("Loading the shipped schema failed: %v", err)
+	}
+	missing := withoutTable(testP4Info(defaultSchema()), l2Fwd)
+	stock := withoutTable(withoutTable(testP4Info(defaultSchema()), arpProxy), ndProxy)
+	tests := map[string]struct {
+		schema *pipelineSchema
+		info   *p4config.P4Info
+		errMsg string
+	}{
+		"default schema matches": {
+			schema: defaultSchema(),
+			info:   testP4Info(defaultSchema()),
+		},
+		"shipped schema matches the default": {
+			schema: shipped,
+			info:   testP4Info(defaultSchema()),
+		},
+		"pipeline without the optional tables": {
+			schema: defaultSchema(),
+			info:   stock,
+		},
+		"missing table": {
+			schema: defaultSchema(),
+			info:   missing,
+			errMsg: "is not in the p4info",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := tt.schema.check(tt.info)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLoadPipelineInfo(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "evpn_gw.p4info.txt")
+	b, err := prototext.Marshal(testP4Info(defaultSchema()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.p4info.txt")
+	tests := map[string]struct {
+		file   string
+		dryRun bool
+		info   bool
+		errMsg string
+	}{
+		"p4info read":               {file: file, info: true},
+		"p4info missing":            {file: missing, errMsg: "pipeline schema evpn_gw cannot be checked without the p4info"},
+		"p4info missing in dry run": {file: missing, dryRun: true},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			info, err := loadPipelineInfo(tt.file, tt.dryRun)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+			if tt.info != (info != nil) {
+				t.Errorf("Expected a p4info %v, received %v", tt.info, info)
+			}
+		})
+	}
+}
+
+func TestSchema_CheckOptional(t *testing.T) {
+	info := testP4Info(defaultSchema())
+	for _, a := range info.Actions {
+		if a.GetPreamble().GetName() == controlPrefix+"pbr_set_neighbor" {
+			a.Params = nil
+		}
+	}
+	for _, tb := range info.Tables {
+		if tb.GetPreamble().GetName() == arpProxy {
+			tb.MatchFields = nil
+		}
+	}
+	s := defaultSchema()
+	warnings, err := s.check(info)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if len(warnings) == 0 {
+		t.Errorf("Expected warnings, received none")
+	}
+	if s.hasTables(info, pbrTable) || s.hasTables(info, arpProxy) {
+		t.Errorf("Expected the mismatched pbr and arp proxy tables refused")
+	}
+	if !s.hasTables(info, ndProxy, aclBp) {
+		t.Errorf("Expected the matching optional tables kept")
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	tests := map[string]struct {
+		schema pipelineSchema
+		errMsg string
+	}{
+		"unknown table": {
+			schema: pipelineSchema{Tables: map[string]tableSchema{"no_table": {}}},
+			errMsg: "unknown table no_table",
+		},
+		"unknown field": {
+			schema: pipelineSchema{Tables: map[string]tableSchema{"l2_fwd_rx_table": {Fields: map[string]string{"sa": "src"}}}},
+			errMsg: "unknown field sa of table l2_fwd_rx_table",
+		},
+		"params missing one": {
+			schema: pipelineSchema{Actions: map[string]actionSchema{"set_neighbor": {Params: []string{"neighbor"}}}},
+			errMsg: "action set_neighbor takes 2 params, the schema lists 1",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if err := tt.schema.validate(); err == nil || err.Error() != tt.errMsg {
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
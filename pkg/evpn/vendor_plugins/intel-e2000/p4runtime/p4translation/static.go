@@ -0,0 +1,310 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"gopkg.in/yaml.v3"
+)
+
+// staticFileStr is the name of the static entries file decoder
+const staticFileStr = "static-file"
+
+// staticValue is a match field or action param of the static entries file.
+// Type is one of mac, ipv4, prefix, bool, uint16 or uint32 and is guessed
+// from the value when left out, numbers default to uint32.
+type staticValue struct {
+	Value string `yaml:"value"`
+	Type  string `yaml:"type"`
+	Kind  string `yaml:"kind"`
+}
+
+// staticEntry is an entry of the static entries file, tables and actions
+// are given by their logical name
+type staticEntry struct {
+	Table    string                 `yaml:"table"`
+	Match    map[string]staticValue `yaml:"match"`
+	Priority int32                  `yaml:"priority"`
+	Action   string                 `yaml:"action"`
+	Params   []staticValue          `yaml:"params"`
+}
+
+// staticEntriesFile is the layout of the static entries file
+type staticEntriesFile struct {
+	Entries []staticEntry `yaml:"entries"`
+}
+
+// StaticFileDecoder programs the static entries of the file given in the
+// config after the static entries of the built-in decoders
+type StaticFileDecoder struct {
+	mu      sync.Mutex
+	path    string
+	entries []p4client.TableEntry
+}
+
+// staticFile is the static entries file decoder, nil when no file is configured
+var staticFile *StaticFileDecoder
+
+// NewStaticFileDecoder loads and validates the static entries file
+func NewStaticFileDecoder(path string) (*StaticFileDecoder, error) {
+	entries, err := readStaticEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticFileDecoder{path: path, entries: entries}, nil
+}
+
+// Name returns the decoder name
+func (s *StaticFileDecoder) Name() string {
+	return staticFileStr
+}
+
+// StaticAdditions returns the entries of the file
+func (s *StaticFileDecoder) StaticAdditions() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []interface{}
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// StaticDeletions returns the deletions of the entries of the file
+func (s *StaticFileDecoder) StaticDeletions() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []interface{}
+	for _, e := range s.entries {
+		entries = append(entries, p4client.TableEntry{Tablename: e.Tablename, TableField: e.TableField})
+	}
+	return entries
+}
+
+// reload reads the file again and returns the deletions and additions that
+// bring the programmed entries in line with it
+func (s *StaticFileDecoder) reload() ([]interface{}, []interface{}, error) {
+	entries, err := readStaticEntries(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wanted := make(map[string]bool)
+	for _, e := range entries {
+		wanted[staticEntryString(e)] = true
+	}
+	programmed := make(map[string]bool)
+	var dels, adds []interface{}
+	for _, e := range s.entries {
+		programmed[staticEntryString(e)] = true
+		if !wanted[staticEntryString(e)] {
+			dels = append(dels, p4client.TableEntry{Tablename: e.Tablename, TableField: e.TableField})
+		}
+	}
+	for _, e := range entries {
+		if !programmed[staticEntryString(e)] {
+			adds = append(adds, e)
+		}
+	}
+	s.entries = entries
+	return dels, adds, nil
+}
+
+// ReloadStaticEntries reconciles the programmed static entries with the
+// static entries file
+func ReloadStaticEntries() error {
+	if staticFile == nil {
+		return fmt.Errorf("no static entries file configured")
+	}
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	dels, adds, err := staticFile.reload()
+	if err != nil {
+		return err
+	}
+	log.Printf("intel-e2000: Reloaded %s, %d entries removed, %d added\n", staticFile.path, len(dels), len(adds))
+	if err := delEntries(dels); err != nil {
+		return err
+	}
+	return addEntries(adds)
+}
+
+// staticEntryString identifies an entry with its action, a changed action
+// replaces the entry
+func staticEntryString(e p4client.TableEntry) string {
+	return entryString(entryOp{Entry: p4client.NewEntryRecord(e)})
+}
+
+// readStaticEntries reads and validates the static entries file
+func readStaticEntries(path string) ([]p4client.TableEntry, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var file staticEntriesFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	var entries []p4client.TableEntry
+	seen := make(map[string]bool)
+	for i, se := range file.Entries {
+		e, err := se.tableEntry()
+		if err != nil {
+			return nil, fmt.Errorf("%s entry %d: %w", path, i+1, err)
+		}
+		key := e.Tablename + entryKey(e)
+		if seen[key] {
+			return nil, fmt.Errorf("%s entry %d: duplicate match in %s", path, i+1, se.Table)
+		}
+		seen[key] = true
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// tableEntry converts and validates the entry
+func (se staticEntry) tableEntry() (p4client.TableEntry, error) {
+	if se.Table == "" || se.Action == "" {
+		return p4client.TableEntry{}, fmt.Errorf("table and action are needed")
+	}
+	e := p4client.TableEntry{
+		Tablename: controlPrefix + se.Table,
+		TableField: p4client.TableField{
+			FieldValue: make(map[string][2]interface{}),
+			Priority:   se.Priority,
+		},
+		Action: p4client.Action{ActionName: controlPrefix + se.Action},
+	}
+	for field, v := range se.Match {
+		value, err := v.parse()
+		if err != nil {
+			return e, fmt.Errorf("field %s: %w", field, err)
+		}
+		kind := v.Kind
+		if kind == "" {
+			kind = "exact"
+		}
+		if kind != "exact" && kind != "lpm" && kind != "ternary" {
+			return e, fmt.Errorf("field %s: unknown match kind %s", field, kind)
+		}
+		e.FieldValue[field] = [2]interface{}{value, kind}
+	}
+	for i, v := range se.Params {
+		value, err := v.parse()
+		if err != nil {
+			return e, fmt.Errorf("param %d: %w", i+1, err)
+		}
+		e.Params = append(e.Params, value)
+	}
+	if _, _, err := p4client.Buildmfs(e.TableField); err != nil {
+		return e, err
+	}
+	if fields, ok := logicalTables[e.Tablename]; ok {
+		if len(fields) != len(se.Match) {
+			return e, fmt.Errorf("table %s matches on %s", se.Table, strings.Join(fields, ", "))
+		}
+		for _, f := range fields {
+			if _, ok := se.Match[f]; !ok {
+				return e, fmt.Errorf("table %s matches on %s", se.Table, strings.Join(fields, ", "))
+			}
+		}
+	}
+	if params, ok := logicalActions[se.Action]; ok && len(se.Params) > len(params) {
+		return e, fmt.Errorf("action %s takes %d params", se.Action, len(params))
+	}
+	if pipelineInfo != nil {
+		if err := checkEntry(schema.resolve(e)); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// checkEntry checks a resolved entry against the p4info of the pipeline
+func checkEntry(e p4client.TableEntry) error {
+	for _, t := range pipelineInfo.GetTables() {
+		if t.GetPreamble().GetName() != e.Tablename {
+			continue
+		}
+		for field := range e.FieldValue {
+			found := false
+			for _, f := range t.GetMatchFields() {
+				found = found || f.GetName() == field
+			}
+			if !found {
+				return fmt.Errorf("table %s has no field %s", e.Tablename, field)
+			}
+		}
+		for _, a := range pipelineInfo.GetActions() {
+			if a.GetPreamble().GetName() == e.ActionName {
+				if len(a.GetParams()) != len(e.Params) {
+					return fmt.Errorf("action %s takes %d params", e.ActionName, len(a.GetParams()))
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("action %s is not in the p4info", e.ActionName)
+	}
+	return fmt.Errorf("table %s is not in the p4info", e.Tablename)
+}
+
+// parse converts the value to the go type Buildmfs and the driver expect
+func (v staticValue) parse() (interface{}, error) {
+	typ := v.Type
+	if typ == "" {
+		typ = guessType(v.Value)
+	}
+	switch typ {
+	case "mac":
+		return net.ParseMAC(v.Value)
+	case "ipv4":
+		ip := net.ParseIP(v.Value).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ipv4 address %s", v.Value)
+		}
+		return ip, nil
+	case "prefix":
+		_, prefix, err := net.ParseCIDR(v.Value)
+		return prefix, err
+	case "bool":
+		return strconv.ParseBool(v.Value)
+	case "uint16":
+		n, err := strconv.ParseUint(v.Value, 0, 16)
+		return uint16(n), err
+	case "uint32":
+		n, err := strconv.ParseUint(v.Value, 0, 32)
+		return uint32(n), err
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// guessType picks the type of an untyped value. Only true and false are
+// bools, an untyped number is an uint32 even if it is 0 or 1.
+func guessType(value string) string {
+	if _, err := net.ParseMAC(value); err == nil {
+		return "mac"
+	}
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return "prefix"
+	}
+	if net.ParseIP(value) != nil {
+		return "ipv4"
+	}
+	if value == "true" || value == "false" {
+		return "bool"
+	}
+	return "uint32"
+}
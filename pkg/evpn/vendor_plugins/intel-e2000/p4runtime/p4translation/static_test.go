@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeStaticFile writes the static entries file into a temporary directory
+func writeStaticFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "static.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Writing the static entries failed: %v", err)
+	}
+	return path
+}
+
+func TestReadStaticEntries(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		count   int
+		errMsg  string
+	}{
+		"typed values": {
+			content: `entries:
+  - table: l3_nexthop_table_rx
+    match:
+      neighbor: {value: "8", type: uint16}
+      bit32_zeros: {value: "0"}
+    action: push_dmac_vlan
+    params: [{value: "21"}, {value: "3"}]
+  - table: l2_fwd_rx_table
+    match:
+      da: {value: "00:11:22:33:44:55"}
+    action: l2_fwd
+    params: [{value: "28"}]
+`,
+			count: 2,
+		},
+		"missing field": {
+			content: `entries:
+  - table: l2_dmac_table
+    match:
+      da: {value: "00:11:22:33:44:55"}
+    action: l2_fwd
+`,
+			errMsg: "entry 1: table l2_dmac_table matches on vlan_id, da, direction",
+		},
+		"too many params": {
+			content: `entries:
+  - table: l2_fwd_rx_table
+    match:
+      da: {value: "00:11:22:33:44:55"}
+    action: l2_fwd
+    params: [{value: "28"}, {value: "29"}]
+`,
+			errMsg: "entry 1: action l2_fwd takes 1 params",
+		},
+		"bad value": {
+			content: `entries:
+  - table: l2_fwd_rx_table
+    match:
+      da: {value: "00:11:22", type: mac}
+    action: l2_fwd
+`,
+			errMsg: "entry 1: field da",
+		},
+		"duplicate match": {
+			content: `entries:
+  - table: l2_fwd_rx_table
+    match:
+      da: {value: "00:11:22:33:44:55"}
+    action: l2_fwd
+    params: [{value: "28"}]
+  - table: l2_fwd_rx_table
+    match:
+      da: {value: "00:11:22:33:44:55"}
+    action: l2_fwd
+    params: [{value: "29"}]
+`,
+			errMsg: "entry 2: duplicate match in l2_fwd_rx_table",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			entries, err := readStaticEntries(writeStaticFile(t, tt.content))
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			case len(entries) != tt.count:
+				t.Errorf("Expected %d entries, received %d", tt.count, len(entries))
+			}
+		})
+	}
+}
+
+func TestStaticValue_Parse(t *testing.T) {
+	tests := map[string]struct {
+		value staticValue
+		want  interface{}
+	}{
+		"untyped zero": {
+			value: staticValue{Value: "0"},
+			want:  uint32(0),
+		},
+		"untyped one": {
+			value: staticValue{Value: "1"},
+			want:  uint32(1),
+		},
+		"untyped true": {
+			value: staticValue{Value: "true"},
+			want:  true,
+		},
+		"typed bool": {
+			value: staticValue{Value: "1", Type: "bool"},
+			want:  true,
+		},
+		"typed uint16": {
+			value: staticValue{Value: "8", Type: "uint16"},
+			want:  uint16(8),
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			got, err := tt.value.parse()
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v of type %T, received %v of type %T %v", tt.want, tt.want, got, got, err)
+			}
+		})
+	}
+}
+
+func TestReadStaticEntries_Example(t *testing.T) {
+	entries, err := readStaticEntries("../../../../../../static-entries-example.yaml")
+	if err != nil {
+		t.Fatalf("Loading the shipped example failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries, received %d", len(entries))
+	}
+	if zeros := entries[1].FieldValue["bit32_zeros"][0]; zeros != uint32(0) {
+		t.Errorf("Expected bit32_zeros as an uint32 0, received %v of type %T", zeros, zeros)
+	}
+}
+
+func TestStaticFileDecoder_Reload(t *testing.T) {
+	entry := func(mac, port string) string {
+		return `  - table: l2_fwd_rx_table
+    match:
+      da: {value: "` + mac + `"}
+    action: l2_fwd
+    params: [{value: "` + port + `"}]
+`
+	}
+	path := writeStaticFile(t, "entries:\n"+entry("00:00:00:00:00:01", "28")+entry("00:00:00:00:00:02", "28"))
+	d, err := NewStaticFileDecoder(path)
+	if err != nil {
+		t.Fatalf("Loading the static entries failed: %v", err)
+	}
+	// drop the first, change the action of the second and add a third
+	if err := os.WriteFile(path, []byte("entries:\n"+entry("00:00:00:00:00:02", "29")+entry("00:00:00:00:00:03", "28")), 0o600); err != nil {
+		t.Fatalf("Writing the static entries failed: %v", err)
+	}
+	dels, adds, err := d.reload()
+	if err != nil {
+		t.Fatalf("Reloading the static entries failed: %v", err)
+	}
+	if len(dels) != 2 || len(adds) != 2 {
+		t.Errorf("Expected 2 deletions and 2 additions, received %d and %d", len(dels), len(adds))
+	}
+	if got := len(d.StaticAdditions()); got != 2 {
+		t.Errorf("Expected 2 static additions, received %d", got)
+	}
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"log"
+	"math"
+	"net"
+
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// arpSuppression answers the tenant arp and nd requests for known neighbors
+// in the fast path instead of flooding them, set from p4.arpsuppression
+var arpSuppression bool
+
+// setUpArpSuppression enables arp suppression when the config asks for it
+// and the pipeline has the proxy tables
+func setUpArpSuppression() {
+	arpSuppression = pluginCfg.P4.ArpSuppression
+	if !arpSuppression {
+		return
+	}
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, arpProxy, ndProxy) {
+		log.Fatalf("intel-e2000: arp suppression needs the %s and %s tables in pipeline %s\n",
+			schema.tableName(arpProxy), schema.tableName(ndProxy), schema.Pipeline)
+	}
+	log.Printf("intel-e2000: arp suppression enabled\n")
+}
+
+// neighborBinding returns the vlan, ip and mac a neighbor nexthop binds.
+// Local neighbors are learned on the svi, remote ones come from the evpn
+// mac/ip routes and carry the l2 nexthop of their vlan.
+func neighborBinding(nexthop netlink_polling.NexthopStruct) (uint16, net.IP, net.HardwareAddr, bool) {
+	var vlanID int
+	var mac string
+	switch nexthop.NhType {
+	case netlink_polling.SVI:
+		vid, _ := nexthop.Metadata["vlanID"].(uint32)
+		vlanID = int(vid)
+		mac, _ = nexthop.Metadata["dmac"].(string)
+	case netlink_polling.VXLAN:
+		if nexthop.Neighbor == nil {
+			return 0, nil, nil, false
+		}
+		switch l2n := nexthop.Neighbor.Metadata["l2_nh"].(type) {
+		case *netlink_polling.L2NexthopStruct:
+			vlanID = l2n.VlanID
+		case netlink_polling.L2NexthopStruct:
+			vlanID = l2n.VlanID
+		}
+		mac, _ = nexthop.Metadata["inner_dmac"].(string)
+	default:
+		return 0, nil, nil, false
+	}
+	ip := net.ParseIP(nexthop.Key.Dst)
+	hwaddr, err := net.ParseMAC(mac)
+	if vlanID <= 0 || vlanID > math.MaxUint16 || ip == nil || err != nil {
+		return 0, nil, nil, false
+	}
+	return uint16(vlanID), ip, hwaddr, true
+}
+
+// translateNeighbor installs or removes the proxy reply for the address of
+// the neighbor, arp for ipv4 and nd for ipv6
+func (p PodDecoder) translateNeighbor(op Operation, nexthop netlink_polling.NexthopStruct) []interface{} {
+	var entries = make([]interface{}, 0)
+	if !arpSuppression {
+		return entries
+	}
+	vid, ip, mac, ok := neighborBinding(nexthop)
+	if !ok {
+		return entries
+	}
+	table, action := arpProxy, "evpn_gw_control.arp_reply"
+	if ip.To4() != nil {
+		ip = ip.To4()
+	} else {
+		table, action = ndProxy, "evpn_gw_control.nd_reply"
+	}
+	entry := p4client.TableEntry{
+		Tablename: table,
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{
+				"vlan_id":   {vid, "exact"},
+				"target_ip": {ip, "exact"},
+			},
+			Priority: int32(0),
+		},
+	}
+	if op == OpAdded {
+		entry.Action = p4client.Action{
+			ActionName: action,
+			Params:     []interface{}{mac},
+		}
+	}
+	return append(entries, entry)
+}
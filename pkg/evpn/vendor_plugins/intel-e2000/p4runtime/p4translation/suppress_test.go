@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestPodDecoder_TranslateNeighbor(t *testing.T) {
+	mac, _ := net.ParseMAC("00:00:00:bb:bb:bb")
+	svi := func(dst string) netlink_polling.NexthopStruct {
+		return netlink_polling.NexthopStruct{
+			NhType:   netlink_polling.SVI,
+			Key:      netlink_polling.NexthopKey{Dst: dst},
+			Metadata: map[interface{}]interface{}{"vlanID": uint32(10), "dmac": mac.String()},
+		}
+	}
+	remote := netlink_polling.NexthopStruct{
+		NhType:   netlink_polling.VXLAN,
+		Key:      netlink_polling.NexthopKey{Dst: "10.0.1.7"},
+		Neighbor: &netlink_polling.NeighStruct{Metadata: map[interface{}]interface{}{"l2_nh": &netlink_polling.L2NexthopStruct{VlanID: 20}}},
+		Metadata: map[interface{}]interface{}{"inner_dmac": mac.String()},
+	}
+	proxy := func(table string, vid uint16, ip net.IP, action string) []interface{} {
+		return []interface{}{p4client.TableEntry{
+			Tablename: table,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"vlan_id":   {vid, "exact"},
+					"target_ip": {ip, "exact"},
+				},
+			},
+			Action: p4client.Action{ActionName: controlPrefix + action, Params: []interface{}{mac}},
+		}}
+	}
+	tests := map[string]struct {
+		enabled bool
+		nexthop netlink_polling.NexthopStruct
+		out     []interface{}
+	}{
+		"suppression disabled": {
+			nexthop: svi("10.0.1.5"),
+			out:     []interface{}{},
+		},
+		"local ipv4 neighbor": {
+			enabled: true,
+			nexthop: svi("10.0.1.5"),
+			out:     proxy(arpProxy, 10, net.ParseIP("10.0.1.5").To4(), "arp_reply"),
+		},
+		"local ipv6 neighbor": {
+			enabled: true,
+			nexthop: svi("2001:db8::5"),
+			out:     proxy(ndProxy, 10, net.ParseIP("2001:db8::5"), "nd_reply"),
+		},
+		"remote neighbor of a mac/ip route": {
+			enabled: true,
+			nexthop: remote,
+			out:     proxy(arpProxy, 20, net.ParseIP("10.0.1.7").To4(), "arp_reply"),
+		},
+		"physical nexthop": {
+			enabled: true,
+			nexthop: netlink_polling.NexthopStruct{NhType: netlink_polling.PHY, Key: netlink_polling.NexthopKey{Dst: "192.168.0.2"}},
+			out:     []interface{}{},
+		},
+	}
+	defer func() { arpSuppression = false }()
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			arpSuppression = tt.enabled
+			if got := Pod.translateNeighbor(OpAdded, tt.nexthop); !reflect.DeepEqual(got, tt.out) {
+				t.Errorf("Expected entries: %v, received %v", tt.out, got)
+			}
+		})
+	}
+}
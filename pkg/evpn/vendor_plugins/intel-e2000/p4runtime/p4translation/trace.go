@@ -0,0 +1,708 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/antoninbas/p4runtime-go-client/pkg/client"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// modTables maps the actions setting a mod pointer to their mod table
+var modTables = map[string]string{
+	"push_mac":                              macMod,
+	"send_p2p_push_mac":                     macMod,
+	"push_dmac_vlan":                        pushDmacVlan,
+	"push_mac_vlan":                         pushMacVlan,
+	"push_outermac_vxlan_innermac":          pushVxlanHdr,
+	"send_p2p_push_outermac_vxlan_innermac": pushVxlanHdr,
+	"push_outermac_vxlan":                   pushVxlanOutHdr,
+	"push_vlan_l2":                          pushVlan,
+	"push_stag_ctag":                        pushQnQFlood,
+	"send_to_port_mux_access":               podOutAccess,
+	"send_to_port_mux_trunk":                podOutTrunk,
+	"pop_ctag_stag_vlan":                    popCtagStag,
+	"pop_stag_vlan":                         popStag,
+}
+
+// TracePacket describes a synthetic packet entering the pipeline
+type TracePacket struct {
+	Port        int      // ingress physical port, -1 when the packet comes from a vsi
+	Vsi         int      // ingress vsi, -1 when the packet comes from a physical port
+	Vlans       []uint16 // vlan tags, outermost first
+	Vni         uint32   // vni of a vxlan packet, 0 when not encapsulated
+	OuterSrcMac net.HardwareAddr
+	OuterDstMac net.HardwareAddr
+	OuterSrcIP  net.IP
+	OuterDstIP  net.IP
+	SrcMac      net.HardwareAddr
+	DstMac      net.HardwareAddr
+	SrcIP       net.IP
+	DstIP       net.IP
+	Proto       uint8  // ip protocol, 0 when not given
+	SrcPort     uint16 // tcp or udp ports
+	DstPort     uint16
+	Arp         bool
+	Hash        uint16 // ecmp hash of the flow
+}
+
+// etherType returns the ethertype of the packet below the vlan tags
+func (p TracePacket) etherType() uint16 {
+	switch {
+	case p.Arp:
+		return 0x0806
+	case p.DstIP != nil && p.DstIP.To4() == nil:
+		return 0x86dd
+	}
+	return 0x0800
+}
+
+// String formats the headers of the packet
+func (p TracePacket) String() string {
+	var parts []string
+	if p.Vni != 0 {
+		parts = append(parts, fmt.Sprintf("outer %v>%v %v>%v vni %d", p.OuterSrcMac, p.OuterDstMac, p.OuterSrcIP, p.OuterDstIP, p.Vni))
+	}
+	if len(p.Vlans) != 0 {
+		parts = append(parts, fmt.Sprintf("vlans %v", p.Vlans))
+	}
+	parts = append(parts, fmt.Sprintf("%v>%v", p.SrcMac, p.DstMac))
+	if p.Arp {
+		parts = append(parts, "arp")
+	} else {
+		parts = append(parts, fmt.Sprintf("%v>%v", p.SrcIP, p.DstIP))
+	}
+	if p.Proto != 0 {
+		parts = append(parts, fmt.Sprintf("proto %d %d>%d", p.Proto, p.SrcPort, p.DstPort))
+	}
+	return strings.Join(parts, " ")
+}
+
+// TraceStep is a table lookup done by the trace
+type TraceStep struct {
+	Table    string
+	Key      []string
+	Hit      bool
+	Action   string
+	Params   []string
+	Rewrites []string
+}
+
+// TraceResult is the path of a packet through the desired entries
+type TraceResult struct {
+	Steps   []TraceStep
+	Packet  TracePacket // the packet after the rewrites
+	Verdict string
+}
+
+// String prints a line per lookup followed by the resulting packet
+func (r *TraceResult) String() string {
+	var b strings.Builder
+	for _, s := range r.Steps {
+		fmt.Fprintf(&b, "%s %s: ", strings.TrimPrefix(s.Table, controlPrefix), strings.Join(s.Key, " "))
+		if !s.Hit {
+			b.WriteString("miss\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%s(%s)\n", s.Action, strings.Join(s.Params, ", "))
+		for _, rw := range s.Rewrites {
+			fmt.Fprintf(&b, "    %s\n", rw)
+		}
+	}
+	fmt.Fprintf(&b, "packet: %v\n", r.Packet)
+	fmt.Fprintf(&b, "verdict: %s\n", r.Verdict)
+	return b.String()
+}
+
+// tracer walks a packet through the tables
+type tracer struct {
+	state  *desiredState
+	pkt    TracePacket
+	meta   map[string]interface{}
+	result *TraceResult
+}
+
+// Trace evaluates the packet against the desired entries the way the
+// pipeline matches them and returns every lookup on its path. The entries
+// are held still for the walk, the debug service traces the live ones.
+func Trace(pkt TracePacket) *TraceResult {
+	translateMu.Lock()
+	defer translateMu.Unlock()
+	t := &tracer{
+		state:  desired,
+		pkt:    pkt,
+		meta:   map[string]interface{}{"bit32_zeros": uint32(0)},
+		result: &TraceResult{},
+	}
+	t.pkt.Vlans = append([]uint16(nil), pkt.Vlans...)
+	if e, ok := t.ingress(); ok {
+		t.next(e)
+	} else if t.result.Verdict == "" {
+		t.result.Verdict = "dropped, no ingress entry"
+	}
+	t.result.Packet = t.pkt
+	return t.result
+}
+
+// flowFields sets the fields the filter tables match the packet on
+func (t *tracer) flowFields() {
+	t.meta["smac"] = t.pkt.SrcMac
+	t.meta["dmac"] = t.pkt.DstMac
+	t.meta["ether_type"] = t.pkt.etherType()
+	t.meta["sip"] = t.pkt.SrcIP
+	t.meta["dip"] = t.pkt.DstIP
+	t.meta["ip_proto"] = t.pkt.Proto
+	t.meta["sport"] = t.pkt.SrcPort
+	t.meta["dport"] = t.pkt.DstPort
+}
+
+// filter looks the packet up in a table the pipeline drops packets with,
+// tables without entries are left out. It tells whether the packet goes
+// on, a drop action ends the trace.
+func (t *tracer) filter(table string, drops ...string) bool {
+	if len(t.state.entries(table)) == 0 {
+		return true
+	}
+	e, ok := t.lookup(table)
+	if !ok {
+		return true
+	}
+	for _, drop := range drops {
+		if actionName(e) == drop {
+			t.result.Verdict = fmt.Sprintf("dropped by %s", drop)
+			return false
+		}
+	}
+	return true
+}
+
+// ingress looks the packet up in the ingress tables of its port
+func (t *tracer) ingress() (p4client.TableEntry, bool) {
+	t.meta["da"] = t.pkt.DstMac
+	if t.pkt.Port >= 0 {
+		t.meta["port_id"] = uint16(t.pkt.Port)
+		switch {
+		case t.pkt.Vni != 0:
+			t.meta["dst_ip"] = t.pkt.OuterDstIP
+			t.meta["vni"] = t.pkt.Vni
+			return t.first(phyInVxlan, phyInVxlanL2)
+		case t.pkt.Arp:
+			return t.first(phyInArp)
+		}
+		return t.first(phyInIP)
+	}
+	t.meta["vsi"] = uint16(t.pkt.Vsi)
+	t.flowFields()
+	if !t.filter(portSecTable, "port_security_drop") || !t.filter(aclBp, "acl_deny") {
+		return p4client.TableEntry{}, false
+	}
+	if len(t.pkt.Vlans) != 0 {
+		t.meta["vid"] = t.pkt.Vlans[0]
+		if t.pkt.Arp {
+			// the proxy entries are in the logical bridge vlan, the port
+			// may tag it with a customer vid of its own
+			vid := t.portVlan()
+			if vid == 0 {
+				vid = t.pkt.Vlans[0]
+			}
+			if e, ok := t.proxyArp(vid); ok {
+				return e, true
+			}
+			return t.first(portMuxIn, podInArpTrunk)
+		}
+		return t.first(portMuxIn, portInSviTrunk, podInIPTrunk)
+	}
+	if t.pkt.Arp {
+		if e, ok := t.proxyArp(t.portVlan()); ok {
+			return e, true
+		}
+		return t.first(podInArpAccess)
+	}
+	return t.first(portInSviAccess, podInIPAccess)
+}
+
+// proxyArp looks the target of an arp request from a pod port up in the
+// proxy table, pipelines without arp suppression have no entries there
+func (t *tracer) proxyArp(vid uint16) (p4client.TableEntry, bool) {
+	if len(t.state.entries(arpProxy)) == 0 {
+		return p4client.TableEntry{}, false
+	}
+	t.meta["vlan_id"] = vid
+	t.meta["target_ip"] = t.pkt.DstIP
+	return t.lookup(arpProxy)
+}
+
+// portVlan returns the logical bridge vlan of the packet on the bridge
+// port it came in on, without recording a lookup
+func (t *tracer) portVlan() uint16 {
+	for _, table := range []string{podInIPAccess, podInIPTrunk} {
+		for _, e := range t.state.entries(table) {
+			if _, ok := matchEntry(e, t.meta); ok {
+				vid, _ := number(paramsOf(e)["vlan_id"])
+				return uint16(vid)
+			}
+		}
+	}
+	return 0
+}
+
+// next applies the action of the hit entry and continues with the stage it selects
+func (t *tracer) next(e p4client.TableEntry) {
+	params := paramsOf(e)
+	t.rewrite(actionName(e), params)
+	switch {
+	case params[tcamPrefixField] != nil:
+		t.route()
+	case params["vlan_id"] != nil:
+		t.bridge()
+	case actionName(e) == "arp_reply":
+		t.result.Verdict = fmt.Sprintf("answered with %v by proxy arp", params["mac"])
+	case params["vport"] != nil:
+		t.result.Verdict = fmt.Sprintf("sent to vport %v", params["vport"])
+	case params["port"] != nil:
+		t.result.Verdict = fmt.Sprintf("sent to port %v", params["port"])
+	default:
+		t.result.Verdict = fmt.Sprintf("dropped, %s does not forward", actionName(e))
+	}
+}
+
+// bridge does the l2 lookup in the vlan set by the ingress action
+func (t *tracer) bridge() {
+	var dir = Direction.Tx
+	if t.pkt.Port >= 0 {
+		dir = Direction.Rx
+	}
+	t.meta["direction"] = uint16(dir)
+	t.meta["da"] = t.pkt.DstMac
+	if _, ok := t.lookup(l2Fwd); ok {
+		if e, ok := t.lookup(l2Nh); ok {
+			t.next(e)
+			return
+		}
+		t.result.Verdict = "dropped, no l2 nexthop"
+		return
+	}
+	if e, ok := t.lookup(l2FwdLoop); ok {
+		t.next(e)
+		return
+	}
+	t.result.Verdict = fmt.Sprintf("flooded in vlan %v", t.meta["vlan_id"])
+	vid, _ := number(t.meta["vlan_id"])
+	for _, g := range desired.multicastGroups() {
+		if uint64(g.ID) == vid {
+			rec := p4client.NewMulticastGroupRecord(g)
+			t.result.Verdict += fmt.Sprintf(" to replicas %s", strings.Join(rec.Replicas, " "))
+		}
+	}
+}
+
+// route does the l3 lookup in the vrf and direction set by the ingress action
+func (t *tracer) route() {
+	tcam, _ := number(t.meta[tcamPrefixField])
+	host, lut, lpm, nh := l3RtHost, tcamEntries, l3Rt, l3NhRx
+	dir := uint16(tcam % 10)
+	if uint32(tcam) == TcamPrefix.P2P {
+		host, lut, lpm, nh = l3P2PRtHost, tcamEntries2, l3P2PRt, p2pIn
+		dir = uint16(Direction.Rx)
+	} else if dir == uint16(Direction.Tx) {
+		nh = l3NhTx
+	}
+	t.meta["direction"] = dir
+	t.meta["dst_ip"] = t.pkt.DstIP
+	delete(t.meta, "ecmp_on")
+	if t.pkt.Vsi >= 0 {
+		// the acl of the svi filters the packets routed out of its vlan
+		if vid := t.portVlan(); vid != 0 {
+			t.meta["vlan_id"] = vid
+			if !t.filter(aclSvi, "acl_deny") {
+				return
+			}
+		}
+		// a pbr rule steers the flow ahead of the vrf lookup
+		if len(t.state.entries(pbrTable)) != 0 {
+			if e, ok := t.lookup(pbrTable); ok {
+				if actionName(e) == "pbr_set_neighbor" {
+					if e, ok = t.lookup(nh); !ok {
+						t.result.Verdict = "dropped, no nexthop"
+						return
+					}
+				}
+				t.next(e)
+				return
+			}
+		}
+	}
+
+	_, ok := t.lookup(host)
+	if !ok {
+		if _, ok = t.lookup(lut); ok {
+			_, ok = t.lookup(lpm)
+		}
+	}
+	if !ok {
+		t.result.Verdict = "dropped, no route"
+		return
+	}
+	if ecmp, _ := number(t.meta["ecmp_on"]); ecmp != 0 {
+		t.meta["hash"] = t.pkt.Hash
+		if _, ok := t.lookup(l3EcmpSel); !ok {
+			t.result.Verdict = "dropped, no ecmp member"
+			return
+		}
+	}
+	e, ok := t.lookup(nh)
+	if !ok {
+		t.result.Verdict = "dropped, no nexthop"
+		return
+	}
+	t.next(e)
+}
+
+// rewrite applies the header changes of the action and of its mod table entry
+func (t *tracer) rewrite(action string, params map[string]interface{}) {
+	switch action {
+	case "pop_vxlan_set_vrf_id", "pop_vxlan_set_vlan_id":
+		t.note("pop vxlan vni %d", t.pkt.Vni)
+		t.pkt.Vni = 0
+		t.pkt.OuterSrcMac, t.pkt.OuterDstMac, t.pkt.OuterSrcIP, t.pkt.OuterDstIP = nil, nil, nil, nil
+	case "set_vlan_and_pop_vlan", "pop_vlan_set_vrf_id", "pop_vlan_set_vrfid":
+		if vid, ok := number(params["vlan_id"]); ok && len(t.pkt.Vlans) != 0 && uint16(vid) != t.pkt.Vlans[0] {
+			t.note("customer vlan %d is logical bridge vlan %d", t.pkt.Vlans[0], vid)
+		}
+		t.popVlans(1)
+	}
+	table, ok := modTables[action]
+	if ptr, _ := number(params[modPtrField]); !ok || ptr == uint64(ModPointer.ignorePtr) {
+		return
+	}
+	e, ok := t.lookup(table)
+	if !ok {
+		return
+	}
+	p := paramsOf(e)
+	switch actionName(e) {
+	case "update_smac_dmac":
+		t.setMacs(p["smac"], p["dmac"])
+	case "dmac_vlan_push":
+		t.setMacs(nil, p["dmac"])
+		t.pushVlan(p["vid"])
+	case "update_smac_dmac_vlan":
+		t.setMacs(p["smac"], p["dmac"])
+		t.pushVlan(p["vid"])
+	case "omac_vxlan_imac_push":
+		t.setMacs(p["ismac"], p["idmac"])
+		t.encap(p)
+	case "omac_vxlan_push":
+		t.encap(p)
+	case "vlan_push":
+		t.pushVlan(p["vid"])
+	case "vlan_push_access":
+		t.pushVlan(p["ctag_id"])
+		t.pushVlan(p["stag_id"])
+	case "vlan_push_trunk":
+		t.pushVlan(p["stag_id"])
+	case "vlan_ctag_stag_pop":
+		t.popVlans(2)
+	case "vlan_stag_pop":
+		t.popVlans(1)
+	case "vlan_push_stag_ctag_flood":
+		t.note("push stag and ctag of the flooding port")
+	}
+}
+
+// setMacs rewrites the inner macs, a nil mac is kept
+func (t *tracer) setMacs(smac, dmac interface{}) {
+	if mac, ok := smac.(net.HardwareAddr); ok {
+		t.pkt.SrcMac = mac
+		t.note("smac %v", mac)
+	}
+	if mac, ok := dmac.(net.HardwareAddr); ok {
+		t.pkt.DstMac = mac
+		t.note("dmac %v", mac)
+	}
+}
+
+// pushVlan pushes an outermost vlan tag
+func (t *tracer) pushVlan(vid interface{}) {
+	n, _ := number(vid)
+	t.pkt.Vlans = append([]uint16{uint16(n)}, t.pkt.Vlans...)
+	t.note("push vlan %d", n)
+}
+
+// popVlans pops the outermost vlan tags
+func (t *tracer) popVlans(count int) {
+	for ; count > 0 && len(t.pkt.Vlans) != 0; count-- {
+		t.note("pop vlan %d", t.pkt.Vlans[0])
+		t.pkt.Vlans = t.pkt.Vlans[1:]
+	}
+}
+
+// encap pushes the vxlan headers
+func (t *tracer) encap(p map[string]interface{}) {
+	t.pkt.OuterSrcMac, _ = p["osmac"].(net.HardwareAddr)
+	t.pkt.OuterDstMac, _ = p["odmac"].(net.HardwareAddr)
+	t.pkt.OuterSrcIP, _ = p["sip"].(net.IP)
+	t.pkt.OuterDstIP, _ = p["dip"].(net.IP)
+	vni, _ := number(p["vni"])
+	t.pkt.Vni = uint32(vni)
+	t.note("push vxlan %v>%v %v>%v port %v vni %d", t.pkt.OuterSrcMac, t.pkt.OuterDstMac, t.pkt.OuterSrcIP, t.pkt.OuterDstIP, p["dst_port"], vni)
+}
+
+// note adds a rewrite to the last lookup
+func (t *tracer) note(format string, a ...interface{}) {
+	if len(t.result.Steps) == 0 {
+		return
+	}
+	step := &t.result.Steps[len(t.result.Steps)-1]
+	step.Rewrites = append(step.Rewrites, fmt.Sprintf(format, a...))
+}
+
+// first looks the tables up in order and returns the first hit
+func (t *tracer) first(tables ...string) (p4client.TableEntry, bool) {
+	for _, table := range tables {
+		if e, ok := t.lookup(table); ok {
+			return e, true
+		}
+	}
+	return p4client.TableEntry{}, false
+}
+
+// lookup finds the entry of the table hit by the current fields, the params
+// of its action become fields of the later lookups
+func (t *tracer) lookup(table string) (p4client.TableEntry, bool) {
+	step := TraceStep{Table: table}
+	for _, k := range logicalTables[table] {
+		step.Key = append(step.Key, fmt.Sprintf("%s=%v", k, t.meta[k]))
+	}
+
+	var best p4client.TableEntry
+	bestLen, found := 0, false
+	for _, e := range t.state.entries(table) {
+		plen, ok := matchEntry(e, t.meta)
+		if !ok {
+			continue
+		}
+		if !found || plen > bestLen || (plen == bestLen && e.Priority > best.Priority) {
+			best, bestLen, found = e, plen, true
+		}
+	}
+	if found {
+		step.Hit = true
+		step.Action = actionName(best)
+		names := logicalActions[step.Action]
+		for i, p := range best.Params {
+			name := fmt.Sprintf("param%d", i)
+			if i < len(names) {
+				name = names[i]
+				t.meta[name] = p
+			}
+			step.Params = append(step.Params, fmt.Sprintf("%s=%v", name, p))
+		}
+	}
+	t.result.Steps = append(t.result.Steps, step)
+	return best, found
+}
+
+// actionName is the action of the entry without the control prefix
+func actionName(e p4client.TableEntry) string {
+	return strings.TrimPrefix(e.ActionName, controlPrefix)
+}
+
+// paramsOf maps the param names of the entry action to their values
+func paramsOf(e p4client.TableEntry) map[string]interface{} {
+	params := make(map[string]interface{})
+	names := logicalActions[actionName(e)]
+	for i, p := range e.Params {
+		if i < len(names) {
+			params[names[i]] = p
+		}
+	}
+	return params
+}
+
+// matchEntry tells whether the fields hit the entry, with the prefix length
+// of its lpm fields. The key is built like the entry with Buildmfs so both
+// compare with the device semantics
+func matchEntry(e p4client.TableEntry, meta map[string]interface{}) (int, bool) {
+	mfs, _, err := p4client.Buildmfs(e.TableField)
+	if err != nil {
+		return 0, false
+	}
+	plen := 0
+	for field, mf := range mfs {
+		like := e.FieldValue[field][0]
+		key, ok := keyBytes(meta[field], like)
+		if !ok {
+			return 0, false
+		}
+		switch m := mf.(type) {
+		case *client.ExactMatch:
+			if !bytes.Equal(ipv4Bytes(m.Value, like), key) {
+				return 0, false
+			}
+		case *client.LpmMatch:
+			if !prefixEqual(ipv4Bytes(m.Value, like), key, int(m.PLen)) {
+				return 0, false
+			}
+			plen += int(m.PLen)
+		case *client.TernaryMatch:
+			if !maskedEqual(m.Value, key, m.Mask) {
+				return 0, false
+			}
+		default:
+			return 0, false
+		}
+	}
+	return plen, true
+}
+
+// keyBytes encodes the field value the way an exact match on a value of
+// the type of like is encoded
+func keyBytes(v interface{}, like interface{}) ([]byte, bool) {
+	var conv interface{}
+	switch l := like.(type) {
+	case uint16, uint32, bool:
+		n, ok := number(v)
+		if !ok {
+			return nil, false
+		}
+		switch like.(type) {
+		case uint16:
+			conv = uint16(n)
+		case uint32:
+			conv = uint32(n)
+		default:
+			conv = n != 0
+		}
+	case net.HardwareAddr:
+		mac, ok := v.(net.HardwareAddr)
+		if !ok {
+			return nil, false
+		}
+		conv = mac
+	case net.IP, *net.IPNet:
+		ip, ok := v.(net.IP)
+		if !ok || ip == nil {
+			return nil, false
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		conv = ip
+	case p4client.Ternary:
+		return ternaryKey(v, len(l.Value))
+	default:
+		return nil, false
+	}
+	mfs, _, err := p4client.Buildmfs(p4client.TableField{
+		FieldValue: map[string][2]interface{}{"key": {conv, "exact"}},
+	})
+	if err != nil {
+		return nil, false
+	}
+	m, ok := mfs["key"].(*client.ExactMatch)
+	if !ok {
+		return nil, false
+	}
+	return m.Value, true
+}
+
+// ternaryKey encodes the field value in the size bytes of a ternary
+// value, numbers in network order
+func ternaryKey(v interface{}, size int) ([]byte, bool) {
+	var b []byte
+	switch v := v.(type) {
+	case net.HardwareAddr:
+		b = v
+	case net.IP:
+		b = v.To16()
+		if size == net.IPv4len {
+			b = v.To4()
+		}
+	default:
+		n, ok := number(v)
+		if !ok {
+			return nil, false
+		}
+		b = make([]byte, size)
+		for i := size - 1; i >= 0; i-- {
+			b[i] = byte(n)
+			n >>= 8
+		}
+	}
+	return b, len(b) == size
+}
+
+// ipv4Bytes returns the 4 byte form of an ipv4 address field
+func ipv4Bytes(b []byte, like interface{}) []byte {
+	switch like.(type) {
+	case net.IP, *net.IPNet:
+		if ip4 := net.IP(b).To4(); ip4 != nil {
+			return ip4
+		}
+	}
+	return b
+}
+
+// prefixEqual compares the first plen bits of a and b
+func prefixEqual(a, b []byte, plen int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		bits := plen - i*8
+		if bits <= 0 {
+			break
+		}
+		mask := byte(0xff)
+		if bits < 8 {
+			mask <<= 8 - bits
+		}
+		if a[i]&mask != b[i]&mask {
+			return false
+		}
+	}
+	return true
+}
+
+// maskedEqual compares a and b under the mask, missing mask bytes match all bits
+func maskedEqual(a, b, mask []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		m := byte(0xff)
+		if i < len(mask) {
+			m = mask[i]
+		}
+		if a[i]&m != b[i]&m {
+			return false
+		}
+	}
+	return true
+}
+
+// number returns the value of an integer or boolean field
+func number(v interface{}) (uint64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), true
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
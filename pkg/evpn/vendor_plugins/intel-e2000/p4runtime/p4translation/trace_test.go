@@ -0,0 +1,310 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// traceEntry builds an entry of the trace test tables
+func traceEntry(table string, fields map[string][2]interface{}, priority int32, action string, params ...interface{}) p4client.TableEntry {
+	return p4client.TableEntry{
+		Tablename:  table,
+		TableField: p4client.TableField{FieldValue: fields, Priority: priority},
+		Action:     p4client.Action{ActionName: controlPrefix + action, Params: params},
+	}
+}
+
+func TestTrace(t *testing.T) {
+	rmac, _ := net.ParseMAC("00:00:00:aa:aa:aa")
+	vmMac, _ := net.ParseMAC("00:00:00:bb:bb:bb")
+	_, dst, _ := net.ParseCIDR("10.0.1.0/24")
+	entries := []p4client.TableEntry{
+		traceEntry(phyInVxlan, map[string][2]interface{}{
+			"dst_ip": {net.ParseIP("192.168.0.1"), "exact"},
+			"vni":    {uint32(100), "exact"},
+			"da":     {rmac, "exact"},
+		}, 0, "pop_vxlan_set_vrf_id", ModPointer.ignorePtr, uint32(20), uint32(16), uint32(2)),
+		traceEntry(tcamEntries, map[string][2]interface{}{
+			tcamPrefixField: {uint32(20), "ternary"},
+		}, 5, "ecmp_lpm_root_lut1_action", uint32(5)),
+		traceEntry(l3Rt, map[string][2]interface{}{
+			"ipv4_table_lpm_root1": {uint32(5), "exact"},
+			"dst_ip":               {dst, "lpm"},
+		}, 1, "set_neighbor", uint16(8), uint16(0)),
+		traceEntry(l3Rt, map[string][2]interface{}{
+			"ipv4_table_lpm_root1": {uint32(5), "exact"},
+			"dst_ip":               {&net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)}, "lpm"},
+		}, 1, "set_neighbor", uint16(4), uint16(0)),
+		traceEntry(l3NhRx, map[string][2]interface{}{
+			"neighbor":    {uint16(8), "exact"},
+			"bit32_zeros": {uint32(0), "exact"},
+		}, 0, "push_dmac_vlan", uint32(3), uint32(21)),
+		traceEntry(pushDmacVlan, map[string][2]interface{}{
+			modPtrField: {uint32(3), "exact"},
+		}, 0, "dmac_vlan_push", uint16(0), uint16(1), uint16(10), vmMac),
+		traceEntry(podInIPAccess, map[string][2]interface{}{
+			"vsi":         {uint16(5), "exact"},
+			"bit32_zeros": {uint32(0), "exact"},
+		}, 0, "set_vlan", uint16(10), uint32(0)),
+		traceEntry(arpProxy, map[string][2]interface{}{
+			"vlan_id":   {uint16(10), "exact"},
+			"target_ip": {net.ParseIP("10.0.1.7").To4(), "exact"},
+		}, 0, "arp_reply", vmMac),
+		// the trunk port tags logical bridge vlan 10 with customer vid 100
+		traceEntry(podInIPTrunk, map[string][2]interface{}{
+			"vsi": {uint16(6), "exact"},
+			"vid": {uint16(100), "exact"},
+		}, 0, "set_vlan_and_pop_vlan", ModPointer.ignorePtr, uint16(10), uint32(0)),
+	}
+	tests := map[string]struct {
+		pkt     TracePacket
+		tables  []string
+		verdict string
+		vlans   []uint16
+		dstMac  net.HardwareAddr
+		note    string
+	}{
+		"vxlan packet routed to the longest prefix": {
+			pkt: TracePacket{
+				Port: 0, Vsi: -1, Vni: 100, OuterDstIP: net.ParseIP("192.168.0.1"),
+				DstMac: rmac, DstIP: net.ParseIP("10.0.1.7"),
+			},
+			tables:  []string{phyInVxlan, l3RtHost, tcamEntries, l3Rt, l3NhRx, pushDmacVlan},
+			verdict: "sent to vport 21",
+			vlans:   []uint16{10},
+			dstMac:  vmMac,
+		},
+		"unknown vni is dropped": {
+			pkt: TracePacket{
+				Port: 0, Vsi: -1, Vni: 200, OuterDstIP: net.ParseIP("192.168.0.1"),
+				DstMac: rmac, DstIP: net.ParseIP("10.0.1.7"),
+			},
+			tables:  []string{phyInVxlan, phyInVxlanL2},
+			verdict: "dropped, no ingress entry",
+			dstMac:  rmac,
+		},
+		"unknown mac on an access port is flooded": {
+			pkt:     TracePacket{Port: -1, Vsi: 5, DstMac: vmMac},
+			tables:  []string{portInSviAccess, podInIPAccess, l2Fwd, l2FwdLoop},
+			verdict: "flooded in vlan 10",
+			dstMac:  vmMac,
+		},
+		"arp for a known neighbor is answered": {
+			pkt:     TracePacket{Port: -1, Vsi: 5, DstIP: net.ParseIP("10.0.1.7"), Arp: true},
+			tables:  []string{arpProxy},
+			verdict: "answered with 00:00:00:bb:bb:bb by proxy arp",
+		},
+		"customer vlan of a trunk port bridged in the logical bridge vlan": {
+			pkt:     TracePacket{Port: -1, Vsi: 6, Vlans: []uint16{100}, DstMac: vmMac},
+			tables:  []string{portMuxIn, portInSviTrunk, podInIPTrunk, l2Fwd, l2FwdLoop},
+			verdict: "flooded in vlan 10",
+			vlans:   []uint16{},
+			dstMac:  vmMac,
+			note:    "customer vlan 100 is logical bridge vlan 10",
+		},
+		"arp in the customer vlan of a trunk port is answered": {
+			pkt:     TracePacket{Port: -1, Vsi: 6, Vlans: []uint16{100}, DstIP: net.ParseIP("10.0.1.7"), Arp: true},
+			tables:  []string{arpProxy},
+			verdict: "answered with 00:00:00:bb:bb:bb by proxy arp",
+			vlans:   []uint16{100},
+		},
+		"arp for an unknown address goes to the slow path": {
+			pkt:     TracePacket{Port: -1, Vsi: 5, DstIP: net.ParseIP("10.0.1.9"), Arp: true},
+			tables:  []string{arpProxy, podInArpAccess},
+			verdict: "dropped, no ingress entry",
+		},
+	}
+	defer func() { desired = newDesiredState() }()
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			desired = newDesiredState()
+			for _, e := range entries {
+				desired.add(e)
+			}
+			res := Trace(tt.pkt)
+			var tables []string
+			for _, s := range res.Steps {
+				tables = append(tables, s.Table)
+			}
+			if !reflect.DeepEqual(tables, tt.tables) {
+				t.Errorf("Expected tables: %v, received %v", tt.tables, tables)
+			}
+			if res.Verdict != tt.verdict {
+				t.Errorf("Expected verdict: %v, received %v", tt.verdict, res.Verdict)
+			}
+			if !reflect.DeepEqual(res.Packet.Vlans, tt.vlans) {
+				t.Errorf("Expected vlans: %v, received %v", tt.vlans, res.Packet.Vlans)
+			}
+			if res.Packet.DstMac.String() != tt.dstMac.String() {
+				t.Errorf("Expected dmac: %v, received %v", tt.dstMac, res.Packet.DstMac)
+			}
+			if tt.note != "" && !strings.Contains(res.String(), tt.note) {
+				t.Errorf("Expected the trace to note: %s, received %s", tt.note, res)
+			}
+		})
+	}
+}
+
+// filterEntries builds the entries of the rules on the port of the table
+func filterEntries(t *testing.T, port aclPort, rules ...aclRule) []p4client.TableEntry {
+	policies := aclPolicies{rules: map[string][]aclMatch{"policy": nil}}
+	for _, r := range rules {
+		m, err := r.match()
+		if err != nil {
+			t.Fatalf("Expected no error, received %v", err)
+		}
+		policies.rules["policy"] = append(policies.rules["policy"], m)
+	}
+	return policies.entries("policy", port)
+}
+
+func TestTrace_Filters(t *testing.T) {
+	sviMac, _ := net.ParseMAC("00:00:00:aa:aa:aa")
+	vmMac, _ := net.ParseMAC("00:00:00:cc:cc:cc")
+	podMac, _ := net.ParseMAC("00:00:00:dd:dd:dd")
+	entries := []p4client.TableEntry{
+		traceEntry(podInIPAccess, map[string][2]interface{}{
+			"vsi":         {uint16(5), "exact"},
+			"bit32_zeros": {uint32(0), "exact"},
+		}, 0, "set_vlan", uint16(10), uint32(0)),
+		traceEntry(portInSviAccess, map[string][2]interface{}{
+			"vsi": {uint16(5), "exact"},
+			"da":  {sviMac, "exact"},
+		}, 0, "set_vrf_id_tx", uint32(21), uint32(0), uint16(4)),
+		traceEntry(l3RtHost, map[string][2]interface{}{
+			"vrf":       {uint16(4), "exact"},
+			"direction": {uint16(Direction.Tx), "exact"},
+			"dst_ip":    {net.ParseIP("10.0.2.7").To4(), "exact"},
+		}, 0, "set_neighbor", uint16(9), uint16(0)),
+		traceEntry(l3NhTx, map[string][2]interface{}{
+			"neighbor":    {uint16(9), "exact"},
+			"bit32_zeros": {uint32(0), "exact"},
+		}, 0, "push_mac", uint32(3), uint32(30)),
+		traceEntry(macMod, map[string][2]interface{}{
+			modPtrField: {uint32(3), "exact"},
+		}, 0, "update_smac_dmac", sviMac, vmMac),
+	}
+	entries = append(entries, filterEntries(t, aclPort{table: aclBp, field: "vsi", key: 5},
+		aclRule{Action: "deny", Protocol: "tcp", DstPort: "22"})...)
+	entries = append(entries, filterEntries(t, aclPort{table: aclSvi, field: "vlan_id", key: 10},
+		aclRule{Action: "deny", DstIP: "10.0.2.0/24", Protocol: "udp"})...)
+	pbr := &PbrDecoder{
+		vports:   map[string]uint16{"bp-a": 5, "bp-fw": 8},
+		vrfs:     map[string]bool{"vrf-blue": true},
+		nexthops: map[string]netlink_polling.NexthopStruct{"vrf-blue/10.0.9.1": {ID: 9}},
+		policies: pbrPolicies{
+			bridgePorts: map[string]string{"bp-a": "steer"},
+			rules: map[string][]pbrMatch{"steer": {
+				{fields: map[string][2]interface{}{"dport": {exactTernary(uint16toBytes(443)), "ternary"}}, bridgePort: "bp-fw"},
+				{fields: map[string][2]interface{}{"dport": {exactTernary(uint16toBytes(8080)), "ternary"}}, vrf: "vrf-blue", nexthop: "vrf-blue/10.0.9.1"},
+			}},
+		},
+	}
+	for _, e := range pbr.wanted() {
+		entries = append(entries, e.entry)
+	}
+	secured := portSecurityPort{allowed: []p4client.Ternary{exactTernary(net.ParseIP("10.0.1.5").To4())}}
+	for _, e := range (&PortSecurityDecoder{}).entries(OpAdded, portSecurityBp{vsi: 5, macs: []net.HardwareAddr{podMac}}, secured) {
+		entries = append(entries, e.(p4client.TableEntry))
+	}
+	entries = append(entries, traceEntry(l3NhTx, map[string][2]interface{}{
+		"neighbor":    {uint16(_p4NexthopID(netlink_polling.NexthopStruct{ID: 9}, Direction.Tx)), "exact"},
+		"bit32_zeros": {uint32(0), "exact"},
+	}, 0, "push_mac", uint32(3), uint32(31)))
+	routed := TracePacket{Port: -1, Vsi: 5, SrcMac: podMac, DstMac: sviMac, SrcIP: net.ParseIP("10.0.1.5"), DstIP: net.ParseIP("10.0.2.7")}
+	tests := map[string]struct {
+		srcIP   string
+		proto   uint8
+		dport   uint16
+		tables  []string
+		verdict string
+	}{
+		"spoofed source dropped by port security": {
+			srcIP:   "10.0.1.6",
+			proto:   6,
+			dport:   80,
+			tables:  []string{portSecTable},
+			verdict: "dropped by port_security_drop",
+		},
+		"denied by the bridge port acl": {
+			proto:   6,
+			dport:   22,
+			tables:  []string{portSecTable, aclBp},
+			verdict: "dropped by acl_deny",
+		},
+		"routed out of the vlan and denied by the svi acl": {
+			proto:   17,
+			dport:   53,
+			tables:  []string{portSecTable, aclBp, portInSviAccess, aclSvi},
+			verdict: "dropped by acl_deny",
+		},
+		"permitted by both acls": {
+			proto:   6,
+			dport:   80,
+			tables:  []string{portSecTable, aclBp, portInSviAccess, aclSvi, pbrTable, l3RtHost, l3NhTx, macMod},
+			verdict: "sent to vport 30",
+		},
+		"steered to a bridge port by pbr ahead of the route": {
+			proto:   6,
+			dport:   443,
+			tables:  []string{portSecTable, aclBp, portInSviAccess, aclSvi, pbrTable},
+			verdict: "sent to port " + fmt.Sprint(uint32(_toEgressVsi(8))),
+		},
+		"steered to a nexthop by pbr ahead of the route": {
+			proto:   6,
+			dport:   8080,
+			tables:  []string{portSecTable, aclBp, portInSviAccess, aclSvi, pbrTable, l3NhTx, macMod},
+			verdict: "sent to vport 31",
+		},
+	}
+	defer func() { desired = newDesiredState() }()
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			desired = newDesiredState()
+			for _, e := range entries {
+				desired.add(e)
+			}
+			pkt := routed
+			pkt.Proto, pkt.DstPort = tt.proto, tt.dport
+			if tt.srcIP != "" {
+				pkt.SrcIP = net.ParseIP(tt.srcIP)
+			}
+			res := Trace(pkt)
+			var tables []string
+			for _, s := range res.Steps {
+				tables = append(tables, s.Table)
+			}
+			if !reflect.DeepEqual(tables, tt.tables) {
+				t.Errorf("Expected tables: %v, received %v", tt.tables, tables)
+			}
+			if res.Verdict != tt.verdict {
+				t.Errorf("Expected verdict: %v, received %v", tt.verdict, res.Verdict)
+			}
+		})
+	}
+}
+
+func TestDesiredState_Remove(t *testing.T) {
+	add := traceEntry(l2FwdLoop, map[string][2]interface{}{"da": {net.HardwareAddr{0, 0, 0, 0, 0, 1}, "exact"}}, 0, "l2_fwd", uint32(17))
+	del := p4client.TableEntry{Tablename: add.Tablename, TableField: add.TableField}
+	d := newDesiredState()
+	d.add(add)
+	if got := len(d.entries(l2FwdLoop)); got != 1 {
+		t.Errorf("Expected entries: 1, received %v", got)
+	}
+	d.remove(del)
+	if got := len(d.entries(l2FwdLoop)); got != 0 {
+		t.Errorf("Expected entries: 0, received %v", got)
+	}
+}
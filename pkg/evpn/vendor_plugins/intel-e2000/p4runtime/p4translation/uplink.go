@@ -0,0 +1,289 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"github.com/vishvananda/netlink"
+)
+
+// uplinkSlots is the number of hash slots an uplink group spreads its
+// flows over, like the slots of the ecmp groups
+const uplinkSlots = 16
+
+// uplinkGroupConfig is a group of the uplinks.groups config. Name is the
+// linux bond over the member uplinks, listed in interfaces.phyports with
+// the port id netlink gives the nexthops through it.
+type uplinkGroupConfig struct {
+	Name    string   `mapstructure:"name"`
+	Members []string `mapstructure:"members"`
+}
+
+// uplinkPort is a physical port of the interfaces.phyports config, index
+// is its place in the list and port the id netlink resolves it to
+type uplinkPort struct {
+	index int
+	port  int
+}
+
+// uplinkGroup bonds physical ports, the nexthops through the bond egress on
+// the group port which the pipeline spreads over the members that are up
+type uplinkGroup struct {
+	name    string
+	port    int
+	mac     string
+	members []string
+	ids     map[string]int
+	down    map[string]bool
+}
+
+// uplinkGroups are the configured groups, guarded by translateMu
+var uplinkGroups []*uplinkGroup
+
+// uplinkDone stops following the members of the uplink groups
+var uplinkDone chan struct{}
+
+// setUpUplinks applies the uplink groups of the config, the pipeline needs
+// the uplink group table to spread the group port over the members
+func setUpUplinks(c uplinksSection) {
+	if len(c.Groups) == 0 {
+		return
+	}
+	parsed, err := parseUplinkGroups(c.Groups, phyPorts())
+	if err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
+	}
+	if pipelineInfo != nil && !schema.hasTables(pipelineInfo, uplinkGroupTable) {
+		log.Fatalf("intel-e2000: uplink groups need the %s table in pipeline %s\n",
+			schema.tableName(uplinkGroupTable), schema.Pipeline)
+	}
+	for _, g := range parsed {
+		g.mac = getMac(g.name)
+		log.Printf("intel-e2000: uplink group %s on port %d over %v\n", g.name, g.port, g.members)
+	}
+	uplinkGroups = parsed
+}
+
+// parseUplinkGroups validates the groups against the physical ports
+func parseUplinkGroups(groups []uplinkGroupConfig, ports map[string]uplinkPort) ([]*uplinkGroup, error) {
+	var parsed []*uplinkGroup
+	taken := make(map[string]string)
+	for _, g := range groups {
+		if _, ok := taken[g.Name]; ok || g.Name == "" {
+			return nil, fmt.Errorf("uplinks: group %q is unnamed or given twice", g.Name)
+		}
+		bond, ok := ports[g.Name]
+		if !ok {
+			return nil, fmt.Errorf("uplinks: group %s is not in interfaces.phyports", g.Name)
+		}
+		if len(g.Members) == 0 {
+			return nil, fmt.Errorf("uplinks: group %s has no members", g.Name)
+		}
+		taken[g.Name] = g.Name
+		group := &uplinkGroup{name: g.Name, port: bond.port, ids: make(map[string]int), down: make(map[string]bool)}
+		for _, m := range g.Members {
+			p, ok := ports[m]
+			if !ok {
+				return nil, fmt.Errorf("uplinks: member %s of group %s is not in interfaces.phyports", m, g.Name)
+			}
+			if other, ok := taken[m]; ok {
+				return nil, fmt.Errorf("uplinks: member %s of group %s is taken by %s", m, g.Name, other)
+			}
+			taken[m] = g.Name
+			group.members = append(group.members, m)
+			group.ids[m] = p.index
+		}
+		parsed = append(parsed, group)
+	}
+	return parsed, nil
+}
+
+// isUplinkGroup tells whether the physical port is the bond of a group,
+// it has no representor of its own
+func isUplinkGroup(rep string) bool {
+	for _, g := range uplinkGroups {
+		if g.name == rep {
+			return true
+		}
+	}
+	return false
+}
+
+// uplinkGroupOf returns the group of the port netlink resolved a nexthop to
+func uplinkGroupOf(port int) *uplinkGroup {
+	for _, g := range uplinkGroups {
+		if g.port == port {
+			return g
+		}
+	}
+	return nil
+}
+
+// up returns the port ids of the members that are up in member order
+func (g *uplinkGroup) up() []int {
+	var ids []int
+	for _, m := range g.members {
+		if !g.down[m] {
+			ids = append(ids, g.ids[m])
+		}
+	}
+	return ids
+}
+
+// slotEntries spread the hash slots of the group over the members that are
+// up, the slots are deleted when no member is left
+func (g *uplinkGroup) slotEntries(op Operation) []interface{} {
+	var entries = make([]interface{}, 0, uplinkSlots)
+	up := g.up()
+	for slot := 0; slot < uplinkSlots; slot++ {
+		entry := p4client.TableEntry{
+			Tablename: uplinkGroupTable,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"port": {uint16(g.port), "exact"},
+					"slot": {uint16(slot), "exact"},
+				},
+				Priority: int32(0),
+			},
+		}
+		if op == OpAdded && len(up) != 0 {
+			entry.Action = p4client.Action{
+				ActionName: "evpn_gw_control.set_uplink_port",
+				Params:     []interface{}{uint16(up[slot%len(up)])},
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ingressEntries let the packets to the mac of the bond in on the members
+// whose own mac differs
+func (g *uplinkGroup) ingressEntries(op Operation, ports []PhyPort, defaultVsi int) []interface{} {
+	var entries = make([]interface{}, 0)
+	da, err := net.ParseMAC(g.mac)
+	if err != nil {
+		return entries
+	}
+	for _, port := range ports {
+		if !g.hasMember(port.id) || port.mac == g.mac {
+			continue
+		}
+		entry := p4client.TableEntry{
+			Tablename: phyInIP,
+			TableField: p4client.TableField{
+				FieldValue: map[string][2]interface{}{
+					"port_id": {uint16(port.id), "exact"},
+					"da":      {da, "exact"},
+				},
+				Priority: int32(0),
+			},
+		}
+		if op == OpAdded {
+			entry.Action = p4client.Action{
+				ActionName: "evpn_gw_control.set_vrf_id",
+				Params:     []interface{}{TcamPrefix.GRD, uint32(_toEgressVsi(defaultVsi)), uint32(0)},
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// hasMember tells whether the port id is a member of the group
+func (g *uplinkGroup) hasMember(id int) bool {
+	for _, m := range g.members {
+		if g.ids[m] == id {
+			return true
+		}
+	}
+	return false
+}
+
+// uplinkEntries are the static entries of the uplink groups
+func uplinkEntries(op Operation, ports []PhyPort, defaultVsi int) []interface{} {
+	var entries = make([]interface{}, 0)
+	for _, g := range uplinkGroups {
+		entries = append(entries, g.ingressEntries(op, ports, defaultVsi)...)
+		entries = append(entries, g.slotEntries(op)...)
+	}
+	return entries
+}
+
+// setUplinkMember marks the member up or down and returns the deleted, the
+// added and the rewritten slots of its group, none when the member is
+// unknown or unchanged
+func setUplinkMember(name string, up bool) ([]interface{}, []interface{}, []interface{}) {
+	for _, g := range uplinkGroups {
+		if _, ok := g.ids[name]; !ok || g.down[name] == !up {
+			continue
+		}
+		// the slots are programmed while a member is up
+		programmed := len(g.up()) != 0
+		g.down[name] = !up
+		state := "down"
+		if up {
+			state = "up"
+		}
+		log.Printf("intel-e2000: uplink %s of group %s went %s, %d members up\n", name, g.name, state, len(g.up()))
+		switch {
+		case len(g.up()) == 0:
+			return g.slotEntries(OpDeleted), nil, nil
+		case !programmed:
+			return nil, g.slotEntries(OpAdded), nil
+		}
+		return nil, nil, g.slotEntries(OpAdded)
+	}
+	return nil, nil, nil
+}
+
+// watchUplinks follows the oper state of the members and moves their slots
+// to the other members while they are down, in dry run the links of the
+// host are left alone and every member stays up
+func watchUplinks(done <-chan struct{}) {
+	if len(uplinkGroups) == 0 || p4client.IsDryRun() {
+		return
+	}
+	updates := make(chan netlink.LinkUpdate)
+	// the existing links come first and catch members down at start
+	options := netlink.LinkSubscribeOptions{ListExisting: true}
+	if err := netlink.LinkSubscribeWithOptions(updates, done, options); err != nil {
+		log.Printf("intel-e2000: cannot follow the uplink members: %v\n", err)
+		return
+	}
+	go func() {
+		for u := range updates {
+			attrs := u.Link.Attrs()
+			translateMu.Lock()
+			dels, adds, mods := setUplinkMember(attrs.Name, attrs.OperState == netlink.OperUp)
+			_ = delEntries(dels)
+			_ = addEntries(adds)
+			_ = modEntries(mods)
+			translateMu.Unlock()
+		}
+	}()
+}
+
+// uplinkGroupList returns the groups with their members that are down
+func uplinkGroupList() []DebugUplinkGroup {
+	var list []DebugUplinkGroup
+	for _, g := range uplinkGroups {
+		var down []string
+		for m, d := range g.down {
+			if d {
+				down = append(down, m)
+			}
+		}
+		sort.Strings(down)
+		list = append(list, DebugUplinkGroup{Name: g.name, Port: g.port, Members: g.members, Down: down})
+	}
+	return list
+}
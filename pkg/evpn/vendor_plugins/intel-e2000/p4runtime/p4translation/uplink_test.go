@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/journal"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+var testUplinkPorts = map[string]uplinkPort{
+	"enp0s1f0d1": {index: 0, port: 0},
+	"enp0s1f0d3": {index: 1, port: 1},
+	"bond0":      {index: 2, port: 4},
+}
+
+func TestParseUplinkGroups(t *testing.T) {
+	tests := map[string]struct {
+		groups []uplinkGroupConfig
+		errMsg string
+	}{
+		"bond of two uplinks": {
+			groups: []uplinkGroupConfig{{Name: "bond0", Members: []string{"enp0s1f0d1", "enp0s1f0d3"}}},
+		},
+		"bond not a physical port": {
+			groups: []uplinkGroupConfig{{Name: "bond1", Members: []string{"enp0s1f0d1"}}},
+			errMsg: "uplinks: group bond1 is not in interfaces.phyports",
+		},
+		"bond without members": {
+			groups: []uplinkGroupConfig{{Name: "bond0"}},
+			errMsg: "uplinks: group bond0 has no members",
+		},
+		"unknown member": {
+			groups: []uplinkGroupConfig{{Name: "bond0", Members: []string{"enp0s1f0d7"}}},
+			errMsg: "uplinks: member enp0s1f0d7 of group bond0 is not in interfaces.phyports",
+		},
+		"member given twice": {
+			groups: []uplinkGroupConfig{{Name: "bond0", Members: []string{"enp0s1f0d1", "enp0s1f0d1"}}},
+			errMsg: "uplinks: member enp0s1f0d1 of group bond0 is taken by bond0",
+		},
+		"group given twice": {
+			groups: []uplinkGroupConfig{
+				{Name: "bond0", Members: []string{"enp0s1f0d1"}},
+				{Name: "bond0", Members: []string{"enp0s1f0d3"}},
+			},
+			errMsg: `uplinks: group "bond0" is unnamed or given twice`,
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := parseUplinkGroups(tt.groups, testUplinkPorts)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestUplinkGroupMembers(t *testing.T) {
+	groups, err := parseUplinkGroups([]uplinkGroupConfig{{Name: "bond0", Members: []string{"enp0s1f0d1", "enp0s1f0d3"}}}, testUplinkPorts)
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	uplinkGroups = groups
+	t.Cleanup(func() { uplinkGroups = nil })
+
+	members := func(entries []interface{}) []interface{} {
+		var ports []interface{}
+		for _, entry := range entries {
+			e := entry.(p4client.TableEntry)
+			if e.Tablename != uplinkGroupTable || e.FieldValue["port"][0] != uint16(4) {
+				t.Fatalf("Expected the slots of port 4, received %v", e)
+			}
+			if len(e.Params) != 0 {
+				ports = append(ports, e.Params[0])
+			}
+		}
+		return ports
+	}
+	slots := members(uplinkEntries(OpAdded, nil, 0))
+	if len(slots) != uplinkSlots || slots[0] != uint16(0) || slots[1] != uint16(1) {
+		t.Errorf("Expected the slots spread over ports 0 and 1, received %v", slots)
+	}
+	if _p2pQid(4) != _p2pQid(PortID.PHY0) {
+		t.Errorf("Expected the queue of the first member, received %d", _p2pQid(4))
+	}
+
+	dels, adds, mods := setUplinkMember("enp0s1f0d1", false)
+	if dels != nil || adds != nil || !reflect.DeepEqual(members(mods), repeated(uint16(1), uplinkSlots)) {
+		t.Errorf("Expected every slot rewritten to port 1, received %v %v %v", dels, adds, mods)
+	}
+	if dels, adds, mods := setUplinkMember("enp0s1f0d1", false); dels != nil || adds != nil || mods != nil {
+		t.Errorf("Expected no change for a member already down, received %v %v %v", dels, adds, mods)
+	}
+	if dels, adds, mods := setUplinkMember("enp0s1f0d2", false); dels != nil || adds != nil || mods != nil {
+		t.Errorf("Expected no change for a port outside the groups, received %v %v %v", dels, adds, mods)
+	}
+	dels, adds, mods = setUplinkMember("enp0s1f0d3", false)
+	if len(dels) != uplinkSlots || len(members(dels)) != 0 || adds != nil || mods != nil {
+		t.Errorf("Expected the slots deleted without members, received %v %v %v", dels, adds, mods)
+	}
+	dels, adds, mods = setUplinkMember("enp0s1f0d3", true)
+	if dels != nil || mods != nil || !reflect.DeepEqual(members(adds), repeated(uint16(1), uplinkSlots)) {
+		t.Errorf("Expected the slots added back on port 1, received %v %v %v", dels, adds, mods)
+	}
+	if list := uplinkGroupList(); len(list) != 1 || !reflect.DeepEqual(list[0].Down, []string{"enp0s1f0d1"}) {
+		t.Errorf("Expected enp0s1f0d1 down, received %v", list)
+	}
+	dels, adds, mods = setUplinkMember("enp0s1f0d1", true)
+	if dels != nil || adds != nil || len(mods) != uplinkSlots {
+		t.Errorf("Expected the slots rewritten over both members, received %v %v %v", dels, adds, mods)
+	}
+}
+
+// repeated returns n copies of the value
+func repeated(value interface{}, n int) []interface{} {
+	values := make([]interface{}, n)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
+
+func TestModEntries(t *testing.T) {
+	resetState()
+	defer resetState()
+	var buf bytes.Buffer
+	p4client.SetDryRun(journal.New(&buf))
+	defer p4client.SetDryRun(nil)
+
+	g := &uplinkGroup{name: "bond0", port: 4, members: []string{"enp0s1f0d1"}, ids: map[string]int{"enp0s1f0d1": 0}, down: map[string]bool{}}
+	_ = modEntries(g.slotEntries(OpAdded))
+	if n := strings.Count(buf.String(), `"op":"modify"`); n != uplinkSlots {
+		t.Errorf("Expected %d slots modified, received %d\n%s", uplinkSlots, n, buf.String())
+	}
+	if strings.Contains(buf.String(), `"op":"add"`) {
+		t.Errorf("Expected no slot added, received %s", buf.String())
+	}
+	if n := len(desired.entries(uplinkGroupTable)); n != uplinkSlots {
+		t.Errorf("Expected %d desired slots, received %d", uplinkSlots, n)
+	}
+}
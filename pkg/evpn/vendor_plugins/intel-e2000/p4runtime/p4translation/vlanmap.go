@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"log"
+
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vlanmap"
+)
+
+// trunkVports names the trunk bridge ports by vport, for the nexthops that
+// only know the vport they leave on, guarded by translateMu
+var trunkVports = make(map[string]string)
+
+// setUpVlanMap logs the trunk bridge ports with a vlan translation
+func setUpVlanMap() {
+	if ports := vlanmap.Ports(); len(ports) != 0 {
+		log.Printf("intel-e2000: vlan translation on %d bridge ports\n", len(ports))
+	}
+}
+
+// customerVid returns the vid the tenant uses for the logical bridge vid on
+// the trunk bridge port of the vport
+func customerVid(vport string, vid uint16) uint16 {
+	name, ok := trunkVports[vport]
+	if !ok {
+		return vid
+	}
+	return vlanmap.CustomerVid(name, vid)
+}
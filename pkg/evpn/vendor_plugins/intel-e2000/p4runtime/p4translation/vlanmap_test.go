@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	netlink_polling "github.com/opiproject/opi-evpn-bridge/pkg/netlink"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vlanmap"
+)
+
+func TestPodDecoder_TrunkVlanTranslation(t *testing.T) {
+	err := vlanmap.Set([]vlanmap.Port{{BridgePort: "bp-vm1", Vlans: []vlanmap.Mapping{{Customer: 10, Bridge: 1010}}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = vlanmap.Set(nil) }()
+	trunkVports["24"] = "//network.opiproject.org/ports/bp-vm1"
+	defer delete(trunkVports, "24")
+
+	tests := map[string]struct {
+		vport string
+		vid   int
+		want  uint16
+	}{
+		"mapped vid":           {vport: "24", vid: 1010, want: 10},
+		"unmapped vid":         {vport: "24", vid: 1020, want: 1020},
+		"port without any map": {vport: "25", vid: 1010, want: 1010},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			nexthop := netlink_polling.L2NexthopStruct{
+				Key:      netlink_polling.L2NexthopKey{Dev: "vport-" + tt.vport, VlanID: tt.vid},
+				VlanID:   tt.vid,
+				ID:       17,
+				Type:     netlink_polling.BRIDGEPORT,
+				Metadata: map[interface{}]interface{}{"portType": infradb.BridgePortType(infradb.Trunk), "vport_id": tt.vport},
+			}
+			p := PodDecoder{}
+			entries := p.translateAddedL2Nexthop(nexthop)
+			defer p.translateDeletedL2Nexthop(nexthop)
+			push := entries[0].(p4client.TableEntry)
+			if push.ActionName != "evpn_gw_control.vlan_push" || push.Params[2] != tt.want {
+				t.Errorf("Expected vid %d pushed towards the tenant, received %v", tt.want, push)
+			}
+		})
+	}
+}
+
+func TestPodDecoder_TrunkVportOfFailedAdd(t *testing.T) {
+	lb := &infradb.LogicalBridge{
+		Name: "//network.opiproject.org/bridges/lb-10",
+		Spec: &infradb.LogicalBridgeSpec{VlanID: 10},
+	}
+	mac, _ := net.ParseMAC("13:88:00:00:03:14")
+	bp := &infradb.BridgePort{
+		Name:     "//network.opiproject.org/ports/bp-vm1",
+		Spec:     &infradb.BridgePortSpec{MacAddress: &mac, Ptype: infradb.Trunk, LogicalBridges: []string{lb.Name}},
+		Metadata: &infradb.BridgePortMetadata{VPort: "5000"},
+	}
+	store := newSnapshotStore()
+	objects = store
+	t.Cleanup(func() {
+		objects = infradbStore{}
+		delete(trunkVports, "5000")
+	})
+	p := PodDecoder{_portMuxVsi: 4}
+
+	// the logical bridge is missing, the port is not added
+	if _, err := p.translateAddedBp(bp); err == nil {
+		t.Fatalf("Expected the add to fail without the logical bridge")
+	}
+	if name, ok := trunkVports["5000"]; ok {
+		t.Errorf("Expected no trunk port on vport 5000 after the failed add, received %s", name)
+	}
+
+	store.load(&objectSnapshot{LogicalBridges: []*infradb.LogicalBridge{lb}})
+	if _, err := p.translateAddedBp(bp); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if name := trunkVports["5000"]; name != bp.Name {
+		t.Errorf("Expected %s on vport 5000, received %s", bp.Name, name)
+	}
+	if _, err := p.translateDeletedBp(bp); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if name, ok := trunkVports["5000"]; ok {
+		t.Errorf("Expected no trunk port on vport 5000 after the delete, received %s", name)
+	}
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vport"
+)
+
+// checkVport makes sure the bridge port is on the vport the resolver gives
+// its mac, the linux vendor module sets the vport up with the same resolver
+func checkVport(bp *infradb.BridgePort) error {
+	id, err := vport.Of(*bp.Spec.MacAddress)
+	if err != nil {
+		return err
+	}
+	if strconv.Itoa(id) != bp.Metadata.VPort {
+		return fmt.Errorf("bridge port %s is on vport %s, the resolver gives %d", bp.Name, bp.Metadata.VPort, id)
+	}
+	return nil
+}
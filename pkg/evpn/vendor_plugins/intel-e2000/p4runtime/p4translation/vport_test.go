@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"net"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	"github.com/opiproject/opi-intel-bridge/pkg/evpn/vport"
+)
+
+func TestCheckVport(t *testing.T) {
+	table, err := vport.NewTableResolver([]vport.TableEntry{{Mac: "02:00:00:00:00:01", Vport: 24}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer vport.Set(vport.MacResolver{})
+
+	tests := map[string]struct {
+		resolver vport.Resolver
+		mac      string
+		vport    string
+		errorMsg string
+	}{
+		"mac derived vport": {
+			resolver: vport.MacResolver{},
+			mac:      "00:18:00:00:03:14",
+			vport:    "24",
+		},
+		"locally administered mac in the table": {
+			resolver: table,
+			mac:      "02:00:00:00:00:01",
+			vport:    "24",
+		},
+		"vport of another resolver": {
+			resolver: vport.MacResolver{},
+			mac:      "02:00:00:00:00:01",
+			vport:    "24",
+			errorMsg: "bridge port bp-vm1 is on vport 24, the resolver gives 512",
+		},
+		"mac missing from the table": {
+			resolver: table,
+			mac:      "02:00:00:00:00:02",
+			vport:    "24",
+			errorMsg: "vport: no vport for mac 02:00:00:00:00:02",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			vport.Set(tt.resolver)
+			mac, _ := net.ParseMAC(tt.mac)
+			bp := &infradb.BridgePort{
+				Name:     "bp-vm1",
+				Spec:     &infradb.BridgePortSpec{MacAddress: &mac},
+				Metadata: &infradb.BridgePortMetadata{VPort: tt.vport},
+			}
+			err := checkVport(bp)
+			if tt.errorMsg == "" && err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+			if tt.errorMsg != "" && (err == nil || err.Error() != tt.errorMsg) {
+				t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
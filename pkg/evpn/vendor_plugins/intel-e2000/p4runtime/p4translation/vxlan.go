@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+//
+//nolint:all
+package p4translation
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sync"
+
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+// the iana vxlan port the parser of the evpn_gw program takes, and the ttl
+// of the outer header when a policy sets none
+const (
+	defaultVxlanPort = 4789
+	defaultVxlanTTL  = 64
+)
+
+// vxlanConfig is the vxlan config, the udp port, outer ttl and source port
+// entropy of all tunnels and the encaps overriding them for a logical
+// bridge or a vrf
+type vxlanConfig struct {
+	UDPPort uint16             `mapstructure:"udpport"`
+	TTL     uint16             `mapstructure:"ttl"`
+	Entropy bool               `mapstructure:"entropy"`
+	Encaps  []vxlanEncapConfig `mapstructure:"encaps"`
+}
+
+// vxlanEncapConfig is an encap of the vxlan.encaps config, given for a
+// logical bridge or a vrf. The settings it leaves out are the ones of the
+// vxlan config.
+type vxlanEncapConfig struct {
+	LogicalBridge string `mapstructure:"logicalbridge"`
+	Vrf           string `mapstructure:"vrf"`
+	UDPPort       uint16 `mapstructure:"udpport"`
+	TTL           uint16 `mapstructure:"ttl"`
+	Entropy       *bool  `mapstructure:"entropy"`
+}
+
+// vxlanEncap is the resolved encap of a logical bridge or vrf
+type vxlanEncap struct {
+	udpPort uint16
+	ttl     uint16
+	entropy bool
+}
+
+// vxlanEncaps are the encaps of the logical bridges and vrfs by the base of
+// their name, the others take the defaults
+type vxlanEncaps struct {
+	mu       sync.RWMutex
+	defaults vxlanEncap
+	lbs      map[string]vxlanEncap
+	vrfs     map[string]vxlanEncap
+	outer    bool
+}
+
+// encaps are the configured encaps, all tunnels use the iana port and the
+// outer header of the pipeline when empty
+var encaps = vxlanEncaps{defaults: vxlanEncap{udpPort: defaultVxlanPort, ttl: defaultVxlanTTL}}
+
+// setUpVxlan applies the encaps of the config. Other ports than the iana
+// one need the vxlan port table of the pipeline to decap, the ttl and
+// entropy need the outer header params of the vxlan push actions.
+func setUpVxlan(config vxlanConfig) {
+	if err := encaps.set(config); err != nil {
+		log.Fatalf("intel-e2000: %v\n", err)
+	}
+	if encaps.otherPorts() && pipelineInfo != nil && !schema.hasTables(pipelineInfo, vxlanPort) {
+		log.Fatalf("intel-e2000: vxlan udp ports other than %d need table %s in pipeline %s\n",
+			defaultVxlanPort, schema.tableName(vxlanPort), schema.Pipeline)
+	}
+	if encaps.outer {
+		for _, action := range []string{"omac_vxlan_imac_push", "omac_vxlan_push"} {
+			if order := schema.Actions[action].Params; len(order) != 0 && !contains(order, "ttl") {
+				log.Fatalf("intel-e2000: vxlan ttl and entropy need the schema to map the outer header params of %s\n", action)
+			}
+			if pipelineInfo != nil && !schema.hasActionParams(pipelineInfo, action, paramsThrough(action, "entropy")) {
+				log.Fatalf("intel-e2000: vxlan ttl and entropy need the outer header params of %s in pipeline %s\n",
+					schema.actionName(action), schema.Pipeline)
+			}
+		}
+	}
+	log.Printf("intel-e2000: vxlan udp port %d, encaps of %d logical bridges and %d vrfs\n",
+		encaps.defaults.udpPort, len(encaps.lbs), len(encaps.vrfs))
+}
+
+// newVxlanEncaps validates and resolves the encaps
+func newVxlanEncaps(config vxlanConfig) (*vxlanEncaps, error) {
+	defaults := vxlanEncap{udpPort: config.UDPPort, ttl: config.TTL, entropy: config.Entropy}
+	outer := config.TTL != 0 || config.Entropy
+	if defaults.udpPort == 0 {
+		defaults.udpPort = defaultVxlanPort
+	}
+	if defaults.ttl == 0 {
+		defaults.ttl = defaultVxlanTTL
+	}
+	if defaults.ttl > 255 {
+		return nil, fmt.Errorf("vxlan: ttl %d above 255", defaults.ttl)
+	}
+	lbs := make(map[string]vxlanEncap)
+	vrfs := make(map[string]vxlanEncap)
+	for i, e := range config.Encaps {
+		if (e.LogicalBridge == "") == (e.Vrf == "") {
+			return nil, fmt.Errorf("vxlan: encap %d needs either a logical bridge or a vrf", i+1)
+		}
+		if e.TTL > 255 {
+			return nil, fmt.Errorf("vxlan: encap %d: ttl %d above 255", i+1, e.TTL)
+		}
+		encap := defaults
+		if e.UDPPort != 0 {
+			encap.udpPort = e.UDPPort
+		}
+		if e.TTL != 0 {
+			encap.ttl = e.TTL
+			outer = true
+		}
+		if e.Entropy != nil {
+			encap.entropy = *e.Entropy
+			outer = true
+		}
+		encaps, name := lbs, e.LogicalBridge
+		if e.Vrf != "" {
+			encaps, name = vrfs, e.Vrf
+		}
+		if _, ok := encaps[path.Base(name)]; ok {
+			return nil, fmt.Errorf("vxlan: duplicate encap of %s", name)
+		}
+		encaps[path.Base(name)] = encap
+	}
+	return &vxlanEncaps{defaults: defaults, lbs: lbs, vrfs: vrfs, outer: outer}, nil
+}
+
+// set validates and stores the encaps
+func (x *vxlanEncaps) set(config vxlanConfig) error {
+	parsed, err := newVxlanEncaps(config)
+	if err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.defaults, x.lbs, x.vrfs, x.outer = parsed.defaults, parsed.lbs, parsed.vrfs, parsed.outer
+	return nil
+}
+
+// otherPorts tells whether a tunnel uses another port than the iana one
+func (x *vxlanEncaps) otherPorts() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.defaults.udpPort != defaultVxlanPort {
+		return true
+	}
+	for _, encaps := range []map[string]vxlanEncap{x.lbs, x.vrfs} {
+		for _, e := range encaps {
+			if e.udpPort != defaultVxlanPort {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// debug returns the defaults and the encaps of the logical bridges and vrfs
+func (x *vxlanEncaps) debug() DebugVxlanEncaps {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	dump := func(e vxlanEncap) DebugVxlanEncap {
+		return DebugVxlanEncap{UDPPort: e.udpPort, TTL: e.ttl, Entropy: e.entropy}
+	}
+	state := DebugVxlanEncaps{Default: dump(x.defaults)}
+	for name, e := range x.lbs {
+		if state.LogicalBridges == nil {
+			state.LogicalBridges = make(map[string]DebugVxlanEncap)
+		}
+		state.LogicalBridges[name] = dump(e)
+	}
+	for name, e := range x.vrfs {
+		if state.Vrfs == nil {
+			state.Vrfs = make(map[string]DebugVxlanEncap)
+		}
+		state.Vrfs[name] = dump(e)
+	}
+	return state
+}
+
+// logicalBridge returns the encap of the logical bridge
+func (x *vxlanEncaps) logicalBridge(name string) vxlanEncap {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if e, ok := x.lbs[path.Base(name)]; ok {
+		return e
+	}
+	return x.defaults
+}
+
+// vrf returns the encap of the vrf, the nexthops give the linux name of the
+// vrf which is the base of its name
+func (x *vxlanEncaps) vrf(name string) vxlanEncap {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if e, ok := x.vrfs[path.Base(name)]; ok {
+		return e
+	}
+	return x.defaults
+}
+
+// vlan returns the encap of the logical bridge of the vlan
+func (x *vxlanEncaps) vlan(vid uint16) vxlanEncap {
+	if name, ok := vlanBridge(vid); ok {
+		return x.logicalBridge(name)
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.defaults
+}
+
+// outerParams returns the trailing params of the vxlan push actions, the
+// dscp of the marking followed by the ttl and entropy of the encap once a
+// policy sets them, so pipelines without them keep working
+func (x *vxlanEncaps) outerParams(marking qosMarking, encap vxlanEncap) []interface{} {
+	params := qos.dscpParams(marking)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if !x.outer {
+		return params
+	}
+	if params == nil {
+		params = []interface{}{uint16(0), uint16(0)}
+	}
+	var entropy uint16
+	if encap.entropy {
+		entropy = 1
+	}
+	return append(params, encap.ttl, entropy)
+}
+
+// _vxlanPortEntry builds the entry letting the vxlan packets of the vni in
+// on the udp port of the encap, none for the iana port the parser takes
+func _vxlanPortEntry(encap vxlanEncap, vni uint32, delete bool) []interface{} {
+	if encap.udpPort == defaultVxlanPort {
+		return nil
+	}
+	entry := p4client.TableEntry{
+		Tablename: vxlanPort,
+		TableField: p4client.TableField{
+			FieldValue: map[string][2]interface{}{
+				"dst_port": {encap.udpPort, "exact"},
+				"vni":      {vni, "exact"},
+			},
+			Priority: int32(0),
+		},
+	}
+	if !delete {
+		entry.Action = p4client.Action{ActionName: "evpn_gw_control.accept_vxlan_port"}
+	}
+	return []interface{}{entry}
+}
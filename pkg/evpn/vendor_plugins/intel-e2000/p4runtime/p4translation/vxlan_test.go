@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package p4translation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opiproject/opi-evpn-bridge/pkg/infradb"
+	p4client "github.com/opiproject/opi-intel-bridge/pkg/evpn/vendor_plugins/intel-e2000/p4runtime/p4driverapi"
+)
+
+func TestVxlanEncaps_Set(t *testing.T) {
+	tests := map[string]struct {
+		config vxlanConfig
+		errMsg string
+	}{
+		"logical bridge and vrf": {
+			config: vxlanConfig{UDPPort: 8472, Encaps: []vxlanEncapConfig{
+				{LogicalBridge: "lb-lab", TTL: 8},
+				{Vrf: "//network.opiproject.org/vrfs/vrf-blue", UDPPort: 4789},
+			}},
+		},
+		"ttl out of range": {
+			config: vxlanConfig{TTL: 256},
+			errMsg: "vxlan: ttl 256 above 255",
+		},
+		"neither logical bridge nor vrf": {
+			config: vxlanConfig{Encaps: []vxlanEncapConfig{{UDPPort: 8472}}},
+			errMsg: "vxlan: encap 1 needs either a logical bridge or a vrf",
+		},
+		"both logical bridge and vrf": {
+			config: vxlanConfig{Encaps: []vxlanEncapConfig{{LogicalBridge: "lb-lab", Vrf: "vrf-blue"}}},
+			errMsg: "vxlan: encap 1 needs either a logical bridge or a vrf",
+		},
+		"encap ttl out of range": {
+			config: vxlanConfig{Encaps: []vxlanEncapConfig{{Vrf: "vrf-blue", TTL: 300}}},
+			errMsg: "vxlan: encap 1: ttl 300 above 255",
+		},
+		"duplicate encap": {
+			config: vxlanConfig{Encaps: []vxlanEncapConfig{{Vrf: "vrf-blue"}, {Vrf: "//network.opiproject.org/vrfs/vrf-blue"}}},
+			errMsg: "vxlan: duplicate encap of //network.opiproject.org/vrfs/vrf-blue",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var x vxlanEncaps
+			err := x.set(tt.config)
+			switch {
+			case tt.errMsg == "" && err != nil:
+				t.Errorf("Expected no error, received %v", err)
+			case tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)):
+				t.Errorf("Expected error: %v, received %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestVxlanEncaps_Lookup(t *testing.T) {
+	trackVlanBridge(OpAdded, &infradb.LogicalBridge{
+		Name: "//network.opiproject.org/bridges/lb-lab",
+		Spec: &infradb.LogicalBridgeSpec{VlanID: 20},
+	})
+	t.Cleanup(resetVlanBridges)
+
+	var x vxlanEncaps
+	entropy := true
+	if err := x.set(vxlanConfig{Encaps: []vxlanEncapConfig{
+		{LogicalBridge: "lb-lab", TTL: 8},
+		{Vrf: "vrf-blue", UDPPort: 8472, Entropy: &entropy},
+	}}); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if port := x.debug().Default.UDPPort; port != defaultVxlanPort {
+		t.Errorf("Expected the default port %d, received %d", defaultVxlanPort, port)
+	}
+	vrf := x.vrf("vrf-blue")
+	if vrf != (vxlanEncap{udpPort: 8472, ttl: defaultVxlanTTL, entropy: true}) {
+		t.Errorf("Expected port 8472 with entropy, received %v", vrf)
+	}
+	if e := x.vlan(20); e.ttl != 8 || e.udpPort != defaultVxlanPort {
+		t.Errorf("Expected ttl 8 on vlan 20, received %v", e)
+	}
+	if e := x.vlan(30); e != x.defaults {
+		t.Errorf("Expected the defaults on vlan 30, received %v", e)
+	}
+	if e := x.debug().Vrfs["vrf-blue"]; e != (DebugVxlanEncap{UDPPort: 8472, TTL: defaultVxlanTTL, Entropy: true}) {
+		t.Errorf("Expected the encap of vrf-blue in the debug dump, received %v", e)
+	}
+	if !x.otherPorts() {
+		t.Errorf("Expected the port of vrf-blue to need the vxlan port table")
+	}
+	if params := x.outerParams(qosMarking{}, vrf); !reflect.DeepEqual(params,
+		[]interface{}{uint16(0), uint16(0), uint16(defaultVxlanTTL), uint16(1)}) {
+		t.Errorf("Expected the outer header params, received %v", params)
+	}
+
+	entries := _vxlanPortEntry(vrf, 1000, false)
+	if len(entries) != 1 {
+		t.Fatalf("Expected a vxlan port entry, received %v", entries)
+	}
+	if e := entries[0].(p4client.TableEntry); e.Tablename != vxlanPort || e.FieldValue["dst_port"][0] != uint16(8472) {
+		t.Errorf("Expected the vni on port 8472, received %v", e)
+	}
+	if entries := _vxlanPortEntry(x.vrf("vrf-red"), 1000, false); entries != nil {
+		t.Errorf("Expected no vxlan port entry on port %d, received %v", defaultVxlanPort, entries)
+	}
+
+	// without ttl or entropy the vxlan push actions keep their params
+	if err := x.set(vxlanConfig{UDPPort: 4790}); err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	if params := x.outerParams(qosMarking{}, x.vrf("vrf-blue")); params != nil {
+		t.Errorf("Expected no outer header params, received %v", params)
+	}
+	if port := x.debug().Default.UDPPort; port != 4790 {
+		t.Errorf("Expected the default port 4790, received %d", port)
+	}
+}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package vlanmap keeps the vlan translation of the trunk bridge ports, the
+// vids the tenants use on the port for the vids of the logical bridges
+//
+//nolint:all
+package vlanmap
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+)
+
+// Mapping translates the customer vid of the port to the logical bridge vid
+type Mapping struct {
+	Customer uint16 `mapstructure:"customer"`
+	Bridge   uint16 `mapstructure:"bridge"`
+}
+
+// Port is a trunk bridge port with its vlan translation, the logical
+// bridges without a mapping keep their vid
+type Port struct {
+	BridgePort string    `mapstructure:"bridgeport"`
+	Vlans      []Mapping `mapstructure:"vlans"`
+}
+
+var (
+	mu    sync.RWMutex
+	ports map[string]Port
+)
+
+// Set validates and stores the bridge ports
+func Set(p []Port) error {
+	byName := make(map[string]Port)
+	for i, port := range p {
+		if port.BridgePort == "" {
+			return fmt.Errorf("vlanmap: bridge port %d has no name", i+1)
+		}
+		name := path.Base(port.BridgePort)
+		if _, ok := byName[name]; ok {
+			return fmt.Errorf("vlanmap: duplicate bridge port %s", port.BridgePort)
+		}
+		customer := make(map[uint16]bool)
+		bridge := make(map[uint16]bool)
+		for _, m := range port.Vlans {
+			if m.Customer < 1 || m.Customer > 4094 || m.Bridge < 1 || m.Bridge > 4094 {
+				return fmt.Errorf("vlanmap: bridge port %s: invalid mapping %d to %d", port.BridgePort, m.Customer, m.Bridge)
+			}
+			if customer[m.Customer] || bridge[m.Bridge] {
+				return fmt.Errorf("vlanmap: bridge port %s: vid %d or %d mapped twice", port.BridgePort, m.Customer, m.Bridge)
+			}
+			customer[m.Customer] = true
+			bridge[m.Bridge] = true
+		}
+		byName[name] = port
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	ports = byName
+	return nil
+}
+
+// Of returns the vlan translation of the bridge port, the port is given by
+// its name or its full resource name
+func Of(bridgePort string) (Port, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := ports[path.Base(bridgePort)]
+	return p, ok
+}
+
+// Ports returns the bridge ports sorted by name
+func Ports() []Port {
+	mu.RLock()
+	defer mu.RUnlock()
+	var list []Port
+	for _, p := range ports {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return path.Base(list[i].BridgePort) < path.Base(list[j].BridgePort) })
+	return list
+}
+
+// Customer returns the vid the tenant uses on the port for the logical
+// bridge vid
+func (p Port) Customer(vid uint16) uint16 {
+	for _, m := range p.Vlans {
+		if m.Bridge == vid {
+			return m.Customer
+		}
+	}
+	return vid
+}
+
+// CustomerVid returns the vid the tenant uses on the bridge port for the
+// logical bridge vid, the vid itself when the port does not translate it
+func CustomerVid(bridgePort string, vid uint16) uint16 {
+	p, _ := Of(bridgePort)
+	return p.Customer(vid)
+}
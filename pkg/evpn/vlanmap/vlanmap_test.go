@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package vlanmap
+
+import (
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := map[string]struct {
+		ports    []Port
+		errorMsg string
+	}{
+		"valid ports": {
+			ports: []Port{
+				{BridgePort: "bp-a", Vlans: []Mapping{{Customer: 10, Bridge: 1010}, {Customer: 20, Bridge: 1020}}},
+				{BridgePort: "//network.opiproject.org/ports/bp-b", Vlans: []Mapping{{Customer: 10, Bridge: 2010}}},
+			},
+		},
+		"unnamed port": {
+			ports:    []Port{{Vlans: []Mapping{{Customer: 10, Bridge: 1010}}}},
+			errorMsg: "vlanmap: bridge port 1 has no name",
+		},
+		"duplicate port": {
+			ports:    []Port{{BridgePort: "bp-a"}, {BridgePort: "//network.opiproject.org/ports/bp-a"}},
+			errorMsg: "vlanmap: duplicate bridge port //network.opiproject.org/ports/bp-a",
+		},
+		"invalid vid": {
+			ports:    []Port{{BridgePort: "bp-a", Vlans: []Mapping{{Customer: 10, Bridge: 4095}}}},
+			errorMsg: "vlanmap: bridge port bp-a: invalid mapping 10 to 4095",
+		},
+		"customer vid mapped twice": {
+			ports:    []Port{{BridgePort: "bp-a", Vlans: []Mapping{{Customer: 10, Bridge: 1010}, {Customer: 10, Bridge: 1020}}}},
+			errorMsg: "vlanmap: bridge port bp-a: vid 10 or 1020 mapped twice",
+		},
+		"bridge vid mapped twice": {
+			ports:    []Port{{BridgePort: "bp-a", Vlans: []Mapping{{Customer: 10, Bridge: 1010}, {Customer: 20, Bridge: 1010}}}},
+			errorMsg: "vlanmap: bridge port bp-a: vid 20 or 1010 mapped twice",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			defer func() { _ = Set(nil) }()
+			err := Set(tt.ports)
+			if tt.errorMsg == "" && err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+			if tt.errorMsg != "" && (err == nil || err.Error() != tt.errorMsg) {
+				t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestCustomerVid(t *testing.T) {
+	err := Set([]Port{{BridgePort: "bp-a", Vlans: []Mapping{{Customer: 10, Bridge: 1010}}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	defer func() { _ = Set(nil) }()
+
+	tests := map[string]struct {
+		bridgePort string
+		vid        uint16
+		want       uint16
+	}{
+		"mapped vid":         {bridgePort: "//network.opiproject.org/ports/bp-a", vid: 1010, want: 10},
+		"unmapped vid":       {bridgePort: "bp-a", vid: 1020, want: 1020},
+		"port without a map": {bridgePort: "bp-b", vid: 1010, want: 1010},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if got := CustomerVid(tt.bridgePort, tt.vid); got != tt.want {
+				t.Errorf("Expected vid %d, received %d", tt.want, got)
+			}
+		})
+	}
+}
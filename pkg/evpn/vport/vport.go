@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+// Package vport resolves the vport of the interfaces behind the bridge ports
+// and the representors, the linux vendor module and the intel-e2000 plugin
+// share the resolver so they always agree on the vport of a mac
+//
+//nolint:all
+package vport
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Resolver finds the vport of the interface with the mac
+type Resolver interface {
+	Vport(mac net.HardwareAddr) (int, error)
+}
+
+// MacResolver derives the vport from the first two bytes of the mac, the
+// allocation scheme of the macs the idpf driver gives its interfaces
+type MacResolver struct{}
+
+// Vport returns the first two bytes of the mac
+func (MacResolver) Vport(mac net.HardwareAddr) (int, error) {
+	if len(mac) != 6 {
+		return -1, fmt.Errorf("vport: invalid mac %s", mac)
+	}
+	return int(mac[0])<<8 | int(mac[1]), nil
+}
+
+// TableEntry gives the vport of a mac
+type TableEntry struct {
+	Mac   string `mapstructure:"mac"`
+	Vport int    `mapstructure:"vport"`
+}
+
+// TableResolver looks the vport up in a table of the config, for the macs
+// that do not follow the allocation scheme like the locally administered ones
+type TableResolver struct {
+	vports map[string]int
+}
+
+// NewTableResolver validates the entries and returns a resolver for them
+func NewTableResolver(entries []TableEntry) (*TableResolver, error) {
+	t := &TableResolver{vports: make(map[string]int)}
+	for _, e := range entries {
+		mac, err := net.ParseMAC(e.Mac)
+		if err != nil || len(mac) != 6 {
+			return nil, fmt.Errorf("vport: invalid mac %s", e.Mac)
+		}
+		if e.Vport < 0 || e.Vport > math.MaxUint16 {
+			return nil, fmt.Errorf("vport: mac %s: invalid vport %d", mac, e.Vport)
+		}
+		if _, ok := t.vports[mac.String()]; ok {
+			return nil, fmt.Errorf("vport: duplicate mac %s", mac)
+		}
+		t.vports[mac.String()] = e.Vport
+	}
+	return t, nil
+}
+
+// Vport returns the vport of the mac in the table
+func (t *TableResolver) Vport(mac net.HardwareAddr) (int, error) {
+	vport, ok := t.vports[mac.String()]
+	if !ok {
+		return -1, fmt.Errorf("vport: no vport for mac %s", mac)
+	}
+	return vport, nil
+}
+
+// SysfsResolver finds the netdev with the mac over netlink and reads the
+// vport from an attribute of the netdev in sysfs
+type SysfsResolver struct {
+	Root  string
+	Attr  string
+	links func() ([]netlink.Link, error)
+}
+
+// NewSysfsResolver returns a resolver reading the attribute, dev_port when
+// none is given
+func NewSysfsResolver(attr string) *SysfsResolver {
+	if attr == "" {
+		attr = "dev_port"
+	}
+	return &SysfsResolver{Root: "/sys/class/net", Attr: attr, links: netlink.LinkList}
+}
+
+// Vport returns the attribute of the netdev with the mac, in decimal or hex
+func (s *SysfsResolver) Vport(mac net.HardwareAddr) (int, error) {
+	links, err := s.links()
+	if err != nil {
+		return -1, fmt.Errorf("vport: %w", err)
+	}
+	for _, link := range links {
+		if link.Attrs().HardwareAddr.String() != mac.String() {
+			continue
+		}
+		name := link.Attrs().Name
+		data, err := os.ReadFile(filepath.Join(s.Root, name, s.Attr))
+		if err != nil {
+			return -1, fmt.Errorf("vport: %w", err)
+		}
+		vport, err := strconv.ParseUint(strings.TrimSpace(string(data)), 0, 16)
+		if err != nil {
+			return -1, fmt.Errorf("vport: %s of %s: %w", s.Attr, name, err)
+		}
+		return int(vport), nil
+	}
+	return -1, fmt.Errorf("vport: no interface with mac %s", mac)
+}
+
+var (
+	mu       sync.RWMutex
+	resolver Resolver = MacResolver{}
+)
+
+// Config is the vport section of the config
+type Config struct {
+	Resolver  string       `mapstructure:"resolver"`
+	Table     []TableEntry `mapstructure:"table"`
+	SysfsAttr string       `mapstructure:"sysfsattr"`
+}
+
+// NewResolver returns the resolver of the config: mac, the default, table
+// with the table entries or sysfs with the sysfs attribute
+func NewResolver(c Config) (Resolver, error) {
+	switch c.Resolver {
+	case "", "mac":
+		return MacResolver{}, nil
+	case "table":
+		return NewTableResolver(c.Table)
+	case "sysfs":
+		return NewSysfsResolver(c.SysfsAttr), nil
+	default:
+		return nil, fmt.Errorf("vport: unknown resolver %s", c.Resolver)
+	}
+}
+
+// Set replaces the resolver
+func Set(r Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	resolver = r
+}
+
+// Of returns the vport of the interface with the mac
+func Of(mac net.HardwareAddr) (int, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return resolver.Vport(mac)
+}
+
+// OfRepresentor returns the vport of the representor with the mac. A table
+// lists the bridge ports, the representors missing from it keep the vport
+// of their mac.
+func OfRepresentor(mac net.HardwareAddr) (int, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if t, ok := resolver.(*TableResolver); ok {
+		if vport, ok := t.vports[mac.String()]; ok {
+			return vport, nil
+		}
+		return MacResolver{}.Vport(mac)
+	}
+	return resolver.Vport(mac)
+}
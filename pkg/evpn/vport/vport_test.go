@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2022-2023 Intel Corporation, or its subsidiaries.
+// Copyright (C) 2023 Nordix Foundation.
+
+package vport
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestMacResolver(t *testing.T) {
+	mac, _ := net.ParseMAC("00:18:00:00:03:14")
+	vport, err := MacResolver{}.Vport(mac)
+	if err != nil || vport != 24 {
+		t.Errorf("Expected vport 24, received %d %v", vport, err)
+	}
+	if _, err := (MacResolver{}).Vport(nil); err == nil {
+		t.Errorf("Expected an error for a missing mac")
+	}
+}
+
+func TestNewTableResolver(t *testing.T) {
+	tests := map[string]struct {
+		entries  []TableEntry
+		errorMsg string
+	}{
+		"valid entries": {
+			entries: []TableEntry{{Mac: "02:00:00:00:00:01", Vport: 24}, {Mac: "02:00:00:00:00:02", Vport: 25}},
+		},
+		"invalid mac": {
+			entries:  []TableEntry{{Mac: "02:00", Vport: 24}},
+			errorMsg: "vport: invalid mac 02:00",
+		},
+		"invalid vport": {
+			entries:  []TableEntry{{Mac: "02:00:00:00:00:01", Vport: 70000}},
+			errorMsg: "vport: mac 02:00:00:00:00:01: invalid vport 70000",
+		},
+		"duplicate mac": {
+			entries:  []TableEntry{{Mac: "02:00:00:00:00:01", Vport: 24}, {Mac: "02:00:00:00:00:01", Vport: 25}},
+			errorMsg: "vport: duplicate mac 02:00:00:00:00:01",
+		},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := NewTableResolver(tt.entries)
+			if tt.errorMsg == "" && err != nil {
+				t.Errorf("Expected no error, received %v", err)
+			}
+			if tt.errorMsg != "" && (err == nil || err.Error() != tt.errorMsg) {
+				t.Errorf("Expected error: %s, received %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestTableResolver_Vport(t *testing.T) {
+	r, err := NewTableResolver([]TableEntry{{Mac: "02:00:00:00:00:01", Vport: 24}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	known, _ := net.ParseMAC("02:00:00:00:00:01")
+	if vport, err := r.Vport(known); err != nil || vport != 24 {
+		t.Errorf("Expected vport 24, received %d %v", vport, err)
+	}
+	unknown, _ := net.ParseMAC("02:00:00:00:00:02")
+	if _, err := r.Vport(unknown); err == nil || err.Error() != "vport: no vport for mac 02:00:00:00:00:02" {
+		t.Errorf("Expected no vport for an unknown mac, received %v", err)
+	}
+}
+
+func TestSysfsResolver_Vport(t *testing.T) {
+	root := t.TempDir()
+	for name, value := range map[string]string{"vm1": "24\n", "vm2": "0x19\n", "vm3": "none\n"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name, "dev_port"), []byte(value), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(name string, mac string) netlink.Link {
+		hw, _ := net.ParseMAC(mac)
+		return &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: name, HardwareAddr: hw}}
+	}
+	r := NewSysfsResolver("")
+	r.Root = root
+	r.links = func() ([]netlink.Link, error) {
+		return []netlink.Link{
+			link("vm1", "02:00:00:00:00:01"),
+			link("vm2", "02:00:00:00:00:02"),
+			link("vm3", "02:00:00:00:00:03"),
+		}, nil
+	}
+
+	tests := map[string]struct {
+		mac   string
+		vport int
+		err   bool
+	}{
+		"decimal attribute": {mac: "02:00:00:00:00:01", vport: 24},
+		"hex attribute":     {mac: "02:00:00:00:00:02", vport: 25},
+		"invalid attribute": {mac: "02:00:00:00:00:03", err: true},
+		"no interface":      {mac: "02:00:00:00:00:04", err: true},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			mac, _ := net.ParseMAC(tt.mac)
+			vport, err := r.Vport(mac)
+			if tt.err != (err != nil) {
+				t.Fatalf("Expected error %v, received %v", tt.err, err)
+			}
+			if !tt.err && vport != tt.vport {
+				t.Errorf("Expected vport %d, received %d", tt.vport, vport)
+			}
+		})
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	defer Set(MacResolver{})
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+
+	r, err := NewResolver(Config{Resolver: "table", Table: []TableEntry{{Mac: "02:00:00:00:00:01", Vport: 24}}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	Set(r)
+	if vport, err := Of(mac); err != nil || vport != 24 {
+		t.Errorf("Expected vport 24 of the table, received %d %v", vport, err)
+	}
+
+	if _, err := NewResolver(Config{Resolver: "vsi"}); err == nil || err.Error() != "vport: unknown resolver vsi" {
+		t.Errorf("Expected an unknown resolver, received %v", err)
+	}
+
+	r, err = NewResolver(Config{})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	Set(r)
+	if vport, err := Of(mac); err != nil || vport != 512 {
+		t.Errorf("Expected vport 512 of the mac, received %d %v", vport, err)
+	}
+}
+
+func TestOfRepresentor(t *testing.T) {
+	defer Set(MacResolver{})
+	r, err := NewTableResolver([]TableEntry{{Mac: "00:18:00:00:00:01", Vport: 40}})
+	if err != nil {
+		t.Fatalf("Expected no error, received %v", err)
+	}
+	Set(r)
+
+	tests := map[string]struct {
+		mac   string
+		vport int
+	}{
+		"listed bridge port":   {mac: "00:18:00:00:00:01", vport: 40},
+		"unlisted representor": {mac: "00:1a:00:00:03:14", vport: 26},
+	}
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			mac, _ := net.ParseMAC(tt.mac)
+			vport, err := OfRepresentor(mac)
+			if err != nil || vport != tt.vport {
+				t.Errorf("Expected vport %d, received %d %v", tt.vport, vport, err)
+			}
+		})
+	}
+	unlisted, _ := net.ParseMAC("00:1a:00:00:03:14")
+	if _, err := Of(unlisted); err == nil {
+		t.Errorf("Expected no vport for an unlisted bridge port")
+	}
+}